@@ -0,0 +1,124 @@
+package safeinput
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultMaxKeyLength is the key length limit used when ValidateKeys is set
+// and MaxKeyLength is zero.
+const defaultMaxKeyLength = 256
+
+// FieldError reports the key of a map or form entry, or the path of a
+// struct field, that failed sanitization. Use errors.As on the error
+// returned by SanitizeMap, SanitizeValues or SanitizeStruct to list every
+// failing field.
+type FieldError struct {
+	Key string
+	Err error
+}
+
+// Error formats the error with the quoted key.
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field %q: %v", e.Key, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// SanitizeMap sanitizes every value in values for ctx. It returns the
+// sanitized entries along with one error joining a *FieldError for each key
+// that failed, ordered by key; failed keys are left out of the result.
+func (s *Sanitizer) SanitizeMap(values map[string]string, ctx Context) (map[string]string, error) {
+	out := make(map[string]string, len(values))
+	var errs []error
+	for _, key := range sortedKeys(values) {
+		if err := s.checkKey(key); err != nil {
+			errs = append(errs, &FieldError{Key: key, Err: err})
+			continue
+		}
+		v, err := s.Sanitize(values[key], ctx)
+		if err != nil {
+			errs = append(errs, &FieldError{Key: key, Err: err})
+			continue
+		}
+		out[key] = v
+	}
+	return out, errors.Join(errs...)
+}
+
+// SanitizeValues sanitizes each form field in values for the context
+// contexts gives its key. Keys without a context fail with
+// ErrUnexpectedKey, so contexts also acts as an allowlist. As with
+// SanitizeMap, the result holds every field whose values all passed and
+// the error names each failing key once.
+func (s *Sanitizer) SanitizeValues(values url.Values, contexts map[string]Context) (url.Values, error) {
+	out := make(url.Values, len(values))
+	var errs []error
+	for _, key := range sortedKeys(values) {
+		if err := s.sanitizeField(out, key, values[key], contexts); err != nil {
+			errs = append(errs, &FieldError{Key: key, Err: err})
+		}
+	}
+	return out, errors.Join(errs...)
+}
+
+// sanitizeField sanitizes the values of one form field into out.
+func (s *Sanitizer) sanitizeField(out url.Values, key string, values []string, contexts map[string]Context) error {
+	if err := s.checkKey(key); err != nil {
+		return err
+	}
+	ctx, ok := contexts[key]
+	if !ok {
+		return ErrUnexpectedKey
+	}
+	sanitized := make([]string, len(values))
+	for i, v := range values {
+		var err error
+		if sanitized[i], err = s.Sanitize(v, ctx); err != nil {
+			return err
+		}
+	}
+	out[key] = sanitized
+	return nil
+}
+
+// checkKey validates a map or form key when ValidateKeys is set. Keys must
+// be non-empty, valid UTF-8 without control characters, and no longer than
+// MaxKeyLength bytes.
+func (s *Sanitizer) checkKey(key string) error {
+	if !s.config.ValidateKeys {
+		return nil
+	}
+	limit := s.config.MaxKeyLength
+	if limit == 0 {
+		limit = defaultMaxKeyLength
+	}
+	if len(key) > limit {
+		return fmt.Errorf("%w: key is longer than %d bytes", ErrInvalidKey, limit)
+	}
+	if key == "" || !utf8.ValidString(key) {
+		return ErrInvalidKey
+	}
+	for _, r := range key {
+		if unicode.IsControl(r) || r == '\u2028' || r == '\u2029' {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
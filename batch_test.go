@@ -0,0 +1,125 @@
+package safeinput
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fieldErrors returns the error for each key joined in err
+func fieldErrors(t *testing.T, err error) map[string]error {
+	t.Helper()
+	got := make(map[string]error)
+	if err == nil {
+		return got
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected a joined error, got %T", err)
+	}
+	for _, e := range joined.Unwrap() {
+		var fe *FieldError
+		if !errors.As(e, &fe) {
+			t.Fatalf("expected *FieldError, got %T", e)
+		}
+		got[fe.Key] = fe.Err
+	}
+	return got
+}
+
+func TestSanitizeMap(t *testing.T) {
+	s := Default()
+	out, err := s.SanitizeMap(map[string]string{
+		"name":    "alice",
+		"comment": "<b>hi</b>",
+		"bio":     "a\x00b",
+		"long":    strings.Repeat("x", 10001),
+	}, HTMLBody)
+
+	want := map[string]string{"name": "alice", "comment": "hi", "bio": "ab"}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("got %v, want %v", out, want)
+	}
+	errs := fieldErrors(t, err)
+	if len(errs) != 1 || !errors.Is(errs["long"], ErrInputTooLong) {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if !errors.Is(err, ErrInputTooLong) || !strings.Contains(err.Error(), `field "long"`) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	out, err = s.SanitizeMap(nil, HTMLBody)
+	if err != nil || out == nil || len(out) != 0 {
+		t.Errorf("SanitizeMap(nil) = %v, %v", out, err)
+	}
+}
+
+func TestSanitizeValues(t *testing.T) {
+	s := Default()
+	form := url.Values{
+		"name":     {"<i>Bob</i>"},
+		"file":     {"report.pdf", "../../etc/passwd"},
+		"id":       {"f47ac10b-58cc-4372-a567-0e02b2c3d479"},
+		"is_admin": {"true"},
+	}
+	contexts := map[string]Context{
+		"name": HTMLBody,
+		"file": FilePath,
+		"id":   UUID,
+		"tags": HTMLBody,
+	}
+	out, err := s.SanitizeValues(form, contexts)
+
+	want := url.Values{"name": {"Bob"}, "id": {"f47ac10b-58cc-4372-a567-0e02b2c3d479"}}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("got %v, want %v", out, want)
+	}
+	errs := fieldErrors(t, err)
+	if len(errs) != 2 || errs["file"] == nil || !errors.Is(errs["is_admin"], ErrUnexpectedKey) {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	// Errors are ordered by key
+	if msg := err.Error(); strings.Index(msg, `"file"`) > strings.Index(msg, `"is_admin"`) {
+		t.Errorf("errors out of order: %v", msg)
+	}
+}
+
+func TestSanitizeMap_ValidateKeys(t *testing.T) {
+	values := map[string]string{
+		"ok":                     "v",
+		"":                       "v",
+		"bad\nkey":               "v",
+		"bad\u2028key":           "v",
+		"bad\xffkey":             "v",
+		strings.Repeat("k", 300): "v",
+		strings.Repeat("k", 256): "v",
+	}
+
+	// Keys are not checked by default
+	out, err := Default().SanitizeMap(values, LogLine)
+	if err != nil || len(out) != len(values) {
+		t.Fatalf("unexpected result %v, %v", out, err)
+	}
+
+	s := New(Config{StrictMode: true, ValidateKeys: true})
+	out, err = s.SanitizeMap(values, LogLine)
+	if len(out) != 2 {
+		t.Errorf("expected 2 valid keys, got %v", out)
+	}
+	errs := fieldErrors(t, err)
+	if len(errs) != 5 {
+		t.Errorf("expected 5 errors, got %v", errs)
+	}
+	for key, e := range errs {
+		if !errors.Is(e, ErrInvalidKey) {
+			t.Errorf("key %q: expected ErrInvalidKey, got %v", key, e)
+		}
+	}
+
+	s = New(Config{ValidateKeys: true, MaxKeyLength: 2})
+	if _, err := s.SanitizeValues(url.Values{"abc": {"x"}}, map[string]Context{"abc": LogLine}); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package safeinput
+
+import (
+	"strings"
+	"unicode"
+)
+
+// IndexBidiControl returns the byte offset of the first Unicode
+// bidirectional control character in input, or -1 if there is none. These
+// are the embeddings, overrides and isolates (U+202A to U+202E, U+2066 to
+// U+2069) that Trojan Source attacks use to make text display in a
+// different order than it is stored, and the marks U+061C, U+200E and
+// U+200F.
+func IndexBidiControl(input string) int {
+	return strings.IndexFunc(input, isBidiControl)
+}
+
+// StripBidiControls removes Unicode bidirectional control characters from
+// a string. Other bytes, including invalid UTF-8, are kept as they are.
+func StripBidiControls(input string) string {
+	if IndexBidiControl(input) < 0 {
+		return input
+	}
+	return stripRunes(input, isBidiControl)
+}
+
+func isBidiControl(r rune) bool {
+	return unicode.Is(unicode.Bidi_Control, r)
+}
+
+// checkBidi applies RejectBidiControls and StripBidiControls to input.
+func (s *Sanitizer) checkBidi(input string) (string, error) {
+	if !s.config.RejectBidiControls && !s.config.StripBidiControls {
+		return input, nil
+	}
+	if IndexBidiControl(input) < 0 {
+		return input, nil
+	}
+	if s.config.RejectBidiControls {
+		return "", ErrBidiControl
+	}
+	return StripBidiControls(input), nil
+}
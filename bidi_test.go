@@ -0,0 +1,87 @@
+package safeinput
+
+import (
+	"errors"
+	"testing"
+)
+
+// spoofedName displays as "invoicexe.jpg" but is an executable
+const spoofedName = "invoice\u202egpj.exe"
+
+func TestIndexBidiControl(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"invoice.pdf", -1},
+		{"", -1},
+		{"café שלום", -1}, // Hebrew text itself is fine
+		{spoofedName, 7},
+		{"\u2066a\u2069", 0},
+		{"a\u200fb", 1},
+		{"x\u061c", 1},
+		{"ab\u202a", 2},
+	}
+	for _, tt := range tests {
+		if got := IndexBidiControl(tt.input); got != tt.want {
+			t.Errorf("IndexBidiControl(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+	if got := StripBidiControls("\u202aa\u202bb\u202cc\u202dd\u202ee\u2066f\u2067g\u2068h\u2069\u200e\u200f\u061c"); got != "abcdefgh" {
+		t.Errorf("StripBidiControls = %q", got)
+	}
+}
+
+func TestSanitize_BidiControls(t *testing.T) {
+	reject := New(Config{RejectBidiControls: true, StripBidiControls: true})
+	strip := New(Config{StripBidiControls: true})
+	off := New(Config{})
+
+	for _, ctx := range []Context{Filename, HTMLBody, LogLine, CSVField} {
+		if _, err := reject.Sanitize(spoofedName, ctx); !errors.Is(err, ErrBidiControl) {
+			t.Errorf("%v: expected ErrBidiControl, got %v", ctx, err)
+		}
+		if err := strip.Validate(spoofedName, ctx); !errors.Is(err, ErrBidiControl) {
+			t.Errorf("%v: Validate expected ErrBidiControl, got %v", ctx, err)
+		}
+	}
+
+	if got, err := strip.Sanitize(spoofedName, Filename); err != nil || got != "invoicegpj.exe" {
+		t.Errorf("strip: got %q, %v", got, err)
+	}
+	if got, err := off.Sanitize(spoofedName, Filename); err != nil || got != spoofedName {
+		t.Errorf("off: got %q, %v", got, err)
+	}
+	if err := off.Validate(spoofedName, Filename); err != nil {
+		t.Errorf("off: Validate = %v", err)
+	}
+}
+
+func TestSanitizeDetailed_BidiControls(t *testing.T) {
+	s := New(Config{StripBidiControls: true, StripNullBytes: true})
+	res, err := s.SanitizeDetailed("a\u202e\x00<b>x</b>", HTMLBody)
+	if err != nil || res.Output != "ax" {
+		t.Fatalf("unexpected result %+v, %v", res, err)
+	}
+	want := []Finding{
+		{Kind: FindingRemovedBidiControl, Text: "\u202e", Offset: 1, Severity: SeveritySuspicious},
+		{Kind: FindingRemovedNullByte, Text: "\x00", Offset: 4, Severity: SeveritySuspicious},
+		{Kind: FindingRemovedTag, Text: "<b>", Detail: "b", Offset: 5, Severity: SeverityInfo},
+		{Kind: FindingRemovedTag, Text: "</b>", Detail: "b", Offset: 9, Severity: SeverityInfo},
+	}
+	if len(res.Findings) != len(want) {
+		t.Fatalf("expected %d findings, got %+v", len(want), res.Findings)
+	}
+	for i, f := range res.Findings {
+		if f != want[i] {
+			t.Errorf("finding %d = %+v, want %+v", i, f, want[i])
+		}
+	}
+
+	s = New(Config{RejectBidiControls: true})
+	res, err = s.SanitizeDetailed(spoofedName, Filename)
+	if !errors.Is(err, ErrBidiControl) || len(res.Findings) != 1 ||
+		res.Findings[0].Offset != 7 || res.Findings[0].Text != "\u202e" {
+		t.Errorf("unexpected rejection %+v, %v", res, err)
+	}
+}
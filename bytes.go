@@ -0,0 +1,137 @@
+package safeinput
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// text is the input types the append functions shared by the string and
+// byte slice APIs accept.
+type text interface {
+	string | []byte
+}
+
+// decodeRune decodes the first rune of s like utf8.DecodeRuneInString and
+// utf8.DecodeRune.
+func decodeRune[T text](s T) (rune, int) {
+	if len(s) > 0 && s[0] < utf8.RuneSelf {
+		return rune(s[0]), 1
+	}
+	switch v := any(s).(type) {
+	case string:
+		return utf8.DecodeRuneInString(v)
+	case []byte:
+		return utf8.DecodeRune(v)
+	}
+	panic("unreachable")
+}
+
+// SanitizeBytes processes input for the specified context like Sanitize,
+// returning the result in a new slice.
+func (s *Sanitizer) SanitizeBytes(input []byte, ctx Context) ([]byte, error) {
+	return s.AppendSanitized(make([]byte, 0, len(input)), input, ctx)
+}
+
+// AppendSanitized appends input processed for ctx to dst and returns the
+// extended slice, or dst unchanged and the error Sanitize would return.
+// The output is the same as Sanitize's. HTMLAttribute, ShellArg and
+// LogLine work on input directly, without converting it to a string, when
+// the shared checks have nothing to do but strip null bytes; other
+// contexts, and input that needs normalizing or has bidi controls or
+// invisible characters to handle, go through Sanitize.
+func (s *Sanitizer) AppendSanitized(dst, input []byte, ctx Context) ([]byte, error) {
+	if !s.appendsDirectly(input, ctx) {
+		out, err := s.Sanitize(string(input), ctx)
+		if err != nil {
+			return dst, err
+		}
+		return append(dst, out...), nil
+	}
+
+	switch ctx {
+	case HTMLAttribute:
+		return appendHTMLEscaped(dst, input), nil
+	case ShellArg:
+		return appendShellArg(dst, input, s.shellExtra, nil), nil
+	default: // LogLine
+		return appendLogLine(dst, input, s.config.MaxLogLength), nil
+	}
+}
+
+// appendsDirectly reports whether AppendSanitized can process input for ctx
+// without Sanitize: ctx is HTMLAttribute, ShellArg or LogLine, input is
+// within the length limit and already normalized, and the shared checks
+// would at most strip null bytes, which those contexts skip. LogLine also
+// needs input without null bytes, since stripping one can join an ANSI
+// escape sequence.
+func (s *Sanitizer) appendsDirectly(input []byte, ctx Context) bool {
+	switch ctx {
+	case HTMLAttribute, ShellArg:
+		if bytes.IndexByte(input, 0) >= 0 && !s.stripsNullBytes(ctx) {
+			return false
+		}
+	case LogLine:
+		if bytes.IndexByte(input, 0) >= 0 {
+			return false
+		}
+	default:
+		return false
+	}
+	if len(input) > s.maxLength(ctx) {
+		return false
+	}
+	if isASCII(input) {
+		// ASCII is in NFC and has no bidi controls or invisible characters
+		return true
+	}
+	if s.config.NormalizeUnicode && !norm.NFC.IsNormal(input) {
+		return false
+	}
+	if (s.config.RejectBidiControls || s.config.StripBidiControls) && bytes.IndexFunc(input, isBidiControl) >= 0 {
+		return false
+	}
+	if (s.config.RejectInvisible || s.config.StripInvisible) && bytes.IndexFunc(input, isInvisibleRune) >= 0 {
+		return false
+	}
+	return true
+}
+
+// isASCII reports whether input consists only of ASCII characters.
+func isASCII(input []byte) bool {
+	for _, c := range input {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// appendHTMLEscaped appends input to dst escaped as html.EscapeString
+// escapes it, skipping null bytes.
+func appendHTMLEscaped(dst, input []byte) []byte {
+	last := 0
+	for i, c := range input {
+		var escaped string
+		switch c {
+		case 0:
+		case '<':
+			escaped = "&lt;"
+		case '>':
+			escaped = "&gt;"
+		case '&':
+			escaped = "&amp;"
+		case '\'':
+			escaped = "&#39;"
+		case '"':
+			escaped = "&#34;"
+		default:
+			continue
+		}
+		dst = append(dst, input[last:i]...)
+		dst = append(dst, escaped...)
+		last = i + 1
+	}
+	return append(dst, input[last:]...)
+}
@@ -0,0 +1,153 @@
+package safeinput
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ravisastryk/go-safeinput/path"
+)
+
+// byteInputs exercise the direct paths of AppendSanitized and its
+// fallbacks
+var byteInputs = []string{
+	"",
+	"plain text",
+	`<a href="x">Tom & Jerry's</a>`,
+	"file; rm -rf / && echo $HOME",
+	"a\x00b<c>\x00",
+	"line1\nline2\r\n\tend",
+	"\x1b[31mred\x1b[0m \x1b]0;title\a \u009b2J",
+	"\x1b\x00[31m",
+	"bad \xff\xfe utf8",
+	"café café",
+	"‮evil​",
+	"--name=value@host:1,2",
+	strings.Repeat("x", 20),
+}
+
+func TestSanitizeBytes_MatchesSanitize(t *testing.T) {
+	sanitizers := map[string]*Sanitizer{
+		"default": Default(),
+		"plain":   New(Config{}),
+		"custom": New(Config{
+			StripNullBytes:     true,
+			RejectBidiControls: true,
+			ShellExtraAllowed:  "=@:,",
+			ShellAllowSpace:    true,
+			MaxLogLength:       12,
+			MaxLengths:         map[Context]int{HTMLAttribute: 16},
+		}),
+	}
+	contexts := []Context{HTMLAttribute, ShellArg, LogLine, HTMLBody, SQLValue, FilePath, ShellArgQuoted}
+	for name, s := range sanitizers {
+		for _, ctx := range contexts {
+			for _, input := range byteInputs {
+				want, wantErr := s.Sanitize(input, ctx)
+				got, err := s.SanitizeBytes([]byte(input), ctx)
+				if fmt.Sprint(err) != fmt.Sprint(wantErr) || string(got) != want {
+					t.Errorf("%s %v %q: SanitizeBytes = %q, %v, want %q, %v", name, ctx, input, got, err, want, wantErr)
+				}
+			}
+		}
+	}
+}
+
+func TestAppendSanitized(t *testing.T) {
+	s := Default()
+	dst := []byte("prefix:")
+	dst, err := s.AppendSanitized(dst, []byte(`"a"&b`), HTMLAttribute)
+	if err != nil || string(dst) != "prefix:&#34;a&#34;&amp;b" {
+		t.Errorf("AppendSanitized = %q, %v", dst, err)
+	}
+	dst, err = s.AppendSanitized(dst, []byte(",x;y"), ShellArg)
+	if err != nil || string(dst) != "prefix:&#34;a&#34;&amp;bxy" {
+		t.Errorf("AppendSanitized = %q, %v", dst, err)
+	}
+
+	before := string(dst)
+	dst, err = s.AppendSanitized(dst, []byte("../etc"), FilePath)
+	if !errors.Is(err, path.ErrPathTraversal) || string(dst) != before {
+		t.Errorf("AppendSanitized on error = %q, %v", dst, err)
+	}
+}
+
+func TestAppendSanitized_Allocations(t *testing.T) {
+	s := Default()
+	input := []byte(strings.Repeat(`say "hi" & <wave>; `, 50))
+	dst := make([]byte, 0, 4*len(input))
+	for _, ctx := range []Context{HTMLAttribute, ShellArg, LogLine} {
+		allocs := testing.AllocsPerRun(100, func() {
+			_, _ = s.AppendSanitized(dst[:0], input, ctx)
+		})
+		if allocs != 0 {
+			t.Errorf("AppendSanitized(%v) allocated %v times", ctx, allocs)
+		}
+	}
+}
+
+func benchmarkInput() []byte {
+	return bytes.Repeat([]byte("user <b>said</b> \"hi\" & left; "), 1024/32)
+}
+
+func BenchmarkSanitize_HTMLAttribute1KB(b *testing.B) {
+	s := Default()
+	input := benchmarkInput()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		out, _ := s.Sanitize(string(input), HTMLAttribute)
+		_ = []byte(out)
+	}
+}
+
+func BenchmarkAppendSanitized_HTMLAttribute1KB(b *testing.B) {
+	s := Default()
+	input := benchmarkInput()
+	dst := make([]byte, 0, 2*len(input))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		dst, _ = s.AppendSanitized(dst[:0], input, HTMLAttribute)
+	}
+}
+
+func BenchmarkSanitize_ShellArg1KB(b *testing.B) {
+	s := Default()
+	input := benchmarkInput()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		out, _ := s.Sanitize(string(input), ShellArg)
+		_ = []byte(out)
+	}
+}
+
+func BenchmarkAppendSanitized_ShellArg1KB(b *testing.B) {
+	s := Default()
+	input := benchmarkInput()
+	dst := make([]byte, 0, len(input))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		dst, _ = s.AppendSanitized(dst[:0], input, ShellArg)
+	}
+}
+
+func BenchmarkSanitize_LogLine1KB(b *testing.B) {
+	s := Default()
+	input := benchmarkInput()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		out, _ := s.Sanitize(string(input), LogLine)
+		_ = []byte(out)
+	}
+}
+
+func BenchmarkAppendSanitized_LogLine1KB(b *testing.B) {
+	s := Default()
+	input := benchmarkInput()
+	dst := make([]byte, 0, len(input))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		dst, _ = s.AppendSanitized(dst[:0], input, LogLine)
+	}
+}
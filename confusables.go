@@ -0,0 +1,119 @@
+package safeinput
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DetectConfusables returns the UTS #39 skeleton of input, under which
+// strings that look alike compare equal, and reports whether input mixes
+// scripts. "admin" spelled with a Cyrillic а has the skeleton "admin", the
+// same as the all-Latin spelling, and is mixed-script. Compare skeletons to
+// catch look-alike user names; the skeleton itself is not meant for
+// display. Only the common confusables of Latin, Cyrillic, Greek, Armenian,
+// Cherokee and Lisu and the fullwidth forms are mapped.
+//
+// Input is mixed-script if its letters come from more than one script,
+// other than the combinations UTS #39 allows at its Highly Restrictive
+// level: Latin with Han, Hiragana and Katakana, with Han and Bopomofo, or
+// with Han and Hangul. Digits, punctuation and combining marks belong to no
+// script of their own.
+func (s *Sanitizer) DetectConfusables(input string) (skeleton string, mixedScript bool) {
+	return confusableSkeleton(input), isMixedScript(input)
+}
+
+// confusableSkeleton computes NFD(map(NFD(input))) with the confusables table.
+func confusableSkeleton(input string) string {
+	mapped := strings.Map(func(r rune) rune {
+		if c, ok := confusables[r]; ok {
+			return c
+		}
+		return r
+	}, norm.NFD.String(input))
+	return norm.NFD.String(mapped)
+}
+
+// commonScripts are checked before the rest of unicode.Scripts when
+// finding the script of a character.
+var commonScripts = []string{"Latin", "Cyrillic", "Greek", "Han", "Hiragana", "Katakana", "Hangul", "Arabic", "Hebrew"}
+
+// allowedScriptSets are the script combinations UTS #39 allows in one
+// identifier at its Highly Restrictive level.
+var allowedScriptSets = [][]string{
+	{"Latin", "Han", "Hiragana", "Katakana"},
+	{"Latin", "Han", "Bopomofo"},
+	{"Latin", "Han", "Hangul"},
+}
+
+// isMixedScript reports whether the letters of input come from a
+// combination of scripts that allowedScriptSets does not cover.
+func isMixedScript(input string) bool {
+	var scripts []string
+	for _, r := range input {
+		script := scriptOf(r)
+		if script != "" && !slices.Contains(scripts, script) {
+			scripts = append(scripts, script)
+		}
+	}
+	if len(scripts) <= 1 {
+		return false
+	}
+	for _, allowed := range allowedScriptSets {
+		if !slices.ContainsFunc(scripts, func(s string) bool { return !slices.Contains(allowed, s) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// scriptOf returns the Unicode script of r, or "" for characters of the
+// Common and Inherited scripts and unassigned code points.
+func scriptOf(r rune) string {
+	if r < 0x80 {
+		if unicode.IsLetter(r) {
+			return "Latin"
+		}
+		return ""
+	}
+	if unicode.In(r, unicode.Common, unicode.Inherited) {
+		return ""
+	}
+	for _, name := range commonScripts {
+		if unicode.Is(unicode.Scripts[name], r) {
+			return name
+		}
+	}
+	for name, table := range unicode.Scripts {
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
+
+// mixedScriptContexts are the identifier-like contexts RejectMixedScript
+// applies to.
+var mixedScriptContexts = map[Context]bool{
+	SQLIdentifier: true,
+	Filename:      true,
+	Hostname:      true,
+}
+
+// checkMixedScript applies RejectMixedScript to input for ctx. Each
+// dot-separated part is checked on its own, so a Cyrillic file name with a
+// Latin extension, or an internationalized domain under a Latin TLD, is
+// accepted.
+func (s *Sanitizer) checkMixedScript(input string, ctx Context) error {
+	if !s.config.RejectMixedScript || !mixedScriptContexts[ctx] {
+		return nil
+	}
+	for _, part := range strings.Split(input, ".") {
+		if isMixedScript(part) {
+			return ErrMixedScript
+		}
+	}
+	return nil
+}
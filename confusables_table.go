@@ -0,0 +1,226 @@
+package safeinput
+
+// confusables maps characters that look like Latin letters or digits to
+// their UTS #39 skeleton character. It covers the common confusables from
+// the Unicode confusables.txt data for Latin, Cyrillic, Greek, Armenian,
+// Cherokee and Lisu, plus the fullwidth forms, rather than the whole file.
+var confusables = map[rune]rune{
+	// Latin and Common
+	'0':      'O', // DIGIT ZERO
+	'1':      'l', // DIGIT ONE
+	'I':      'l', // LATIN CAPITAL LETTER I
+	'|':      'l', // VERTICAL LINE
+	'\u01c0': 'l', // LATIN LETTER DENTAL CLICK
+	'\u0131': 'i', // LATIN SMALL LETTER DOTLESS I
+	'\u0251': 'a', // LATIN SMALL LETTER ALPHA
+	'\u0261': 'g', // LATIN SMALL LETTER SCRIPT G
+	'\u0269': 'i', // LATIN SMALL LETTER IOTA
+	'\u1d04': 'c', // LATIN LETTER SMALL CAPITAL C
+	'\u1d0f': 'o', // LATIN LETTER SMALL CAPITAL O
+	'\u1d1c': 'u', // LATIN LETTER SMALL CAPITAL U
+	'\u1d20': 'v', // LATIN LETTER SMALL CAPITAL V
+	'\u1d21': 'w', // LATIN LETTER SMALL CAPITAL W
+	'\u1d22': 'z', // LATIN LETTER SMALL CAPITAL Z
+	// Number forms
+	'\u2170': 'i', // SMALL ROMAN NUMERAL ONE
+	'\u217c': 'l', // SMALL ROMAN NUMERAL FIFTY
+	'\u217d': 'c', // SMALL ROMAN NUMERAL ONE HUNDRED
+	'\u217e': 'd', // SMALL ROMAN NUMERAL FIVE HUNDRED
+	'\u217f': 'm', // SMALL ROMAN NUMERAL ONE THOUSAND
+	'\u2174': 'v', // SMALL ROMAN NUMERAL FIVE
+	'\u2179': 'x', // SMALL ROMAN NUMERAL TEN
+	'\u2160': 'l', // ROMAN NUMERAL ONE
+	'\u2164': 'V', // ROMAN NUMERAL FIVE
+	'\u2169': 'X', // ROMAN NUMERAL TEN
+	'\u216d': 'C', // ROMAN NUMERAL ONE HUNDRED
+	'\u216e': 'D', // ROMAN NUMERAL FIVE HUNDRED
+	'\u216f': 'M', // ROMAN NUMERAL ONE THOUSAND
+	'\u216c': 'L', // ROMAN NUMERAL FIFTY
+	// Cyrillic small letters
+	'\u0430': 'a', // CYRILLIC SMALL LETTER A
+	'\u0435': 'e', // CYRILLIC SMALL LETTER IE
+	'\u043e': 'o', // CYRILLIC SMALL LETTER O
+	'\u0440': 'p', // CYRILLIC SMALL LETTER ER
+	'\u0441': 'c', // CYRILLIC SMALL LETTER ES
+	'\u0443': 'y', // CYRILLIC SMALL LETTER U
+	'\u0445': 'x', // CYRILLIC SMALL LETTER HA
+	'\u0455': 's', // CYRILLIC SMALL LETTER DZE
+	'\u0456': 'i', // CYRILLIC SMALL LETTER BYELORUSSIAN-UKRAINIAN I
+	'\u0458': 'j', // CYRILLIC SMALL LETTER JE
+	'\u0501': 'd', // CYRILLIC SMALL LETTER KOMI DE
+	'\u051b': 'q', // CYRILLIC SMALL LETTER QA
+	'\u051d': 'w', // CYRILLIC SMALL LETTER WE
+	'\u04bb': 'h', // CYRILLIC SMALL LETTER SHHA
+	'\u04cf': 'l', // CYRILLIC SMALL LETTER PALOCHKA
+	'\u04af': 'y', // CYRILLIC SMALL LETTER STRAIGHT U
+	'\u0475': 'v', // CYRILLIC SMALL LETTER IZHITSA
+	// Cyrillic capital letters
+	'\u0410': 'A', // CYRILLIC CAPITAL LETTER A
+	'\u0412': 'B', // CYRILLIC CAPITAL LETTER VE
+	'\u0415': 'E', // CYRILLIC CAPITAL LETTER IE
+	'\u041a': 'K', // CYRILLIC CAPITAL LETTER KA
+	'\u041c': 'M', // CYRILLIC CAPITAL LETTER EM
+	'\u041d': 'H', // CYRILLIC CAPITAL LETTER EN
+	'\u041e': 'O', // CYRILLIC CAPITAL LETTER O
+	'\u0420': 'P', // CYRILLIC CAPITAL LETTER ER
+	'\u0421': 'C', // CYRILLIC CAPITAL LETTER ES
+	'\u0422': 'T', // CYRILLIC CAPITAL LETTER TE
+	'\u0423': 'Y', // CYRILLIC CAPITAL LETTER U
+	'\u0425': 'X', // CYRILLIC CAPITAL LETTER HA
+	'\u0405': 'S', // CYRILLIC CAPITAL LETTER DZE
+	'\u0406': 'l', // CYRILLIC CAPITAL LETTER BYELORUSSIAN-UKRAINIAN I
+	'\u0408': 'J', // CYRILLIC CAPITAL LETTER JE
+	'\u051a': 'Q', // CYRILLIC CAPITAL LETTER QA
+	'\u051c': 'W', // CYRILLIC CAPITAL LETTER WE
+	'\u04c0': 'l', // CYRILLIC LETTER PALOCHKA
+	'\u04ae': 'Y', // CYRILLIC CAPITAL LETTER STRAIGHT U
+	'\u0417': '3', // CYRILLIC CAPITAL LETTER ZE
+	'\u0474': 'V', // CYRILLIC CAPITAL LETTER IZHITSA
+	'\u050c': 'G', // CYRILLIC CAPITAL LETTER KOMI SJE
+	// Greek capital letters
+	'\u0391': 'A', // GREEK CAPITAL LETTER ALPHA
+	'\u0392': 'B', // GREEK CAPITAL LETTER BETA
+	'\u0395': 'E', // GREEK CAPITAL LETTER EPSILON
+	'\u0396': 'Z', // GREEK CAPITAL LETTER ZETA
+	'\u0397': 'H', // GREEK CAPITAL LETTER ETA
+	'\u0399': 'l', // GREEK CAPITAL LETTER IOTA
+	'\u039a': 'K', // GREEK CAPITAL LETTER KAPPA
+	'\u039c': 'M', // GREEK CAPITAL LETTER MU
+	'\u039d': 'N', // GREEK CAPITAL LETTER NU
+	'\u039f': 'O', // GREEK CAPITAL LETTER OMICRON
+	'\u03a1': 'P', // GREEK CAPITAL LETTER RHO
+	'\u03a4': 'T', // GREEK CAPITAL LETTER TAU
+	'\u03a5': 'Y', // GREEK CAPITAL LETTER UPSILON
+	'\u03a7': 'X', // GREEK CAPITAL LETTER CHI
+	'\u03f9': 'C', // GREEK CAPITAL LUNATE SIGMA SYMBOL
+	'\u037f': 'J', // GREEK CAPITAL LETTER YOT
+	// Greek small letters
+	'\u03b1': 'a', // GREEK SMALL LETTER ALPHA
+	'\u03b9': 'i', // GREEK SMALL LETTER IOTA
+	'\u03bd': 'v', // GREEK SMALL LETTER NU
+	'\u03bf': 'o', // GREEK SMALL LETTER OMICRON
+	'\u03c1': 'p', // GREEK SMALL LETTER RHO
+	'\u03c5': 'u', // GREEK SMALL LETTER UPSILON
+	'\u03f2': 'c', // GREEK LUNATE SIGMA SYMBOL
+	'\u03f3': 'j', // GREEK LETTER YOT
+	'\u03b3': 'y', // GREEK SMALL LETTER GAMMA
+	// Armenian
+	'\u0585': 'o', // ARMENIAN SMALL LETTER OH
+	'\u057d': 'u', // ARMENIAN SMALL LETTER SEH
+	'\u0570': 'h', // ARMENIAN SMALL LETTER HO
+	'\u0578': 'n', // ARMENIAN SMALL LETTER VO
+	'\u0581': 'g', // ARMENIAN SMALL LETTER CO
+	'\u0566': 'q', // ARMENIAN SMALL LETTER ZA
+	'\u0555': 'O', // ARMENIAN CAPITAL LETTER OH
+	'\u054d': 'U', // ARMENIAN CAPITAL LETTER SEH
+	// Cherokee
+	'\u13aa': 'A', // CHEROKEE LETTER GO
+	'\u13f4': 'B', // CHEROKEE LETTER YV
+	'\u13df': 'C', // CHEROKEE LETTER TLI
+	'\u13a0': 'D', // CHEROKEE LETTER A
+	'\u13ac': 'E', // CHEROKEE LETTER GV
+	'\u13c0': 'G', // CHEROKEE LETTER NAH
+	'\u13bb': 'H', // CHEROKEE LETTER MI
+	'\u13ab': 'J', // CHEROKEE LETTER GU
+	'\u13e6': 'K', // CHEROKEE LETTER TSO
+	'\u13de': 'L', // CHEROKEE LETTER TLE
+	'\u13b7': 'M', // CHEROKEE LETTER LU
+	'\u13e2': 'P', // CHEROKEE LETTER TLV
+	'\u13d2': 'R', // CHEROKEE LETTER SV
+	'\u13da': 'S', // CHEROKEE LETTER DU
+	'\u13a2': 'T', // CHEROKEE LETTER I
+	'\u13d9': 'V', // CHEROKEE LETTER DO
+	'\u13b3': 'W', // CHEROKEE LETTER LA
+	'\u13c3': 'Z', // CHEROKEE LETTER NO
+	'\u13a5': 'i', // CHEROKEE LETTER V
+	'\u13bd': 'y', // CHEROKEE LETTER MU
+	// Lisu
+	'\ua4ee': 'A', // LISU LETTER A
+	'\ua4d0': 'B', // LISU LETTER BA
+	'\ua4da': 'C', // LISU LETTER CA
+	'\ua4d3': 'D', // LISU LETTER DA
+	'\ua4f0': 'E', // LISU LETTER E
+	'\ua4dd': 'F', // LISU LETTER TSA
+	'\ua4d6': 'G', // LISU LETTER GA
+	'\ua4e7': 'H', // LISU LETTER XA
+	'\ua4f2': 'l', // LISU LETTER I
+	'\ua4d9': 'J', // LISU LETTER JA
+	'\ua4d7': 'K', // LISU LETTER KA
+	'\ua4e1': 'L', // LISU LETTER LA
+	'\ua4df': 'M', // LISU LETTER MA
+	'\ua4e0': 'N', // LISU LETTER NA
+	'\ua4f3': 'O', // LISU LETTER O
+	'\ua4d1': 'P', // LISU LETTER PA
+	'\ua4e3': 'R', // LISU LETTER ZHA
+	'\ua4e2': 'S', // LISU LETTER SA
+	'\ua4d4': 'T', // LISU LETTER TA
+	'\ua4f4': 'U', // LISU LETTER U
+	'\ua4e6': 'V', // LISU LETTER HA
+	'\ua4ea': 'W', // LISU LETTER WA
+	'\ua4eb': 'X', // LISU LETTER SHA
+	'\ua4ec': 'Y', // LISU LETTER YA
+	'\ua4dc': 'Z', // LISU LETTER DZA
+	// Fullwidth forms, mapped like their ASCII counterparts
+	'\uff21': 'A', // FULLWIDTH LATIN CAPITAL LETTER A
+	'\uff22': 'B', // FULLWIDTH LATIN CAPITAL LETTER B
+	'\uff23': 'C', // FULLWIDTH LATIN CAPITAL LETTER C
+	'\uff24': 'D', // FULLWIDTH LATIN CAPITAL LETTER D
+	'\uff25': 'E', // FULLWIDTH LATIN CAPITAL LETTER E
+	'\uff26': 'F', // FULLWIDTH LATIN CAPITAL LETTER F
+	'\uff27': 'G', // FULLWIDTH LATIN CAPITAL LETTER G
+	'\uff28': 'H', // FULLWIDTH LATIN CAPITAL LETTER H
+	'\uff29': 'l', // FULLWIDTH LATIN CAPITAL LETTER I
+	'\uff2a': 'J', // FULLWIDTH LATIN CAPITAL LETTER J
+	'\uff2b': 'K', // FULLWIDTH LATIN CAPITAL LETTER K
+	'\uff2c': 'L', // FULLWIDTH LATIN CAPITAL LETTER L
+	'\uff2d': 'M', // FULLWIDTH LATIN CAPITAL LETTER M
+	'\uff2e': 'N', // FULLWIDTH LATIN CAPITAL LETTER N
+	'\uff2f': 'O', // FULLWIDTH LATIN CAPITAL LETTER O
+	'\uff30': 'P', // FULLWIDTH LATIN CAPITAL LETTER P
+	'\uff31': 'Q', // FULLWIDTH LATIN CAPITAL LETTER Q
+	'\uff32': 'R', // FULLWIDTH LATIN CAPITAL LETTER R
+	'\uff33': 'S', // FULLWIDTH LATIN CAPITAL LETTER S
+	'\uff34': 'T', // FULLWIDTH LATIN CAPITAL LETTER T
+	'\uff35': 'U', // FULLWIDTH LATIN CAPITAL LETTER U
+	'\uff36': 'V', // FULLWIDTH LATIN CAPITAL LETTER V
+	'\uff37': 'W', // FULLWIDTH LATIN CAPITAL LETTER W
+	'\uff38': 'X', // FULLWIDTH LATIN CAPITAL LETTER X
+	'\uff39': 'Y', // FULLWIDTH LATIN CAPITAL LETTER Y
+	'\uff3a': 'Z', // FULLWIDTH LATIN CAPITAL LETTER Z
+	'\uff41': 'a', // FULLWIDTH LATIN SMALL LETTER A
+	'\uff42': 'b', // FULLWIDTH LATIN SMALL LETTER B
+	'\uff43': 'c', // FULLWIDTH LATIN SMALL LETTER C
+	'\uff44': 'd', // FULLWIDTH LATIN SMALL LETTER D
+	'\uff45': 'e', // FULLWIDTH LATIN SMALL LETTER E
+	'\uff46': 'f', // FULLWIDTH LATIN SMALL LETTER F
+	'\uff47': 'g', // FULLWIDTH LATIN SMALL LETTER G
+	'\uff48': 'h', // FULLWIDTH LATIN SMALL LETTER H
+	'\uff49': 'i', // FULLWIDTH LATIN SMALL LETTER I
+	'\uff4a': 'j', // FULLWIDTH LATIN SMALL LETTER J
+	'\uff4b': 'k', // FULLWIDTH LATIN SMALL LETTER K
+	'\uff4c': 'l', // FULLWIDTH LATIN SMALL LETTER L
+	'\uff4d': 'm', // FULLWIDTH LATIN SMALL LETTER M
+	'\uff4e': 'n', // FULLWIDTH LATIN SMALL LETTER N
+	'\uff4f': 'o', // FULLWIDTH LATIN SMALL LETTER O
+	'\uff50': 'p', // FULLWIDTH LATIN SMALL LETTER P
+	'\uff51': 'q', // FULLWIDTH LATIN SMALL LETTER Q
+	'\uff52': 'r', // FULLWIDTH LATIN SMALL LETTER R
+	'\uff53': 's', // FULLWIDTH LATIN SMALL LETTER S
+	'\uff54': 't', // FULLWIDTH LATIN SMALL LETTER T
+	'\uff55': 'u', // FULLWIDTH LATIN SMALL LETTER U
+	'\uff56': 'v', // FULLWIDTH LATIN SMALL LETTER V
+	'\uff57': 'w', // FULLWIDTH LATIN SMALL LETTER W
+	'\uff58': 'x', // FULLWIDTH LATIN SMALL LETTER X
+	'\uff59': 'y', // FULLWIDTH LATIN SMALL LETTER Y
+	'\uff5a': 'z', // FULLWIDTH LATIN SMALL LETTER Z
+	'\uff10': 'O', // FULLWIDTH DIGIT ZERO
+	'\uff11': 'l', // FULLWIDTH DIGIT ONE
+	'\uff12': '2', // FULLWIDTH DIGIT TWO
+	'\uff13': '3', // FULLWIDTH DIGIT THREE
+	'\uff14': '4', // FULLWIDTH DIGIT FOUR
+	'\uff15': '5', // FULLWIDTH DIGIT FIVE
+	'\uff16': '6', // FULLWIDTH DIGIT SIX
+	'\uff17': '7', // FULLWIDTH DIGIT SEVEN
+	'\uff18': '8', // FULLWIDTH DIGIT EIGHT
+	'\uff19': '9', // FULLWIDTH DIGIT NINE
+}
@@ -0,0 +1,73 @@
+package safeinput
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDetectConfusables(t *testing.T) {
+	s := Default()
+	tests := []struct {
+		input    string
+		skeleton string
+		mixed    bool
+	}{
+		{"admin", "admin", false},
+		{"\u0430dmin", "admin", true},                             // Cyrillic a
+		{"p\u0430yp\u0430l", "paypal", true},                      // Cyrillic a twice
+		{"\u0440\u0430\u0443\u0440\u0430\u04cf", "paypal", false}, // all Cyrillic
+		{"\u0391\u03a1\u0395\u03a3", "APE\u03a3", false},          // Greek
+		{"\uff41dmin", "admin", false},                            // fullwidth a is Latin
+		{"Il1|", "llll", false},
+		{"g00gle", "gOOgle", false},
+		{"\u13aa\u13f4\u13df", "ABC", false}, // Cherokee
+		{"caf\u00e9", "cafe\u0301", false},
+		{"\u043f\u0440\u0438\u0432\u0435\u0442", "\u043fp\u0438\u0432e\u0442", false},
+		{"Tokyo\u6771\u4eac", "Tokyo\u6771\u4eac", false}, // Latin with Han
+		{"\u3072\u3089\u304c\u306a\u30ab\u30bf\u6f22\u5b57", "\u3072\u3089\u304b\u3099\u306a\u30ab\u30bf\u6f22\u5b57", false}, // Japanese
+		{"\ud55c\uad6dabc", "\u1112\u1161\u11ab\u1100\u116e\u11a8abc", false},                                                 // Hangul with Latin
+		{"\u03b1\u0431", "a\u0431", true}, // Greek with Cyrillic
+		{"user_42-\u0301", "user_42-\u0301", false},
+	}
+	for _, tt := range tests {
+		skeleton, mixed := s.DetectConfusables(tt.input)
+		if skeleton != tt.skeleton || mixed != tt.mixed {
+			t.Errorf("DetectConfusables(%q) = %q, %v, want %q, %v", tt.input, skeleton, mixed, tt.skeleton, tt.mixed)
+		}
+	}
+}
+
+func TestSanitize_RejectMixedScript(t *testing.T) {
+	s := New(Config{RejectMixedScript: true})
+	tests := []struct {
+		input   string
+		ctx     Context
+		wantErr error
+	}{
+		{"\u0430dmin", Filename, ErrMixedScript},
+		{"\u0430dmin.txt", Filename, ErrMixedScript},
+		{"\u043e\u0442\u0447\u0435\u0442.pdf", Filename, nil}, // Cyrillic name, Latin extension
+		{"p\u0430ypal.com", Hostname, ErrMixedScript},
+		{"\u043f\u0440\u0438\u043c\u0435\u0440.com", Hostname, nil},
+		{"us\u0435rs", SQLIdentifier, ErrMixedScript},
+		{"users", SQLIdentifier, nil},
+		{"\u0430dmin", HTMLBody, nil}, // not an identifier context
+	}
+	for _, tt := range tests {
+		if _, err := s.Sanitize(tt.input, tt.ctx); !errors.Is(err, tt.wantErr) {
+			t.Errorf("Sanitize(%q, %v) error = %v, want %v", tt.input, tt.ctx, err, tt.wantErr)
+		}
+	}
+
+	if _, err := Default().Sanitize("\u0430dmin", Filename); err != nil {
+		t.Errorf("rejected without RejectMixedScript: %v", err)
+	}
+}
+
+func BenchmarkDetectConfusables(b *testing.B) {
+	s := Default()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = s.DetectConfusables("p\u0430yp\u0430l_support")
+	}
+}
@@ -0,0 +1,96 @@
+package safeinput
+
+import "strings"
+
+// defaultMaxCookieLength is the cookie name and value length limit used by
+// SanitizeCookieName, SanitizeCookieValue and by a Sanitizer whose config
+// does not set one.
+const defaultMaxCookieLength = 4096
+
+// cookieMode selects what the cookie contexts do with invalid bytes.
+type cookieMode int
+
+const (
+	cookieReject cookieMode = iota
+	cookieStrip
+	cookieEncode
+)
+
+// cookieMode returns the cookie mode selected by the config:
+// percent-encoding with EncodeCookies, otherwise rejecting in StrictMode
+// and stripping outside it.
+func (s *Sanitizer) cookieMode() cookieMode {
+	switch {
+	case s.config.EncodeCookies:
+		return cookieEncode
+	case s.config.StrictMode:
+		return cookieReject
+	default:
+		return cookieStrip
+	}
+}
+
+// SanitizeCookieName validates a cookie name against the RFC 6265 token
+// rules. Names that are empty or contain separators such as ";" or "=",
+// whitespace, control characters or non-ASCII bytes are rejected with
+// ErrInvalidCookie, and names longer than 4096 bytes with ErrInputTooLong.
+func SanitizeCookieName(input string) (string, error) {
+	return sanitizeCookieName(input, cookieReject, defaultMaxCookieLength)
+}
+
+// SanitizeCookieValue validates a cookie value against the RFC 6265
+// cookie-octet rules. Values containing ";", ",", quotes, backslashes,
+// whitespace, control characters or non-ASCII bytes are rejected with
+// ErrInvalidCookie, and values longer than 4096 bytes with ErrInputTooLong.
+func SanitizeCookieValue(input string) (string, error) {
+	return sanitizeCookie(input, isCookieValueByte, cookieReject, defaultMaxCookieLength)
+}
+
+// sanitizeCookieName implements the CookieName context, which also rejects
+// names left empty.
+func sanitizeCookieName(input string, mode cookieMode, maxLength int) (string, error) {
+	name, err := sanitizeCookie(input, isCookieNameByte, mode, maxLength)
+	if err == nil && name == "" {
+		return "", ErrInvalidCookie
+	}
+	return name, err
+}
+
+// sanitizeCookie implements the CookieValue context, and CookieName with
+// isCookieNameByte. In cookieEncode mode invalid bytes and "%" are
+// percent-encoded, so the original value can be recovered with
+// url.PathUnescape. The length limit applies to the result.
+func sanitizeCookie(input string, valid func(byte) bool, mode cookieMode, maxLength int) (string, error) {
+	var b strings.Builder
+	b.Grow(len(input))
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case mode == cookieEncode && (c == '%' || !valid(c)):
+			b.WriteByte('%')
+			b.WriteByte(upperHexDigits[c>>4])
+			b.WriteByte(upperHexDigits[c&0xf])
+		case valid(c):
+			b.WriteByte(c)
+		case mode == cookieReject:
+			return "", ErrInvalidCookie
+		}
+	}
+	if b.Len() > maxLength {
+		return "", ErrInputTooLong
+	}
+	return b.String(), nil
+}
+
+const upperHexDigits = "0123456789ABCDEF"
+
+// isCookieNameByte reports whether c may appear in an RFC 6265 cookie
+// name, an RFC 2616 token.
+func isCookieNameByte(c byte) bool {
+	return c > 0x20 && c < 0x7f && !strings.ContainsRune(`()<>@,;:\"/[]?={}`, rune(c))
+}
+
+// isCookieValueByte reports whether c is an RFC 6265 cookie-octet.
+func isCookieValueByte(c byte) bool {
+	return c > 0x20 && c < 0x7f && c != '"' && c != ',' && c != ';' && c != '\\'
+}
@@ -0,0 +1,144 @@
+package safeinput
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// cookieRoundTrip sets a cookie on a response and reads it back from a
+// request carrying the same cookie, as a browser would send it
+func cookieRoundTrip(t *testing.T, name, value string) (string, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	http.SetCookie(rec, &http.Cookie{Name: name, Value: value})
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("cookie %q=%q was not set: %q", name, value, rec.Header().Values("Set-Cookie"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Cookie", cookies[0].Name+"="+cookies[0].Value)
+	got, err := req.Cookie(name)
+	if err != nil {
+		t.Fatalf("cookie %q not found in request: %v", name, err)
+	}
+	return got.Name, got.Value
+}
+
+func TestSanitizeCookieValue(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr error
+	}{
+		{"variant-b", nil},
+		{"abc123_XYZ.~!#$%&'()*+-./:<=>?@[]^`{|}", nil},
+		{"", nil},
+		{"a; Domain=evil.com", ErrInvalidCookie},
+		{"a;Path=/admin", ErrInvalidCookie},
+		{"a,b", ErrInvalidCookie},
+		{"a b", ErrInvalidCookie},
+		{`"quoted"`, ErrInvalidCookie},
+		{`back\slash`, ErrInvalidCookie},
+		{"tab\there", ErrInvalidCookie},
+		{"new\r\nline", ErrInvalidCookie},
+		{"café", ErrInvalidCookie},
+		{strings.Repeat("a", 4097), ErrInputTooLong},
+	}
+	for _, tt := range tests {
+		got, err := SanitizeCookieValue(tt.input)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("SanitizeCookieValue(%.40q) error = %v, want %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got != tt.input {
+			t.Errorf("SanitizeCookieValue(%q) = %q, want the input unchanged", tt.input, got)
+		}
+		if _, value := cookieRoundTrip(t, "c", got); value != got {
+			t.Errorf("cookie value %q round trip = %q", got, value)
+		}
+	}
+}
+
+func TestSanitizeCookieName(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr error
+	}{
+		{"session_label", nil},
+		{"ab-test.v2", nil},
+		{"!#$%&'*+-.^_`|~", nil},
+		{"", ErrInvalidCookie},
+		{"a=b", ErrInvalidCookie},
+		{"a;b", ErrInvalidCookie},
+		{"a b", ErrInvalidCookie},
+		{"a/b", ErrInvalidCookie},
+		{"(name)", ErrInvalidCookie},
+		{"näme", ErrInvalidCookie},
+	}
+	for _, tt := range tests {
+		got, err := SanitizeCookieName(tt.input)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("SanitizeCookieName(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if name, _ := cookieRoundTrip(t, got, "v"); name != got {
+			t.Errorf("cookie name %q round trip = %q", got, name)
+		}
+	}
+}
+
+func TestSanitize_Cookie(t *testing.T) {
+	const input = "a; Domain=evil.com, \"café\" 100%"
+
+	// Strict mode rejects
+	if _, err := Default().Sanitize(input, CookieValue); !errors.Is(err, ErrInvalidCookie) {
+		t.Errorf("strict: error = %v, want ErrInvalidCookie", err)
+	}
+
+	// Otherwise invalid bytes are stripped
+	got, err := New(Config{}).Sanitize(input, CookieValue)
+	if err != nil || got != "aDomain=evil.comcaf100%" {
+		t.Errorf("strip: got %q, %v", got, err)
+	}
+	if _, err := New(Config{}).Sanitize(" ;\t", CookieName); !errors.Is(err, ErrInvalidCookie) {
+		t.Errorf("strip: empty name error = %v, want ErrInvalidCookie", err)
+	}
+
+	// Or percent-encoded, reversibly
+	s := New(Config{StrictMode: true, EncodeCookies: true})
+	for _, ctx := range []Context{CookieName, CookieValue} {
+		got, err := s.Sanitize(input, ctx)
+		if err != nil {
+			t.Fatalf("encode %v: unexpected error %v", ctx, err)
+		}
+		name, value := "n", got
+		if ctx == CookieName {
+			name, value = got, "v"
+		}
+		if gotName, gotValue := cookieRoundTrip(t, name, value); gotName != name || gotValue != value {
+			t.Errorf("encode %v: %q round trip = %q=%q", ctx, got, gotName, gotValue)
+		}
+		if decoded, err := url.PathUnescape(got); err != nil || decoded != input {
+			t.Errorf("encode %v: %q decodes to %q, %v", ctx, got, decoded, err)
+		}
+	}
+
+	// The length limit applies to the encoded result
+	s = New(Config{EncodeCookies: true, MaxCookieLength: 8})
+	if _, err := s.Sanitize("a;b", CookieValue); err != nil {
+		t.Errorf("short value: unexpected error %v", err)
+	}
+	if _, err := s.Sanitize(";;;", CookieValue); !errors.Is(err, ErrInputTooLong) {
+		t.Errorf("long encoded value: error = %v, want ErrInputTooLong", err)
+	}
+}
@@ -0,0 +1,65 @@
+package safeinput
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SanitizeCSVField prepares a value for a comma-separated CSV field that
+// may be opened in a spreadsheet (CWE-1236). A value starting with =, +,
+// -, @, tab or CR, which a spreadsheet would evaluate as a formula, is
+// prefixed with a single quote; plain numbers such as "-42" are left
+// alone. A value containing a quote, comma, CR or LF is then quoted per
+// RFC 4180, with embedded quotes doubled.
+func SanitizeCSVField(input string) string {
+	return sanitizeCSVField(input, ',', false)
+}
+
+// SanitizeCSVRecord applies SanitizeCSVField to every field of a record.
+// The results are ready to be joined with commas; they should not be passed
+// to an encoding/csv Writer, which would quote them again.
+func SanitizeCSVRecord(record []string) []string {
+	out := make([]string, len(record))
+	for i, field := range record {
+		out[i] = SanitizeCSVField(field)
+	}
+	return out
+}
+
+// sanitizeCSVField implements the CSVField context for the given delimiter,
+// ',' if zero. With strip set, formula characters are removed from the
+// start of the value instead of being prefixed with a quote.
+func sanitizeCSVField(input string, delimiter rune, strip bool) string {
+	if delimiter == 0 {
+		delimiter = ','
+	}
+
+	if isCSVFormula(input) {
+		if strip {
+			input = strings.TrimLeft(input, csvFormulaChars)
+		} else {
+			input = "'" + input
+		}
+	}
+
+	if !strings.ContainsRune(input, delimiter) && !strings.ContainsAny(input, "\"\r\n") {
+		return input
+	}
+	return `"` + strings.ReplaceAll(input, `"`, `""`) + `"`
+}
+
+// csvFormulaChars are the leading characters that make a spreadsheet
+// evaluate a cell as a formula.
+const csvFormulaChars = "=+-@\t\r"
+
+// isCSVFormula reports whether a spreadsheet could evaluate s as a formula.
+func isCSVFormula(s string) bool {
+	if s == "" || !strings.ContainsRune(csvFormulaChars, rune(s[0])) {
+		return false
+	}
+	if s[0] == '+' || s[0] == '-' {
+		_, err := strconv.ParseFloat(s, 64)
+		return err != nil
+	}
+	return true
+}
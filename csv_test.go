@@ -0,0 +1,80 @@
+package safeinput
+
+import (
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeCSVField(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"alice", "alice"},
+		{`=HYPERLINK("http://evil.com","Click")`, `"'=HYPERLINK(""http://evil.com"",""Click"")"`},
+		{"=1+2", "'=1+2"},
+		{"@SUM(A1:A9)", "'@SUM(A1:A9)"},
+		{"+cmd|' /C calc'!A0", "'+cmd|' /C calc'!A0"},
+		{"-2+3", "'-2+3"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "\"'\r=1\""},
+		{"-42", "-42"},
+		{"+1.5e3", "+1.5e3"},
+		{"a=b", "a=b"},
+		{"Smith, John", `"Smith, John"`},
+		{`say "hi"`, `"say ""hi"""`},
+		{"line1\nline2", "\"line1\nline2\""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := SanitizeCSVField(tt.input); got != tt.want {
+			t.Errorf("SanitizeCSVField(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeCSVRecord(t *testing.T) {
+	record := []string{"1", "=HYPERLINK(\"http://evil.com\")", "@SUM(A1)", "Smith, John"}
+	got := SanitizeCSVRecord(record)
+
+	// The joined record parses back to the neutralized values
+	r := csv.NewReader(strings.NewReader(strings.Join(got, ",")))
+	fields, err := r.Read()
+	if err != nil {
+		t.Fatalf("sanitized record does not parse: %v", err)
+	}
+	want := []string{"1", "'=HYPERLINK(\"http://evil.com\")", "'@SUM(A1)", "Smith, John"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("parsed %q, want %q", fields, want)
+	}
+}
+
+func TestSanitize_CSVField(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   Config
+		input string
+		want  string
+	}{
+		{"tab delimiter", Config{CSVDelimiter: '\t'}, "a\tb", "\"a\tb\""},
+		{"tab delimiter keeps commas", Config{CSVDelimiter: '\t'}, "a,b", "a,b"},
+		{"semicolon delimiter", Config{CSVDelimiter: ';'}, "=1;2", `"'=1;2"`},
+		{"semicolon delimiter keeps commas", Config{CSVDelimiter: ';'}, "@SUM(1,2)", "'@SUM(1,2)"},
+		{"strip", Config{StripCSVFormula: true}, "=+@SUM(A1)", "SUM(A1)"},
+		{"strip keeps numbers", Config{StripCSVFormula: true}, "-42", "-42"},
+		{"strip tab", Config{StripCSVFormula: true, CSVDelimiter: '\t'}, "\t=cmd", "cmd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.cfg).Sanitize(tt.input, CSVField)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Sanitize(%q, CSVField) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package safeinput
+
+import (
+	"slices"
+	"sync"
+)
+
+// firstCustomContext is the first value RegisterContext hands out. Values
+// below it are reserved for built-in contexts.
+const firstCustomContext Context = 1 << 10
+
+// ContextFunc sanitizes input for a custom context. It receives input
+// after the shared checks Sanitize applies to every context, along with
+// the configuration of the Sanitizer it was registered on, and returns the
+// sanitized value or an error rejecting input.
+type ContextFunc func(input string, cfg Config) (string, error)
+
+// customContexts records the name of every context RegisterContext has
+// created, on any Sanitizer, so that Context.String can report it.
+var customContexts = struct {
+	sync.RWMutex
+	names map[Context]string
+	next  Context
+}{names: make(map[Context]string), next: firstCustomContext}
+
+// RegisterContext adds a context named name to s, handled by fn, and
+// returns its Context value. Sanitize, Validate and the other entry points
+// apply normalization, the length limits, which MaxLengths can set for the
+// new context, and the null byte, bidi control and invisible character
+// checks to input before calling fn. The context is only known to s; other
+// sanitizers reject it with ErrUnknownContext.
+//
+// The name must be non-empty and must not be the name of a built-in
+// context or of another context registered on s. RegisterContext is safe
+// to call concurrently with Sanitize.
+func (s *Sanitizer) RegisterContext(name string, fn ContextFunc) (Context, error) {
+	if name == "" || fn == nil {
+		return 0, ErrInvalidContext
+	}
+	if slices.Contains(contextNames, name) {
+		return 0, ErrContextExists
+	}
+
+	s.customMu.Lock()
+	defer s.customMu.Unlock()
+	for ctx := range s.custom {
+		if ctx.String() == name {
+			return 0, ErrContextExists
+		}
+	}
+
+	customContexts.Lock()
+	ctx := customContexts.next
+	customContexts.next++
+	customContexts.names[ctx] = name
+	customContexts.Unlock()
+
+	if s.custom == nil {
+		s.custom = make(map[Context]ContextFunc)
+	}
+	s.custom[ctx] = fn
+	return ctx, nil
+}
+
+// customContext returns the function registered on s for ctx, or nil.
+func (s *Sanitizer) customContext(ctx Context) ContextFunc {
+	if ctx < firstCustomContext {
+		return nil
+	}
+	s.customMu.RLock()
+	defer s.customMu.RUnlock()
+	return s.custom[ctx]
+}
+
+// customContextName returns the name ctx was registered with.
+func customContextName(ctx Context) (string, bool) {
+	if ctx < firstCustomContext {
+		return "", false
+	}
+	customContexts.RLock()
+	defer customContexts.RUnlock()
+	name, ok := customContexts.names[ctx]
+	return name, ok
+}
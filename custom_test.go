@@ -0,0 +1,123 @@
+package safeinput
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var (
+	ticketPattern    = regexp.MustCompile(`^[A-Z]{2,5}-[0-9]{1,6}$`)
+	errInvalidTicket = errors.New("invalid ticket ID")
+)
+
+// sanitizeTicket accepts ticket IDs such as "OPS-1234", upper-casing them
+func sanitizeTicket(input string, _ Config) (string, error) {
+	id := strings.ToUpper(strings.TrimSpace(input))
+	if !ticketPattern.MatchString(id) {
+		return "", errInvalidTicket
+	}
+	return id, nil
+}
+
+func TestRegisterContext(t *testing.T) {
+	s := New(Config{StrictMode: true, MaxLengths: map[Context]int{}})
+	ticket, err := s.RegisterContext("TicketID", sanitizeTicket)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ticket < firstCustomContext || ticket.String() != "TicketID" {
+		t.Errorf("got context %d named %q", int(ticket), ticket)
+	}
+
+	tests := []struct {
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"ops-1234", "OPS-1234", nil},
+		{" SEC-9 ", "SEC-9", nil},
+		{"OPS-12a", "", errInvalidTicket},
+		{"OPS-1\x00", "", ErrNullByte},
+		{strings.Repeat("A", 10001), "", ErrInputTooLong},
+	}
+	for _, tt := range tests {
+		got, err := s.Sanitize(tt.input, ticket)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("Sanitize(%.20q) = %q, %v, want %q, %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	if err := s.Validate("OPS-1", ticket); err != nil {
+		t.Errorf("Validate(OPS-1) = %v", err)
+	}
+	if err := s.Validate("ops-1", ticket); !errors.Is(err, ErrUnsafeInput) {
+		t.Errorf("Validate(ops-1) = %v, want ErrUnsafeInput", err)
+	}
+
+	// Per-context lengths apply to custom contexts
+	s = New(Config{MaxLengths: map[Context]int{ticket: 4}})
+	if _, err := s.Sanitize("OPS-12", ticket); !errors.Is(err, ErrInputTooLong) || !strings.Contains(err.Error(), "TicketID") {
+		t.Errorf("expected ErrInputTooLong naming TicketID, got %v", err)
+	}
+	// The context belongs to the sanitizer it was registered on
+	if _, err := Default().Sanitize("OPS-1", ticket); !errors.Is(err, ErrUnknownContext) {
+		t.Errorf("expected ErrUnknownContext, got %v", err)
+	}
+}
+
+func TestRegisterContext_Errors(t *testing.T) {
+	s := Default()
+	if _, err := s.RegisterContext("", sanitizeTicket); !errors.Is(err, ErrInvalidContext) {
+		t.Errorf("empty name: got %v", err)
+	}
+	if _, err := s.RegisterContext("SKU", nil); !errors.Is(err, ErrInvalidContext) {
+		t.Errorf("nil function: got %v", err)
+	}
+	if _, err := s.RegisterContext("HTMLBody", sanitizeTicket); !errors.Is(err, ErrContextExists) {
+		t.Errorf("built-in name: got %v", err)
+	}
+	first, err := s.RegisterContext("SKU", sanitizeTicket)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.RegisterContext("SKU", sanitizeTicket); !errors.Is(err, ErrContextExists) {
+		t.Errorf("duplicate name: got %v", err)
+	}
+
+	// Another sanitizer may use the same name and gets its own value
+	second, err := Default().RegisterContext("SKU", sanitizeTicket)
+	if err != nil || second == first || second.String() != "SKU" {
+		t.Errorf("got %d, %v", int(second), err)
+	}
+	if Context(firstCustomContext-1).String() != "Unknown" {
+		t.Errorf("reserved value has a name")
+	}
+}
+
+func TestRegisterContext_Concurrent(t *testing.T) {
+	s := Default()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, err := s.RegisterContext(fmt.Sprintf("Custom%d", i), sanitizeTicket)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for j := 0; j < 100; j++ {
+				if _, err := s.Sanitize("OPS-1", ctx); err != nil {
+					t.Error(err)
+					return
+				}
+				_, _ = s.Sanitize("<b>x</b>", HTMLBody)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,263 @@
+package safeinput
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ravisastryk/go-safeinput/html"
+)
+
+// FindingKind classifies a Finding.
+type FindingKind string
+
+// Kinds of findings reported by SanitizeDetailed.
+const (
+	// FindingRemovedElement is an HTML element removed with its content.
+	FindingRemovedElement FindingKind = "removed_element"
+	// FindingRemovedTag is a single HTML tag that was removed.
+	FindingRemovedTag FindingKind = "removed_tag"
+	// FindingRemovedAttribute is an HTML attribute that was removed.
+	FindingRemovedAttribute FindingKind = "removed_attribute"
+	// FindingRemovedCharacter is a character dropped from a shell argument.
+	FindingRemovedCharacter FindingKind = "removed_character"
+	// FindingRemovedNullByte is a null byte stripped by StripNullBytes.
+	FindingRemovedNullByte FindingKind = "removed_null_byte"
+	// FindingRemovedBidiControl is a bidirectional control character
+	// stripped by StripBidiControls.
+	FindingRemovedBidiControl FindingKind = "removed_bidi_control"
+	// FindingRemovedInvisible is a zero-width or invisible character
+	// stripped by StripInvisibleRunes.
+	FindingRemovedInvisible FindingKind = "removed_invisible"
+	// FindingRejected is the reason input was rejected.
+	FindingRejected FindingKind = "rejected"
+)
+
+// Finding describes one change made to input, or the reason it was rejected.
+type Finding struct {
+	Kind FindingKind
+	// Text is the removed or offending text, if there is one.
+	Text string
+	// Detail is the removed tag or attribute name, or the reason and
+	// pattern for a rejection.
+	Detail string
+	// Offset is the byte offset of Text in the input, or -1 if the finding
+	// is not tied to a position.
+	Offset int
+	// Severity grades what was removed or rejected; see findingSeverity.
+	Severity Severity
+}
+
+// Result is the outcome of SanitizeDetailed.
+type Result struct {
+	Output   string
+	Modified bool
+	// Findings are ordered by offset. Contexts that escape rather than
+	// remove, such as HTMLAttribute, set Modified without findings.
+	Findings []Finding
+	// Severity is the highest severity of the findings, or SeverityNone if
+	// there are none.
+	Severity Severity
+}
+
+// SanitizeDetailed processes input like Sanitize and also reports why it
+// changed: the HTML tags and attributes removed from HTMLBody, the
+// characters dropped from ShellArg, and null bytes, bidi controls and
+// invisible characters stripped from any context. When input is rejected
+// the error is returned along with a FindingRejected describing it, naming
+// the pattern that triggered the rejection for FilePath, SQLIdentifier and
+// SQLValue. With NormalizeUnicode set, offsets are into the NFC form of
+// input.
+func (s *Sanitizer) SanitizeDetailed(input string, ctx Context) (Result, error) {
+	original := input
+	if normalized, err := s.normalize(input, ctx); err == nil {
+		input = normalized
+	}
+	findings := []Finding{}
+	output, err := s.sanitize(input, ctx, &findings)
+
+	// Offsets are into input with the characters strippedCharacters
+	// reports removed; map them back
+	if s.checkLength(input, ctx) == nil {
+		removed := s.strippedCharacters(input, ctx)
+		for i := range findings {
+			for _, r := range removed {
+				if findings[i].Offset >= 0 && r.Offset <= findings[i].Offset {
+					findings[i].Offset += len(r.Text)
+				}
+			}
+		}
+		findings = append(findings, removed...)
+	}
+
+	// HTML findings are graded as they are made, from the reason for the
+	// removal
+	for i := range findings {
+		if findings[i].Severity == SeverityNone {
+			findings[i].Severity = findingSeverity(findings[i])
+		}
+	}
+	if err != nil {
+		f := Finding{Kind: FindingRejected, Detail: err.Error(), Offset: -1, Severity: ErrorSeverity(err)}
+		var se *SanitizeError
+		if errors.As(err, &se) {
+			f.Text, f.Detail, f.Offset = se.Text, se.Err.Error(), se.Offset
+			if se.Pattern != "" {
+				f.Detail += ": " + se.Pattern
+			}
+		}
+		findings = append(findings, f)
+	}
+	slices.SortStableFunc(findings, func(a, b Finding) int { return a.Offset - b.Offset })
+
+	severity := s.escapedSeverity(input, ctx)
+	for _, f := range findings {
+		severity = max(severity, f.Severity)
+	}
+	if err != nil {
+		return Result{Findings: findings, Severity: severity}, err
+	}
+	return Result{Output: output, Modified: output != original, Findings: findings, Severity: severity}, nil
+}
+
+// Classify returns how likely input is an attack on ctx, without building
+// sanitized output when input is clean: SeverityNone if Sanitize would
+// accept it as it is or only escape it, the severity of the error if
+// Sanitize would reject it, and otherwise the highest severity of what
+// Sanitize would remove. Input that the markup escaping contexts would
+// only escape is SeverityMalicious if it holds a cross-site scripting
+// payload, as Result.Severity is.
+func (s *Sanitizer) Classify(input string, ctx Context) Severity {
+	if s.Validate(input, ctx) == nil && s.escapedSeverity(input, ctx) == SeverityNone {
+		return SeverityNone
+	}
+	result, err := s.SanitizeDetailed(input, ctx)
+	if err != nil {
+		return ErrorSeverity(err)
+	}
+	return result.Severity
+}
+
+// markupEscapingContexts are the contexts that escape markup rather than
+// remove it, so that escaped input produces no findings.
+var markupEscapingContexts = map[Context]bool{
+	HTMLAttribute: true, JSONString: true, XMLText: true, XMLAttr: true,
+}
+
+// eventHandlerAttribute matches an event handler attribute that input
+// would add to an element by breaking out of a quoted or unquoted
+// attribute value.
+var eventHandlerAttribute = regexp.MustCompile("(?i)(?:^|[\\s\"'`/])on[a-z]+\\s*=")
+
+// scriptURL matches a value that runs script when used as a link.
+var scriptURL = regexp.MustCompile(`(?i)^[\x00-\x20]*(?:java|vb)script:`)
+
+// escapedSeverity grades input for a markup escaping context, which makes
+// it harmless but reports nothing: SeverityMalicious if it would add an
+// event handler to an element, is a script URL, or holds markup that
+// HTMLBody would remove as script, and otherwise SeverityNone.
+func (s *Sanitizer) escapedSeverity(input string, ctx Context) Severity {
+	if !markupEscapingContexts[ctx] {
+		return SeverityNone
+	}
+	if eventHandlerAttribute.MatchString(input) || scriptURL.MatchString(input) {
+		return SeverityMalicious
+	}
+	if !strings.Contains(input, "<") {
+		return SeverityNone
+	}
+	_, removals := s.html.SanitizeBodyDetailed(input)
+	for _, r := range removals {
+		if htmlRemovalSeverity(r) == SeverityMalicious {
+			return SeverityMalicious
+		}
+	}
+	return SeverityNone
+}
+
+// findingSeverity returns the severity of a finding other than a
+// rejection or an HTML removal. Removed shell metacharacters, null bytes
+// and bidi controls are SeveritySuspicious, and other removals
+// SeverityInfo.
+func findingSeverity(f Finding) Severity {
+	switch f.Kind {
+	case FindingRemovedCharacter:
+		if strings.Contains(shellMetacharacters, f.Text) {
+			return SeveritySuspicious
+		}
+	case FindingRemovedNullByte, FindingRemovedBidiControl:
+		return SeveritySuspicious
+	case FindingRejected:
+		return f.Severity
+	}
+	return SeverityInfo
+}
+
+// strippedCharacters returns a finding for each null byte, bidi control
+// and invisible character Sanitize strips from input before dispatching on
+// the context, ordered by offset.
+func (s *Sanitizer) strippedCharacters(input string, ctx Context) []Finding {
+	stripBidi := s.config.StripBidiControls && !s.config.RejectBidiControls
+	stripInvisible := s.config.StripInvisible && !s.config.RejectInvisible
+	stripNull := s.stripsNullBytes(ctx)
+	var removed []Finding
+	for i, r := range input {
+		switch {
+		case r == 0 && stripNull:
+			removed = append(removed, Finding{Kind: FindingRemovedNullByte, Text: "\x00", Offset: i})
+		case stripBidi && isBidiControl(r):
+			removed = append(removed, Finding{Kind: FindingRemovedBidiControl, Text: string(r), Offset: i})
+		case stripInvisible && isInvisibleRune(r):
+			removed = append(removed, Finding{Kind: FindingRemovedInvisible, Text: string(r), Offset: i})
+		}
+	}
+	return removed
+}
+
+// htmlRemovalKinds maps html.Removal kinds to finding kinds.
+var htmlRemovalKinds = map[string]FindingKind{
+	html.RemovedElement:   FindingRemovedElement,
+	html.RemovedTag:       FindingRemovedTag,
+	html.RemovedAttribute: FindingRemovedAttribute,
+}
+
+// htmlBodyDetailed sanitizes input for HTMLBody, appending a finding for
+// each removal.
+func (s *Sanitizer) htmlBodyDetailed(input string, findings *[]Finding) string {
+	output, removals := s.html.SanitizeBodyDetailed(input)
+	for _, r := range removals {
+		*findings = append(*findings, Finding{
+			Kind:     htmlRemovalKinds[r.Kind],
+			Text:     r.Text,
+			Detail:   r.Name,
+			Offset:   r.Offset,
+			Severity: htmlRemovalSeverity(r),
+		})
+	}
+	return output
+}
+
+// htmlRemovalSeverity returns the severity of an HTML removal by its
+// reason. Markup that runs script, such as script elements, event handlers
+// and javascript: URLs, is SeverityMalicious. URLs not allowed, malformed
+// attribute names and the elements and tags removed whatever the policy,
+// such as style, svg, link and meta, are SeveritySuspicious. Other tags and
+// attributes the policy does not allow, style attributes, comments and
+// incomplete tags are SeverityInfo.
+func htmlRemovalSeverity(r html.Removal) Severity {
+	switch r.Reason {
+	case html.ReasonScript:
+		return SeverityMalicious
+	case html.ReasonURL, html.ReasonMalformed, html.ReasonUnsafe:
+		return SeveritySuspicious
+	}
+	return SeverityInfo
+}
+
+// runeAt returns the character starting at byte offset i of s.
+func runeAt(s string, i int) string {
+	_, size := utf8.DecodeRuneInString(s[i:])
+	return s[i : i+size]
+}
@@ -0,0 +1,202 @@
+package safeinput
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ravisastryk/go-safeinput/path"
+	"github.com/ravisastryk/go-safeinput/sql"
+)
+
+func TestSanitizeDetailed_HTMLBody(t *testing.T) {
+	s := Default()
+	input := `<p onclick="steal()">Hi</p><script>alert(1)</script><b>!</b>`
+	res, err := s.SanitizeDetailed(input, HTMLBody)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want, _ := s.Sanitize(input, HTMLBody); res.Output != want || !res.Modified {
+		t.Errorf("Output = %q, Modified = %v, want %q, true", res.Output, res.Modified, want)
+	}
+
+	want := []Finding{
+		{FindingRemovedTag, `<p onclick="steal()">`, "p", 0, SeverityInfo},
+		{FindingRemovedAttribute, ` onclick="steal()"`, "onclick", 2, SeverityMalicious},
+		{FindingRemovedTag, "</p>", "p", 23, SeverityInfo},
+		{FindingRemovedElement, "<script>alert(1)</script>", "script", 27, SeverityMalicious},
+		{FindingRemovedTag, "<b>", "b", 52, SeverityInfo},
+		{FindingRemovedTag, "</b>", "b", 56, SeverityInfo},
+	}
+	if !reflect.DeepEqual(res.Findings, want) {
+		t.Errorf("Findings =\n%+v\nwant\n%+v", res.Findings, want)
+	}
+}
+
+func TestSanitizeDetailed_ShellArg(t *testing.T) {
+	res, err := Default().SanitizeDetailed("a;b $(c)", ShellArg)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := []Finding{
+		{FindingRemovedCharacter, ";", "", 1, SeveritySuspicious},
+		{FindingRemovedCharacter, " ", "", 3, SeverityInfo},
+		{FindingRemovedCharacter, "$", "", 4, SeveritySuspicious},
+		{FindingRemovedCharacter, "(", "", 5, SeveritySuspicious},
+		{FindingRemovedCharacter, ")", "", 7, SeveritySuspicious},
+	}
+	if res.Output != "abc" || !reflect.DeepEqual(res.Findings, want) {
+		t.Errorf("got %q %+v", res.Output, res.Findings)
+	}
+}
+
+func TestSanitizeDetailed_Rejections(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		ctx     Context
+		wantErr error
+		want    Finding
+	}{
+		{"traversal", "docs/../../etc/passwd", FilePath, path.ErrPathTraversal,
+			Finding{FindingRejected, "../", "path traversal detected", 5, SeverityMalicious}},
+		{"encoded traversal", "docs/..%2F", FilePath, path.ErrPathTraversal,
+			Finding{FindingRejected, "..%2F", "path traversal detected", 5, SeverityMalicious}},
+		{"absolute", "/etc/passwd", FilePath, path.ErrAbsolutePath,
+			Finding{FindingRejected, "/etc/passwd", "absolute paths not allowed", 0, SeveritySuspicious}},
+		{"control character", "a\x01b", FilePath, path.ErrInvalidCharacter,
+			Finding{FindingRejected, "\x01", "invalid character in path", 1, SeverityInfo}},
+		{"sql pattern", "x' OR '1'='1", SQLValue, sql.ErrSuspiciousPattern,
+			Finding{FindingRejected, "OR '1'='1", `suspicious SQL pattern detected: (?i)(\bor\b|\band\b)\s*[\d'"]+\s*=\s*[\d'"]+`, 3, SeverityMalicious}},
+		{"sql comment", "admin--", SQLValue, sql.ErrSuspiciousPattern,
+			Finding{FindingRejected, "--", "suspicious SQL pattern detected: --", 5, SeverityMalicious}},
+		{"reserved word", "DROP", SQLIdentifier, sql.ErrReservedWord,
+			Finding{FindingRejected, "DROP", "SQL reserved word not allowed", 0, SeverityInfo}},
+		{"invalid identifier", "users;drop", SQLIdentifier, sql.ErrInvalidIdentifier,
+			Finding{FindingRejected, ";", "invalid SQL identifier", 5, SeverityInfo}},
+		{"leading digit", "1users", SQLIdentifier, sql.ErrInvalidIdentifier,
+			Finding{FindingRejected, "1", "invalid SQL identifier", 0, SeverityInfo}},
+		{"other context", "a\r\nb", HTTPHeader, ErrHeaderInjection,
+			Finding{FindingRejected, "", "CR or LF in header value", -1, SeveritySuspicious}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Default().SanitizeDetailed(tt.input, tt.ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if res.Output != "" || res.Modified {
+				t.Errorf("rejected input: Output = %q, Modified = %v", res.Output, res.Modified)
+			}
+			if !reflect.DeepEqual(res.Findings, []Finding{tt.want}) {
+				t.Errorf("Findings = %+v, want %+v", res.Findings, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeDetailed_NullBytes(t *testing.T) {
+	// Offsets refer to the original input, null bytes included
+	res, err := Default().SanitizeDetailed("a\x00;\x00b", ShellArg)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := []Finding{
+		{FindingRemovedNullByte, "\x00", "", 1, SeveritySuspicious},
+		{FindingRemovedCharacter, ";", "", 2, SeveritySuspicious},
+		{FindingRemovedNullByte, "\x00", "", 3, SeveritySuspicious},
+	}
+	if res.Output != "ab" || !reflect.DeepEqual(res.Findings, want) {
+		t.Errorf("got %q %+v", res.Output, res.Findings)
+	}
+
+	res, err = New(Config{}).SanitizeDetailed("ab\x00c", HTMLBody)
+	if !errors.Is(err, ErrNullByte) {
+		t.Fatalf("error = %v, want ErrNullByte", err)
+	}
+	if want := (Finding{FindingRejected, "\x00", "null byte detected in input", 2, SeveritySuspicious}); !reflect.DeepEqual(res.Findings, []Finding{want}) {
+		t.Errorf("Findings = %+v", res.Findings)
+	}
+}
+
+func TestSanitizeDetailed_Unchanged(t *testing.T) {
+	res, err := Default().SanitizeDetailed("plain text", HTMLBody)
+	if err != nil || res.Output != "plain text" || res.Modified || len(res.Findings) != 0 {
+		t.Errorf("got %+v, %v", res, err)
+	}
+
+	// Escaping contexts report the change without findings
+	res, err = Default().SanitizeDetailed(`a"b`, HTMLAttribute)
+	if err != nil || res.Output != "a&#34;b" || !res.Modified || len(res.Findings) != 0 {
+		t.Errorf("got %+v, %v", res, err)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	s := Default()
+	tests := []struct {
+		input string
+		ctx   Context
+		want  Severity
+	}{
+		{"plain text", HTMLBody, SeverityNone},
+		{`Tom & "Jerry"`, HTMLAttribute, SeverityNone},
+		{"O'Brien is online", HTMLAttribute, SeverityNone},
+		{`" onmouseover="alert(1)`, HTMLAttribute, SeverityMalicious},
+		{`x' autofocus onfocus='alert(1)`, HTMLAttribute, SeverityMalicious},
+		{"x onclick=alert(1)", HTMLAttribute, SeverityMalicious},
+		{`"><script>alert(1)</script>`, HTMLAttribute, SeverityMalicious},
+		{" javascript:alert(1)", HTMLAttribute, SeverityMalicious},
+		{"</script><script>alert(1)</script>", JSONString, SeverityMalicious},
+		{"<b>bold</b>", XMLText, SeverityNone},
+		{"<b>bold</b>", HTMLBody, SeverityInfo},
+		{"<style>p{}</style>", HTMLBody, SeveritySuspicious},
+		{"<img src=x onerror=alert(1)>", HTMLBody, SeverityMalicious},
+		{"<p>hi</p><script>alert(1)</script>", HTMLBody, SeverityMalicious},
+		{`<b style="font-weight:bold">x</b>`, HTMLBody, SeverityInfo},
+		{`<a href="ftp://example.com/f">f</a>`, HTMLBody, SeveritySuspicious},
+		{`<svg width="10"></svg>ok`, HTMLBody, SeveritySuspicious},
+		{`<svg onload="alert(1)"></svg>ok`, HTMLBody, SeverityMalicious},
+		{`<svg><script>alert(1)</script></svg>`, HTMLBody, SeverityMalicious},
+		{`<a href="javascript:alert(1)">x</a>`, HTMLBody, SeverityMalicious},
+		{"users", SQLIdentifier, SeverityNone},
+		{"1users", SQLIdentifier, SeverityInfo},
+		{"x'; DROP TABLE users--", SQLValue, SeverityMalicious},
+		{"../../etc/passwd", FilePath, SeverityMalicious},
+		{"file name", ShellArg, SeverityInfo},
+		{"a;rm -rf /", ShellArg, SeveritySuspicious},
+		{"a\x00b", LogLine, SeveritySuspicious},
+		{strings.Repeat("a", 10001), HTMLBody, SeverityInfo},
+		{"x", Context(999), SeverityInfo},
+	}
+	for _, tt := range tests {
+		if got := s.Classify(tt.input, tt.ctx); got != tt.want {
+			t.Errorf("Classify(%.40q, %v) = %v, want %v", tt.input, tt.ctx, got, tt.want)
+		}
+		res, err := s.SanitizeDetailed(tt.input, tt.ctx)
+		if got := max(res.Severity, ErrorSeverity(err)); got != tt.want {
+			t.Errorf("SanitizeDetailed(%.40q, %v) severity = %v, want %v", tt.input, tt.ctx, got, tt.want)
+		}
+	}
+}
+
+func TestClassify_AllowedHTML(t *testing.T) {
+	s := New(Config{AllowedHTMLTags: []string{"a", "b"}})
+	tests := []struct {
+		input string
+		want  Severity
+	}{
+		{`<b>bold</b>`, SeverityNone},
+		{`<b style="font-weight:bold">x</b>`, SeverityInfo},
+		{`<a href="ftp://example.com/f">f</a>`, SeveritySuspicious},
+		{`<a href="java&#x09;script:alert(1)">x</a>`, SeverityMalicious},
+		{`<b onmouseover="alert(1)">x</b>`, SeverityMalicious},
+		{`<table>x</table>`, SeverityInfo},
+	}
+	for _, tt := range tests {
+		if got := s.Classify(tt.input, HTMLBody); got != tt.want {
+			t.Errorf("Classify(%q, HTMLBody) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
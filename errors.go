@@ -9,4 +9,62 @@ var (
 	ErrUnknownContext = errors.New("unknown sanitization context")
 	// ErrNullByte is returned when a null byte is detected in input.
 	ErrNullByte = errors.New("null byte detected in input")
+	// ErrHeaderInjection is returned when an HTTP header value contains CR or LF.
+	ErrHeaderInjection = errors.New("CR or LF in header value")
+	// ErrInvalidURL is returned when input is not an absolute URL that can be validated.
+	ErrInvalidURL = errors.New("invalid URL")
+	// ErrURLCredentials is returned when a URL contains userinfo such as user:pass@.
+	ErrURLCredentials = errors.New("URL contains credentials")
+	// ErrURLSchemeNotAllowed is returned when a URL scheme is not in the allowlist.
+	ErrURLSchemeNotAllowed = errors.New("URL scheme not allowed")
+	// ErrURLHostNotAllowed is returned when a URL host is denied or not in the allowlist.
+	ErrURLHostNotAllowed = errors.New("URL host not allowed")
+	// ErrInvalidRegex is returned when a regular expression does not parse.
+	ErrInvalidRegex = errors.New("invalid regular expression")
+	// ErrUnsafeRegex is returned when a regular expression has nested or oversized repetitions.
+	ErrUnsafeRegex = errors.New("unsafe regular expression")
+	// ErrInvalidXMLChar is returned when input contains a character not allowed in XML 1.0.
+	ErrInvalidXMLChar = errors.New("character not allowed in XML")
+	// ErrInvalidHostname is returned when input is not a valid host name or IP address.
+	ErrInvalidHostname = errors.New("invalid hostname")
+	// ErrHostnameNotAllowed is returned when a host name is rejected by the configured policy.
+	ErrHostnameNotAllowed = errors.New("hostname not allowed")
+	// ErrInvalidIPAddress is returned when input is not a valid IP address.
+	ErrInvalidIPAddress = errors.New("invalid IP address")
+	// ErrIPAddressNotAllowed is returned when an IP address is rejected by the configured policy.
+	ErrIPAddressNotAllowed = errors.New("IP address not allowed")
+	// ErrInvalidUUID is returned when input is not a UUID in an accepted form.
+	ErrInvalidUUID = errors.New("invalid UUID")
+	// ErrUUIDVersionNotAllowed is returned when a UUID's version is not in the configured set.
+	ErrUUIDVersionNotAllowed = errors.New("UUID version not allowed")
+	// ErrInvalidCookie is returned when a cookie name or value contains bytes RFC 6265 does not allow.
+	ErrInvalidCookie = errors.New("invalid cookie name or value")
+	// ErrTemplateAction is returned when input contains a template delimiter or backtick.
+	ErrTemplateAction = errors.New("template action in input")
+	// ErrUnsafeInput is returned by Validate when input would be changed by sanitization.
+	ErrUnsafeInput = errors.New("input requires sanitization")
+	// ErrInvalidKey is returned when a map or form key fails key validation.
+	ErrInvalidKey = errors.New("invalid field key")
+	// ErrUnexpectedKey is returned by SanitizeValues for a key with no configured context.
+	ErrUnexpectedKey = errors.New("unexpected field key")
+	// ErrInvalidTarget is returned when a struct walk is given something other than a non-nil pointer.
+	ErrInvalidTarget = errors.New("target must be a non-nil pointer")
+	// ErrBidiControl is returned when input contains a Unicode bidirectional control character.
+	ErrBidiControl = errors.New("bidirectional control character in input")
+	// ErrInvisibleCharacter is returned when input contains a zero-width or other invisible character.
+	ErrInvisibleCharacter = errors.New("invisible character in input")
+	// ErrMixedScript is returned when an identifier-like input mixes letters of different scripts.
+	ErrMixedScript = errors.New("input mixes scripts")
+	// ErrInvalidContext is returned by RegisterContext for an empty name or a nil function.
+	ErrInvalidContext = errors.New("invalid custom context")
+	// ErrContextExists is returned by RegisterContext when the name is already in use.
+	ErrContextExists = errors.New("context name already registered")
+	// ErrStreamingUnsupported is returned by SanitizeCopy for a context that needs all of its input at once.
+	ErrStreamingUnsupported = errors.New("context does not support streaming")
+	// ErrInvalidQueryValue is returned in strict mode when a URL query value contains a delimiter, a control character or a malformed escape.
+	ErrInvalidQueryValue = errors.New("invalid URL query value")
+	// ErrInvalidURLPath is returned in strict mode when a URL path contains a delimiter, a backslash or a malformed escape.
+	ErrInvalidURLPath = errors.New("invalid URL path")
+	// ErrUnquotableShellArg is returned when a shell argument contains a character no quoting can carry.
+	ErrUnquotableShellArg = errors.New("shell argument cannot be quoted safely")
 )
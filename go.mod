@@ -2,4 +2,8 @@ module github.com/ravisastryk/go-safeinput
 
 go 1.23
 
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	golang.org/x/net v0.35.0
+	golang.org/x/text v0.22.0
+	gopkg.in/yaml.v3 v3.0.1
+)
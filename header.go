@@ -0,0 +1,44 @@
+package safeinput
+
+import "strings"
+
+// defaultMaxHeaderLength is the header value length limit used by
+// SanitizeHeaderValue and by a Sanitizer whose config does not set one.
+const defaultMaxHeaderLength = 8192
+
+// SanitizeHeaderValue sanitizes a value for an HTTP response header, such as
+// a Content-Disposition filename or a Location URL (CWE-93, CWE-113).
+// Values containing CR or LF are rejected with ErrHeaderInjection, other
+// control characters are stripped, and Unicode line separators are folded
+// to spaces. Values longer than 8192 bytes are rejected with ErrInputTooLong.
+func SanitizeHeaderValue(input string) (string, error) {
+	return sanitizeHeaderValue(input, defaultMaxHeaderLength, true)
+}
+
+// sanitizeHeaderValue implements the HTTPHeader context. With reject unset,
+// CR and LF are stripped like the other control characters.
+func sanitizeHeaderValue(input string, maxLength int, reject bool) (string, error) {
+	if len(input) > maxLength {
+		return "", ErrInputTooLong
+	}
+
+	var b strings.Builder
+	b.Grow(len(input))
+	for _, r := range input {
+		switch {
+		case r == '\r' || r == '\n':
+			if reject {
+				return "", ErrHeaderInjection
+			}
+		case r == '\u2028' || r == '\u2029' || r == '\u0085':
+			b.WriteByte(' ')
+		case r == '\t':
+			b.WriteRune(r)
+		case r < 0x20 || (r >= 0x7f && r <= 0x9f):
+			// Other C0 and C1 control characters are dropped
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String(), nil
+}
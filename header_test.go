@@ -0,0 +1,63 @@
+package safeinput
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeHeaderValue(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"report.pdf", "report.pdf", nil},
+		{`attachment; filename="a b.txt"`, `attachment; filename="a b.txt"`, nil},
+		{"/next\r\nSet-Cookie: session=evil", "", ErrHeaderInjection},
+		{"value\nX-Injected: 1", "", ErrHeaderInjection},
+		{"bell\x07and\x1bescape\x7f", "bellandescape", nil},
+		{"line\u2028separated\u2029text\u0085end", "line separated text end", nil},
+		{"tab\tkept", "tab\tkept", nil},
+		{"naïve café", "naïve café", nil},
+		{strings.Repeat("a", 8192), strings.Repeat("a", 8192), nil},
+		{strings.Repeat("a", 8193), "", ErrInputTooLong},
+	}
+	for _, tt := range tests {
+		got, err := SanitizeHeaderValue(tt.input)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("SanitizeHeaderValue(%.40q) error = %v, want %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("SanitizeHeaderValue(%.40q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSanitize_HTTPHeader(t *testing.T) {
+	// Strict mode rejects CRLF
+	s := Default()
+	if _, err := s.Sanitize("a\r\nb", HTTPHeader); !errors.Is(err, ErrHeaderInjection) {
+		t.Errorf("strict: error = %v, want ErrHeaderInjection", err)
+	}
+
+	// Otherwise CR and LF are stripped
+	s = New(Config{})
+	got, err := s.Sanitize("a\r\nSet-Cookie: x", HTTPHeader)
+	if err != nil {
+		t.Fatalf("non-strict: unexpected error %v", err)
+	}
+	if got != "aSet-Cookie: x" {
+		t.Errorf("non-strict: got %q", got)
+	}
+
+	// The length limit is configurable
+	s = New(Config{MaxHeaderLength: 4})
+	if _, err := s.Sanitize("12345", HTTPHeader); !errors.Is(err, ErrInputTooLong) {
+		t.Errorf("MaxHeaderLength: error = %v, want ErrInputTooLong", err)
+	}
+	if got := New(Config{}).GetConfig().MaxHeaderLength; got != 8192 {
+		t.Errorf("default MaxHeaderLength = %d, want 8192", got)
+	}
+}
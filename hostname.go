@@ -0,0 +1,137 @@
+package safeinput
+
+import (
+	"net/netip"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+// internalHostnames are the host name patterns rejected by
+// BlockInternalHosts, in addition to the addresses internalIPPolicy
+// rejects.
+var internalHostnames = []string{"localhost", "*.localhost", "*.internal", "*.local"}
+
+// hostnamePolicy holds the Config fields used by the Hostname context.
+type hostnamePolicy struct {
+	rejectIDN     bool
+	rejectIP      bool
+	blockInternal bool
+	denied        []string
+}
+
+// SanitizeHostname validates a host name against RFC 1123 and returns its
+// normalized form: mapped as IDNA resolvers map names (UTS #46, which
+// lowercases and normalizes), with a trailing dot removed and Unicode
+// labels converted to punycode ("münchen.de" becomes "xn--mnchen-3ya.de").
+// IP addresses are accepted and returned in canonical form. Names such as
+// "0x7f.1" that resolvers would read as numeric IPv4 addresses are rejected
+// with ErrInvalidHostname. No host is rejected for where it points; use the
+// Hostname context with Config.BlockInternalHosts for that.
+func SanitizeHostname(input string) (string, error) {
+	return sanitizeHostname(input, hostnamePolicy{})
+}
+
+// sanitizeHostname implements the Hostname context.
+func sanitizeHostname(input string, policy hostnamePolicy) (string, error) {
+	if addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(input, "["), "]")); err == nil {
+		return sanitizeHostAddr(addr, policy)
+	}
+
+	// Map the name as a resolver would before checking it, so that forms
+	// like fullwidth or decomposed letters cannot stand in for a denied
+	// name
+	host, err := idna.Lookup.ToASCII(input)
+	if err != nil {
+		return "", ErrInvalidHostname
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return sanitizeHostAddr(addr, policy)
+	}
+	host = strings.TrimSuffix(host, ".")
+	if host == "" || len(host) > 253 {
+		return "", ErrInvalidHostname
+	}
+	labels := strings.Split(host, ".")
+	for _, label := range labels {
+		if !isHostnameLabel(label) {
+			return "", ErrInvalidHostname
+		}
+		if !strings.HasPrefix(label, "xn--") {
+			continue
+		}
+		if !isIDNLabel(label) {
+			return "", ErrInvalidHostname
+		}
+		if policy.rejectIDN {
+			return "", ErrHostnameNotAllowed
+		}
+	}
+	if isNumericLabel(labels[len(labels)-1]) {
+		return "", ErrInvalidHostname
+	}
+
+	if matchesHost(policy.denied, host) || (policy.blockInternal && matchesHost(internalHostnames, host)) {
+		return "", ErrHostnameNotAllowed
+	}
+	return host, nil
+}
+
+// sanitizeHostAddr implements the Hostname context for an IP address.
+func sanitizeHostAddr(addr netip.Addr, policy hostnamePolicy) (string, error) {
+	if addr.Zone() != "" {
+		return "", ErrInvalidHostname
+	}
+	host := addr.String()
+	if policy.rejectIP || matchesHost(policy.denied, host) || (policy.blockInternal && internalIPPolicy.rejects(addr)) {
+		return "", ErrHostnameNotAllowed
+	}
+	return host, nil
+}
+
+// isHostnameLabel reports whether a lowercased ASCII label follows RFC
+// 1123: letters, digits and hyphens, not at either end, up to 63 bytes.
+func isHostnameLabel(label string) bool {
+	if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(label); i++ {
+		c := label[i]
+		if !(c >= 'a' && c <= 'z') && !(c >= '0' && c <= '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// isIDNLabel reports whether a punycode label decodes to letters, digits,
+// marks and hyphens, leaving out the symbols, such as emoji, that UTS #46
+// maps but IDNA 2008 does not allow.
+func isIDNLabel(label string) bool {
+	decoded, err := idna.Punycode.ToUnicode(label)
+	if err != nil {
+		return false
+	}
+	for _, r := range decoded {
+		if r >= 0x80 && !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNumericLabel reports whether a final label would make a resolver read
+// the host name as an IPv4 address, as decimal or 0x-prefixed hexadecimal.
+func isNumericLabel(label string) bool {
+	digits := "0123456789"
+	if rest, ok := strings.CutPrefix(label, "0x"); ok {
+		label, digits = rest, "0123456789abcdef"
+	}
+	for i := 0; i < len(label); i++ {
+		if !strings.ContainsRune(digits, rune(label[i])) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package safeinput
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeHostname(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"example.com", "example.com", nil},
+		{"API.Example.COM", "api.example.com", nil},
+		{"example.com.", "example.com", nil},
+		{"my-host-1.example.com", "my-host-1.example.com", nil},
+		{"localhost", "localhost", nil},
+		{"münchen.de", "xn--mnchen-3ya.de", nil},
+		{"BÜCHER.example", "xn--bcher-kva.example", nil},
+		{"例え.jp", "xn--r8jz45g.jp", nil},
+		{"xn--mnchen-3ya.de", "xn--mnchen-3ya.de", nil},
+		{"mu\u0308nchen.de", "xn--mnchen-3ya.de", nil},
+		{"\uff4c\uff4f\uff43\uff41\uff4c\uff48\uff4f\uff53\uff54", "localhost", nil},
+		{"localhost\u3002", "localhost", nil},
+		{"169.254.169.254", "169.254.169.254", nil},
+		{"[::1]", "::1", nil},
+		{"::ffff:127.0.0.1", "::ffff:127.0.0.1", nil},
+		{strings.Repeat("a", 63) + ".com", strings.Repeat("a", 63) + ".com", nil},
+
+		{"", "", ErrInvalidHostname},
+		{".", "", ErrInvalidHostname},
+		{"example.com..", "", ErrInvalidHostname},
+		{"a..b", "", ErrInvalidHostname},
+		{"-example.com", "", ErrInvalidHostname},
+		{"example-.com", "", ErrInvalidHostname},
+		{"under_score.com", "", ErrInvalidHostname},
+		{"exa mple.com", "", ErrInvalidHostname},
+		{"evil.com/path", "", ErrInvalidHostname},
+		{"user@evil.com", "", ErrInvalidHostname},
+		{"evil.com:80", "", ErrInvalidHostname},
+		{"emoji😀.com", "", ErrInvalidHostname},
+		{"\uff11\uff12\uff17.1", "", ErrInvalidHostname},
+		{strings.Repeat("a", 64) + ".com", "", ErrInvalidHostname},
+		{strings.Repeat("a.", 127) + "com", "", ErrInvalidHostname},
+		{"fe80::1%eth0", "", ErrInvalidHostname},
+
+		// Numeric forms that resolvers read as IPv4 addresses
+		{"2130706433", "", ErrInvalidHostname},
+		{"127.1", "", ErrInvalidHostname},
+		{"0x7f.1", "", ErrInvalidHostname},
+		{"0x7f000001", "", ErrInvalidHostname},
+		{"127.000.000.001", "", ErrInvalidHostname},
+		{"1.example.123", "", ErrInvalidHostname},
+	}
+	for _, tt := range tests {
+		got, err := SanitizeHostname(tt.input)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("SanitizeHostname(%.40q) error = %v, want %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("SanitizeHostname(%.40q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSanitize_Hostname(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"plain validation allows localhost", Config{}, "localhost.", "localhost", nil},
+		{"block localhost", Config{BlockInternalHosts: true}, "LOCALHOST.", "", ErrHostnameNotAllowed},
+		{"block localhost subdomain", Config{BlockInternalHosts: true}, "app.localhost", "", ErrHostnameNotAllowed},
+		{"block fullwidth localhost", Config{BlockInternalHosts: true}, "\uff4c\uff4f\uff43\uff41\uff4c\uff48\uff4f\uff53\uff54", "", ErrHostnameNotAllowed},
+		{"block mapped loopback name", Config{BlockInternalHosts: true}, "\uff11\uff12\uff17\u3002\uff10.\uff10.\uff11", "", ErrHostnameNotAllowed},
+		{"block internal", Config{BlockInternalHosts: true}, "metadata.google.internal.", "", ErrHostnameNotAllowed},
+		{"block metadata address", Config{BlockInternalHosts: true}, "169.254.169.254", "", ErrHostnameNotAllowed},
+		{"block loopback", Config{BlockInternalHosts: true}, "127.0.0.1", "", ErrHostnameNotAllowed},
+		{"block mapped loopback", Config{BlockInternalHosts: true}, "[::ffff:127.0.0.1]", "", ErrHostnameNotAllowed},
+		{"block private", Config{BlockInternalHosts: true}, "10.1.2.3", "", ErrHostnameNotAllowed},
+		{"block unspecified", Config{BlockInternalHosts: true}, "0.0.0.0", "", ErrHostnameNotAllowed},
+		{"block IPv6 link-local", Config{BlockInternalHosts: true}, "fe80::1", "", ErrHostnameNotAllowed},
+		{"allow public address", Config{BlockInternalHosts: true}, "93.184.216.34", "93.184.216.34", nil},
+		{"allow public name", Config{BlockInternalHosts: true}, "hooks.example.com", "hooks.example.com", nil},
+		{"reject IP", Config{RejectIPHostnames: true}, "93.184.216.34", "", ErrHostnameNotAllowed},
+		{"reject IDN", Config{RejectIDNHostnames: true}, "münchen.de", "", ErrHostnameNotAllowed},
+		{"reject punycode IDN", Config{RejectIDNHostnames: true}, "xn--mnchen-3ya.de", "", ErrHostnameNotAllowed},
+		{"denylist", Config{DeniedHostnames: []string{"*.corp.example.com"}}, "DB.corp.example.com.", "", ErrHostnameNotAllowed},
+		{"denylist address", Config{DeniedHostnames: []string{"203.0.113.7"}}, "203.0.113.7", "", ErrHostnameNotAllowed},
+		{"denylist decomposed", Config{DeniedHostnames: []string{"xn--mnchen-3ya.de"}}, "mu\u0308nchen.de", "", ErrHostnameNotAllowed},
+		{"denylist other", Config{DeniedHostnames: []string{"*.corp.example.com"}}, "example.com", "example.com", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.cfg).Sanitize(tt.input, Hostname)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Sanitize(%q, Hostname) error = %v, want %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Sanitize(%q, Hostname) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
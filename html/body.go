@@ -0,0 +1,462 @@
+package html
+
+import (
+	"errors"
+	"html"
+	"io"
+	"slices"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// removedElements are removed with their content whatever the allowed
+// tags: the elements that run script or embed other documents, and the
+// template and foreign elements whose content browsers parse differently
+// from the rest of the document, which mutation XSS relies on. Content
+// runs to the matching end tag, or to the end of the input if there is
+// none; embed is a void element, so only its tag is removed.
+var removedElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"iframe":   true,
+	"object":   true,
+	"embed":    true,
+	"noscript": true,
+	"noembed":  true,
+	"noframes": true,
+	"template": true,
+	"xmp":      true,
+	"svg":      true,
+	"math":     true,
+}
+
+// scriptElements are the removed elements that run script or plugins.
+var scriptElements = map[string]bool{
+	"script": true,
+	"iframe": true,
+	"object": true,
+	"embed":  true,
+}
+
+// removedTags are removed whatever the allowed tags, keeping their content.
+var removedTags = map[string]bool{
+	"link": true,
+	"meta": true,
+}
+
+// urlAttributes hold URLs, and are removed if their scheme is not allowed.
+var urlAttributes = map[string]bool{
+	"href":       true,
+	"xlink:href": true,
+	"src":        true,
+	"action":     true,
+	"formaction": true,
+	"poster":     true,
+}
+
+// textEscaper escapes the characters that are markup in text.
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// errMismatch stops IsSafeBody at the first change to the input.
+var errMismatch = errors.New("html: output differs from input")
+
+// attribute is an attribute of a tag, as the tokenizer reads it.
+type attribute struct {
+	name     string
+	value    string
+	hasValue bool
+	// text is the attribute with the whitespace before it, and offset
+	// where text starts in the tag
+	text   string
+	offset int
+}
+
+// bodyWalker sanitizes the tokens of a document by a policy, writing what
+// it keeps to w and, if removals is not nil, recording what it removes.
+type bodyWalker struct {
+	p        *compiledPolicy
+	z        *xhtml.Tokenizer
+	w        io.Writer
+	err      error
+	removals *[]Removal
+
+	// offset is where the current token starts in the input
+	offset int
+
+	// skip is the element whose content is being removed, depth how deeply
+	// it is nested in itself, and skipped the text removed with it from
+	// skipStart. skipScript is set once the element or its content is seen
+	// to run script.
+	skip       string
+	depth      int
+	skipped    strings.Builder
+	skipStart  int
+	skipScript bool
+}
+
+// newBodyWalker returns a bodyWalker reading src.
+func newBodyWalker(p *compiledPolicy, dst io.Writer, src io.Reader, removals *[]Removal) *bodyWalker {
+	return &bodyWalker{p: p, z: xhtml.NewTokenizer(src), w: dst, removals: removals}
+}
+
+// walk sanitizes the whole input, returning the first error from reading
+// it other than io.EOF, or from writing the output.
+func (b *bodyWalker) walk() error {
+	for b.err == nil {
+		tt := b.z.Next()
+		raw := string(b.z.Raw())
+		var name string
+		if tt == xhtml.StartTagToken || tt == xhtml.SelfClosingTagToken || tt == xhtml.EndTagToken {
+			tagName, _ := b.z.TagName()
+			name = string(tagName)
+		}
+		if b.skip != "" {
+			b.skipToken(tt, name, raw)
+		} else {
+			b.token(tt, name, raw)
+		}
+		if tt == xhtml.ErrorToken {
+			break
+		}
+		b.offset += len(raw)
+	}
+	if b.skip != "" {
+		b.remove(RemovedElement, b.skip, b.skipped.String(), b.skipStart, b.skipReason())
+	}
+	if b.err != nil {
+		return b.err
+	}
+	if err := b.z.Err(); !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// token handles a token outside removed elements, with its raw text and
+// its lowercased name if it is a tag.
+func (b *bodyWalker) token(tt xhtml.TokenType, name, raw string) {
+	switch tt {
+	case xhtml.ErrorToken:
+		if raw != "" {
+			// a tag the input ends inside
+			reason := ReasonIncomplete
+			if b.runsScript(raw) {
+				reason = ReasonScript
+			}
+			b.remove(RemovedTag, markupName(raw), raw, b.offset, reason)
+		}
+	case xhtml.TextToken:
+		b.write(textEscaper.Replace(string(b.z.Text())))
+	case xhtml.StartTagToken, xhtml.SelfClosingTagToken, xhtml.EndTagToken:
+		b.tag(tt, name, raw)
+	case xhtml.CommentToken:
+		// The tokenizer also reads CDATA sections, processing instructions
+		// and malformed tags as comments, which are always removed
+		data := string(b.z.Text())
+		if b.p.keepComments && strings.HasPrefix(raw, "<!--") && keepsComment(data) {
+			b.write("<!--" + data + "-->")
+		} else {
+			b.remove(RemovedTag, "", raw, b.offset, ReasonMarkup)
+		}
+	default:
+		// doctypes
+		b.remove(RemovedTag, "", raw, b.offset, ReasonMarkup)
+	}
+}
+
+// keepsComment reports whether a comment with data can be kept: it must
+// not be an Internet Explorer conditional comment, and must hold no markup
+// and no "--" that a parser could read differently.
+func keepsComment(data string) bool {
+	return !strings.HasPrefix(strings.TrimSpace(data), "[") && !strings.ContainsAny(data, "<>") && !strings.Contains(data, "--")
+}
+
+// tag writes an allowed tag with its allowed attributes, removes any other,
+// and starts removing the content of removed elements.
+func (b *bodyWalker) tag(tt xhtml.TokenType, tagName, raw string) {
+	if removedElements[tagName] && tt != xhtml.EndTagToken {
+		if tagName == "embed" || (tt == xhtml.SelfClosingTagToken && (tagName == "svg" || tagName == "math")) {
+			// embed is a void element, with no content or end tag, and
+			// foreign elements can be empty
+			reason := ReasonUnsafe
+			if scriptElements[tagName] || b.runsScript(raw) {
+				reason = ReasonScript
+			}
+			b.remove(RemovedElement, tagName, raw, b.offset, reason)
+			return
+		}
+		b.skip, b.depth, b.skipStart = tagName, 1, b.offset
+		b.skipScript = scriptElements[tagName] || b.runsScript(raw)
+		b.skipped.Reset()
+		b.skipToken(xhtml.TextToken, "", raw)
+		return
+	}
+
+	_, allowed := b.p.elements[tagName]
+	if tt == xhtml.EndTagToken {
+		if allowed {
+			b.write("</" + tagName + ">")
+		} else {
+			b.remove(RemovedTag, tagName, raw, b.offset, tagReason(tagName))
+		}
+		return
+	}
+
+	// A removed tag still reports its dangerous attributes, which tell an
+	// attack from stray markup
+	if !allowed {
+		b.remove(RemovedTag, tagName, raw, b.offset, tagReason(tagName))
+	}
+	var kept []attribute
+	for _, attr := range tagAttributes(raw) {
+		if allowed && attr.name == "style" && b.p.styles != nil {
+			attr.value = sanitizeStyle(attr.value, b.p.styles)
+		}
+		reason := b.p.dangerousAttribute(attr)
+		switch {
+		case reason != "":
+		case allowed && !b.p.allowsAttribute(tagName, attr):
+			reason = ReasonNotAllowed
+		case allowed && attr.name == "style" && attr.value == "":
+			// no declarations were allowed
+			reason = ReasonStyle
+		}
+		if reason != "" {
+			b.remove(RemovedAttribute, attr.name, attr.text, b.offset+attr.offset, reason)
+		} else {
+			kept = append(kept, attr)
+		}
+	}
+	if !allowed {
+		return
+	}
+	kept = b.p.linkAttributes(tagName, kept)
+	b.write(startTag(tagName, kept, tt == xhtml.SelfClosingTagToken))
+}
+
+// tagReason returns why a tag named name that the policy does not allow is
+// removed.
+func tagReason(name string) string {
+	if removedTags[name] {
+		return ReasonUnsafe
+	}
+	return ReasonNotAllowed
+}
+
+// runsScript reports whether the raw tag has an attribute that runs script.
+func (b *bodyWalker) runsScript(raw string) bool {
+	return slices.ContainsFunc(tagAttributes(raw), func(attr attribute) bool {
+		return b.p.dangerousAttribute(attr) == ReasonScript
+	})
+}
+
+// skipReason returns why the element being skipped is removed.
+func (b *bodyWalker) skipReason() string {
+	if b.skipScript {
+		return ReasonScript
+	}
+	return ReasonUnsafe
+}
+
+// startTag returns the start tag for element with attrs, quoted and
+// escaped.
+func startTag(element string, attrs []attribute, selfClosing bool) string {
+	var out strings.Builder
+	out.WriteString("<" + element)
+	for _, attr := range attrs {
+		out.WriteString(" " + attr.name)
+		if attr.hasValue {
+			out.WriteString(`="` + html.EscapeString(attr.value) + `"`)
+		}
+	}
+	if selfClosing {
+		out.WriteString("/")
+	}
+	out.WriteString(">")
+	return out.String()
+}
+
+// withRel returns attrs with the values of rel added to its rel attribute,
+// which is added if there is none, and without "opener" if noOpener is set.
+// Values are lowercased and kept once each, in the order they first
+// appear.
+func withRel(attrs []attribute, rel []string, noOpener bool) []attribute {
+	var values []string
+	for _, attr := range attrs {
+		if attr.name == "rel" {
+			values = strings.Fields(strings.ToLower(attr.value))
+			break
+		}
+	}
+	var merged []string
+	for _, value := range append(values, rel...) {
+		if !slices.Contains(merged, value) && !(noOpener && value == "opener") {
+			merged = append(merged, value)
+		}
+	}
+	return withAttribute(attrs, "rel", strings.Join(merged, " "))
+}
+
+// withAttribute returns attrs with the first attribute named name set to
+// value, or with the attribute added if there is none.
+func withAttribute(attrs []attribute, name, value string) []attribute {
+	for i, attr := range attrs {
+		if attr.name == name {
+			attrs[i].value, attrs[i].hasValue = value, true
+			return attrs
+		}
+	}
+	return append(attrs, attribute{name: name, value: value, hasValue: true})
+}
+
+// hasAttribute reports whether attrs hold an attribute named name.
+func hasAttribute(attrs []attribute, name string) bool {
+	return slices.ContainsFunc(attrs, func(attr attribute) bool { return attr.name == name })
+}
+
+// skipToken removes a token inside a removed element, ending the removal
+// at the element's end tag.
+func (b *bodyWalker) skipToken(tt xhtml.TokenType, name, raw string) {
+	if b.removals != nil {
+		b.skipped.WriteString(raw)
+	}
+	if !b.skipScript && (tt == xhtml.StartTagToken || tt == xhtml.SelfClosingTagToken) {
+		b.skipScript = scriptElements[name] || b.runsScript(raw)
+	}
+	if name != b.skip {
+		return
+	}
+	switch tt {
+	case xhtml.StartTagToken:
+		b.depth++
+	case xhtml.EndTagToken:
+		if b.depth--; b.depth == 0 {
+			b.remove(RemovedElement, b.skip, b.skipped.String(), b.skipStart, b.skipReason())
+			b.skip = ""
+		}
+	}
+}
+
+// isAttributeName reports whether name is made of letters, digits and the
+// punctuation of data-*, aria-* and namespaced attributes.
+func isAttributeName(name string) bool {
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z') && !(c >= '0' && c <= '9') && c != '-' && c != '_' && c != ':' && c != '.' {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// write writes text to the output, keeping the first error.
+func (b *bodyWalker) write(text string) {
+	if b.err == nil && text != "" {
+		_, b.err = io.WriteString(b.w, text)
+	}
+}
+
+// remove records a removal if removals are being reported.
+func (b *bodyWalker) remove(kind, name, text string, offset int, reason string) {
+	if b.removals != nil {
+		*b.removals = append(*b.removals, Removal{Kind: kind, Name: name, Text: text, Offset: offset, Reason: reason})
+	}
+}
+
+// tagAttributes returns the attributes of the raw start tag, splitting it
+// as the tokenizer does.
+func tagAttributes(raw string) []attribute {
+	i := 1
+	for i < len(raw) && !isTagSpace(raw[i]) && raw[i] != '/' && raw[i] != '>' {
+		i++
+	}
+	var attrs []attribute
+	for {
+		start := i
+		i = skipTagSpace(raw, i)
+		if i >= len(raw) || raw[i] == '>' {
+			return attrs
+		}
+
+		// The name runs to whitespace, '/', '>' or '=', though a leading
+		// '=' is part of it
+		nameStart := i
+		for i < len(raw) {
+			c := raw[i]
+			if isTagSpace(c) || c == '/' || c == '>' || (c == '=' && i > nameStart) {
+				break
+			}
+			i++
+		}
+		attr := attribute{name: strings.ToLower(raw[nameStart:i])}
+
+		if j := skipTagSpace(raw, i); j < len(raw) && raw[j] == '/' {
+			i = j + 1
+		} else if j < len(raw) && raw[j] == '=' {
+			i = skipTagSpace(raw, j+1)
+			attr.hasValue = true
+			attr.value, i = attributeValue(raw, i)
+		} else {
+			i = j
+		}
+		if attr.name != "" {
+			attr.text, attr.offset = raw[start:i], start
+			attrs = append(attrs, attr)
+		}
+	}
+}
+
+// attributeValue returns the unescaped attribute value starting at raw[i],
+// quoted or not, and the index after it.
+func attributeValue(raw string, i int) (string, int) {
+	if i >= len(raw) || raw[i] == '>' {
+		return "", i
+	}
+	if quote := raw[i]; quote == '"' || quote == '\'' {
+		end := strings.IndexByte(raw[i+1:], quote)
+		if end < 0 {
+			return html.UnescapeString(raw[i+1:]), len(raw)
+		}
+		return html.UnescapeString(raw[i+1 : i+1+end]), i + end + 2
+	}
+	start := i
+	for i < len(raw) && !isTagSpace(raw[i]) && raw[i] != '>' {
+		i++
+	}
+	return html.UnescapeString(raw[start:i]), i
+}
+
+// isTagSpace reports whether c is whitespace inside a tag.
+func isTagSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f'
+}
+
+// skipTagSpace returns the index of the first byte of raw from i that is
+// not whitespace.
+func skipTagSpace(raw string, i int) int {
+	for i < len(raw) && isTagSpace(raw[i]) {
+		i++
+	}
+	return i
+}
+
+// matchWriter accepts writes only while they match the rest of want.
+type matchWriter struct {
+	want string
+}
+
+// Write consumes p from want, failing with errMismatch if want does not
+// start with it.
+func (m *matchWriter) Write(p []byte) (int, error) {
+	return m.WriteString(string(p))
+}
+
+// WriteString consumes s from want, failing with errMismatch if want does
+// not start with it.
+func (m *matchWriter) WriteString(s string) (int, error) {
+	if !strings.HasPrefix(m.want, s) {
+		return 0, errMismatch
+	}
+	m.want = m.want[len(s):]
+	return len(s), nil
+}
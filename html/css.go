@@ -0,0 +1,57 @@
+package html
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultStyleProperties are the CSS properties allowed in style attributes
+// by AllowStyleProperties when it is given none.
+var DefaultStyleProperties = []string{"color", "background-color", "font-size", "text-align"}
+
+var (
+	colorPattern    = regexp.MustCompile(`^(?i:[a-z]+|#[0-9a-f]{3,4}|#[0-9a-f]{6}|#[0-9a-f]{8}|(rgb|hsl)a?\(\s*[0-9.]+%?(\s*[,/]?\s*[0-9.]+%?){2,3}\s*\))$`)
+	lengthPattern   = regexp.MustCompile(`^(?i:[0-9]*\.?[0-9]+(px|em|rem|pt|%)?|xx-small|x-small|small|medium|large|x-large|xx-large|smaller|larger)$`)
+	alignPattern    = regexp.MustCompile(`^(?i:left|right|center|justify|start|end)$`)
+	cssValuePattern = regexp.MustCompile(`^[a-zA-Z0-9#%.,\s+-]*$`)
+)
+
+// styleValuePatterns validate the values of known properties. Values of
+// other allowed properties must match cssValuePattern, which leaves out
+// functions, quotes, escapes and comments, so url(), expression() and
+// @import cannot appear in any of them.
+var styleValuePatterns = map[string]*regexp.Regexp{
+	"color":            colorPattern,
+	"background-color": colorPattern,
+	"border-color":     colorPattern,
+	"font-size":        lengthPattern,
+	"text-align":       alignPattern,
+}
+
+// sanitizeStyle returns the declarations of the style attribute value
+// that set a property in properties to a valid value, or "" if there are
+// none. Declarations are rewritten as "name: value", separated by "; ".
+func sanitizeStyle(style string, properties map[string]bool) string {
+	var kept []string
+	for _, declaration := range strings.Split(style, ";") {
+		name, value, found := strings.Cut(declaration, ":")
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		if !found || !properties[name] || !validStyleValue(name, value) {
+			continue
+		}
+		kept = append(kept, name+": "+value)
+	}
+	return strings.Join(kept, "; ")
+}
+
+// validStyleValue reports whether value is valid for the property name.
+func validStyleValue(name, value string) bool {
+	if value == "" {
+		return false
+	}
+	if pattern, ok := styleValuePatterns[name]; ok {
+		return pattern.MatchString(value)
+	}
+	return cssValuePattern.MatchString(value)
+}
@@ -0,0 +1,74 @@
+package html
+
+import "testing"
+
+func TestSanitizeStyle(t *testing.T) {
+	properties := map[string]bool{"color": true, "background-color": true, "font-size": true, "text-align": true, "margin": true}
+	tests := []struct {
+		style string
+		want  string
+	}{
+		{"color: red", "color: red"},
+		{"COLOR:#ff0000;text-align:center;", "color: #ff0000; text-align: center"},
+		{"color: rgb(255, 0, 0); background-color: hsla(120, 50%, 50%, 0.5)", "color: rgb(255, 0, 0); background-color: hsla(120, 50%, 50%, 0.5)"},
+		{"font-size: 1.5em; font-size: large", "font-size: 1.5em; font-size: large"},
+		{"margin: 0 auto", "margin: 0 auto"},
+		{"color: red !important", ""},
+		{"color: expression(alert(1))", ""},
+		{"background-color: url(javascript:alert(1))", ""},
+		{"margin: url(//evil.example/x.png)", ""},
+		{"margin: expr/**/ession(alert(1))", ""},
+		{`margin: \75rl(x)`, ""},
+		{"@import 'evil.css'; color: blue", "color: blue"},
+		{"font-size: 12px; position: fixed", "font-size: 12px"},
+		{"text-align: center; behavior: url(x.htc)", "text-align: center"},
+		{"color", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := sanitizeStyle(tt.style, properties); got != tt.want {
+			t.Errorf("sanitizeStyle(%q) = %q, want %q", tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy_AllowStyleProperties(t *testing.T) {
+	p := NewPolicy().AllowElements("div", "p").AllowStyleProperties()
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`<p style="color: #c00">red</p>`, `<p style="color: #c00">red</p>`},
+		{`<p style="color:red;font-weight:bold">x</p>`, `<p style="color: red">x</p>`},
+		{`<p style="color: &#x75;rl(x)">x</p>`, `<p>x</p>`},
+		// clickjacking overlays
+		{`<div style="position:fixed;top:0;left:0;width:100%;height:100%;z-index:9999;opacity:0">click</div>`, `<div>click</div>`},
+		{`<div style="position: absolute; top: 0; left: 0; background-color: white">fake login</div>`, `<div style="background-color: white">fake login</div>`},
+		{`<div style="background-color:url(javascript:alert(1))">x</div>`, `<div>x</div>`},
+	}
+	for _, tt := range tests {
+		if got := p.Sanitize(tt.input); got != tt.want {
+			t.Errorf("Sanitize(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	// Without AllowStyleProperties, style attributes are removed
+	if got := NewPolicy().AllowElements("p").AllowAttrs("style").Globally().Sanitize(`<p style="color: red">x</p>`); got != "<p>x</p>" {
+		t.Errorf("Sanitize = %q, want the style removed", got)
+	}
+
+	// Properties given replace the defaults
+	p = NewPolicy().AllowElements("div").AllowStyleProperties("position", "z-index")
+	if got := p.Sanitize(`<div style="position: fixed; z-index: 9999; color: red">x</div>`); got != `<div style="position: fixed; z-index: 9999">x</div>` {
+		t.Errorf("Sanitize = %q", got)
+	}
+
+	s := New([]string{"p"})
+	if got := s.SanitizeBody(`<p style="color: blue; position: fixed">x</p>`); got != `<p style="color: blue">x</p>` {
+		t.Errorf("SanitizeBody = %q", got)
+	}
+	s = New([]string{"p"}, WithStyleProperties())
+	if got := s.SanitizeBody(`<p style="color: blue">x</p>`); got != "<p>x</p>" {
+		t.Errorf("SanitizeBody with no style properties = %q", got)
+	}
+}
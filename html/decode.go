@@ -0,0 +1,51 @@
+package html
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// openTagPattern matches an escaped '<' that one more decode would turn
+	// into the start of a tag, comment or end tag
+	openTagPattern = regexp.MustCompile(`&lt;([a-zA-Z/!?])`)
+	// startTagPattern matches a start tag written by SanitizeBody, whose
+	// attribute values are quoted and hold no '>'
+	startTagPattern = regexp.MustCompile(`<[a-zA-Z][^>]*>`)
+)
+
+// SanitizeBodyDeep sanitizes input like SanitizeBody after decoding its
+// character references up to maxDecodePasses times, so that markup
+// encoded once or more, like "&amp;lt;script&amp;gt;", is sanitized as
+// the markup it decodes to. Decoding stops early once a pass changes
+// nothing; each pass that changes something shortens the input, so the
+// bound only limits how long an input with many layers takes.
+//
+// The output stays safe if a later component decodes it once more before
+// it reaches a browser: an escaped '<' that would start a tag and every
+// '&' in attribute values are escaped a second time, so one more decode
+// gives back the values the policy checked, whatever references input
+// still held after the last pass. Text that holds no such '<' comes out
+// as SanitizeBody would write it.
+func (s *Sanitizer) SanitizeBodyDeep(input string, maxDecodePasses int) string {
+	for i := 0; i < maxDecodePasses; i++ {
+		decoded := html.UnescapeString(input)
+		if decoded == input {
+			break
+		}
+		input = decoded
+	}
+	return escapeAgain(s.SanitizeBody(input))
+}
+
+// escapeAgain escapes the references in sanitized HTML that decoding it
+// once more would turn into markup.
+func escapeAgain(sanitized string) string {
+	// Attribute values go first, since their escaped '<' are escaped again
+	// with the rest of their references
+	sanitized = startTagPattern.ReplaceAllStringFunc(sanitized, func(tag string) string {
+		return strings.ReplaceAll(tag, "&", "&amp;")
+	})
+	return openTagPattern.ReplaceAllString(sanitized, "&amp;lt;$1")
+}
@@ -0,0 +1,106 @@
+package html
+
+import (
+	"html"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeBodyDeep(t *testing.T) {
+	s := UGC()
+	payloads := []string{
+		// single
+		`&lt;script&gt;alert(1)&lt;/script&gt;`,
+		`&#60;script&#62;alert(1)&#60;/script&#62;`,
+		`&#x3C;script&#x3E;alert(1)&#x3C;/script&#x3E;`,
+		`&LT;script&GT;alert(1)&LT;/script&GT;`,
+		`&lt;img src=x onerror=alert(1)&gt;`,
+		// double
+		`&amp;lt;script&amp;gt;alert(1)&amp;lt;/script&amp;gt;`,
+		`&amp;#60;script&amp;#62;alert(1)&amp;#60;/script&amp;#62;`,
+		`&#38;lt;script&#38;gt;alert(1)&#38;lt;/script&#38;gt;`,
+		// mixed numeric and named
+		`&#38;#x3c;script&amp;#62;alert(1)&#x26;lt;/script&#38;gt;`,
+		`&lt;scr&#x69;pt&gt;alert(1)&#60;/script&gt;`,
+		`&amp;amp;lt;script&amp;amp;gt;alert(1)`,
+		`&amp;lt;a href="javascript&amp;#58;alert(1)"&gt;x&lt;/a&gt;`,
+		// markup left over after sanitizing
+		`&lt;&lt;!-- --&gt;img src=x onerror=alert(1)&gt;`,
+		`&lt;<b></b>script&gt;alert(1)&lt;/script&gt;`,
+		`<a title="x&amp;quot; onmouseover=alert(1) y">x</a>`,
+		`<a title='x&amp;#39; onmouseover=alert(1) y'>x</a>`,
+	}
+	for _, input := range payloads {
+		got := s.SanitizeBodyDeep(input, 3)
+		if strings.Contains(strings.ToLower(got), "<script") || strings.Contains(got, "<img") {
+			t.Errorf("SanitizeBodyDeep(%q) = %q", input, got)
+		}
+		// One more decode downstream must leave nothing to remove
+		decoded := html.UnescapeString(got)
+		if _, removals := s.SanitizeBodyDetailed(decoded); len(removals) > 0 {
+			t.Errorf("SanitizeBodyDeep(%q) = %q, decoding it again gives %q", input, got, decoded)
+		}
+	}
+
+	tests := []struct {
+		input  string
+		passes int
+		want   string
+	}{
+		{`<b>1 &lt; 2 &amp;&amp; 3 &gt; 2</b>`, 3, `<b>1 &lt; 2 &amp;&amp; 3 &gt; 2</b>`},
+		{`&lt;b&gt;bold&lt;/b&gt;`, 1, `<b>bold</b>`},
+		{`&amp;lt;b&amp;gt;bold`, 1, `&amp;lt;b&gt;bold`},
+		{`&amp;lt;b&amp;gt;bold&amp;lt;/b&amp;gt;`, 2, `<b>bold</b>`},
+		{`&lt;script&gt;x&lt;/script&gt;ok`, 0, `&amp;lt;script&gt;x&amp;lt;/script&gt;ok`},
+		{`&lt;script&gt;x&lt;/script&gt;ok`, 1, `ok`},
+		{`<a title="it&#39;s &quot;hi&quot;">x</a>`, 0, `<a title="it&amp;#39;s &amp;#34;hi&amp;#34;">x</a>`},
+	}
+	for _, tt := range tests {
+		if got := s.SanitizeBodyDeep(tt.input, tt.passes); got != tt.want {
+			t.Errorf("SanitizeBodyDeep(%q, %d) = %q, want %q", tt.input, tt.passes, got, tt.want)
+		}
+	}
+}
+
+// TestSanitizeBodyDeep_MoreLayers checks input with more layers of
+// encoding than SanitizeBodyDeep decodes, whose output a later decode
+// must not turn into markup the policy removes.
+func TestSanitizeBodyDeep_MoreLayers(t *testing.T) {
+	s := UGC()
+	payloads := []string{
+		`<a href="&#106;avascript:alert(1)">x</a>`,
+		`<a href="javascript&#58;alert(1)">x</a>`,
+		`<a title="&lt;img src=x onerror=alert(1)&gt;">x</a>`,
+		`<a title="x&quot; onmouseover=alert(1) y">x</a>`,
+		`&lt;script&gt;alert(1)&lt;/script&gt;`,
+	}
+	for _, payload := range payloads {
+		// Encode the references in payload once for each layer
+		encoded := payload
+		for layers := 1; layers <= 4; layers++ {
+			encoded = strings.ReplaceAll(encoded, "&", "&amp;")
+			for passes := 0; passes < layers; passes++ {
+				got := s.SanitizeBodyDeep(encoded, passes)
+				decoded := html.UnescapeString(got)
+				if _, removals := s.SanitizeBodyDetailed(decoded); len(removals) > 0 {
+					t.Errorf("SanitizeBodyDeep(%q, %d) = %q, decoding it again gives %q", encoded, passes, got, decoded)
+				}
+			}
+		}
+	}
+
+	tests := []struct {
+		input  string
+		passes int
+		want   string
+	}{
+		{`<a href="&amp;amp;#106;avascript:alert(1)">x</a>`, 1, `<a href="&amp;amp;#106;avascript:alert(1)">x</a>`},
+		{`<a href="&amp;#106;avascript:alert(1)">x</a>`, 0, `<a href="&amp;amp;#106;avascript:alert(1)">x</a>`},
+		{`<a title="1 &lt; 2 &amp; &lt;b">x</a>`, 0, `<a title="1 &amp;lt; 2 &amp;amp; &amp;lt;b">x</a>`},
+	}
+	for _, tt := range tests {
+		if got := s.SanitizeBodyDeep(tt.input, tt.passes); got != tt.want {
+			t.Errorf("SanitizeBodyDeep(%q, %d) = %q, want %q", tt.input, tt.passes, got, tt.want)
+		}
+	}
+}
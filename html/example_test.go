@@ -0,0 +1,28 @@
+package html_test
+
+import (
+	"html/template"
+	"os"
+
+	"github.com/ravisastryk/go-safeinput/html"
+)
+
+// Sanitized HTML goes into a template as template.HTML, so that allowed
+// tags render rather than being escaped again, while raw input in the
+// same template is still escaped.
+func ExampleSanitizer_SanitizeBodyHTML() {
+	page := template.Must(template.New("comment").Parse(
+		"<div class=\"comment\">{{.Body}}</div>\n<p>by {{.Author}}</p>\n"))
+
+	s := html.New([]string{"b", "i"})
+	_ = page.Execute(os.Stdout, struct {
+		Body   template.HTML
+		Author string
+	}{
+		Body:   s.SanitizeBodyHTML(`<b>Nice</b> post! <script>steal()</script>`),
+		Author: "<b>mallory</b>",
+	})
+	// Output:
+	// <div class="comment"><b>Nice</b> post!</div>
+	// <p>by &lt;b&gt;mallory&lt;/b&gt;</p>
+}
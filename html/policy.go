@@ -0,0 +1,414 @@
+package html
+
+import (
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Policy says which elements and attributes SanitizeBody keeps. It is
+// built with chained calls:
+//
+//	p := html.NewPolicy().
+//		AllowElements("p", "a", "img").
+//		AllowAttrs("href").OnElements("a").
+//		AllowAttrs("src").Matching(cdnPattern).OnElements("img").
+//		AllowURLSchemes("https").
+//		RequireRel("nofollow", "noopener")
+//
+// and compiled on first use. A Policy is safe for concurrent use, so it
+// can be built once and shared; changing it after use recompiles it for
+// the calls that follow.
+//
+// Whatever the policy allows, elements that run script or embed other
+// documents are removed with their content, link and meta tags are
+// removed, and so are event handler attributes and attributes with
+// malformed names.
+type Policy struct {
+	mu       sync.Mutex
+	elements map[string]bool
+	// attrs maps element names, or "" for every element, to the rules for
+	// their attributes
+	attrs    map[string]map[string]attrRule
+	anyAttrs bool
+	schemes  map[string]bool
+	rel      []string
+	// noopener adds rel="noopener noreferrer" to links with a target, and
+	// targetBlank sets target="_blank" on every link
+	noopener    bool
+	targetBlank bool
+	// styles are the properties allowed in style attributes, which are
+	// removed if it is nil
+	styles map[string]bool
+	// dataTypes are the media types of the data: URIs allowed, which are
+	// all removed if it is nil, and maxData the most bytes they may decode
+	// to
+	dataTypes map[string]bool
+	maxData   int
+
+	keepComments bool
+
+	compiled atomic.Pointer[compiledPolicy]
+}
+
+// attrRule allows an attribute, with any value or one matching pattern.
+type attrRule struct {
+	pattern *regexp.Regexp
+}
+
+// AttrPolicy allows attributes for a Policy, once OnElements or Globally
+// says where.
+type AttrPolicy struct {
+	p       *Policy
+	names   []string
+	pattern *regexp.Regexp
+}
+
+// NewPolicy returns a Policy that strips every tag and allows the
+// DefaultURLSchemes.
+func NewPolicy() *Policy {
+	return &Policy{
+		elements: make(map[string]bool),
+		attrs:    make(map[string]map[string]attrRule),
+	}
+}
+
+// AllowElements allows the named elements, without attributes unless
+// AllowAttrs allows some.
+func (p *Policy) AllowElements(names ...string) *Policy {
+	p.update(func() {
+		for _, name := range names {
+			p.elements[strings.ToLower(name)] = true
+		}
+	})
+	return p
+}
+
+// AllowAttrs starts allowing the named attributes, on the elements
+// OnElements or Globally then names. It does not allow the elements.
+func (p *Policy) AllowAttrs(names ...string) *AttrPolicy {
+	return &AttrPolicy{p: p, names: names}
+}
+
+// Matching limits the attributes to values matching pattern, after
+// character references are decoded. Without it any value is allowed.
+func (a *AttrPolicy) Matching(pattern *regexp.Regexp) *AttrPolicy {
+	a.pattern = pattern
+	return a
+}
+
+// OnElements allows the attributes on the named elements.
+func (a *AttrPolicy) OnElements(elements ...string) *Policy {
+	for _, element := range elements {
+		a.allow(strings.ToLower(element))
+	}
+	return a.p
+}
+
+// Globally allows the attributes on every allowed element.
+func (a *AttrPolicy) Globally() *Policy {
+	a.allow("")
+	return a.p
+}
+
+// allow allows the attributes on element, or on every element if it is "".
+func (a *AttrPolicy) allow(element string) {
+	a.p.update(func() {
+		rules := a.p.attrs[element]
+		if rules == nil {
+			rules = make(map[string]attrRule)
+			a.p.attrs[element] = rules
+		}
+		for _, name := range a.names {
+			rules[strings.ToLower(name)] = attrRule{pattern: a.pattern}
+		}
+	})
+}
+
+// AllowURLSchemes allows the schemes in the href, src, action, formaction
+// and poster attributes. The first call replaces DefaultURLSchemes, and
+// later calls add to it. Relative URLs are always allowed.
+func (p *Policy) AllowURLSchemes(schemes ...string) *Policy {
+	p.update(func() {
+		if p.schemes == nil {
+			p.schemes = make(map[string]bool)
+		}
+		for _, scheme := range schemes {
+			p.schemes[strings.ToLower(scheme)] = true
+		}
+	})
+	return p
+}
+
+// AllowStyleProperties allows the style attribute on every allowed
+// element, keeping only declarations that set one of properties, or of
+// DefaultStyleProperties if there are none, to a valid value. Colors, font
+// sizes and alignments are checked against their syntax, and the values of
+// other properties may not hold functions, quotes, escapes or comments, so
+// url(), expression() and @import never get through. Later calls add to
+// the properties.
+func (p *Policy) AllowStyleProperties(properties ...string) *Policy {
+	if len(properties) == 0 {
+		properties = DefaultStyleProperties
+	}
+	p.update(func() {
+		if p.styles == nil {
+			p.styles = make(map[string]bool)
+		}
+		for _, property := range properties {
+			p.styles[strings.ToLower(property)] = true
+		}
+	})
+	return p
+}
+
+// AllowDataURIs allows data: URIs in URL attributes, with one of the
+// mediaTypes, or of DefaultDataURIMediaTypes if there are none. The URI
+// must be base64 encoded, with valid base64, and decode to no more than
+// DefaultMaxDataURISize bytes or the limit set by MaxDataURISize. Any
+// other data: URI is removed, and so is every data: URI without this or
+// "data" among the allowed schemes. Later calls add to the media types.
+func (p *Policy) AllowDataURIs(mediaTypes ...string) *Policy {
+	if len(mediaTypes) == 0 {
+		mediaTypes = DefaultDataURIMediaTypes
+	}
+	p.update(func() {
+		if p.dataTypes == nil {
+			p.dataTypes = make(map[string]bool)
+		}
+		for _, mediaType := range mediaTypes {
+			p.dataTypes[strings.ToLower(mediaType)] = true
+		}
+	})
+	return p
+}
+
+// MaxDataURISize sets the most bytes an allowed data: URI may decode to.
+func (p *Policy) MaxDataURISize(bytes int) *Policy {
+	p.update(func() {
+		p.maxData = bytes
+	})
+	return p
+}
+
+// KeepComments sets whether comments are kept, which they are not by
+// default. Even when they are, conditional comments like
+// "<!--[if IE]>" and comments holding markup or "--" are removed, and so
+// are CDATA sections, which mean nothing in HTML and serve only to hide
+// payloads.
+func (p *Policy) KeepComments(keep bool) *Policy {
+	p.update(func() {
+		p.keepComments = keep
+	})
+	return p
+}
+
+// RequireRel adds the rel values, such as "nofollow", to every a and area
+// element kept with an href, along with any rel values it allows.
+func (p *Policy) RequireRel(values ...string) *Policy {
+	p.update(func() {
+		for _, value := range values {
+			if value = strings.ToLower(value); !slices.Contains(p.rel, value) {
+				p.rel = append(p.rel, value)
+			}
+		}
+	})
+	return p
+}
+
+// RequireNofollow adds rel="nofollow" to every a and area element kept
+// with an href, so that search engines give user links no weight.
+func (p *Policy) RequireNofollow() *Policy {
+	return p.RequireRel("nofollow")
+}
+
+// RequireNoopener adds "noopener" and "noreferrer" to the rel values of
+// every a and area element kept with a target attribute, so that a page
+// opened from a user link cannot navigate the page that opened it. An
+// "opener" value is removed.
+func (p *Policy) RequireNoopener() *Policy {
+	p.update(func() {
+		p.noopener = true
+	})
+	return p
+}
+
+// ForceTargetBlank sets whether every a and area element kept with an href
+// opens in a new tab: its target is set to "_blank", and rel values are
+// fixed as RequireNoopener fixes them.
+func (p *Policy) ForceTargetBlank(force bool) *Policy {
+	p.update(func() {
+		p.targetBlank = force
+	})
+	return p
+}
+
+// setStyleProperties replaces the allowed style properties.
+func (p *Policy) setStyleProperties(properties []string) {
+	p.update(func() {
+		p.styles = make(map[string]bool)
+		for _, property := range properties {
+			p.styles[strings.ToLower(property)] = true
+		}
+	})
+}
+
+// setURLSchemes replaces the allowed schemes.
+func (p *Policy) setURLSchemes(schemes []string) {
+	p.update(func() {
+		p.schemes = schemeSet(schemes)
+	})
+}
+
+// update changes the policy with f, dropping its compiled form.
+func (p *Policy) update(f func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	f()
+	p.compiled.Store(nil)
+}
+
+// Sanitize removes from input every element and attribute the policy does
+// not allow, like (*Sanitizer).SanitizeBody.
+func (p *Policy) Sanitize(input string) string {
+	var b strings.Builder
+	_ = p.compile().sanitizeBody(&b, strings.NewReader(input), nil)
+	return strings.TrimSpace(b.String())
+}
+
+// compiledPolicy is a Policy flattened for lookups while sanitizing: the
+// attribute rules of each element include the global ones.
+type compiledPolicy struct {
+	elements map[string]map[string]attrRule
+	anyAttrs bool
+	schemes  map[string]bool
+	rel      []string
+	styles   map[string]bool
+
+	noopener    bool
+	targetBlank bool
+
+	dataTypes map[string]bool
+	maxData   int
+
+	keepComments bool
+}
+
+// compile returns the compiled form of the policy, compiling it if it has
+// changed since it last was.
+func (p *Policy) compile() *compiledPolicy {
+	if c := p.compiled.Load(); c != nil {
+		return c
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c := p.compiled.Load(); c != nil {
+		return c
+	}
+
+	c := &compiledPolicy{
+		elements: make(map[string]map[string]attrRule, len(p.elements)),
+		anyAttrs: p.anyAttrs,
+		schemes:  maps.Clone(p.schemes),
+		rel:      slices.Clone(p.rel),
+		styles:   maps.Clone(p.styles),
+
+		noopener:    p.noopener || p.targetBlank,
+		targetBlank: p.targetBlank,
+
+		dataTypes: maps.Clone(p.dataTypes),
+		maxData:   p.maxData,
+
+		keepComments: p.keepComments,
+	}
+	if c.dataTypes == nil && c.schemes["data"] {
+		c.dataTypes = schemeSet(DefaultDataURIMediaTypes)
+	}
+	if c.maxData <= 0 {
+		c.maxData = DefaultMaxDataURISize
+	}
+	if c.schemes == nil {
+		c.schemes = schemeSet(DefaultURLSchemes)
+	}
+	for element := range p.elements {
+		if removedElements[element] || removedTags[element] {
+			continue
+		}
+		rules := maps.Clone(p.attrs[""])
+		if rules == nil {
+			rules = make(map[string]attrRule)
+		}
+		maps.Copy(rules, p.attrs[element])
+		c.elements[element] = rules
+	}
+	p.compiled.Store(c)
+	return c
+}
+
+// sanitizeBody writes the sanitized HTML read from src to dst, appending
+// what it removes to removals if it is not nil. It returns the first error
+// from reading src other than io.EOF, or from writing dst.
+func (c *compiledPolicy) sanitizeBody(dst io.Writer, src io.Reader, removals *[]Removal) error {
+	return newBodyWalker(c, dst, src, removals).walk()
+}
+
+// dangerousAttribute returns why attr is removed from any element, or ""
+// if it is not: ReasonMalformed for an attribute with a malformed name,
+// ReasonScript for an event handler or a URL attribute with a script URL,
+// and ReasonURL for a URL attribute with another URL not allowed.
+func (c *compiledPolicy) dangerousAttribute(attr attribute) string {
+	switch {
+	case !isAttributeName(attr.name):
+		return ReasonMalformed
+	case strings.HasPrefix(attr.name, "on"):
+		return ReasonScript
+	case urlAttributes[attr.name] && !allowsURLValue(attr.value, c):
+		if isScriptURL(attr.value) {
+			return ReasonScript
+		}
+		return ReasonURL
+	}
+	return ""
+}
+
+// allowsAttribute reports whether the allowed element keeps attr, which is
+// not dangerous: it must be allowed on the element, with a matching value.
+// A style attribute is allowed if style properties are, and must then be
+// passed through sanitizeStyle.
+func (c *compiledPolicy) allowsAttribute(element string, attr attribute) bool {
+	if attr.name == "style" {
+		return c.styles != nil
+	}
+	rule, ok := c.elements[element][attr.name]
+	if !ok {
+		return c.anyAttrs
+	}
+	return rule.pattern == nil || rule.pattern.MatchString(attr.value)
+}
+
+// linkAttributes returns the attributes kept on element with the target
+// and rel values the policy requires of links. Attributes keep their order,
+// and any added follow them, target before rel.
+func (c *compiledPolicy) linkAttributes(element string, attrs []attribute) []attribute {
+	if element != "a" && element != "area" {
+		return attrs
+	}
+	var rel []string
+	if hasAttribute(attrs, "href") {
+		if c.targetBlank {
+			attrs = withAttribute(attrs, "target", "_blank")
+		}
+		rel = append(rel, c.rel...)
+	}
+	if c.noopener && hasAttribute(attrs, "target") {
+		rel = append(rel, "noopener", "noreferrer")
+	}
+	if len(rel) == 0 {
+		return attrs
+	}
+	return withRel(attrs, rel, c.noopener)
+}
@@ -0,0 +1,130 @@
+package html
+
+import (
+	"regexp"
+	"sync"
+	"testing"
+)
+
+func TestPolicy_Sanitize(t *testing.T) {
+	cdn := regexp.MustCompile(`^https://cdn\.example\.com/`)
+	p := NewPolicy().
+		AllowElements("p", "a", "IMG", "b").
+		AllowAttrs("href").OnElements("a").
+		AllowAttrs("src").Matching(cdn).OnElements("img").
+		AllowAttrs("title", "class").Globally().
+		AllowURLSchemes("https").
+		RequireRel("nofollow", "noopener")
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`<p class="x" id="y">hi</p>`, `<p class="x">hi</p>`},
+		{`<b style="color:red" title="t">x</b>`, `<b title="t">x</b>`},
+		{`<a href="https://example.com/">x</a>`, `<a href="https://example.com/" rel="nofollow noopener">x</a>`},
+		{`<a href="/relative" rel="noopener">x</a>`, `<a href="/relative" rel="nofollow noopener">x</a>`},
+		{`<a href="http://example.com/">x</a>`, `<a>x</a>`},
+		{`<a title="no link">x</a>`, `<a title="no link">x</a>`},
+		{`<img src="https://cdn.example.com/a.png">`, `<img src="https://cdn.example.com/a.png">`},
+		{`<img src="https://evil.example/a.png">`, `<img>`},
+		{`<p href="https://example.com/">x</p>`, `<p>x</p>`},
+		{`<a href="javascript:alert(1)" onclick="x()">x</a>`, `<a>x</a>`},
+		{`<div><p>kept</p></div>`, `<p>kept</p>`},
+	}
+	for _, tt := range tests {
+		if got := p.Sanitize(tt.input); got != tt.want {
+			t.Errorf("Sanitize(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy_Defaults(t *testing.T) {
+	if got := NewPolicy().Sanitize("<p>x</p> <b>y</b>"); got != "x y" {
+		t.Errorf("empty policy Sanitize = %q, want every tag stripped", got)
+	}
+
+	p := NewPolicy().AllowElements("a").AllowAttrs("href").OnElements("a")
+	if got := p.Sanitize(`<a href="mailto:me@example.com">x</a>`); got != `<a href="mailto:me@example.com">x</a>` {
+		t.Errorf("Sanitize = %q, want DefaultURLSchemes allowed", got)
+	}
+
+	// Scripts and friends stay removed whatever the policy allows
+	p = NewPolicy().AllowElements("script", "style", "svg", "link", "b").AllowAttrs("onclick").Globally()
+	if got := p.Sanitize(`<script>x()</script><link rel="x"><b onclick="y()">b</b><svg></svg>`); got != "<b>b</b>" {
+		t.Errorf("Sanitize = %q, want <b>b</b>", got)
+	}
+}
+
+func TestPolicy_Concurrent(t *testing.T) {
+	p := NewPolicy().AllowElements("b").AllowAttrs("title").OnElements("b")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if got := p.Sanitize(`<b title="t">x</b><i>y</i>`); got != `<b title="t">x</b>y` && got != `<b title="t">x</b><i>y</i>` {
+					t.Errorf("Sanitize = %q", got)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			p.AllowElements("i")
+		}()
+	}
+	wg.Wait()
+	if got := p.Sanitize("<i>y</i>"); got != "<i>y</i>" {
+		t.Errorf("Sanitize after AllowElements = %q", got)
+	}
+}
+
+func TestPolicy_Links(t *testing.T) {
+	base := func() *Policy {
+		return NewPolicy().
+			AllowElements("a", "area", "b").
+			AllowAttrs("href", "target", "rel", "title").OnElements("a", "area")
+	}
+	tests := []struct {
+		name  string
+		p     *Policy
+		input string
+		want  string
+	}{
+		{"noopener added", base().RequireNoopener(),
+			`<a href="/x" target="_blank">x</a>`, `<a href="/x" target="_blank" rel="noopener noreferrer">x</a>`},
+		{"noopener merged", base().RequireNoopener(),
+			`<a rel="Author noopener" target=_blank href=/x>x</a>`, `<a rel="author noopener noreferrer" target="_blank" href="/x">x</a>`},
+		{"opener removed", base().RequireNoopener(),
+			`<a href="/x" rel="opener" target="w">x</a>`, `<a href="/x" rel="noopener noreferrer" target="w">x</a>`},
+		{"no target", base().RequireNoopener(),
+			`<a href="/x" rel="opener">x</a>`, `<a href="/x" rel="opener">x</a>`},
+		{"area", base().RequireNoopener(),
+			`<area href="/x" target="_top">`, `<area href="/x" target="_top" rel="noopener noreferrer">`},
+		{"target not allowed", NewPolicy().AllowElements("a").AllowAttrs("href").OnElements("a").RequireNoopener(),
+			`<a href="/x" target="_blank">x</a>`, `<a href="/x">x</a>`},
+		{"target forced", base().ForceTargetBlank(true),
+			`<a href="/x" title="t">x</a>`, `<a href="/x" title="t" target="_blank" rel="noopener noreferrer">x</a>`},
+		{"target replaced", base().ForceTargetBlank(true),
+			`<a target="_self" href="/x" rel="opener">x</a>`, `<a target="_blank" href="/x" rel="noopener noreferrer">x</a>`},
+		{"target forced without href", base().ForceTargetBlank(true),
+			`<a title="t">x</a>`, `<a title="t">x</a>`},
+		{"target not forced", base().ForceTargetBlank(true).ForceTargetBlank(false),
+			`<a href="/x">x</a>`, `<a href="/x">x</a>`},
+		{"nofollow", base().RequireNofollow(),
+			`<a href="/x">x</a><b>y</b>`, `<a href="/x" rel="nofollow">x</a><b>y</b>`},
+		{"all", base().RequireNofollow().RequireNoopener().ForceTargetBlank(true),
+			`<a href="/x" rel="nofollow nofollow">x</a>`, `<a href="/x" rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Sanitize(tt.input); got != tt.want {
+			t.Errorf("%s: Sanitize(%q) = %q, want %q", tt.name, tt.input, got, tt.want)
+		}
+	}
+
+	got := UGC().SanitizeBody(`<a href="https://example.com/" target="_blank">x</a>`)
+	if want := `<a href="https://example.com/" target="_blank" rel="noopener noreferrer">x</a>`; got != want {
+		t.Errorf("UGC SanitizeBody = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+package html
+
+import "html/template"
+
+// SafeHTML is HTML produced by sanitizing with a Policy. Its content is
+// unexported, so NewSafeHTML is the only way to make one, and a SafeHTML
+// can be trusted wherever it comes from.
+type SafeHTML struct {
+	html string
+}
+
+// stripPolicy sanitizes for NewSafeHTML when it is given no policy.
+var stripPolicy = NewPolicy()
+
+// NewSafeHTML sanitizes input with p, as p.Sanitize does. A nil p strips
+// every tag, as NewPolicy does.
+func NewSafeHTML(p *Policy, input string) SafeHTML {
+	if p == nil {
+		p = stripPolicy
+	}
+	return SafeHTML{html: p.Sanitize(input)}
+}
+
+// String returns the sanitized HTML.
+func (h SafeHTML) String() string {
+	return h.html
+}
+
+// HTML returns the sanitized HTML as template.HTML, which html/template
+// writes out as it is rather than escaping it again.
+func (h SafeHTML) HTML() template.HTML {
+	return template.HTML(h.html) // #nosec G203 -- sanitized by NewSafeHTML
+}
+
+// SanitizeHTML sanitizes input like Sanitize and returns it as
+// template.HTML, ready for html/template.
+func (p *Policy) SanitizeHTML(input string) template.HTML {
+	return NewSafeHTML(p, input).HTML()
+}
+
+// SanitizeBodyHTML sanitizes input like SanitizeBody and returns it as
+// template.HTML, ready for html/template. Prefer it to converting a
+// string to template.HTML by hand, which would as easily mark raw input
+// safe.
+func (s *Sanitizer) SanitizeBodyHTML(input string) template.HTML {
+	return s.policy.SanitizeHTML(input)
+}
@@ -0,0 +1,39 @@
+package html
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestSafeHTML(t *testing.T) {
+	p := NewPolicy().AllowElements("b")
+	h := NewSafeHTML(p, `<b onclick="x()">bold</b><script>x()</script>`)
+	if h.String() != "<b>bold</b>" || h.HTML() != template.HTML("<b>bold</b>") {
+		t.Errorf("NewSafeHTML = %q, %q", h.String(), h.HTML())
+	}
+	if got := p.SanitizeHTML("<i>x</i>"); got != "x" {
+		t.Errorf("SanitizeHTML = %q", got)
+	}
+	if got := UGC().SanitizeBodyHTML(`<a href="javascript:x">a</a>`); got != "<a>a</a>" {
+		t.Errorf("SanitizeBodyHTML = %q", got)
+	}
+
+	// Templates escape the string form but not the template.HTML one
+	var out strings.Builder
+	tmpl := template.Must(template.New("").Parse("{{.}}|{{.HTML}}"))
+	if err := tmpl.Execute(&out, h); err != nil || out.String() != "&lt;b&gt;bold&lt;/b&gt;|<b>bold</b>" {
+		t.Errorf("template output = %q, %v", out.String(), err)
+	}
+
+	// A nil policy strips every tag rather than panicking
+	if got := NewSafeHTML(nil, `<b>bold</b><script>x()</script>`).String(); got != "bold" {
+		t.Errorf("NewSafeHTML(nil) = %q", got)
+	}
+
+	// A zero SafeHTML is empty, never unsanitized input
+	var zero SafeHTML
+	if zero.HTML() != "" {
+		t.Errorf("zero SafeHTML = %q", zero.HTML())
+	}
+}
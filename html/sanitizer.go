@@ -3,57 +3,237 @@ package html
 
 import (
 	"html"
+	"io"
+	"maps"
 	"regexp"
+	"slices"
 	"strings"
 )
 
 var (
-	tagPattern    = regexp.MustCompile(`<[^>]*>`)
-	scriptPattern = regexp.MustCompile(`(?i)<script[\s\S]*?</script>`)
-	stylePattern  = regexp.MustCompile(`(?i)<style[\s\S]*?</style>`)
-	iframePattern = regexp.MustCompile(`(?i)<iframe[\s\S]*?</iframe>`)
-	objectPattern = regexp.MustCompile(`(?i)<object[\s\S]*?</object>`)
-	embedPattern  = regexp.MustCompile(`(?i)<embed[\s\S]*?</embed>`)
-	linkPattern   = regexp.MustCompile(`(?i)<link[^>]*>`)
-	metaPattern   = regexp.MustCompile(`(?i)<meta[^>]*>`)
-	eventPattern  = regexp.MustCompile(`(?i)\s+on\w+\s*=\s*["'][^"']*["']`)
-	eventPattern2 = regexp.MustCompile(`(?i)\s+on\w+\s*=\s*[^\s>]+`)
+	tagPattern     = regexp.MustCompile(`<[^>]*>`)
+	tagNamePattern = regexp.MustCompile(`^<\s*/?\s*([a-zA-Z][a-zA-Z0-9-]*)`)
+	schemePattern  = regexp.MustCompile(`^[a-z][a-z0-9+.-]*$`)
 )
 
-// Sanitizer provides HTML sanitization.
+// DefaultURLSchemes are the schemes allowed in URL attributes by default.
+var DefaultURLSchemes = []string{"http", "https", "mailto"}
+
+// Kinds of markup reported in a Removal.
+const (
+	RemovedElement   = "element"
+	RemovedTag       = "tag"
+	RemovedAttribute = "attribute"
+)
+
+// Reasons markup is removed, reported in a Removal.
+const (
+	// ReasonScript is markup that runs script: a script, iframe, object or
+	// embed element, an element holding one or an event handler, an event
+	// handler attribute, or a URL such as "javascript:alert(1)".
+	ReasonScript = "script"
+	// ReasonURL is a URL attribute whose URL is malformed or has a scheme
+	// not allowed.
+	ReasonURL = "url"
+	// ReasonUnsafe is an element or tag removed whatever the policy allows
+	// that does not run script itself, such as style, svg or link.
+	ReasonUnsafe = "unsafe"
+	// ReasonMalformed is an attribute with a malformed name.
+	ReasonMalformed = "malformed"
+	// ReasonNotAllowed is a tag or attribute the policy does not allow.
+	ReasonNotAllowed = "not_allowed"
+	// ReasonStyle is a style attribute without any allowed declarations.
+	ReasonStyle = "style"
+	// ReasonMarkup is a comment, CDATA section, processing instruction or
+	// doctype.
+	ReasonMarkup = "markup"
+	// ReasonIncomplete is a tag the input ends inside.
+	ReasonIncomplete = "incomplete"
+)
+
+// Removal describes markup removed by SanitizeBodyDetailed.
+type Removal struct {
+	// Kind is RemovedElement for an element removed with its content,
+	// RemovedTag for a single tag, comment or doctype, or
+	// RemovedAttribute for an attribute.
+	Kind string
+	// Name is the lowercased tag or attribute name, if it has one.
+	Name string
+	// Text is the removed markup.
+	Text string
+	// Offset is the byte offset in the input where Text starts.
+	Offset int
+	// Reason is why the markup was removed, one of the Reason constants.
+	Reason string
+}
+
+// Sanitizer provides HTML sanitization, by a Policy that keeps the allowed
+// tags with any attributes that are not dangerous, and style attributes
+// with only the DefaultStyleProperties.
 type Sanitizer struct {
 	allowedTags map[string]bool
 	stripAll    bool
+	policy      *Policy
+}
+
+// Option configures a Sanitizer created by New.
+type Option func(*Sanitizer)
+
+// WithURLSchemes sets the schemes allowed in the href, src, action,
+// formaction and poster attributes of allowed tags, replacing
+// DefaultURLSchemes. Relative URLs are always allowed.
+func WithURLSchemes(schemes ...string) Option {
+	return func(s *Sanitizer) {
+		s.policy.setURLSchemes(schemes)
+	}
+}
+
+// WithStyleProperties sets the CSS properties allowed in style attributes,
+// replacing DefaultStyleProperties, as Policy.AllowStyleProperties
+// describes. With none, style attributes are removed.
+func WithStyleProperties(properties ...string) Option {
+	return func(s *Sanitizer) {
+		s.policy.setStyleProperties(properties)
+	}
+}
+
+// WithDataURIs allows data: URIs with the media types, or with
+// DefaultDataURIMediaTypes if there are none, as Policy.AllowDataURIs
+// describes.
+func WithDataURIs(mediaTypes ...string) Option {
+	return func(s *Sanitizer) {
+		s.policy.AllowDataURIs(mediaTypes...)
+	}
+}
+
+// WithMaxDataURISize sets the most bytes an allowed data: URI may decode
+// to.
+func WithMaxDataURISize(bytes int) Option {
+	return func(s *Sanitizer) {
+		s.policy.MaxDataURISize(bytes)
+	}
+}
+
+// WithComments sets whether comments are kept, as Policy.KeepComments
+// describes.
+func WithComments(keep bool) Option {
+	return func(s *Sanitizer) {
+		s.policy.KeepComments(keep)
+	}
+}
+
+// WithNoopener adds rel="noopener noreferrer" to links with a target, as
+// Policy.RequireNoopener describes.
+func WithNoopener() Option {
+	return func(s *Sanitizer) {
+		s.policy.RequireNoopener()
+	}
+}
+
+// WithTargetBlank sets whether links open in a new tab, as
+// Policy.ForceTargetBlank describes.
+func WithTargetBlank(force bool) Option {
+	return func(s *Sanitizer) {
+		s.policy.ForceTargetBlank(force)
+	}
+}
+
+// WithNofollow adds rel="nofollow" to links.
+func WithNofollow() Option {
+	return func(s *Sanitizer) {
+		s.policy.RequireNofollow()
+	}
 }
 
 // New creates an HTML Sanitizer.
-func New(allowedTags []string) *Sanitizer {
-	s := &Sanitizer{allowedTags: make(map[string]bool)}
+func New(allowedTags []string, opts ...Option) *Sanitizer {
+	s := &Sanitizer{allowedTags: make(map[string]bool), policy: NewPolicy()}
+	s.policy.anyAttrs = true
+	s.policy.AllowStyleProperties()
 	if len(allowedTags) == 0 {
 		s.stripAll = true
 	} else {
 		for _, tag := range allowedTags {
 			s.allowedTags[strings.ToLower(tag)] = true
 		}
+		s.policy.AllowElements(allowedTags...)
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
 	return s
 }
 
-// SanitizeBody removes dangerous HTML elements.
+// SanitizeBody removes dangerous HTML elements, event handler attributes,
+// URL attributes whose scheme is not allowed, and every tag not in the
+// allowed tags, or every tag if there are none.
+//
+// The input is split into tags and text by an HTML5 tokenizer, as a
+// browser would split it. Allowed tags are written back out with their
+// remaining attributes quoted and escaped, and text is escaped, so the
+// output holds no markup other than the allowed tags. The script, style,
+// iframe, object, embed, noscript, noembed, noframes, template, xmp, svg
+// and math elements are removed with their content whatever the allowed
+// tags, the foreign and template elements because browsers parse what is
+// inside them differently; link and meta tags are always removed.
 func (s *Sanitizer) SanitizeBody(input string) string {
-	result := scriptPattern.ReplaceAllString(input, "")
-	result = stylePattern.ReplaceAllString(result, "")
-	result = iframePattern.ReplaceAllString(result, "")
-	result = objectPattern.ReplaceAllString(result, "")
-	result = embedPattern.ReplaceAllString(result, "")
-	result = linkPattern.ReplaceAllString(result, "")
-	result = metaPattern.ReplaceAllString(result, "")
-	result = eventPattern.ReplaceAllString(result, "")
-	result = eventPattern2.ReplaceAllString(result, "")
-	if s.stripAll {
-		result = tagPattern.ReplaceAllString(result, "")
+	return strings.TrimSpace(s.SanitizeBodyFragment(input))
+}
+
+// SanitizeBodyFragment removes dangerous HTML elements from part of a
+// document like SanitizeBody, but keeps surrounding whitespace so that
+// sanitized parts can be joined.
+func (s *Sanitizer) SanitizeBodyFragment(input string) string {
+	var b strings.Builder
+	_ = s.policy.compile().sanitizeBody(&b, strings.NewReader(input), nil)
+	return b.String()
+}
+
+// CopyBody sanitizes the HTML read from src like SanitizeBodyFragment and
+// writes it to dst a token at a time, so that a document need not be held
+// in memory whole. It returns the first error from reading src, other than
+// io.EOF, or from writing dst.
+func (s *Sanitizer) CopyBody(dst io.Writer, src io.Reader) error {
+	return s.policy.compile().sanitizeBody(dst, src, nil)
+}
+
+// AllowsURL reports whether SanitizeBody keeps a URL attribute with the
+// value attr, which may be quoted and hold character references. After
+// decoding references, the URL must pass SanitizeURL's checks with the
+// allowed schemes, so obfuscated forms like "java&#x09;script:" are
+// caught.
+func (s *Sanitizer) AllowsURL(attr string) bool {
+	return allowsURL(attr, s.policy.compile())
+}
+
+// AllowedURLSchemes returns the schemes allowed in URL attributes.
+func (s *Sanitizer) AllowedURLSchemes() []string {
+	return slices.Sorted(maps.Keys(s.policy.compile().schemes))
+}
+
+// IsSafeBody reports whether SanitizeBody would change nothing in input
+// other than surrounding whitespace. It does not build a new string, and
+// stops at the first change.
+func (s *Sanitizer) IsSafeBody(input string) bool {
+	m := &matchWriter{want: input}
+	return s.policy.compile().sanitizeBody(m, strings.NewReader(input), nil) == nil && m.want == ""
+}
+
+// SanitizeBodyDetailed removes dangerous HTML elements like SanitizeBody and
+// also reports what was removed, ordered by offset.
+func (s *Sanitizer) SanitizeBodyDetailed(input string) (string, []Removal) {
+	var b strings.Builder
+	var removals []Removal
+	_ = s.policy.compile().sanitizeBody(&b, strings.NewReader(input), &removals)
+	return strings.TrimSpace(b.String()), removals
+}
+
+// markupName returns the lowercased tag name in removed markup, or "".
+func markupName(text string) string {
+	if m := tagNamePattern.FindStringSubmatch(text); m != nil {
+		return strings.ToLower(m[1])
 	}
-	return strings.TrimSpace(result)
+	return ""
 }
 
 // SanitizeAttribute escapes HTML attribute values.
@@ -90,7 +270,9 @@ func UnescapeString(s string) string {
 	return html.UnescapeString(s)
 }
 
-// UGC returns a sanitizer for User Generated Content.
+// UGC returns a sanitizer for User Generated Content, allowing basic
+// formatting, lists and links. Links with a target get
+// rel="noopener noreferrer".
 func UGC() *Sanitizer {
-	return New([]string{"b", "i", "u", "strong", "em", "p", "br", "ul", "ol", "li", "a"})
+	return New([]string{"b", "i", "u", "strong", "em", "p", "br", "ul", "ol", "li", "a"}, WithNoopener())
 }
@@ -1,7 +1,14 @@
 package html
 
 import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
+
+	xhtml "golang.org/x/net/html"
 )
 
 func TestNew(t *testing.T) {
@@ -39,7 +46,8 @@ func TestSanitizeBody(t *testing.T) {
 		{"<style>body{display:none}</style>Text", "Text"},
 		{"<iframe src='evil.com'></iframe>Safe", "Safe"},
 		{"<object>Bad</object>OK", "OK"},
-		{"<embed>Bad</embed>OK", "OK"},
+		{"<embed>Bad</embed>OK", "BadOK"},
+		{`<embed src="x.swf"> <b>hello</b> world`, "hello world"},
 		{"<link href='x'>Text", "Text"},
 		{"<meta charset='x'>Text", "Text"},
 		{"", ""},
@@ -52,6 +60,237 @@ func TestSanitizeBody(t *testing.T) {
 		if got != tt.want {
 			t.Errorf("SanitizeBody(%q) = %q, want %q", tt.input, got, tt.want)
 		}
+		if detailed, _ := s.SanitizeBodyDetailed(tt.input); detailed != got {
+			t.Errorf("SanitizeBodyDetailed(%q) = %q, want %q", tt.input, detailed, got)
+		}
+		if safe := s.IsSafeBody(tt.input); safe != (got == tt.input) {
+			t.Errorf("IsSafeBody(%q) = %v", tt.input, safe)
+		}
+	}
+}
+
+func TestSanitizeBodyDetailed(t *testing.T) {
+	input := `<a href="#" onclick="x()">go</a> <style>*{}</style><meta charset="x">`
+	got, removals := New([]string{"a"}).SanitizeBodyDetailed(input)
+	if got != `<a href="#">go</a>` {
+		t.Errorf("SanitizeBodyDetailed(%q) = %q", input, got)
+	}
+	want := []Removal{
+		{RemovedAttribute, "onclick", ` onclick="x()"`, 11, ReasonScript},
+		{RemovedElement, "style", "<style>*{}</style>", 33, ReasonUnsafe},
+		{RemovedTag, "meta", `<meta charset="x">`, 51, ReasonUnsafe},
+	}
+	if !reflect.DeepEqual(removals, want) {
+		t.Errorf("removals =\n%+v\nwant\n%+v", removals, want)
+	}
+
+	// Offsets stay in input coordinates after earlier passes remove text
+	_, removals = New(nil).SanitizeBodyDetailed("<script>x</script><i>y</i>")
+	if len(removals) != 3 || removals[1].Offset != 18 || removals[2].Text != "</i>" || removals[2].Offset != 22 {
+		t.Errorf("unexpected removals %+v", removals)
+	}
+
+	if _, removals := New(nil).SanitizeBodyDetailed("plain"); removals != nil {
+		t.Errorf("expected no removals, got %+v", removals)
+	}
+
+	reasons := []struct {
+		input  string
+		reason string
+	}{
+		{`<b onclick="x()">`, ReasonScript},
+		{`<a href=" java&#x09;script:x()">`, ReasonScript},
+		{`<a href="data:text/html,<script>x()</script>">`, ReasonScript},
+		{`<a href="ftp://example.com/f">`, ReasonURL},
+		{`<b style="font-weight:bold">`, ReasonStyle},
+		{`<b title="t" lang="en">`, ""},
+		{`<b ="x">`, ReasonMalformed},
+		{`<table>`, ReasonNotAllowed},
+		{`<link rel="x">`, ReasonUnsafe},
+		{`<svg width="10"></svg>`, ReasonUnsafe},
+		{`<svg onload="x()"/>`, ReasonScript},
+		{`<svg><g><script>x()</script></g></svg>`, ReasonScript},
+		{`<math><mi><a onclick="x()">m</a></mi></math>`, ReasonScript},
+		{`<embed src="x.swf">`, ReasonScript},
+		{`<!-- c -->`, ReasonMarkup},
+		{`<!DOCTYPE html>`, ReasonMarkup},
+		{`x <b`, ReasonIncomplete},
+		{`x <b onclick=x()`, ReasonScript},
+	}
+	for _, tt := range reasons {
+		_, removals := UGC().SanitizeBodyDetailed(tt.input)
+		var got string
+		for _, r := range removals {
+			if got == "" || r.Reason == ReasonScript {
+				got = r.Reason
+			}
+		}
+		if got != tt.reason {
+			t.Errorf("SanitizeBodyDetailed(%q) reason = %q, want %q (%+v)", tt.input, got, tt.reason, removals)
+		}
+	}
+}
+
+// bypasses are known ways past regex sanitizers: mutation XSS through
+// foreign content, noscript and comments, malformed tags and attributes,
+// and markup hidden in text
+var bypasses = []string{
+	`<svg><script>alert(1)</script></svg>`,
+	`<svg><style><img src=x onerror=alert(1)></style></svg>`,
+	`<math><mtext><table><mglyph><style><img src=x onerror=alert(1)>`,
+	`<math><mi><style><!--</style><img src=x onerror=alert(1)>--></style></mi></math>`,
+	`<noscript><p title="</noscript><img src=x onerror=alert(1)>">`,
+	`<noscript><style></noscript><img src=x onerror=alert(1)></style></noscript>`,
+	`<template><script>alert(1)</script></template>`,
+	`<xmp></xmp><img src=x onerror=alert(1)></xmp>`,
+	`<scr<script>ipt>alert(1)</script>`,
+	`<scr<script>ipt>alert(1)</scr</script>ipt>`,
+	`<script>alert(1)</script `,
+	`<script src=x>`,
+	`<script src=//evil.js>`,
+	`<script src=//evil.js>after`,
+	`<style>@import "//evil.css";`,
+	`<<script>script>alert(1)<</script>/script>`,
+	`<b/onclick=alert(1)>x</b>`,
+	`<b onclick=alert(1)//>x</b>`,
+	`<b ="onclick=alert(1)">x</b>`,
+	`<b title="x" onmouseover="alert(1)">x</b>`,
+	`<b title='a"onclick="alert(1)'>x</b>`,
+	`<a href="javas&#99;ript:alert(1)">x</a>`,
+	`<a href="  javascript:alert(1)">x</a>`,
+	`<p title="</p><img src=x onerror=alert(1)>">x</p>`,
+	`<!--><img src=x onerror=alert(1)>-->`,
+	`<!-- --!><img src=x onerror=alert(1)> -->`,
+	`<![CDATA[><img src=x onerror=alert(1)>]]>`,
+	`<?xml ><img src=x onerror=alert(1)>?>`,
+	`<textarea><img src=x onerror=alert(1)></textarea>`,
+	`<title><img src=x onerror=alert(1)></title>`,
+	`<iframe srcdoc="<script>alert(1)</script>"></iframe>`,
+	`<iframe srcdoc="&lt;script&gt;alert(1)&lt;/script&gt;">`,
+	`<object data="javascript:alert(1)">`,
+	`<embed src="javascript:alert(1)">`,
+	`<img src=x onerror=alert(1)`,
+}
+
+func TestSanitizeBody_Bypasses(t *testing.T) {
+	for _, s := range []*Sanitizer{New(nil), UGC()} {
+		for _, input := range bypasses {
+			// The output is a fixed point, with nothing left for a second
+			// pass to remove
+			got := s.SanitizeBody(input)
+			if again := s.SanitizeBody(got); again != got {
+				t.Errorf("SanitizeBody(%q) = %q, which sanitizes again to %q", input, got, again)
+			}
+			// Tokenize the output as a browser would: only allowed tags, and no
+			// event handlers or script URLs
+			z := xhtml.NewTokenizer(strings.NewReader(got))
+			for tt := z.Next(); tt != xhtml.ErrorToken; tt = z.Next() {
+				tok := z.Token()
+				switch tt {
+				case xhtml.StartTagToken, xhtml.SelfClosingTagToken, xhtml.EndTagToken:
+					if !s.allowedTags[tok.Data] {
+						t.Errorf("SanitizeBody(%q) = %q, keeping <%s>", input, got, tok.Data)
+					}
+					for _, attr := range tok.Attr {
+						if strings.HasPrefix(attr.Key, "on") || strings.Contains(strings.ToLower(attr.Val), "script:") {
+							t.Errorf("SanitizeBody(%q) = %q, keeping %s=%q", input, got, attr.Key, attr.Val)
+						}
+					}
+				case xhtml.CommentToken, xhtml.DoctypeToken:
+					t.Errorf("SanitizeBody(%q) = %q, keeping %q", input, got, z.Raw())
+				}
+			}
+			if s.IsSafeBody(input) {
+				t.Errorf("IsSafeBody(%q) = true", input)
+			}
+		}
+	}
+
+	// Markup inside attribute values and text comes out escaped
+	s := UGC()
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`<noscript><p title="</noscript><img src=x onerror=alert(1)>">`, `"&gt;`},
+		{`<p title="</p><img src=x onerror=alert(1)>">x</p>`, `<p title="&lt;/p&gt;&lt;img src=x onerror=alert(1)&gt;">x</p>`},
+		{`<b title='a"onclick="alert(1)'>x</b>`, `<b title="a&#34;onclick=&#34;alert(1)">x</b>`},
+		{`&lt;img src=x onerror=alert(1)&gt;`, `&lt;img src=x onerror=alert(1)&gt;`},
+		{`<textarea><img src=x></textarea>`, `&lt;img src=x&gt;`},
+		{`<scr<script>ipt>alert(1)</script>`, "ipt&gt;alert(1)"},
+		{`<scr<script>ipt>alert(1)</scr</script>ipt>`, "ipt&gt;alert(1)ipt&gt;"},
+		{`<script src=//evil.js>after`, ""},
+		{`<b>kept</b><iframe srcdoc="<script>alert(1)</script>">after`, "<b>kept</b>"},
+		{"Tom & Jerry < 3", "Tom &amp; Jerry &lt; 3"},
+	}
+	for _, tt := range tests {
+		if got := s.SanitizeBody(tt.input); got != tt.want {
+			t.Errorf("SanitizeBody(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeBody_Comments(t *testing.T) {
+	// Comments used to split or hide dangerous tags
+	evasions := []string{
+		`<scr<!-- -->ipt>alert(1)</script>`,
+		`<!-- --><script>alert(1)</script>`,
+		`<sc<!---->ript>alert(1)</sc<!---->ript>`,
+		`<img<!-- --> src=x onerror=alert(1)>`,
+		`<a href="java<!-- -->script:alert(1)">x</a>`,
+		`<!--[if IE]><script>alert(1)</script><![endif]-->`,
+		`<!--[if gte IE 4]><img src=x onerror=alert(1)><![endif]-->`,
+		`<![CDATA[<script>alert(1)</script>]]>`,
+		`<!-- <script>alert(1)</script> -->`,
+	}
+	for _, keep := range []bool{false, true} {
+		s := New([]string{"a", "b"}, WithComments(keep))
+		for _, input := range evasions {
+			got := s.SanitizeBody(input)
+			if strings.Contains(got, "<script") || strings.Contains(got, "<img") || strings.Contains(got, "href") {
+				t.Errorf("keep comments %v: SanitizeBody(%q) = %q", keep, input, got)
+			}
+		}
+	}
+
+	tests := []struct {
+		input string
+		keep  bool
+		want  string
+	}{
+		{"a<!-- note -->b", false, "ab"},
+		{"a<!-- note -->b", true, "a<!-- note -->b"},
+		{"a<!-- unterminated", true, "a<!-- unterminated-->"},
+		{"<!--[if IE]>x<![endif]-->b", true, "b"},
+		{"<!-- a -- b -->c", true, "c"},
+		{"<!-- <b>x</b> -->c", true, "c"},
+		{"<![CDATA[ x ]]>c", true, "c"},
+		{"<?xml version=\"1.0\"?>c", true, "c"},
+		{"<!DOCTYPE html>c", true, "c"},
+	}
+	for _, tt := range tests {
+		p := NewPolicy().KeepComments(tt.keep)
+		if got := p.Sanitize(tt.input); got != tt.want {
+			t.Errorf("KeepComments(%v).Sanitize(%q) = %q, want %q", tt.keep, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestCopyBody(t *testing.T) {
+	s := UGC()
+	for _, input := range append(bypasses, "<b>bold</b> text", "<p>unterminated <script>") {
+		var out strings.Builder
+		if err := s.CopyBody(&out, iotest.OneByteReader(strings.NewReader(input))); err != nil {
+			t.Fatal(err)
+		}
+		if want := s.SanitizeBodyFragment(input); out.String() != want {
+			t.Errorf("CopyBody(%q) = %q, want %q", input, out.String(), want)
+		}
+	}
+
+	readErr := errors.New("read failed")
+	if err := s.CopyBody(io.Discard, iotest.ErrReader(readErr)); !errors.Is(err, readErr) {
+		t.Errorf("CopyBody error = %v, want %v", err, readErr)
 	}
 }
 
@@ -104,6 +343,168 @@ func TestUGC(t *testing.T) {
 	}
 }
 
+func TestSanitizeBody_AllowedTags(t *testing.T) {
+	s := UGC()
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"<b>bold</b> and <i>italic</i>", "<b>bold</b> and <i>italic</i>"},
+		{"<table><tr><td>cell</td></tr></table>", "cell"},
+		{"<b>loud</b>", "<b>loud</b>"},
+		{`<p class="x">para</p>`, `<p class="x">para</p>`},
+		{`<a href="/x">link</a>`, `<a href="/x">link</a>`},
+		{`<b onclick="steal()">x</b>`, "<b>x</b>"},
+		{`<form action="/steal"><input name="pw"><button>Go</button></form>`, "Go"},
+		{`<base href="https://evil.example/">text`, "text"},
+		{`<video src=x onerror=alert(1)>`, ""},
+		{"<svg><circle/></svg>", ""},
+		{`<embed src="x.swf"> <b>hello</b> world`, "<b>hello</b> world"},
+		{"<bx>not b</bx>", "not b"},
+		{"<!-- comment -->text", "text"},
+		{"<script>alert(1)</script><b>ok</b>", "<b>ok</b>"},
+	}
+	for _, tt := range tests {
+		if got := s.SanitizeBody(tt.input); got != tt.want {
+			t.Errorf("SanitizeBody(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+		if got, _ := s.SanitizeBodyDetailed(tt.input); got != tt.want {
+			t.Errorf("SanitizeBodyDetailed(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+		if safe := s.IsSafeBody(tt.input); safe != (tt.input == tt.want) {
+			t.Errorf("IsSafeBody(%q) = %v", tt.input, safe)
+		}
+	}
+
+	_, removals := s.SanitizeBodyDetailed("<b>x</b><table>y</table>")
+	want := []Removal{
+		{RemovedTag, "table", "<table>", 8, ReasonNotAllowed},
+		{RemovedTag, "table", "</table>", 16, ReasonNotAllowed},
+	}
+	if !reflect.DeepEqual(removals, want) {
+		t.Errorf("removals = %+v, want %+v", removals, want)
+	}
+}
+
+// schemeAttacks are obfuscations of disallowed URL schemes
+var schemeAttacks = []string{
+	`javascript:alert(1)`,
+	`JavaScript:alert(1)`,
+	`vbscript:msgbox(1)`,
+	`data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==`,
+	"java\tscript:alert(1)",
+	"java\nscript:alert(1)",
+	"java\nscript:alert(1)",
+	" \x01javascript:alert(1)",
+	"javascript\x00:alert(1)",
+	`&#106;avascript:alert(1)`,
+	`&#106avascript:alert(1)`,
+	`&#x6A;avascript:alert(1)`,
+	`&#X6a;avascript:alert(1)`,
+	`&#0000106;avascript:alert(1)`,
+	`java&#x09;script:alert(1)`,
+	`java&Tab;script:alert(1)`,
+	`java&NewLine;script:alert(1)`,
+	`javascript&colon;alert(1)`,
+	`javascript&#58;alert(1)`,
+	`&#x20;javascript:alert(1)`,
+	`ftp://example.com/`,
+	`foo bar:baz`,
+}
+
+func TestSanitizeBody_URLSchemes(t *testing.T) {
+	s := UGC()
+	safe := []string{
+		`<a href="https://example.com/">x</a>`,
+		`<a href="http://example.com/a:b">x</a>`,
+		`<a href="mailto:me@example.com">x</a>`,
+		`<a href="/relative/path">x</a>`,
+		`<a href="page.html?t=10:30">x</a>`,
+		`<a href="#section:2">x</a>`,
+		`<a title="javascript:x">x</a>`,
+		`<a data-href="javascript:x">x</a>`,
+		// References are decoded once, as the browser decodes them
+		`<a href="/search?q=&amp;#10;">x</a>`,
+		`<a href="/search?q=a&amp;amp;b">x</a>`,
+	}
+	for _, input := range safe {
+		if got := s.SanitizeBody(input); got != input {
+			t.Errorf("SanitizeBody(%q) = %q, want it unchanged", input, got)
+		}
+	}
+
+	// Allowed tags are written back lowercased with quoted attributes
+	for input, want := range map[string]string{
+		`<a href='HTTPS://EXAMPLE.COM'>x</a>`: `<a href="HTTPS://EXAMPLE.COM">x</a>`,
+		`<a href=https://example.com>x</a>`:   `<a href="https://example.com">x</a>`,
+		"<B>loud</B>":                         "<b>loud</b>",
+	} {
+		if got := s.SanitizeBody(input); got != want {
+			t.Errorf("SanitizeBody(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	// common scheme obfuscations, all of which must lose the href
+	for _, url := range schemeAttacks {
+		for _, quote := range []string{`"`, `'`} {
+			input := `<a href=` + quote + url + quote + `>x</a>`
+			if got := s.SanitizeBody(input); got != "<a>x</a>" {
+				t.Errorf("SanitizeBody(%q) = %q, want <a>x</a>", input, got)
+			}
+			if s.IsSafeBody(input) {
+				t.Errorf("IsSafeBody(%q) = true", input)
+			}
+		}
+	}
+
+	for _, input := range []string{
+		`<a href=javascript:alert(1)>x</a>`,
+		`<a/href="javascript:alert(1)">x</a>`,
+		`<a href = "javascript:alert(1)">x</a>`,
+		`<a HREF="javascript:alert(1)">x</a>`,
+	} {
+		if got := s.SanitizeBody(input); got != "<a>x</a>" {
+			t.Errorf("SanitizeBody(%q) = %q, want <a>x</a>", input, got)
+		}
+	}
+
+	img := New([]string{"img", "form", "video", "button"})
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`<img src="javascript:alert(1)" alt="x">`, `<img alt="x">`},
+		{`<img src="/logo.png">`, `<img src="/logo.png">`},
+		{`<form action="javascript:x()"></form>`, `<form></form>`},
+		{`<button formaction="vbscript:x">b</button>`, `<button>b</button>`},
+		{`<video poster="data:image/svg+xml,<svg>">`, `<video>`},
+	}
+	for _, tt := range tests {
+		if got := img.SanitizeBody(tt.input); got != tt.want {
+			t.Errorf("SanitizeBody(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	_, removals := s.SanitizeBodyDetailed(`<a href="javascript:x">y</a>`)
+	want := []Removal{{RemovedAttribute, "href", ` href="javascript:x"`, 2, ReasonScript}}
+	if !reflect.DeepEqual(removals, want) {
+		t.Errorf("removals = %+v, want %+v", removals, want)
+	}
+}
+
+func TestWithURLSchemes(t *testing.T) {
+	s := New([]string{"a"}, WithURLSchemes("HTTPS", "tel"))
+	if got := s.AllowedURLSchemes(); !reflect.DeepEqual(got, []string{"https", "tel"}) {
+		t.Errorf("AllowedURLSchemes() = %v", got)
+	}
+	if got := s.SanitizeBody(`<a href="tel:+15551234">call</a><a href="http://x">x</a>`); got != `<a href="tel:+15551234">call</a><a>x</a>` {
+		t.Errorf("SanitizeBody = %q", got)
+	}
+	if got := New(nil).AllowedURLSchemes(); !reflect.DeepEqual(got, DefaultURLSchemes) {
+		t.Errorf("default AllowedURLSchemes() = %v, want %v", got, DefaultURLSchemes)
+	}
+}
+
 func TestEscapeString(t *testing.T) {
 	tests := []struct {
 		input string
@@ -0,0 +1,121 @@
+package html
+
+import (
+	"encoding/base64"
+	"html"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// DefaultDataURIMediaTypes are the media types of the data: URIs allowed
+// by AllowDataURIs when it is given none: raster images, which cannot
+// carry script as HTML and SVG can.
+var DefaultDataURIMediaTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// DefaultMaxDataURISize is the most bytes a data: URI may decode to unless
+// MaxDataURISize changes it.
+const DefaultMaxDataURISize = 256 << 10
+
+// defaultURLPolicy checks URLs for SanitizeURL.
+var defaultURLPolicy = &compiledPolicy{schemes: schemeSet(DefaultURLSchemes)}
+
+// SanitizeURL reports whether raw is safe to put in an href or src
+// attribute, returning it cleaned if it is. The URL must parse, hold no
+// control characters, tabs or newlines, and be relative or have one of the
+// DefaultURLSchemes. Backslashes, which browsers read as slashes, are
+// turned into slashes, and a user name and password are removed.
+//
+// raw is the URL itself, not HTML, and the result should be escaped when
+// written into a page. In case it is not, raw is also rejected if it would
+// fail the checks after decoding character references, as in
+// "&#106;avascript:". Attribute filtering in SanitizeBody applies the same
+// checks to the decoded attribute value.
+func SanitizeURL(raw string) (string, bool) {
+	u, ok := parseURL(raw, defaultURLPolicy)
+	if !ok || !allowsURL(raw, defaultURLPolicy) {
+		return "", false
+	}
+	u.User = nil
+	return u.String(), true
+}
+
+// allowsURL reports whether the URL attribute value attr, as written in
+// HTML, quoted or not and with character references, is allowed by c.
+func allowsURL(attr string, c *compiledPolicy) bool {
+	if len(attr) >= 2 && (attr[0] == '"' || attr[0] == '\'') && attr[len(attr)-1] == attr[0] {
+		attr = attr[1 : len(attr)-1]
+	}
+	return allowsURLValue(html.UnescapeString(attr), c)
+}
+
+// allowsURLValue reports whether the URL attribute value, with its
+// character references already decoded, is allowed by c.
+func allowsURLValue(value string, c *compiledPolicy) bool {
+	_, ok := parseURL(value, c)
+	return ok
+}
+
+// parseURL parses raw as SanitizeURL checks it, reporting whether it is
+// allowed by the schemes and data: URI rules of c. Surrounding spaces,
+// which browsers ignore, are trimmed.
+func parseURL(raw string, c *compiledPolicy) (*url.URL, bool) {
+	raw = strings.Trim(raw, " ")
+	if strings.ContainsFunc(raw, func(r rune) bool { return r < ' ' || r == 0x7f }) {
+		return nil, false
+	}
+	u, err := url.Parse(strings.ReplaceAll(raw, `\`, "/"))
+	if err != nil {
+		return nil, false
+	}
+	if u.Scheme == "" {
+		return u, true
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme == "data" {
+		_, data, _ := strings.Cut(raw, ":")
+		return u, c.allowsData(data)
+	}
+	return u, schemePattern.MatchString(scheme) && c.schemes[scheme]
+}
+
+// allowsData reports whether c allows a data: URI with the text after the
+// scheme: one of the allowed media types, base64 encoded, holding no more
+// than the most bytes allowed once decoded. Parameters such as charset
+// may come between the media type and ";base64".
+func (c *compiledPolicy) allowsData(data string) bool {
+	header, payload, found := strings.Cut(data, ",")
+	params := strings.Split(strings.ToLower(header), ";")
+	if !found || c.dataTypes == nil || !c.dataTypes[strings.TrimSpace(params[0])] || params[len(params)-1] != "base64" {
+		return false
+	}
+	if base64.StdEncoding.DecodedLen(len(payload)) > c.maxData+2 {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(payload)
+	return err == nil && len(decoded) <= c.maxData
+}
+
+// scriptURLPrefixes start the URLs that run script when followed.
+var scriptURLPrefixes = []string{"javascript:", "vbscript:", "data:text/html"}
+
+// isScriptURL reports whether the decoded URL value runs script, ignoring
+// the whitespace and control characters browsers strip from it.
+func isScriptURL(value string) bool {
+	value = strings.ToLower(strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, value))
+	return slices.ContainsFunc(scriptURLPrefixes, func(prefix string) bool { return strings.HasPrefix(value, prefix) })
+}
+
+// schemeSet returns schemes, lowercased, as a set.
+func schemeSet(schemes []string) map[string]bool {
+	set := make(map[string]bool, len(schemes))
+	for _, scheme := range schemes {
+		set[strings.ToLower(scheme)] = true
+	}
+	return set
+}
@@ -0,0 +1,61 @@
+package safeinput
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// StripInvisibleRunes removes zero-width and other invisible format
+// characters from a string: the zero-width space, non-joiner and joiner
+// (U+200B to U+200D), the word joiner and invisible operators (U+2060 to
+// U+2064), the byte order mark U+FEFF and the soft hyphen U+00AD. Note
+// that this also splits emoji sequences joined with U+200D. Other bytes,
+// including invalid UTF-8, are kept as they are.
+func StripInvisibleRunes(input string) string {
+	if strings.IndexFunc(input, isInvisibleRune) < 0 {
+		return input
+	}
+	return stripRunes(input, isInvisibleRune)
+}
+
+// stripRunes removes the runes for which strip reports true from input.
+// Unlike strings.Map it copies invalid UTF-8 as it is, so stripping text
+// in pieces gives the same result as stripping it whole.
+func stripRunes(input string, strip func(rune) bool) string {
+	var b strings.Builder
+	b.Grow(len(input))
+	for i := 0; i < len(input); {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		if !strip(r) {
+			b.WriteString(input[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+func isInvisibleRune(r rune) bool {
+	switch {
+	case r == '\u00ad', r == '\ufeff':
+		return true
+	case r >= '\u200b' && r <= '\u200d':
+		return true
+	case r >= '\u2060' && r <= '\u2064':
+		return true
+	}
+	return false
+}
+
+// checkInvisible applies RejectInvisible and StripInvisible to input.
+func (s *Sanitizer) checkInvisible(input string) (string, error) {
+	if !s.config.RejectInvisible && !s.config.StripInvisible {
+		return input, nil
+	}
+	if strings.IndexFunc(input, isInvisibleRune) < 0 {
+		return input, nil
+	}
+	if s.config.RejectInvisible {
+		return "", ErrInvisibleCharacter
+	}
+	return StripInvisibleRunes(input), nil
+}
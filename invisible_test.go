@@ -0,0 +1,72 @@
+package safeinput
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ravisastryk/go-safeinput/sql"
+)
+
+func TestStripInvisibleRunes(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"plain", "plain"},
+		{"", ""},
+		{"ad\u200bmin", "admin"},
+		{"\ufeffheader", "header"},
+		{"pass\u00adword", "password"},
+		{"a\u200cb\u200dc\u2060d\u2061e\u2064f", "abcdef"},
+		{"caf\u00e9 \u05e9", "caf\u00e9 \u05e9"},
+	}
+	for _, tt := range tests {
+		if got := StripInvisibleRunes(tt.input); got != tt.want {
+			t.Errorf("StripInvisibleRunes(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSanitize_Invisible(t *testing.T) {
+	s := Default()
+	tests := []struct {
+		input   string
+		ctx     Context
+		want    string
+		wantErr error
+	}{
+		{"<scr\u200bipt>alert(1)</script>Hi", HTMLBody, "Hi", nil},
+		{"<img src=x on\u200derror=alert(1)>", HTMLBody, "", nil},
+		{"x' O\u200bR '1'='1", SQLValue, "", sql.ErrSuspiciousPattern},
+		{"ad\u00admin", LogLine, "admin", nil},
+		{"us\u2060ers", SQLIdentifier, "users", nil},
+	}
+	for _, tt := range tests {
+		got, err := s.Sanitize(tt.input, tt.ctx)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("Sanitize(%q, %v) = %q, %v, want %q, %v", tt.input, tt.ctx, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	reject := New(Config{StripInvisible: true, RejectInvisible: true})
+	if _, err := reject.Sanitize("ad\u200bmin", LogLine); !errors.Is(err, ErrInvisibleCharacter) {
+		t.Errorf("expected ErrInvisibleCharacter, got %v", err)
+	}
+	if err := s.Validate("ad\u200bmin", FilePath); !errors.Is(err, ErrInvisibleCharacter) {
+		t.Errorf("Validate: expected ErrInvisibleCharacter, got %v", err)
+	}
+	if got, _ := New(Config{}).Sanitize("ad\u200bmin", LogLine); got != "ad\u200bmin" {
+		t.Errorf("stripped without StripInvisible: %q", got)
+	}
+
+	res, err := s.SanitizeDetailed("a\u200b<b>", HTMLBody)
+	if err != nil || len(res.Findings) != 2 ||
+		res.Findings[0] != (Finding{Kind: FindingRemovedInvisible, Text: "\u200b", Offset: 1, Severity: SeverityInfo}) ||
+		res.Findings[1].Offset != 4 {
+		t.Errorf("unexpected result %+v, %v", res, err)
+	}
+	res, err = reject.SanitizeDetailed("ad\u200bmin", LogLine)
+	if !errors.Is(err, ErrInvisibleCharacter) || len(res.Findings) != 1 || res.Findings[0].Offset != 2 {
+		t.Errorf("unexpected rejection %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,55 @@
+package safeinput
+
+import "net/netip"
+
+// ipPolicy holds the Config fields used by the IPAddress context.
+type ipPolicy struct {
+	rejectPrivate   bool
+	rejectLoopback  bool
+	rejectLinkLocal bool
+	rejectMulticast bool
+}
+
+// internalIPPolicy rejects every address BlockInternalHosts treats as
+// internal.
+var internalIPPolicy = ipPolicy{
+	rejectPrivate:   true,
+	rejectLoopback:  true,
+	rejectLinkLocal: true,
+	rejectMulticast: true,
+}
+
+// SanitizeIPAddress parses an IPv4 or IPv6 address and returns it in
+// canonical form, with IPv6 zeros collapsed ("2001:db8:0::1" becomes
+// "2001:db8::1"). Addresses with leading zeros, such as the octal-looking
+// "0177.0.0.1", hexadecimal or shortened IPv4 forms, and IPv6 zones are
+// rejected with ErrInvalidIPAddress. No address is rejected for where it
+// points; use the IPAddress context with the Config Reject*IPs fields for
+// that.
+func SanitizeIPAddress(input string) (string, error) {
+	return sanitizeIPAddress(input, ipPolicy{})
+}
+
+// sanitizeIPAddress implements the IPAddress context.
+func sanitizeIPAddress(input string, policy ipPolicy) (string, error) {
+	addr, err := netip.ParseAddr(input)
+	if err != nil || addr.Zone() != "" {
+		return "", ErrInvalidIPAddress
+	}
+	if policy.rejects(addr) {
+		return "", ErrIPAddressNotAllowed
+	}
+	return addr.String(), nil
+}
+
+// rejects reports whether policy rejects addr. IPv4 addresses mapped to
+// IPv6, like ::ffff:127.0.0.1, are checked as the IPv4 address, and the
+// unspecified addresses count as loopback since connecting to them reaches
+// the local host.
+func (p ipPolicy) rejects(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return (p.rejectPrivate && addr.IsPrivate()) ||
+		(p.rejectLoopback && (addr.IsLoopback() || addr.IsUnspecified())) ||
+		(p.rejectLinkLocal && (addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast())) ||
+		(p.rejectMulticast && addr.IsMulticast())
+}
@@ -0,0 +1,88 @@
+package safeinput
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSanitizeIPAddress(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"192.0.2.1", "192.0.2.1", nil},
+		{"2001:DB8:0:0:0:0:0:1", "2001:db8::1", nil},
+		{"2001:db8:0000::0001", "2001:db8::1", nil},
+		{"::ffff:127.0.0.1", "::ffff:127.0.0.1", nil},
+		{"127.0.0.1", "127.0.0.1", nil},
+
+		// Forms that naive checks and inet_aton disagree on
+		{"0177.0.0.1", "", ErrInvalidIPAddress},
+		{"127.000.000.001", "", ErrInvalidIPAddress},
+		{"0x7f.0.0.1", "", ErrInvalidIPAddress},
+		{"0x7f000001", "", ErrInvalidIPAddress},
+		{"2130706433", "", ErrInvalidIPAddress},
+		{"127.1", "", ErrInvalidIPAddress},
+
+		{"", "", ErrInvalidIPAddress},
+		{"256.0.0.1", "", ErrInvalidIPAddress},
+		{"1.2.3.4.5", "", ErrInvalidIPAddress},
+		{" 1.2.3.4", "", ErrInvalidIPAddress},
+		{"[::1]", "", ErrInvalidIPAddress},
+		{"fe80::1%eth0", "", ErrInvalidIPAddress},
+		{"1.2.3.4:80", "", ErrInvalidIPAddress},
+		{"example.com", "", ErrInvalidIPAddress},
+	}
+	for _, tt := range tests {
+		got, err := SanitizeIPAddress(tt.input)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("SanitizeIPAddress(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("SanitizeIPAddress(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSanitize_IPAddress(t *testing.T) {
+	s := New(Config{
+		RejectPrivateIPs:   true,
+		RejectLoopbackIPs:  true,
+		RejectLinkLocalIPs: true,
+		RejectMulticastIPs: true,
+	})
+	rejected := []string{
+		"10.0.0.1", "172.16.5.4", "192.168.1.1", "fd00::1",
+		"127.0.0.1", "127.8.9.10", "::1", "0.0.0.0", "::",
+		"169.254.169.254", "fe80::1", "224.0.0.1", "ff02::1",
+		"::ffff:127.0.0.1", "::ffff:169.254.169.254", "::ffff:10.0.0.1",
+	}
+	for _, input := range rejected {
+		if _, err := s.Sanitize(input, IPAddress); !errors.Is(err, ErrIPAddressNotAllowed) {
+			t.Errorf("Sanitize(%q, IPAddress) error = %v, want ErrIPAddressNotAllowed", input, err)
+		}
+	}
+	for _, input := range []string{"93.184.216.34", "2606:2800:220:1::1"} {
+		if !s.IsValid(input, IPAddress) {
+			t.Errorf("IsValid(%q, IPAddress) = false, want true", input)
+		}
+	}
+
+	// Each flag only rejects its own range
+	s = New(Config{RejectLoopbackIPs: true})
+	if !s.IsValid("10.0.0.1", IPAddress) || !s.IsValid("169.254.169.254", IPAddress) {
+		t.Error("RejectLoopbackIPs rejected a non-loopback address")
+	}
+	if s.IsValid("::ffff:127.0.0.1", IPAddress) {
+		t.Error("RejectLoopbackIPs accepted an IPv4-mapped loopback address")
+	}
+}
+
+func BenchmarkSanitize_IPAddress(b *testing.B) {
+	s := New(Config{RejectPrivateIPs: true, RejectLoopbackIPs: true, RejectLinkLocalIPs: true})
+	for i := 0; i < b.N; i++ {
+		_ = s.IsValid("::ffff:169.254.169.254", IPAddress)
+	}
+}
@@ -0,0 +1,49 @@
+package safeinput
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// EscapeJSONString escapes a value for inclusion inside a JSON string
+// literal. Quotes, backslashes and control characters are escaped as JSON
+// requires, and <, >, &, U+2028 and U+2029 are escaped as \u sequences so the
+// JSON stays safe inside an HTML <script> element. Unescaping the result
+// with a JSON decoder reproduces input, except that invalid UTF-8 bytes are
+// replaced by U+FFFD.
+func EscapeJSONString(input string) string {
+	var b strings.Builder
+	b.Grow(len(input))
+	for i := 0; i < len(input); {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		i += size
+		switch {
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == '<' || r == '>' || r == '&' || r == '\u2028' || r == '\u2029':
+			writeUnicodeEscape(&b, r)
+		case r == utf8.RuneError && size == 1:
+			b.WriteString(`\ufffd`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// writeUnicodeEscape writes r, which must be in the Basic Multilingual
+// Plane, as a \uXXXX escape.
+func writeUnicodeEscape(b *strings.Builder, r rune) {
+	b.WriteString(`\u`)
+	for shift := 12; shift >= 0; shift -= 4 {
+		b.WriteByte(hexDigits[(r>>shift)&0x0f])
+	}
+}
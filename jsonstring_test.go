@@ -0,0 +1,82 @@
+package safeinput
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func TestEscapeJSONString(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"hello", "hello"},
+		{`say "hi"`, `say \"hi\"`},
+		{`C:\temp`, `C:\\temp`},
+		{"a\nb\r\tc", `a\nb\r\tc`},
+		{"\x00\x1f", `\u0000\u001f`},
+		{"</script><script>alert(1)</script>", `\u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e`},
+		{"a&b", `a\u0026b`},
+		{"line\u2028para\u2029", `line\u2028para\u2029`},
+		{"naïve 日本 🎉", "naïve 日本 🎉"},
+		{"bad\xffbyte", `bad\ufffdbyte`},
+	}
+	for _, tt := range tests {
+		if got := EscapeJSONString(tt.input); got != tt.want {
+			t.Errorf("EscapeJSONString(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+// jsonStringInput generates strings biased towards characters that need
+// escaping
+type jsonStringInput string
+
+func (jsonStringInput) Generate(r *rand.Rand, size int) reflect.Value {
+	special := []rune{'"', '\\', '/', '<', '>', '&', '\n', '\r', '\t', 0, 0x1f, 0x7f, '\u2028', '\u2029', 'é', '日', '🎉'}
+	var b strings.Builder
+	for i := r.Intn(size + 1); i > 0; i-- {
+		if r.Intn(2) == 0 {
+			b.WriteRune(special[r.Intn(len(special))])
+		} else {
+			b.WriteRune(rune(r.Intn(0x80)))
+		}
+	}
+	return reflect.ValueOf(jsonStringInput(b.String()))
+}
+
+func TestEscapeJSONString_RoundTrip(t *testing.T) {
+	roundTrips := func(input string) bool {
+		escaped := EscapeJSONString(input)
+		if strings.ContainsAny(escaped, "<>&\u2028\u2029") {
+			return false
+		}
+		var got string
+		if err := json.Unmarshal([]byte(`"`+escaped+`"`), &got); err != nil {
+			return false
+		}
+		return got == input
+	}
+
+	if err := quick.Check(func(s jsonStringInput) bool { return roundTrips(string(s)) }, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+	// quick's own strings cover the rest of Unicode
+	if err := quick.Check(roundTrips, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSanitize_JSONString(t *testing.T) {
+	got, err := Default().Sanitize(`{"x":"</script>"}`, JSONString)
+	if err != nil {
+		t.Fatalf("Sanitize error = %v", err)
+	}
+	if got != `{\"x\":\"\u003c/script\u003e\"}` {
+		t.Errorf("Sanitize(JSONString) = %q", got)
+	}
+}
@@ -0,0 +1,188 @@
+package safeinput
+
+import (
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LengthUnit is the unit the length limits count in.
+type LengthUnit int
+
+// Length units.
+const (
+	// Bytes counts the bytes of the UTF-8 input. It is the default.
+	Bytes LengthUnit = iota
+	// Runes counts Unicode code points, so a CJK character counts as one
+	// rather than three.
+	Runes
+	// Graphemes counts user-perceived characters, so a letter with
+	// combining accents, a flag or an emoji joined with ZWJ counts as one
+	// whether or not it is composed.
+	Graphemes
+)
+
+var lengthUnitNames = map[LengthUnit]string{
+	Bytes:     "bytes",
+	Runes:     "runes",
+	Graphemes: "graphemes",
+}
+
+// String returns the plural name of the unit, such as "runes".
+func (u LengthUnit) String() string {
+	if name, ok := lengthUnitNames[u]; ok {
+		return name
+	}
+	return "bytes"
+}
+
+// count returns the length of input in the unit.
+func (u LengthUnit) count(input string) int {
+	c := lengthCounter{unit: u}
+	c.add(input)
+	return c.n
+}
+
+// maxBytes returns the most bytes input for ctx may have: its length limit,
+// or with a LengthUnit other than Bytes, utf8.UTFMax bytes for each unit of
+// it. Graphemes can be any number of bytes long, so without this cap a
+// limit on them would not bound the input.
+func (s *Sanitizer) maxBytes(ctx Context) int {
+	limit := s.maxLength(ctx)
+	if s.config.LengthUnit == Bytes {
+		return limit
+	}
+	if limit > math.MaxInt/utf8.UTFMax {
+		return math.MaxInt
+	}
+	return limit * utf8.UTFMax
+}
+
+// lengthCounter measures text in a LengthUnit a piece at a time, so that a
+// grapheme split between pieces counts once.
+type lengthCounter struct {
+	unit LengthUnit
+	n    int
+
+	// prev is the last rune counted for Graphemes, and pairing is set when
+	// it is a regional indicator starting a flag
+	prev    rune
+	started bool
+	pairing bool
+}
+
+// add counts the runes of p, which must not end inside one.
+func (c *lengthCounter) add(p string) {
+	switch c.unit {
+	case Runes:
+		c.n += utf8.RuneCountInString(p)
+	case Graphemes:
+		for _, r := range p {
+			if !c.started || !c.joins(r) {
+				c.n++
+			}
+			c.pairing = isRegionalIndicator(r) && !c.pairing
+			c.prev, c.started = r, true
+		}
+	default:
+		c.n += len(p)
+	}
+}
+
+// joins reports whether r continues the grapheme of the previous rune,
+// following the rules of Unicode Standard Annex #29 except for prepended
+// characters, and for ZWJ sequences allowing any pictograph after a ZWJ.
+func (c *lengthCounter) joins(r rune) bool {
+	prev := c.prev
+	switch {
+	case prev == '\r' && r == '\n':
+		return true
+	case isGraphemeControl(prev) || isGraphemeControl(r):
+		return false
+	case joinsHangul(prev, r):
+		return true
+	case isGraphemeExtend(r):
+		return true
+	case prev == '\u200d' && isPictograph(r):
+		return true
+	case isRegionalIndicator(r):
+		return c.pairing
+	}
+	return false
+}
+
+// isGraphemeControl reports whether r is a control, which is a grapheme on
+// its own.
+func isGraphemeControl(r rune) bool {
+	return unicode.In(r, unicode.Cc, unicode.Zl, unicode.Zp) || (unicode.Is(unicode.Cf, r) && !isGraphemeExtend(r))
+}
+
+// isGraphemeExtend reports whether r extends the grapheme before it: a
+// combining mark, a variation selector, ZWJ or ZWNJ, an emoji skin tone
+// modifier or tag, or a halfwidth katakana sound mark.
+func isGraphemeExtend(r rune) bool {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r == '\u200c' || r == '\u200d':
+		return true
+	case r >= 0x1f3fb && r <= 0x1f3ff, r >= 0xe0020 && r <= 0xe007f:
+		return true
+	case r == '\uff9e' || r == '\uff9f':
+		return true
+	}
+	return false
+}
+
+// isPictograph reports whether r is in the main emoji and symbol blocks.
+func isPictograph(r rune) bool {
+	return (r >= 0x2600 && r <= 0x27bf) || (r >= 0x1f000 && r <= 0x1faff) || r == 0x00a9 || r == 0x00ae
+}
+
+// isRegionalIndicator reports whether r is one of the letters pairs of
+// which make flags.
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+// Hangul syllable types for joinsHangul.
+const (
+	hangulNone = iota
+	hangulL
+	hangulV
+	hangulT
+	hangulLV
+	hangulLVT
+)
+
+// hangulType returns the Hangul syllable type of r.
+func hangulType(r rune) int {
+	switch {
+	case r >= 0x1100 && r <= 0x115f, r >= 0xa960 && r <= 0xa97c:
+		return hangulL
+	case r >= 0x1160 && r <= 0x11a7, r >= 0xd7b0 && r <= 0xd7c6:
+		return hangulV
+	case r >= 0x11a8 && r <= 0x11ff, r >= 0xd7cb && r <= 0xd7fb:
+		return hangulT
+	case r >= 0xac00 && r <= 0xd7a3:
+		if (r-0xac00)%28 == 0 {
+			return hangulLV
+		}
+		return hangulLVT
+	}
+	return hangulNone
+}
+
+// joinsHangul reports whether the Hangul jamo r continues the syllable
+// ending in prev.
+func joinsHangul(prev, r rune) bool {
+	switch p, t := hangulType(prev), hangulType(r); p {
+	case hangulL:
+		return t == hangulL || t == hangulV || t == hangulLV || t == hangulLVT
+	case hangulLV, hangulV:
+		return t == hangulV || t == hangulT
+	case hangulLVT, hangulT:
+		return t == hangulT
+	}
+	return false
+}
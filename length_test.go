@@ -0,0 +1,112 @@
+package safeinput
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLengthUnit_Count(t *testing.T) {
+	tests := []struct {
+		input            string
+		runes, graphemes int
+	}{
+		{"", 0, 0},
+		{"hello", 5, 5},
+		{"日本語", 3, 3},
+		{"caf\u00e9", 4, 4},
+		{"cafe\u0301", 5, 4},
+		{"e\u0301\u0302\u0303\u0304", 5, 1},
+		{"\r\n", 2, 1},
+		{"\n\r", 2, 2},
+		{"a\u0300\n\u0300", 4, 3},
+		{"\U0001f1ef\U0001f1f5\U0001f1fa\U0001f1f8", 4, 2},
+		{"\U0001f1ef\U0001f1f5\U0001f1fa", 3, 2},
+		{"\U0001f468\u200d\U0001f469\u200d\U0001f467", 5, 1},
+		{"\U0001f44d\U0001f3fd", 2, 1},
+		{"\u2764\ufe0f", 2, 1},
+		{"\u1100\u1161\u11a8", 3, 1},
+		{"\uac01\u1100", 2, 2},
+		{"\uff76\uff9e\uff77", 3, 2},
+		{"\xff\xfe", 2, 2},
+	}
+	for _, tt := range tests {
+		if got := Bytes.count(tt.input); got != len(tt.input) {
+			t.Errorf("Bytes.count(%q) = %d, want %d", tt.input, got, len(tt.input))
+		}
+		if got := Runes.count(tt.input); got != tt.runes {
+			t.Errorf("Runes.count(%q) = %d, want %d", tt.input, got, tt.runes)
+		}
+		if got := Graphemes.count(tt.input); got != tt.graphemes {
+			t.Errorf("Graphemes.count(%q) = %d, want %d", tt.input, got, tt.graphemes)
+		}
+
+		// counting a rune at a time gives the same length
+		c := lengthCounter{unit: Graphemes}
+		for _, r := range tt.input {
+			c.add(string(r))
+		}
+		if c.n != tt.graphemes && !strings.Contains(tt.input, "\xff") {
+			t.Errorf("Graphemes counted by rune in %q = %d, want %d", tt.input, c.n, tt.graphemes)
+		}
+	}
+}
+
+func TestSanitize_LengthUnit(t *testing.T) {
+	japanese := strings.Repeat("日本語", 4) // 12 runes, 36 bytes
+	decomposed := strings.Repeat("e\u0301", 10)
+
+	tests := []struct {
+		unit    LengthUnit
+		input   string
+		wantErr string
+	}{
+		{Bytes, japanese, "HTMLBody input is limited to 12 bytes, got 36"},
+		{Runes, japanese, ""},
+		{Graphemes, japanese, ""},
+		{Runes, japanese + "語", "HTMLBody input is limited to 12 runes, got 13"},
+		{Runes, decomposed, "HTMLBody input is limited to 12 runes, got 20"},
+		{Graphemes, decomposed, ""},
+		{Graphemes, decomposed + "xyz", "HTMLBody input is limited to 12 graphemes, got 13"},
+		// combining marks stacked on few letters are capped at 4 bytes per
+		// grapheme of the limit
+		{Graphemes, "a" + strings.Repeat("\u0301", 24), "HTMLBody input is limited to 48 bytes, got 49"},
+	}
+	for _, tt := range tests {
+		s := New(Config{MaxInputLength: 12, LengthUnit: tt.unit})
+		_, err := s.Sanitize(tt.input, HTMLBody)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%v %q: unexpected error %v", tt.unit, tt.input, err)
+			}
+			continue
+		}
+		var se *SanitizeError
+		if !errors.Is(err, ErrInputTooLong) || !errors.As(err, &se) || se.Kind != ErrorKindTooLong || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%v %q: expected error %q, got %v", tt.unit, tt.input, tt.wantErr, err)
+		}
+	}
+
+	// normalization runs before the limit, so decomposed input is measured
+	// composed
+	s := New(Config{MaxInputLength: 10, LengthUnit: Runes, NormalizeUnicode: true})
+	if _, err := s.Sanitize(decomposed, HTMLBody); err != nil {
+		t.Errorf("normalized: unexpected error %v", err)
+	}
+}
+
+func TestSanitizeCopy_LengthUnit(t *testing.T) {
+	input := strings.Repeat("e\u0301", 10)
+	for _, tt := range []struct {
+		limit   int
+		wantErr bool
+	}{{10, false}, {9, true}} {
+		s := New(Config{MaxInputLength: tt.limit, LengthUnit: Graphemes})
+		_, err := s.SanitizeCopy(&bytes.Buffer{}, iotest.OneByteReader(strings.NewReader(input)), HTMLBody)
+		if tt.wantErr != errors.Is(err, ErrInputTooLong) {
+			t.Errorf("limit %d: got %v", tt.limit, err)
+		}
+	}
+}
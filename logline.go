@@ -0,0 +1,126 @@
+package safeinput
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// SanitizeForLog makes a value safe to interpolate into a log line (CWE-117).
+// CR, LF, tab and other control characters are escaped in Go syntax, such as
+// \n and \x1b, as are invalid UTF-8 bytes and Unicode line separators, and
+// ANSI escape sequences that could rewrite a terminal are removed.
+func SanitizeForLog(input string) string {
+	return sanitizeLogLine(input, 0)
+}
+
+// sanitizeLogLine implements the LogLine context. If maxLength is positive
+// the output is cut to at most maxLength bytes, without splitting an escape,
+// and followed by "...".
+func sanitizeLogLine(input string, maxLength int) string {
+	if (maxLength <= 0 || len(input) <= maxLength) && isPrintableASCII(input) {
+		return input
+	}
+	return string(appendLogLine(make([]byte, 0, len(input)), input, maxLength))
+}
+
+// isPrintableASCII reports whether input consists only of printable ASCII
+// characters, which sanitizeLogLine leaves alone.
+func isPrintableASCII[T text](input T) bool {
+	for i := 0; i < len(input); i++ {
+		if input[i] < 0x20 || input[i] >= 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// appendLogLine appends input sanitized as sanitizeLogLine describes to dst.
+func appendLogLine[T text](dst []byte, input T, maxLength int) []byte {
+	if (maxLength <= 0 || len(input) <= maxLength) && isPrintableASCII(input) {
+		return append(dst, input...)
+	}
+	start := len(dst)
+	for i := 0; i < len(input); {
+		if n := ansiSequenceLength(input[i:]); n > 0 {
+			i += n
+			continue
+		}
+
+		r, size := decodeRune(input[i:])
+		i += size
+		var escaped string
+		switch {
+		case r == '\n':
+			escaped = `\n`
+		case r == '\r':
+			escaped = `\r`
+		case r == '\t':
+			escaped = `\t`
+		case r == utf8.RuneError && size == 1:
+			escaped = fmt.Sprintf(`\x%02x`, input[i-1])
+		case r < 0x20 || r == 0x7f:
+			escaped = fmt.Sprintf(`\x%02x`, r)
+		case (r >= 0x80 && r <= 0x9f) || r == '\u2028' || r == '\u2029':
+			escaped = fmt.Sprintf(`\u%04x`, r)
+		}
+
+		pieceLen := size
+		if escaped != "" {
+			pieceLen = len(escaped)
+		}
+		if maxLength > 0 && len(dst)-start+pieceLen > maxLength {
+			dst = append(dst, "..."...)
+			break
+		}
+		if escaped != "" {
+			dst = append(dst, escaped...)
+		} else {
+			dst = append(dst, input[i-size:i]...)
+		}
+	}
+	return dst
+}
+
+// ansiSequenceLength returns the length of the ANSI escape sequence at the
+// start of s, or 0 if s does not start with one. It recognizes CSI sequences
+// such as ESC [ 31 m, OSC sequences such as terminal title changes, and
+// two-character escapes.
+func ansiSequenceLength[T text](s T) int {
+	switch {
+	case len(s) >= 2 && s[0] == '\x1b' && s[1] == '[':
+		return csiLength(s, 2)
+	case len(s) >= 2 && s[0] == 0xc2 && s[1] == 0x9b: // U+009B
+		return csiLength(s, 2)
+	case len(s) >= 2 && s[0] == '\x1b' && s[1] == ']':
+		// OSC ends with BEL or ESC \, or runs to the end of the input
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return i + 1
+			}
+			if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	case len(s) >= 2 && s[0] == '\x1b' && s[1] >= 0x20 && s[1] < 0x7f:
+		return 2
+	}
+	return 0
+}
+
+// csiLength returns the length of the CSI sequence at the start of s whose
+// parameters begin at start: parameter and intermediate bytes followed by
+// a final byte in 0x40-0x7e.
+func csiLength[T text](s T, start int) int {
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x40 && c <= 0x7e {
+			return i + 1
+		}
+		if c < 0x20 || c > 0x7e {
+			// Not a well-formed sequence; drop only the introducer
+			return start
+		}
+	}
+	return len(s)
+}
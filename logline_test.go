@@ -0,0 +1,62 @@
+package safeinput
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeForLog(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"login ok", "login ok"},
+		{"alice\nINFO admin logged in", `alice\nINFO admin logged in`},
+		{"a\r\nb\tc", `a\r\nb\tc`},
+		{"\x1b[31mred\x1b[0m", "red"},
+		{"\x1b[2J\x1b[1;1Hcleared", "cleared"},
+		{"\x1b]0;pwned\atitle", "title"},
+		{"\x1b]8;;http://evil\x1b\\link", "link"},
+		{"\x1bcreset", "reset"},
+		{"\u009b31mc1", "c1"},
+		{"bell\x07 del\x7f", `bell\x07 del\x7f`},
+		{"trailing esc\x1b", `trailing esc\x1b`},
+		{"line\u2028sep", `line\u2028sep`},
+		{"bad\xffutf8", `bad\xffutf8`},
+		{"naïve café", "naïve café"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeForLog(tt.input); got != tt.want {
+			t.Errorf("SanitizeForLog(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSanitize_LogLine(t *testing.T) {
+	s := New(Config{MaxLogLength: 8})
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"short", "short"},
+		{"exactly8", "exactly8"},
+		{"much longer value", "much lon..."},
+		{"abcdef\n", `abcdef\n`},
+		{"abcdefg\n", "abcdefg..."},
+		{"héllo wörld", "héllo w..."},
+	}
+	for _, tt := range tests {
+		got, err := s.Sanitize(tt.input, LogLine)
+		if err != nil {
+			t.Errorf("Sanitize(%q, LogLine) error = %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("Sanitize(%q, LogLine) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	got, _ := Default().Sanitize(strings.Repeat("x", 5000), LogLine)
+	if len(got) != 5000 {
+		t.Errorf("unlimited LogLine truncated to %d bytes", len(got))
+	}
+}
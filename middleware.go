@@ -0,0 +1,95 @@
+package safeinput
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+)
+
+// maxMultipartMemory is the memory limit Middleware passes to
+// ParseMultipartForm, the same as net/http's default.
+const maxMultipartMemory = 32 << 20
+
+// Middleware returns HTTP middleware that sanitizes the query parameters
+// and, for application/x-www-form-urlencoded and multipart/form-data
+// requests, the form values of each request before next runs. Each
+// parameter uses the context policy gives its name. Parameters not in
+// policy use Config.DefaultParamContext, or are rejected in strict mode.
+//
+// The handler sees the sanitized values through r.URL.Query(), r.Form,
+// r.PostForm and r.FormValue. If any parameter is rejected the request
+// fails with 400 Bad Request and a fixed message that does not echo the
+// input.
+func Middleware(s *Sanitizer, policy map[string]Context) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			clone, err := s.sanitizeRequest(r, policy)
+			if r.MultipartForm == nil && clone.MultipartForm != nil {
+				// net/http only removes the temporary files of a form
+				// parsed on the request it passed to the handler
+				defer clone.MultipartForm.RemoveAll()
+			}
+			if err != nil {
+				http.Error(w, "invalid request parameters", http.StatusBadRequest)
+				return
+			}
+			next.ServeHTTP(w, clone)
+		})
+	}
+}
+
+// sanitizeRequest returns a copy of r with its query and form values
+// sanitized.
+func (s *Sanitizer) sanitizeRequest(r *http.Request, policy map[string]Context) (*http.Request, error) {
+	r = r.Clone(r.Context())
+	query, queryErr := s.SanitizeValues(r.URL.Query(), s.paramContexts(r.URL.Query(), policy))
+	r.URL.RawQuery = query.Encode()
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var err error
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		err = r.ParseForm()
+	case "multipart/form-data":
+		err = r.ParseMultipartForm(maxMultipartMemory)
+	default:
+		return r, queryErr
+	}
+	if err != nil {
+		return r, err
+	}
+
+	post, postErr := s.SanitizeValues(r.PostForm, s.paramContexts(r.PostForm, policy))
+	r.PostForm = post
+	if r.MultipartForm != nil {
+		r.MultipartForm.Value = post
+	}
+	// r.Form holds the post values followed by the query values, as
+	// ParseForm builds it
+	r.Form = make(url.Values, len(post)+len(query))
+	for k, vs := range post {
+		r.Form[k] = append(r.Form[k], vs...)
+	}
+	for k, vs := range query {
+		r.Form[k] = append(r.Form[k], vs...)
+	}
+	return r, errors.Join(queryErr, postErr)
+}
+
+// paramContexts returns policy extended with DefaultParamContext for the
+// keys of values it does not cover, or policy itself in strict mode.
+func (s *Sanitizer) paramContexts(values url.Values, policy map[string]Context) map[string]Context {
+	if s.config.StrictMode {
+		return policy
+	}
+	contexts := make(map[string]Context, len(values))
+	for k := range values {
+		ctx, ok := policy[k]
+		if !ok {
+			ctx = s.config.DefaultParamContext
+		}
+		contexts[k] = ctx
+	}
+	return contexts
+}
@@ -0,0 +1,174 @@
+package safeinput
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+// echoHandler writes the parameters the handler sees
+var echoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.WriteString(w, "query="+r.URL.Query().Encode()+" form="+r.Form.Encode()+
+		" post="+r.PostForm.Encode()+" name="+r.FormValue("name"))
+})
+
+var testPolicy = map[string]Context{
+	"name": HTMLBody,
+	"file": Filename,
+	"next": URL,
+}
+
+func TestMiddleware(t *testing.T) {
+	strict := Middleware(Default(), testPolicy)(echoHandler)
+	lenient := Middleware(New(Config{DefaultParamContext: ShellArg}), testPolicy)(echoHandler)
+
+	tests := []struct {
+		name     string
+		handler  http.Handler
+		req      func() *http.Request
+		wantCode int
+		want     string
+	}{
+		{
+			name:    "query",
+			handler: strict,
+			req: func() *http.Request {
+				return httptest.NewRequest("GET", "/?name=%3Cb%3EBob%3C%2Fb%3E&file=a%3Fb.txt", nil)
+			},
+			wantCode: http.StatusOK,
+			want:     "query=file=a_b.txt&name=Bob form= post= name=Bob",
+		},
+		{
+			name:    "urlencoded form",
+			handler: strict,
+			req: func() *http.Request {
+				r := httptest.NewRequest("POST", "/?file=x.txt", strings.NewReader("name=%3Ci%3EAl%3C%2Fi%3E&next=https%3A%2F%2Fexample.com%2F"))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return r
+			},
+			wantCode: http.StatusOK,
+			want:     "query=file=x.txt form=file=x.txt&name=Al&next=https%3A%2F%2Fexample.com%2F post=name=Al&next=https%3A%2F%2Fexample.com%2F name=Al",
+		},
+		{
+			name:    "multipart form",
+			handler: strict,
+			req: func() *http.Request {
+				var body bytes.Buffer
+				mw := multipart.NewWriter(&body)
+				_ = mw.WriteField("name", "<u>Cy</u>")
+				_ = mw.Close()
+				r := httptest.NewRequest("POST", "/", &body)
+				r.Header.Set("Content-Type", mw.FormDataContentType())
+				return r
+			},
+			wantCode: http.StatusOK,
+			want:     "query= form=name=Cy post=name=Cy name=Cy",
+		},
+		{
+			name:    "rejected value",
+			handler: strict,
+			req: func() *http.Request {
+				return httptest.NewRequest("GET", "/?next=javascript%3Aalert(1)", nil)
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:    "rejected form value",
+			handler: strict,
+			req: func() *http.Request {
+				r := httptest.NewRequest("POST", "/", strings.NewReader("file=..%2Fetc"))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+				return r
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:    "unknown parameter in strict mode",
+			handler: strict,
+			req: func() *http.Request {
+				return httptest.NewRequest("GET", "/?name=a&%3Cscript%3E=1", nil)
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:    "unknown parameter uses default context",
+			handler: lenient,
+			req: func() *http.Request {
+				return httptest.NewRequest("GET", "/?name=a&cmd=ls%3Brm", nil)
+			},
+			wantCode: http.StatusOK,
+			want:     "query=cmd=lsrm&name=a form= post= name=a",
+		},
+		{
+			name:    "other content types are left alone",
+			handler: strict,
+			req: func() *http.Request {
+				r := httptest.NewRequest("POST", "/?name=a", strings.NewReader(`{"name":"<b>x</b>"}`))
+				r.Header.Set("Content-Type", "application/json")
+				return r
+			},
+			wantCode: http.StatusOK,
+			want:     "query=name=a form= post= name=a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, tt.req())
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body)
+			}
+			if tt.wantCode == http.StatusOK && rec.Body.String() != tt.want {
+				t.Errorf("handler saw %q, want %q", rec.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddleware_ErrorDoesNotReflectInput(t *testing.T) {
+	h := Middleware(Default(), testPolicy)(echoHandler)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/?"+url.Values{"<script>x</script>": {"<img>"}}.Encode(), nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "script") || strings.Contains(body, "img") {
+		t.Errorf("response reflects input: %q", body)
+	}
+}
+
+func TestMiddleware_RemovesMultipartFiles(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	for _, name := range []string{"report.txt", "../etc"} {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		_ = mw.WriteField("file", name)
+		part, _ := mw.CreateFormFile("upload", "big.bin")
+		_, _ = part.Write(make([]byte, maxMultipartMemory+1))
+		_ = mw.Close()
+		r := httptest.NewRequest("POST", "/", &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+
+		var spilled bool
+		h := Middleware(Default(), testPolicy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			entries, _ := os.ReadDir(tmp)
+			spilled = len(entries) > 0
+		}))
+		h.ServeHTTP(httptest.NewRecorder(), r)
+
+		if name == "report.txt" && !spilled {
+			t.Fatal("upload was not written to a temporary file")
+		}
+		if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+			t.Errorf("file=%q: %d temporary files left behind", name, len(entries))
+		}
+	}
+}
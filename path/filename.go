@@ -0,0 +1,98 @@
+package path
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxFilenameLength is the filename length limit, in bytes, applied
+// when FilenameOptions does not set one.
+const DefaultMaxFilenameLength = 255
+
+// FilenameOptions configures SanitizeFilename.
+type FilenameOptions struct {
+	// MaxLength is the maximum length of the result in bytes.
+	// Zero means DefaultMaxFilenameLength.
+	MaxLength int
+	// AllowLeadingDot keeps leading dots, allowing names such as ".env".
+	AllowLeadingDot bool
+}
+
+// windowsReservedNames are the device names Windows reserves with or
+// without an extension.
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SanitizeFilename cleans a single filename component, such as an upload
+// or attachment name, so that it is safe on Unix and Windows. Names
+// containing a path separator are rejected with ErrPathSeparator, "." and
+// ".." with ErrPathTraversal, and Windows device names such as "CON" or
+// "nul.txt" with ErrReservedName. Everything else is transformed: control
+// characters, invalid UTF-8 and the characters <>:"|?* become "_", leading
+// dots are removed unless allowed, trailing dots and spaces are trimmed,
+// and names over the length limit are shortened, keeping the extension.
+// ErrEmptyPath is returned if nothing is left.
+func SanitizeFilename(name string, opts FilenameOptions) (string, error) {
+	if strings.ContainsAny(name, `/\`) {
+		return "", ErrPathSeparator
+	}
+	if name == "." || name == ".." {
+		return "", ErrPathTraversal
+	}
+
+	var b strings.Builder
+	b.Grow(len(name))
+	for i := 0; i < len(name); {
+		r, size := utf8.DecodeRuneInString(name[i:])
+		i += size
+		switch {
+		case r == utf8.RuneError && size == 1,
+			r < 0x20, r == 0x7f, strings.ContainsRune(`<>:"|?*`, r):
+			b.WriteByte('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	name = b.String()
+	if !opts.AllowLeadingDot {
+		name = strings.TrimLeft(name, ".")
+	}
+	name = strings.TrimRight(name, ". ")
+
+	maxLength := opts.MaxLength
+	if maxLength <= 0 {
+		maxLength = DefaultMaxFilenameLength
+	}
+	name = truncateFilename(name, maxLength)
+	if name == "" {
+		return "", ErrEmptyPath
+	}
+
+	stem, _, _ := strings.Cut(name, ".")
+	if windowsReservedNames[strings.ToUpper(strings.TrimRight(stem, " "))] {
+		return "", ErrReservedName
+	}
+	return name, nil
+}
+
+// truncateFilename shortens name to at most maxLength bytes at a UTF-8
+// boundary, keeping its extension when that is at most half the limit.
+func truncateFilename(name string, maxLength int) string {
+	if len(name) <= maxLength {
+		return name
+	}
+	ext := ""
+	if i := strings.LastIndexByte(name, '.'); i > 0 && len(name)-i <= maxLength/2 {
+		name, ext = name[:i], name[i:]
+	}
+	cut := maxLength - len(ext)
+	for cut > 0 && !utf8.RuneStart(name[cut]) {
+		cut--
+	}
+	return strings.TrimRight(name[:cut], ". ") + ext
+}
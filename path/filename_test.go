@@ -0,0 +1,99 @@
+package path
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"report.pdf", "report.pdf", nil},
+		{"My Photo (1).JPG", "My Photo (1).JPG", nil},
+		{"résumé.docx", "résumé.docx", nil},
+		{`what<is>this:"a|b"?*.txt`, "what_is_this__a_b___.txt", nil},
+		{"tab\there\x00nul\x1f.txt", "tab_here_nul_.txt", nil},
+		{"bad\xffutf8.txt", "bad_utf8.txt", nil},
+		{".htaccess", "htaccess", nil},
+		{"...hidden", "hidden", nil},
+		{"name.txt. . ", "name.txt", nil},
+		{"trailing...", "trailing", nil},
+		{"connect.txt", "connect.txt", nil},
+		{"com10", "com10", nil},
+
+		{"", "", ErrEmptyPath},
+		{"...", "", ErrEmptyPath},
+		{" . ", "", ErrEmptyPath},
+		{".", "", ErrPathTraversal},
+		{"..", "", ErrPathTraversal},
+		{"dir/file.txt", "", ErrPathSeparator},
+		{"../etc/passwd", "", ErrPathSeparator},
+		{`dir\file.txt`, "", ErrPathSeparator},
+		{"CON", "", ErrReservedName},
+		{"nul.txt", "", ErrReservedName},
+		{"Com1.tar.gz", "", ErrReservedName},
+		{"lpt9", "", ErrReservedName},
+		{"aux .log", "", ErrReservedName},
+		{"PRN.", "", ErrReservedName},
+	}
+	for _, tt := range tests {
+		got, err := SanitizeFilename(tt.input, FilenameOptions{})
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("SanitizeFilename(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeFilename_AllowLeadingDot(t *testing.T) {
+	opts := FilenameOptions{AllowLeadingDot: true}
+	if got, err := SanitizeFilename(".env", opts); err != nil || got != ".env" {
+		t.Errorf("SanitizeFilename(.env) = %q, %v", got, err)
+	}
+	for _, input := range []string{".", ".."} {
+		if _, err := SanitizeFilename(input, opts); !errors.Is(err, ErrPathTraversal) {
+			t.Errorf("SanitizeFilename(%q) error = %v, want ErrPathTraversal", input, err)
+		}
+	}
+}
+
+func TestSanitizeFilename_MaxLength(t *testing.T) {
+	long := strings.Repeat("a", 300) + ".pdf"
+	got, err := SanitizeFilename(long, FilenameOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(got) != DefaultMaxFilenameLength || !strings.HasSuffix(got, ".pdf") {
+		t.Errorf("default limit: got %d bytes %q", len(got), got)
+	}
+
+	tests := []struct {
+		input string
+		max   int
+		want  string
+	}{
+		{"abcdefghij.txt", 10, "abcdef.txt"},
+		{"abcdefghij", 4, "abcd"},
+		{"ab.verylongextension", 10, "ab.verylon"},
+		{"ééééé.txt", 8, "éé.txt"},
+		{"abc...def.txt", 8, "abc.txt"},
+	}
+	for _, tt := range tests {
+		got, err := SanitizeFilename(tt.input, FilenameOptions{MaxLength: tt.max})
+		if err != nil {
+			t.Errorf("SanitizeFilename(%q, %d) error = %v", tt.input, tt.max, err)
+			continue
+		}
+		if got != tt.want || len(got) > tt.max || !utf8.ValidString(got) {
+			t.Errorf("SanitizeFilename(%q, %d) = %q, want %q", tt.input, tt.max, got, tt.want)
+		}
+	}
+}
@@ -2,9 +2,11 @@
 package path
 
 import (
+	"bytes"
 	"errors"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 )
 
 // Errors returned by the path sanitizer.
@@ -14,6 +16,8 @@ var (
 	ErrInvalidCharacter = errors.New("invalid character in path")
 	ErrOutsideBasePath  = errors.New("path escapes base directory")
 	ErrEmptyPath        = errors.New("empty path not allowed")
+	ErrPathSeparator    = errors.New("path separator in filename")
+	ErrReservedName     = errors.New("reserved device name")
 )
 
 var blockedSequences = []string{
@@ -21,15 +25,38 @@ var blockedSequences = []string{
 	"....//", "..../", ".%2e", "%2e.", "..%252f", "..%255c",
 }
 
-// Sanitizer provides path sanitization.
+// Sanitizer provides path sanitization. It is safe for concurrent use.
+// Configure it with options to New, or derive a differently configured
+// copy with WithAllowAbsolute.
 type Sanitizer struct {
-	basePath      string
-	allowAbsolute bool
+	basePath string
+	// allowAbsolute is atomic only so that the deprecated SetAllowAbsolute
+	// does not race with sanitization
+	allowAbsolute atomic.Bool
+}
+
+// Option configures a Sanitizer created by New.
+type Option func(*Sanitizer)
+
+// WithAllowAbsolute sets whether Sanitize accepts absolute paths. They are
+// rejected by default.
+func WithAllowAbsolute(allow bool) Option {
+	return func(s *Sanitizer) { s.allowAbsolute.Store(allow) }
 }
 
 // New creates a path Sanitizer.
-func New(basePath string) *Sanitizer {
-	return &Sanitizer{basePath: basePath}
+func New(basePath string, opts ...Option) *Sanitizer {
+	s := &Sanitizer{basePath: basePath}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WithAllowAbsolute returns a copy of s with absolute paths allowed or
+// not, leaving s unchanged.
+func (s *Sanitizer) WithAllowAbsolute(allow bool) *Sanitizer {
+	return New(s.basePath, WithAllowAbsolute(allow))
 }
 
 // validateCharacters checks for invalid characters.
@@ -103,7 +130,7 @@ func (s *Sanitizer) Sanitize(input string) (string, error) {
 
 	cleaned := filepath.Clean(normalized)
 
-	if !s.allowAbsolute && filepath.IsAbs(cleaned) {
+	if !s.allowAbsolute.Load() && filepath.IsAbs(cleaned) {
 		return "", ErrAbsolutePath
 	}
 
@@ -134,8 +161,12 @@ func (s *Sanitizer) Join(base string, components ...string) (string, error) {
 }
 
 // SetAllowAbsolute configures whether absolute paths are allowed.
+//
+// Deprecated: Changing a shared Sanitizer changes it under the calls
+// already using it. Pass WithAllowAbsolute to New, or use the
+// WithAllowAbsolute method to derive a new Sanitizer.
 func (s *Sanitizer) SetAllowAbsolute(allow bool) {
-	s.allowAbsolute = allow
+	s.allowAbsolute.Store(allow)
 }
 
 // BasePath returns the configured base path.
@@ -145,7 +176,29 @@ func (s *Sanitizer) BasePath() string {
 
 // AllowAbsolute returns whether absolute paths are allowed.
 func (s *Sanitizer) AllowAbsolute() bool {
-	return s.allowAbsolute
+	return s.allowAbsolute.Load()
+}
+
+// FindTraversal returns the earliest traversal sequence in input, as it
+// appears in input, and its byte offset. The offset is -1 if there is none.
+func FindTraversal(input string) (string, int) {
+	// Only ASCII case matters to the sequences, and offsets must match input
+	lower := []byte(input)
+	for i, c := range lower {
+		if c >= 'A' && c <= 'Z' {
+			lower[i] = c + 'a' - 'A'
+		}
+	}
+	seq, offset := "", -1
+	for _, s := range blockedSequences {
+		if i := bytes.Index(lower, []byte(s)); i >= 0 && (offset < 0 || i < offset || (i == offset && len(s) > len(seq))) {
+			seq, offset = s, i
+		}
+	}
+	if offset < 0 {
+		return "", -1
+	}
+	return input[offset : offset+len(seq)], offset
 }
 
 // IsTraversal checks if a path contains traversal sequences.
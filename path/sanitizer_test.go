@@ -151,6 +151,44 @@ func TestSetAllowAbsolute(t *testing.T) {
 	}
 }
 
+func TestWithAllowAbsolute(t *testing.T) {
+	s := New("", WithAllowAbsolute(true))
+	if _, err := s.Sanitize("/etc/passwd"); err != nil {
+		t.Errorf("Should allow absolute paths: %v", err)
+	}
+	denied := s.WithAllowAbsolute(false)
+	if !s.AllowAbsolute() || denied.AllowAbsolute() {
+		t.Error("WithAllowAbsolute should copy s with the setting changed")
+	}
+	if _, err := denied.Sanitize("/etc/passwd"); err != ErrAbsolutePath {
+		t.Errorf("expected ErrAbsolutePath, got %v", err)
+	}
+	if based := New("/srv").WithAllowAbsolute(true); based.BasePath() != "/srv" {
+		t.Errorf("BasePath = %q, want /srv", based.BasePath())
+	}
+}
+
+// TestConcurrentConfiguration fails under -race if changing a Sanitizer
+// races with calls using it.
+func TestConcurrentConfiguration(t *testing.T) {
+	s := New("")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			s.SetAllowAbsolute(i%2 == 0)
+			_ = s.WithAllowAbsolute(i%2 == 1)
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		if _, err := s.Sanitize("docs/file.txt"); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		_, _ = s.Join("base", "a", "b")
+	}
+	<-done
+}
+
 func TestIsTraversal(t *testing.T) {
 	tests := []struct {
 		input string
@@ -170,6 +208,26 @@ func TestIsTraversal(t *testing.T) {
 	}
 }
 
+func TestFindTraversal(t *testing.T) {
+	tests := []struct {
+		input  string
+		seq    string
+		offset int
+	}{
+		{"a/../b", "../", 2},
+		{"files/..%2F..%2Fetc", "..%2F", 6},
+		{"x/%2E%2E/", "%2E%2E", 2},
+		{"ÄÖ/..\\win", "..\\", 5},
+		{"safe/path.txt", "", -1},
+	}
+	for _, tt := range tests {
+		seq, offset := FindTraversal(tt.input)
+		if seq != tt.seq || offset != tt.offset {
+			t.Errorf("FindTraversal(%q) = %q, %d, want %q, %d", tt.input, seq, offset, tt.seq, tt.offset)
+		}
+	}
+}
+
 func BenchmarkSanitize(b *testing.B) {
 	s := New("/var/www")
 	for i := 0; i < b.N; i++ {
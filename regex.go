@@ -0,0 +1,75 @@
+package safeinput
+
+import (
+	"regexp"
+	"regexp/syntax"
+)
+
+// defaultMaxRegexLength is the pattern length limit used by
+// ValidateRegexPattern and by a Sanitizer whose config does not set one.
+const defaultMaxRegexLength = 256
+
+// maxRegexRepeat bounds n*m for a counted repetition {n,m} in a pattern
+// accepted by ValidateRegexPattern.
+const maxRegexRepeat = 1000
+
+// EscapeRegex escapes all regular expression metacharacters in input, so
+// the result matches input literally when compiled.
+func EscapeRegex(input string) string {
+	return regexp.QuoteMeta(input)
+}
+
+// ValidateRegexPattern checks a user-supplied regular expression before it
+// is compiled (CWE-1333). Patterns that do not parse are rejected with
+// ErrInvalidRegex; nested quantifiers such as (a+)+ and counted
+// repetitions {n,m} with n*m over 1000 are rejected with ErrUnsafeRegex.
+// Patterns longer than 256 bytes are rejected with ErrInputTooLong. The
+// pattern is returned unchanged.
+func ValidateRegexPattern(input string) (string, error) {
+	return validateRegexPattern(input, defaultMaxRegexLength)
+}
+
+// validateRegexPattern implements the RegexPattern context.
+func validateRegexPattern(input string, maxLength int) (string, error) {
+	if len(input) > maxLength {
+		return "", ErrInputTooLong
+	}
+	re, err := syntax.Parse(input, syntax.Perl)
+	if err != nil {
+		return "", ErrInvalidRegex
+	}
+	if !isSafeRegex(re, false) {
+		return "", ErrUnsafeRegex
+	}
+	return input, nil
+}
+
+// isSafeRegex reports whether re has no oversized counted repetition and,
+// with repeated set, no quantifier inside an enclosing quantifier.
+func isSafeRegex(re *syntax.Regexp, repeated bool) bool {
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus:
+		if repeated {
+			return false
+		}
+		repeated = true
+	case syntax.OpRepeat:
+		if re.Max != 1 && repeated {
+			return false
+		}
+		n, m := re.Min, re.Max
+		if m < 0 {
+			m = n
+		}
+		if n*m > maxRegexRepeat {
+			return false
+		}
+		repeated = repeated || re.Max != 1
+	}
+	for _, sub := range re.Sub {
+		if !isSafeRegex(sub, repeated) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package safeinput
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestEscapeRegex(t *testing.T) {
+	inputs := []string{
+		"(a+)+$",
+		`1.5 * [x] {2} ^start | end\ ?`,
+		"plain text",
+		"",
+	}
+	for _, input := range inputs {
+		escaped := EscapeRegex(input)
+		re, err := regexp.Compile("^" + escaped + "$")
+		if err != nil {
+			t.Errorf("EscapeRegex(%q) = %q does not compile: %v", input, escaped, err)
+			continue
+		}
+		if !re.MatchString(input) {
+			t.Errorf("EscapeRegex(%q) = %q does not match the input", input, escaped)
+		}
+	}
+	if got := EscapeRegex("a.b"); regexp.MustCompile(got).MatchString("axb") {
+		t.Errorf("EscapeRegex(%q) = %q matches a metacharacter", "a.b", got)
+	}
+}
+
+func TestValidateRegexPattern(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr error
+	}{
+		{"^error: .*$", nil},
+		{`\d{3}-\d{4}`, nil},
+		{"(foo|bar)+baz", nil},
+		{"a{1,1000}", nil},
+		{"(ab)?c*", nil},
+		{"(a?)+", nil},
+		{"(a+)+$", ErrUnsafeRegex},
+		{"(a*)*b", ErrUnsafeRegex},
+		{"(?:x+y)+", ErrUnsafeRegex},
+		{"(a|b+){2,5}", ErrUnsafeRegex},
+		{"((ab)*c)+", ErrUnsafeRegex},
+		{"a{100,100}", ErrUnsafeRegex},
+		{"a{2000}", ErrInvalidRegex},
+		{"(unclosed", ErrInvalidRegex},
+		{"a**", ErrInvalidRegex},
+		{strings.Repeat("a", 256), nil},
+		{strings.Repeat("a", 257), ErrInputTooLong},
+	}
+	for _, tt := range tests {
+		got, err := ValidateRegexPattern(tt.input)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidateRegexPattern(%.40q) error = %v, want %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got != tt.input {
+			t.Errorf("ValidateRegexPattern(%.40q) = %q, want the input unchanged", tt.input, got)
+		}
+	}
+}
+
+func TestSanitize_Regex(t *testing.T) {
+	s := Default()
+	if got, err := s.Sanitize("(a+)+$", RegexLiteral); err != nil || got != `\(a\+\)\+\$` {
+		t.Errorf("RegexLiteral: got %q, %v", got, err)
+	}
+	if s.IsValid("(a+)+$", RegexLiteral) || !s.IsValid("plain text", RegexLiteral) {
+		t.Error("RegexLiteral: expected only input without metacharacters to be valid")
+	}
+	if s.IsValid("(a+)+$", RegexPattern) {
+		t.Error("RegexPattern: expected nested quantifiers to be invalid")
+	}
+	if !s.IsValid("^[a-z]+$", RegexPattern) {
+		t.Error("RegexPattern: expected a simple pattern to be valid")
+	}
+
+	s = New(Config{MaxRegexLength: 4})
+	if _, err := s.Sanitize("abcde", RegexPattern); !errors.Is(err, ErrInputTooLong) {
+		t.Errorf("MaxRegexLength: error = %v, want ErrInputTooLong", err)
+	}
+}
@@ -0,0 +1,83 @@
+package safedeserialize
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+
+	"github.com/ravisastryk/go-safeinput"
+)
+
+// auditExcerptBytes is the number of payload bytes included in an AuditEvent
+const auditExcerptBytes = 128
+
+// AuditEvent describes a rejected decode
+type AuditEvent struct {
+	// Format is the format that was being decoded
+	Format string
+
+	// Kind classifies the rejection using the Outcome constants
+	Kind string
+
+	// Err is the error returned to the caller
+	Err error
+
+	// Size is the number of input bytes seen
+	Size int
+
+	// Depth is the measured JSON nesting depth, 0 when not measured
+	Depth int
+
+	// TargetType is the type of the decode target, such as *main.User
+	TargetType string
+
+	// Excerpt holds at most the first 128 bytes of the payload sanitized
+	// as safeinput.SanitizeForLog does: newlines, control characters, line
+	// separators and invalid UTF-8 escaped and ANSI escape sequences removed
+	Excerpt string
+}
+
+// newAuditEvent builds the audit record for a rejected decode of data
+func newAuditEvent(format Format, kind string, err error, data []byte, size int, v any, opts *Options) AuditEvent {
+	event := AuditEvent{
+		Format:  string(format),
+		Kind:    kind,
+		Err:     err,
+		Size:    size,
+		Excerpt: auditExcerpt(data),
+	}
+	if v != nil {
+		event.TargetType = reflect.TypeOf(v).String()
+	}
+	// Reader paths only keep a prefix, so depth is measured for complete
+	// in-memory JSON input within the size limit
+	if format == FormatJSON && len(data) == size && int64(size) <= opts.MaxSize {
+		event.Depth = measureJSONDepth(data)
+	}
+	return event
+}
+
+// auditExcerpt returns the start of data sanitized with
+// safeinput.SanitizeForLog so that it cannot forge or split log lines
+func auditExcerpt(data []byte) string {
+	if len(data) > auditExcerptBytes {
+		data = data[:auditExcerptBytes]
+	}
+	return safeinput.SanitizeForLog(string(data))
+}
+
+// SlogAuditLogger returns an audit logger that writes each event to logger
+// at warning level
+func SlogAuditLogger(logger *slog.Logger) func(AuditEvent) {
+	return func(event AuditEvent) {
+		logger.LogAttrs(context.Background(), slog.LevelWarn, "safedeserialize: payload rejected",
+			slog.String("format", event.Format),
+			slog.String("kind", event.Kind),
+			slog.String("error", event.Err.Error()),
+			slog.Int("size", event.Size),
+			slog.Int("depth", event.Depth),
+			slog.String("target", event.TargetType),
+			slog.String("excerpt", event.Excerpt),
+		)
+	}
+}
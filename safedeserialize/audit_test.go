@@ -0,0 +1,82 @@
+package safedeserialize
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/ravisastryk/go-safeinput"
+)
+
+func TestAuditLogger(t *testing.T) {
+	var events []AuditEvent
+	decoder := NewDecoder(WithAuditLogger(func(event AuditEvent) {
+		events = append(events, event)
+	}))
+
+	t.Run("not called on success", func(t *testing.T) {
+		events = nil
+		if err := decoder.JSON([]byte(`{"id":1}`), &SimpleUser{}); err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 0 {
+			t.Errorf("expected no events, got %+v", events)
+		}
+	})
+
+	t.Run("rejection", func(t *testing.T) {
+		events = nil
+		data := []byte("{\"id\":[[[1]]],\n\"name\":\"x\x1b[31m\u2028\"}")
+		err := decoder.JSON(data, &SimpleUser{}, WithMaxDepth(2))
+		if !errors.Is(err, ErrMaxDepthExceeded) {
+			t.Fatalf("expected ErrMaxDepthExceeded, got %v", err)
+		}
+		if len(events) != 1 {
+			t.Fatalf("expected one event, got %d", len(events))
+		}
+
+		event := events[0]
+		if event.Format != "json" || event.Kind != OutcomeTooDeep || event.Err != err {
+			t.Errorf("unexpected event: %+v", event)
+		}
+		if event.Size != len(data) || event.Depth != 4 || event.TargetType != "*safedeserialize.SimpleUser" {
+			t.Errorf("unexpected size, depth or target: %+v", event)
+		}
+		if strings.ContainsAny(event.Excerpt, "\n\x1b") || event.Excerpt != safeinput.SanitizeForLog(string(data)) {
+			t.Errorf("excerpt not sanitized for logging: %q", event.Excerpt)
+		}
+	})
+
+	t.Run("excerpt is truncated", func(t *testing.T) {
+		events = nil
+		data := strings.Repeat("A", 4096)
+		if err := decoder.JSONReader(strings.NewReader(data), &SimpleUser{}); err == nil {
+			t.Fatal("expected an error")
+		}
+		if len(events) != 1 || len(events[0].Excerpt) != auditExcerptBytes || events[0].Size != len(data) {
+			t.Errorf("unexpected event: %+v", events)
+		}
+	})
+}
+
+func TestSlogAuditLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	err := YAML([]byte("nickname: x\n"), &SimpleUser{}, WithAuditLogger(SlogAuditLogger(logger)))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	out := buf.String()
+	for _, want := range []string{"level=WARN", "format=yaml", "kind=unknown_field", "target=*safedeserialize.SimpleUser"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output %q does not contain %q", out, want)
+		}
+	}
+	if strings.Count(out, "\n") != 1 {
+		t.Errorf("expected a single log line, got %q", out)
+	}
+}
@@ -0,0 +1,57 @@
+package safedeserialize
+
+import "errors"
+
+// Error categories. Every error returned by the package matches exactly
+// one of them with errors.Is, so callers can tell a payload that broke the
+// rules from one that tripped a safety control without matching strings.
+var (
+	// ErrSecurityLimit matches errors raised by a safety control: size,
+	// depth and count limits, target and type restrictions, denied fields
+	// and content, signatures, and the guards around them
+	ErrSecurityLimit = errors.New("safedeserialize: security limit")
+
+	// ErrMalformedInput matches errors for input that is syntactically
+	// invalid or does not fit what was asked for: parse errors, unknown
+	// fields, empty or trailing data, failed validation, unreadable input,
+	// and malformed policies or option sets
+	ErrMalformedInput = errors.New("safedeserialize: malformed input")
+)
+
+// categorizedError is a sentinel error that belongs to a category
+type categorizedError struct {
+	msg      string
+	category error
+}
+
+func (e *categorizedError) Error() string { return e.msg }
+func (e *categorizedError) Unwrap() error { return e.category }
+
+// securityError returns a sentinel error in the ErrSecurityLimit category
+func securityError(msg string) error {
+	return &categorizedError{msg: msg, category: ErrSecurityLimit}
+}
+
+// malformedError returns a sentinel error in the ErrMalformedInput category
+func malformedError(msg string) error {
+	return &categorizedError{msg: msg, category: ErrMalformedInput}
+}
+
+// uncategorizedError places an error from a parser, reader or codec in the
+// ErrMalformedInput category without changing its message
+type uncategorizedError struct {
+	err error
+}
+
+func (e *uncategorizedError) Error() string   { return e.err.Error() }
+func (e *uncategorizedError) Unwrap() []error { return []error{ErrMalformedInput, e.err} }
+
+// categorize returns err unchanged if it already belongs to a category, and
+// otherwise as malformed input; errors from the package itself all carry a
+// category, so anything else came from parsing or reading the input
+func categorize(err error) error {
+	if err == nil || errors.Is(err, ErrSecurityLimit) || errors.Is(err, ErrMalformedInput) {
+		return err
+	}
+	return &uncategorizedError{err: err}
+}
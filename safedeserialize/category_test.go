@@ -0,0 +1,174 @@
+package safedeserialize
+
+import (
+	"encoding/json"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sentinels lists every exported sentinel error with its category
+var sentinels = map[string]struct {
+	err      error
+	security bool
+}{
+	"ErrDataTooLarge":          {ErrDataTooLarge, true},
+	"ErrNilTarget":             {ErrNilTarget, true},
+	"ErrNotPointer":            {ErrNotPointer, true},
+	"ErrInterfaceTarget":       {ErrInterfaceTarget, true},
+	"ErrMapInterface":          {ErrMapInterface, true},
+	"ErrMapInterfaceKey":       {ErrMapInterfaceKey, true},
+	"ErrSliceInterface":        {ErrSliceInterface, true},
+	"ErrTypeNotAllowed":        {ErrTypeNotAllowed, true},
+	"ErrMaxDepthExceeded":      {ErrMaxDepthExceeded, true},
+	"ErrEmptyData":             {ErrEmptyData, false},
+	"ErrFieldNotAllowed":       {ErrFieldNotAllowed, true},
+	"ErrInvalidNumber":         {ErrInvalidNumber, false},
+	"ErrInvalidTime":           {ErrInvalidTime, false},
+	"ErrTimeOutOfRange":        {ErrTimeOutOfRange, true},
+	"ErrNonFiniteNumber":       {ErrNonFiniteNumber, true},
+	"ErrDeniedField":           {ErrDeniedField, true},
+	"ErrDTDNotAllowed":         {ErrDTDNotAllowed, true},
+	"ErrEntityNotAllowed":      {ErrEntityNotAllowed, true},
+	"ErrCharsetNotAllowed":     {ErrCharsetNotAllowed, true},
+	"ErrProcInstNotAllowed":    {ErrProcInstNotAllowed, true},
+	"ErrNamespaceNotAllowed":   {ErrNamespaceNotAllowed, true},
+	"ErrUnknownXMLElement":     {ErrUnknownXMLElement, false},
+	"ErrUnknownXMLAttribute":   {ErrUnknownXMLAttribute, false},
+	"ErrTooManyElements":       {ErrTooManyElements, true},
+	"ErrTooManyDocuments":      {ErrTooManyDocuments, true},
+	"ErrTooManyRecords":        {ErrTooManyRecords, true},
+	"ErrTooManyAttributes":     {ErrTooManyAttributes, true},
+	"ErrTooManyMapKeys":        {ErrTooManyMapKeys, true},
+	"ErrStringTooLong":         {ErrStringTooLong, true},
+	"ErrUnmarshalerNotAllowed": {ErrUnmarshalerNotAllowed, true},
+	"ErrSanitizationFailed":    {ErrSanitizationFailed, true},
+	"ErrInvalidSignature":      {ErrInvalidSignature, true},
+	"ErrHMACKeyRequired":       {ErrHMACKeyRequired, true},
+	"ErrUnknownFormat":         {ErrUnknownFormat, false},
+	"ErrFormatRegistered":      {ErrFormatRegistered, false},
+	"ErrRegistryFrozen":        {ErrRegistryFrozen, true},
+	"ErrFormatNotAllowed":      {ErrFormatNotAllowed, true},
+	"ErrUnsupportedOption":     {ErrUnsupportedOption, true},
+	"ErrInvalidPolicy":         {ErrInvalidPolicy, false},
+	"ErrValidationFailed":      {ErrValidationFailed, false},
+	"ErrTrailingData":          {ErrTrailingData, false},
+	"ErrFileAccess":            {ErrFileAccess, false},
+	"ErrInvalidOptions":        {ErrInvalidOptions, false},
+	"ErrInvalidDefault":        {ErrInvalidDefault, false},
+}
+
+// checkOneCategory fails unless err matches exactly one category
+func checkOneCategory(t *testing.T, name string, err error) (security bool) {
+	t.Helper()
+	security, malformed := errors.Is(err, ErrSecurityLimit), errors.Is(err, ErrMalformedInput)
+	if security == malformed {
+		t.Errorf("%s: security=%v malformed=%v, want exactly one: %v", name, security, malformed, err)
+	}
+	return security
+}
+
+func TestSentinelCategories(t *testing.T) {
+	// Every exported Err variable declared in the package must be listed
+	names, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	for _, name := range names {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				for _, ident := range spec.(*ast.ValueSpec).Names {
+					name := ident.Name
+					if !strings.HasPrefix(name, "Err") || name == "ErrSecurityLimit" || name == "ErrMalformedInput" {
+						continue
+					}
+					if _, ok := sentinels[name]; !ok {
+						t.Errorf("%s has no category in this test", name)
+					}
+				}
+			}
+		}
+	}
+
+	for name, s := range sentinels {
+		if got := checkOneCategory(t, name, s.err); got != s.security {
+			t.Errorf("%s: security=%v, want %v", name, got, s.security)
+		}
+		if !errors.Is(s.err, s.err) {
+			t.Errorf("%s does not match itself", name)
+		}
+	}
+}
+
+// failingReader fails on the first read
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestReturnedErrorCategories(t *testing.T) {
+	var gobTarget SimpleUser
+	type withAny struct {
+		Data any `json:"data"`
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		security bool
+	}{
+		{"json syntax", JSON([]byte(`{"id":`), &SimpleUser{}), false},
+		{"json type", JSON([]byte(`{"id":"x"}`), &SimpleUser{}), false},
+		{"json unknown field", JSON([]byte(`{"admin":true}`), &SimpleUser{}), false},
+		{"json trailing", JSON([]byte(`{"id":1} x`), &SimpleUser{}), false},
+		{"yaml syntax", YAML([]byte("id: [1\n"), &SimpleUser{}), false},
+		{"yaml unknown field", YAML([]byte("admin: true\n"), &SimpleUser{}), false},
+		{"xml syntax", XML([]byte("<SimpleUser><id>1</SimpleUser>"), &SimpleUser{}), false},
+		{"gob garbage", Gob([]byte("\x03abc"), &gobTarget), false},
+		{"reader failure", JSONReader(failingReader{}, &SimpleUser{}), false},
+		{"codec failure", DecodeFormat(formatCSV, []byte("x"), &SimpleUser{}), false},
+		{"valid syntax", Valid(FormatJSON, []byte(`{"id":`)), false},
+		{"any struct field", JSON([]byte(`{"data":1}`), &withAny{}), true},
+		{"too large", JSON([]byte(`{"id":1}`), &SimpleUser{}, WithMaxSize(2)), true},
+		{"too large reader", JSONReader(strings.NewReader(`{"id":1}`), &SimpleUser{}, WithMaxSize(2)), true},
+		{"dtd", XML([]byte(`<!DOCTYPE x><SimpleUser/>`), &SimpleUser{}), true},
+		{"bad signature", VerifyAndDecode(FormatJSON, make([]byte, 40), &SimpleUser{}, WithHMAC([]byte("k"), nil)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err == nil {
+				t.Fatal("expected error")
+			}
+			if got := checkOneCategory(t, tt.name, tt.err); got != tt.security {
+				t.Errorf("security=%v, want %v: %v", got, tt.security, tt.err)
+			}
+		})
+	}
+
+	// Categorizing keeps the underlying error and its message
+	err := JSON([]byte(`{"id":`), &SimpleUser{})
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("underlying parse error lost: %#v", err)
+	}
+	if strings.Contains(err.Error(), "malformed input") {
+		t.Errorf("category leaked into the message: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package safedeserialize
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// minMaxSize is the smallest MaxSize that can hold a JSON object or array
+const minMaxSize = 2
+
+// reject records that an option value was ignored
+func (o *Options) reject(format string, args ...any) {
+	o.rejected = append(o.rejected, fmt.Sprintf(format, args...))
+}
+
+// checkConfig reports ignored option values and combinations of options
+// that conflict or have no effect
+func (o *Options) checkConfig() error {
+	var errs []error
+	for _, msg := range o.rejected {
+		errs = append(errs, errors.New(msg))
+	}
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if o.MaxSize < minMaxSize {
+		add("MaxSize %d cannot hold any document", o.MaxSize)
+	}
+	if o.Registry != nil && len(o.AllowedTypes) > 0 {
+		add("AllowedTypes matches by name and widens Registry; register the types instead")
+	}
+	if o.RequireRegisteredGraph && o.Registry == nil && len(o.AllowedTypes) == 0 && len(o.AllowedPackages) == 0 {
+		add("RequireRegisteredGraph without a Registry, AllowedTypes or AllowedPackages rejects every struct")
+	}
+	for _, name := range o.AllowedFields {
+		if containsFold(o.DeniedFields, name) {
+			add("field %q is both allowed and denied", name)
+		}
+	}
+	if !o.StrictMode && len(o.AllowedUnmarshalers) > 0 {
+		add("AllowedUnmarshalers has no effect without StrictMode")
+	}
+	if o.Sanitizer == nil && o.SanitizeMode != SanitizeReplace {
+		add("SanitizeMode has no effect without a Sanitizer")
+	}
+	if len(o.HMACKey) == 0 && o.HMACHash != nil {
+		add("HMAC hash set without a key")
+	}
+	if o.AtomicDecode && o.ZeroTarget {
+		add("ZeroTarget is implied by AtomicDecode")
+	}
+	for _, format := range o.AllowedFormats {
+		if _, _, err := lookupCodec(format); err != nil {
+			add("AllowedFormats: %v", err)
+		}
+	}
+	if o.maxDepthSet && slices.Contains(o.AllowedFormats, FormatGob) {
+		add("MaxDepth cannot be enforced for gob, which AllowedFormats includes")
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidOptions, errors.Join(errs...))
+}
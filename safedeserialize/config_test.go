@@ -0,0 +1,89 @@
+package safedeserialize
+
+import (
+	"crypto/sha512"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ravisastryk/go-safeinput"
+)
+
+func TestNewDecoderStrict(t *testing.T) {
+	registry := NewTypeRegistry().Register(SimpleUser{})
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr string
+	}{
+		{"zero max size", []Option{WithMaxSize(0)}, "WithMaxSize(0)"},
+		{"negative max depth", []Option{WithMaxDepth(-1)}, "WithMaxDepth(-1)"},
+		{"zero max elements", []Option{WithMaxElements(0)}, "WithMaxElements(0)"},
+		{"zero max attributes", []Option{WithMaxAttributes(0)}, "WithMaxAttributes(0)"},
+		{"negative max string length", []Option{WithMaxStringLength(-1)}, "WithMaxStringLength(-1)"},
+		{"zero max map keys", []Option{WithMaxMapKeys(0)}, "WithMaxMapKeys(0)"},
+		{"tiny max size", []Option{WithMaxSize(1)}, "cannot hold any document"},
+		{"types and registry", []Option{WithAllowedTypes("safedeserialize.SimpleUser"), registry.Option()}, "widens Registry"},
+		{"graph without whitelist", []Option{WithRequireRegisteredGraph(true)}, "rejects every struct"},
+		{"allowed and denied", []Option{WithAllowedFields("id", "role"), WithDeniedFields("Role")}, `"role" is both allowed and denied`},
+		{"unmarshalers without strict", []Option{WithStrictMode(false), WithAllowedUnmarshalers(SimpleUser{})}, "without StrictMode"},
+		{"sanitize mode alone", []Option{WithSanitizeMode(SanitizeReject)}, "without a Sanitizer"},
+		{"hmac hash alone", []Option{WithHMAC(nil, sha512.New)}, "without a key"},
+		{"atomic and zero", []Option{WithAtomicDecode(true), WithZeroTarget(true)}, "implied by AtomicDecode"},
+		{"unknown format", []Option{WithAllowedFormats(FormatJSON, "toml")}, `"toml"`},
+		{"depth with gob", []Option{WithMaxDepth(8), WithAllowedFormats(FormatJSON, FormatGob)}, "cannot be enforced for gob"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder, err := NewDecoderStrict(tt.opts...)
+			if !errors.Is(err, ErrInvalidOptions) {
+				t.Fatalf("expected ErrInvalidOptions, got %v", err)
+			}
+			if decoder != nil {
+				t.Error("expected nil decoder")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected %q in %v", tt.wantErr, err)
+			}
+
+			// NewDecoder keeps accepting the same options
+			if NewDecoder(tt.opts...) == nil {
+				t.Error("NewDecoder returned nil")
+			}
+		})
+	}
+}
+
+func TestNewDecoderStrictValid(t *testing.T) {
+	decoder, err := NewDecoderStrict(
+		WithMaxSize(1<<10),
+		WithMaxDepth(8),
+		NewTypeRegistry().Register(SimpleUser{}).Option(),
+		WithRequireRegisteredGraph(true),
+		WithStringSanitizer(safeinput.Default(), safeinput.HTMLBody),
+		WithSanitizeMode(SanitizeReject),
+		WithAllowedFormats(FormatJSON, formatCSV),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var user SimpleUser
+	if err := decoder.JSON([]byte(`{"id":1}`), &user); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewDecoderStrictReportsAll(t *testing.T) {
+	_, err := NewDecoderStrict(WithMaxSize(-5), WithMaxDepth(0))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{"WithMaxSize(-5)", "WithMaxDepth(0)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
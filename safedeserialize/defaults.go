@@ -0,0 +1,176 @@
+package safedeserialize
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// fieldSet holds the Go paths of struct fields present in a document, such
+// as ".Server.Port" or ".Servers[1].Port"
+type fieldSet map[string]bool
+
+// needsDefaultTags reports whether DefaultTags is set and target v can hold
+// a struct with default:"..." tags
+func needsDefaultTags(v any, opts *Options) bool {
+	return v != nil && opts.DefaultTags &&
+		typeReaches(reflect.TypeOf(v), hasDefaultTags, make(map[reflect.Type]bool))
+}
+
+// hasDefaultTags reports whether t is a struct with a default:"..." tag on
+// one of its own fields
+func hasDefaultTags(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("default"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldAt returns the type of the field of struct t at index, as returned
+// by jsonFieldIndex or yamlFieldIndex, and its Go path below parent
+func fieldAt(t reflect.Type, parent string, index []int) (reflect.Type, string) {
+	for _, i := range index {
+		field := derefType(t).Field(i)
+		t, parent = field.Type, parent+"."+field.Name
+	}
+	return t, parent
+}
+
+// applyDefaultTags sets every field of the decoded target v tagged
+// default:"..." that is zero and not in present to the tag's value. Tags
+// are converted for every field, present or not, so a bad tag fails every
+// decode. Fields of nil pointers and of map values are left alone.
+func applyDefaultTags(v any, present fieldSet) error {
+	return applyDefaults(reflect.ValueOf(v).Elem(), "", present)
+}
+
+func applyDefaults(v reflect.Value, path string, present fieldSet) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return applyDefaults(v.Elem(), path, present)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := applyDefaults(v.Index(i), fmt.Sprintf("%s[%d]", path, i), present); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() && !field.Anonymous {
+				continue
+			}
+			value, fieldPath := v.Field(i), path+"."+field.Name
+			if tag, ok := field.Tag.Lookup("default"); ok && field.IsExported() {
+				def, err := parseDefault(tag, field.Type)
+				if err != nil {
+					return fmt.Errorf("%w: field %s default %q: %v",
+						ErrInvalidDefault, strings.TrimPrefix(fieldPath, "."), tag, err)
+				}
+				if value.IsZero() && !present[fieldPath] {
+					value.Set(def)
+				}
+			}
+			if err := applyDefaults(value, fieldPath, present); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseDefault converts the text of a default tag to a value of type t
+func parseDefault(s string, t reflect.Type) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	if t == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return v, err
+		}
+		v.SetInt(int64(d))
+		return v, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return v, err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 0, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetFloat(f)
+	default:
+		return v, fmt.Errorf("unsupported field type %s", t)
+	}
+	return v, nil
+}
+
+// yamlPresence records in present the Go paths of the struct fields that
+// node n sets when decoded into type t. It expands aliases and merge keys,
+// so it only runs after yaml.v3 has accepted the document.
+func yamlPresence(n *yaml.Node, t reflect.Type, path string, present fieldSet) {
+	t = derefType(t)
+	if t.Implements(yamlUnmarshalerType) || reflect.PointerTo(t).Implements(yamlUnmarshalerType) {
+		return
+	}
+
+	n = resolveYAMLAlias(n)
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, child := range n.Content {
+			yamlPresence(child, t, path, present)
+		}
+	case yaml.MappingNode:
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		_ = yamlMapEntries(n, make(map[*yaml.Node]bool), func(key, value *yaml.Node) error {
+			if index, ok := yamlFieldIndex(t, key.Value); ok {
+				ft, fieldPath := fieldAt(t, path, index)
+				present[fieldPath] = true
+				yamlPresence(value, ft, fieldPath, present)
+			}
+			return nil
+		})
+	case yaml.SequenceNode:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return
+		}
+		for i, child := range n.Content {
+			yamlPresence(child, t.Elem(), fmt.Sprintf("%s[%d]", path, i), present)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package safedeserialize
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type defaultsListener struct {
+	Port    int           `json:"port" yaml:"port" default:"8080"`
+	Timeout time.Duration `json:"timeout" yaml:"timeout" default:"30s"`
+}
+
+type defaultsBase struct {
+	Region string `json:"region" yaml:"region" default:"us-east-1"`
+}
+
+type defaultsConfig struct {
+	defaultsBase `yaml:",inline"`
+	Name         string             `json:"name" yaml:"name" default:"service"`
+	Debug        bool               `json:"debug" yaml:"debug" default:"true"`
+	Retries      uint8              `json:"retries" yaml:"retries" default:"3"`
+	Ratio        float64            `json:"ratio" yaml:"ratio" default:"0.5"`
+	Listener     defaultsListener   `json:"listener" yaml:"listener"`
+	Extra        []defaultsListener `json:"extra" yaml:"extra"`
+	Optional     *defaultsListener  `json:"optional" yaml:"optional"`
+}
+
+func TestDefaultTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		yaml  string
+		check func(t *testing.T, got defaultsConfig)
+	}{
+		{
+			name: "empty document",
+			json: `{}`,
+			yaml: "{}\n",
+			check: func(t *testing.T, got defaultsConfig) {
+				if got.Name != "service" || !got.Debug || got.Retries != 3 || got.Ratio != 0.5 || got.Region != "us-east-1" {
+					t.Errorf("top-level defaults not applied: %+v", got)
+				}
+				if got.Listener.Port != 8080 || got.Listener.Timeout != 30*time.Second {
+					t.Errorf("nested defaults not applied: %+v", got.Listener)
+				}
+				if got.Optional != nil {
+					t.Errorf("nil pointer allocated: %+v", got.Optional)
+				}
+			},
+		},
+		{
+			name: "explicit zero values kept",
+			json: `{"name":"","debug":false,"retries":0,"region":"","listener":{"port":0}}`,
+			yaml: "name: ''\ndebug: false\nretries: 0\nregion: ''\nlistener: {port: 0}\n",
+			check: func(t *testing.T, got defaultsConfig) {
+				if got.Name != "" || got.Debug || got.Retries != 0 || got.Region != "" || got.Listener.Port != 0 {
+					t.Errorf("present fields overwritten: %+v", got)
+				}
+				if got.Listener.Timeout != 30*time.Second {
+					t.Errorf("absent sibling not defaulted: %+v", got.Listener)
+				}
+			},
+		},
+		{
+			name: "set values kept",
+			json: `{"name":"api","listener":{"port":9000,"timeout":5000000000}}`,
+			yaml: "name: api\nlistener: {port: 9000, timeout: 5s}\n",
+			check: func(t *testing.T, got defaultsConfig) {
+				if got.Name != "api" || got.Listener.Port != 9000 || got.Listener.Timeout != 5*time.Second {
+					t.Errorf("set fields overwritten: %+v", got)
+				}
+			},
+		},
+		{
+			name: "slice elements and pointers",
+			json: `{"extra":[{"port":1},{}],"optional":{"port":0}}`,
+			yaml: "extra: [{port: 1}, {}]\noptional: {port: 0}\n",
+			check: func(t *testing.T, got defaultsConfig) {
+				if len(got.Extra) != 2 || got.Extra[0].Port != 1 || got.Extra[1].Port != 8080 || got.Extra[0].Timeout != 30*time.Second {
+					t.Errorf("slice element defaults wrong: %+v", got.Extra)
+				}
+				if got.Optional == nil || got.Optional.Port != 0 || got.Optional.Timeout != 30*time.Second {
+					t.Errorf("pointer defaults wrong: %+v", got.Optional)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, format := range []Format{FormatJSON, FormatYAML} {
+			t.Run(tt.name+"/"+string(format), func(t *testing.T) {
+				data := tt.json
+				if format == FormatYAML {
+					data = tt.yaml
+				}
+				var got defaultsConfig
+				if err := DecodeFormat(format, []byte(data), &got, WithDefaultTags(true)); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				tt.check(t, got)
+			})
+		}
+	}
+}
+
+func TestDefaultTagsPresence(t *testing.T) {
+	// encoding/json matches keys case-insensitively
+	var got defaultsConfig
+	if err := JSON([]byte(`{"NAME":""}`), &got, WithDefaultTags(true), WithStrictMode(false)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Name != "" {
+		t.Errorf("expected present field kept empty, got %q", got.Name)
+	}
+
+	// An exact-case field and a shallower field win as in encoding/json
+	type casingBase struct {
+		Label string `default:"base"`
+	}
+	var casing struct {
+		casingBase
+		ID    int    `default:"1"`
+		Id    int    `default:"2"` //nolint:revive // both casings on purpose
+		Label string `default:"outer"`
+	}
+	if err := JSON([]byte(`{"Id":0,"label":""}`), &casing, WithDefaultTags(true), WithStrictMode(false)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if casing.ID != 1 || casing.Id != 0 || casing.Label != "" || casing.casingBase.Label != "base" {
+		t.Errorf("defaults applied to the wrong fields: %+v", casing)
+	}
+
+	// Keys pulled in by YAML merge keys and aliases are present
+	data := []byte("base: &b {port: 0}\nlistener: {<<: *b}\nextra: [*b]\n")
+	var merged struct {
+		Base     map[string]int     `yaml:"base"`
+		Listener defaultsListener   `yaml:"listener"`
+		Extra    []defaultsListener `yaml:"extra"`
+	}
+	if err := YAML(data, &merged, WithDefaultTags(true)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if merged.Listener.Port != 0 || merged.Extra[0].Port != 0 {
+		t.Errorf("merged fields overwritten: %+v", merged)
+	}
+	if merged.Listener.Timeout != 30*time.Second {
+		t.Errorf("absent field not defaulted: %+v", merged.Listener)
+	}
+
+	// Without the option tags are ignored
+	got = defaultsConfig{}
+	if err := JSON([]byte(`{}`), &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Name != "" || got.Listener.Port != 0 {
+		t.Errorf("defaults applied without WithDefaultTags: %+v", got)
+	}
+}
+
+func TestDefaultTagsInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		target any
+		detail string
+	}{
+		{"int", &struct {
+			Port int `json:"port" default:"http"`
+		}{}, `field Port default "http"`},
+		{"overflow", &struct {
+			Small int8 `json:"small" default:"300"`
+		}{}, "out of range"},
+		{"bool", &struct {
+			On bool `json:"on" default:"yes please"`
+		}{}, `field On default "yes please"`},
+		{"duration", &struct {
+			Wait time.Duration `json:"wait" default:"30"`
+		}{}, "missing unit"},
+		{"unsupported type", &struct {
+			Tags []string `json:"tags" default:"a,b"`
+		}{}, "unsupported field type []string"},
+		{"nested", &struct {
+			Listener struct {
+				Port int `json:"port" default:"x"`
+			} `json:"listener"`
+		}{}, "field Listener.Port"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A bad tag fails even when the document sets the field
+			for _, data := range []string{`{}`, `{"port":1,"small":1,"on":true,"wait":1,"tags":["a"],"listener":{"port":1}}`} {
+				err := JSON([]byte(data), tt.target, WithDefaultTags(true), WithStrictMode(false))
+				if !errors.Is(err, ErrInvalidDefault) {
+					t.Fatalf("%s: expected ErrInvalidDefault, got %v", data, err)
+				}
+				if !strings.Contains(err.Error(), tt.detail) {
+					t.Errorf("expected %q in %q", tt.detail, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultTagsUnsupportedFormat(t *testing.T) {
+	if err := XML([]byte(`<c><name>x</name></c>`), &defaultsConfig{}, WithDefaultTags(true)); !errors.Is(err, ErrUnsupportedOption) {
+		t.Errorf("XML: expected ErrUnsupportedOption, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(defaultsListener{Port: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Gob(buf.Bytes(), &defaultsListener{}, WithDefaultTags(true)); !errors.Is(err, ErrUnsupportedOption) {
+		t.Errorf("Gob: expected ErrUnsupportedOption, got %v", err)
+	}
+
+	// Targets without default tags are unaffected
+	if err := XML([]byte(`<SimpleUser><id>1</id></SimpleUser>`), &SimpleUser{}, WithDefaultTags(true)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	var calls []string
+	defaults := func(v any) {
+		calls = append(calls, "defaults")
+		if c, ok := v.(*defaultsConfig); ok && c.Name == "" {
+			c.Name = "filled"
+		}
+	}
+	validator := func(v any) error {
+		calls = append(calls, "validator")
+		if v.(*defaultsConfig).Name == "" {
+			return errors.New("name is required")
+		}
+		return nil
+	}
+
+	var got defaultsConfig
+	err := JSON([]byte(`{"debug":true}`), &got, WithDefaults(defaults), WithValidator(validator))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Name != "filled" {
+		t.Errorf("expected defaulted name, got %q", got.Name)
+	}
+	if strings.Join(calls, ",") != "defaults,validator" {
+		t.Errorf("unexpected call order %v", calls)
+	}
+
+	// The hook runs after tag defaults and only after a successful decode
+	calls = nil
+	got = defaultsConfig{}
+	if err := JSON([]byte(`{}`), &got, WithDefaultTags(true), WithDefaults(func(v any) {
+		if v.(*defaultsConfig).Name != "service" {
+			t.Errorf("hook ran before tag defaults")
+		}
+		calls = append(calls, "defaults")
+	})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	calls = nil
+	if err := JSON([]byte(`{"debug":`), &got, WithDefaults(defaults)); err == nil {
+		t.Fatal("expected error for malformed input")
+	}
+	if len(calls) != 0 {
+		t.Errorf("hook ran after a failed decode: %v", calls)
+	}
+}
+
+func TestJSONFieldIndex(t *testing.T) {
+	type inner struct {
+		Name  string
+		Value string `json:"value"`
+		Deep  string
+	}
+	type other struct {
+		Deep string
+	}
+	type tagged struct {
+		Both string `json:"both"`
+	}
+	type untagged struct {
+		Both string
+	}
+	type target struct {
+		inner
+		*other
+		tagged
+		untagged
+		ID    string
+		Id    string //nolint:revive // both casings on purpose
+		Name  string
+		Skip  string `json:"-"`
+		Dash  string `json:"-,"`
+		Named string `json:"named"`
+	}
+
+	// Each key must resolve to the field encoding/json sets
+	for _, key := range []string{"ID", "Id", "id", "iD", "name", "Name", "value", "deep", "both", "-", "Skip", "named", "missing"} {
+		var decoded target
+		decoded.other = &other{}
+		if err := json.Unmarshal([]byte(`{"`+key+`":"x"}`), &decoded); err != nil {
+			t.Fatalf("Unmarshal(%q): %v", key, err)
+		}
+		var want []int
+		v := reflect.ValueOf(decoded)
+		for _, index := range [][]int{{0, 0}, {0, 1}, {0, 2}, {1, 0}, {2, 0}, {3, 0}, {4}, {5}, {6}, {7}, {8}, {9}} {
+			if f, err := v.FieldByIndexErr(index); err == nil && f.Kind() == reflect.String && f.String() == "x" {
+				want = index
+			}
+		}
+		got, ok := jsonFieldIndex(reflect.TypeOf(decoded), key)
+		if ok != (want != nil) || !slices.Equal(got, want) {
+			t.Errorf("jsonFieldIndex(%q) = %v, %v, want %v", key, got, ok, want)
+		}
+	}
+}
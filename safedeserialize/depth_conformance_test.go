@@ -0,0 +1,146 @@
+package safedeserialize
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// depthNode decodes nested documents from every text format: each JSON
+// object, YAML mapping or XML element is one level
+type depthNode struct {
+	C *depthNode `json:"c" yaml:"c" xml:"c"`
+}
+
+// nestedDoc returns a document in format nested exactly depth levels deep
+func nestedDoc(format Format, depth int) []byte {
+	switch format {
+	case FormatJSON:
+		return []byte(strings.Repeat(`{"c":`, depth-1) + `{}` + strings.Repeat(`}`, depth-1))
+	case FormatYAML:
+		if depth == 1 {
+			return []byte("{}\n")
+		}
+		var b strings.Builder
+		for i := 0; i < depth-1; i++ {
+			b.WriteString(strings.Repeat("  ", i) + "c:")
+			if i == depth-2 {
+				b.WriteString(" {}")
+			}
+			b.WriteString("\n")
+		}
+		return []byte(b.String())
+	case FormatXML:
+		return []byte(strings.Repeat("<c>", depth) + strings.Repeat("</c>", depth))
+	}
+	panic("unsupported format " + format)
+}
+
+// depthPaths are the entry points that must apply MaxDepth identically
+var depthPaths = map[string]func(format Format, data []byte, opts ...Option) error{
+	"bytes": func(format Format, data []byte, opts ...Option) error {
+		return DecodeFormat(format, data, &depthNode{}, opts...)
+	},
+	"reader": func(format Format, data []byte, opts ...Option) error {
+		r := bytes.NewReader(data)
+		switch format {
+		case FormatJSON:
+			return JSONReader(r, &depthNode{}, opts...)
+		case FormatYAML:
+			return YAMLReader(r, &depthNode{}, opts...)
+		default:
+			return XMLReader(r, &depthNode{}, opts...)
+		}
+	},
+	"valid": func(format Format, data []byte, opts ...Option) error {
+		return Valid(format, data, opts...)
+	},
+}
+
+func TestDepthConformance(t *testing.T) {
+	limits := []struct {
+		name  string
+		limit int
+		opts  []Option
+	}{
+		{"default", DefaultMaxDepth, nil},
+		{"configured", 8, []Option{WithMaxDepth(8)}},
+	}
+
+	for _, lim := range limits {
+		for _, strict := range []bool{true, false} {
+			for _, format := range []Format{FormatJSON, FormatYAML, FormatXML} {
+				for path, decode := range depthPaths {
+					for _, depth := range []int{lim.limit - 1, lim.limit, lim.limit + 1} {
+						name := fmt.Sprintf("%s/strict=%v/%s/%s/depth=%d", lim.name, strict, format, path, depth)
+						t.Run(name, func(t *testing.T) {
+							opts := append([]Option{WithStrictMode(strict)}, lim.opts...)
+							err := decode(format, nestedDoc(format, depth), opts...)
+							if depth <= lim.limit {
+								if err != nil {
+									t.Fatalf("unexpected error: %v", err)
+								}
+								return
+							}
+							if !errors.Is(err, ErrMaxDepthExceeded) {
+								t.Fatalf("expected ErrMaxDepthExceeded, got %v", err)
+							}
+							if want := fmt.Sprintf("depth %d exceeds limit %d", depth, lim.limit); !strings.Contains(err.Error(), want) {
+								t.Errorf("expected %q in %q", want, err)
+							}
+						})
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestDepthYAMLAliases(t *testing.T) {
+	// Each alias expands to the anchored sequence, nesting deeper than
+	// either the anchor or the alias appears in the text
+	data := []byte("a: &x [[[1]]]\nb: {c: {d: {e: *x}}}\n")
+	var target struct {
+		A [][][]int                                  `yaml:"a"`
+		B map[string]map[string]map[string][][][]int `yaml:"b"`
+	}
+	err := YAML(data, &target, WithMaxDepth(6))
+	if !errors.Is(err, ErrMaxDepthExceeded) {
+		t.Fatalf("expected ErrMaxDepthExceeded, got %v", err)
+	}
+	if err := YAML(data, &target, WithMaxDepth(7)); err != nil {
+		t.Errorf("unexpected error at limit: %v", err)
+	}
+}
+
+func TestDepthUnsupportedFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(SimpleUser{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	checks := map[string]func(opts ...Option) error{
+		"Gob":          func(opts ...Option) error { return Gob(data, &SimpleUser{}, opts...) },
+		"GobReader":    func(opts ...Option) error { return GobReader(bytes.NewReader(data), &SimpleUser{}, opts...) },
+		"DecodeFormat": func(opts ...Option) error { return DecodeFormat(FormatGob, data, &SimpleUser{}, opts...) },
+		"Valid":        func(opts ...Option) error { return Valid(FormatGob, data, opts...) },
+	}
+	for name, check := range checks {
+		t.Run(name, func(t *testing.T) {
+			if err := check(); err != nil {
+				t.Fatalf("unexpected error with the default depth: %v", err)
+			}
+			err := check(WithMaxDepth(8))
+			if !errors.Is(err, ErrUnsupportedOption) {
+				t.Fatalf("expected ErrUnsupportedOption, got %v", err)
+			}
+			if errors.Is(err, ErrMaxDepthExceeded) {
+				t.Errorf("unsupported option reported as a depth failure: %v", err)
+			}
+		})
+	}
+}
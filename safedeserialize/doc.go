@@ -63,6 +63,7 @@
 //	    var req Request
 //	    err := safedeserialize.JSONReader(r.Body, &req,
 //	        safedeserialize.WithMaxSize(1<<16),
+//	        safedeserialize.WithExpectedSize(r.ContentLength),
 //	    )
 //	    if err != nil {
 //	        http.Error(w, "Invalid request", http.StatusBadRequest)
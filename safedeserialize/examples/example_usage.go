@@ -6,16 +6,23 @@
 // - Type registries for whitelisting
 // - HTTP handler integration
 // - Configuration file loading
+// - Loading a deserialization policy
+// - Decoding a stream of gob messages
 //
 // Run: go run example_usage.go
 package main
 
 import (
+	"bytes"
+	_ "embed"
+	"encoding/gob"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 
+	"github.com/ravisastryk/go-safeinput"
 	"github.com/ravisastryk/go-safeinput/safedeserialize"
 )
 
@@ -191,7 +198,8 @@ func example6HTTPHandler() {
 		var req APIRequest
 
 		err := safedeserialize.JSONReader(r.Body, &req,
-			safedeserialize.WithMaxSize(1<<16), // 64KB max request
+			safedeserialize.WithMaxSize(1<<16),                // 64KB max request
+			safedeserialize.WithExpectedSize(r.ContentLength), // reject oversized bodies unread
 		)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
@@ -199,9 +207,9 @@ func example6HTTPHandler() {
 		}
 
 		// Process request...
-		// In production, use a structured logging library (zap/zerolog) to safely log user data
-		// For this example, we acknowledge the request without logging user-controlled data
-		log.Println("Request processed successfully")
+		// Decoded strings are still user-controlled, so escape them before
+		// they reach a log line
+		log.Printf("Request processed: action=%s", safeinput.SanitizeForLog(req.Action))
 		w.WriteHeader(http.StatusOK)
 	}
 
@@ -320,6 +328,74 @@ features:
 	fmt.Printf("  Features: %v\n\n", config.Features)
 }
 
+//go:embed policy.yaml
+var policyFile []byte
+
+// Example 9: Loading limits from a reviewed policy file
+func example9Policy() {
+	fmt.Println("=== Example 9: Policy File ===")
+
+	policy, err := safedeserialize.LoadPolicy(policyFile)
+	if err != nil {
+		log.Printf("Failed to load policy: %v", err)
+		return
+	}
+	decoder := policy.Decoder()
+
+	var user User
+	if err := decoder.JSON([]byte(`{"id": 1, "username": "alice"}`), &user); err != nil {
+		log.Printf("Unexpected error: %v", err)
+		return
+	}
+	fmt.Printf("Decoded user: %s\n", user.Username)
+
+	err = decoder.JSON([]byte(`{"id": 2, "is_admin": true}`), &user)
+	fmt.Printf("Denied field: %v\n", err)
+
+	err = decoder.XML([]byte(`<User><id>3</id></User>`), &user)
+	fmt.Printf("Disabled format: %v\n\n", err)
+}
+
+// Example 10: Decoding a stream of gob messages
+func example10GobStream() {
+	fmt.Println("=== Example 10: Gob Stream ===")
+
+	// A connection carrying one gob message per user
+	var conn bytes.Buffer
+	enc := gob.NewEncoder(&conn)
+	for _, u := range []User{{ID: 1, Username: "alice"}, {ID: 2, Username: "bob"}} {
+		if err := enc.Encode(u); err != nil {
+			log.Printf("Failed to encode: %v", err)
+			return
+		}
+	}
+
+	// Instead of a raw loop over gob.NewDecoder(conn), which has no limits:
+	//
+	//	dec := gob.NewDecoder(conn)
+	//	for {
+	//		var u User
+	//		if err := dec.Decode(&u); err != nil { ... }
+	//	}
+	stream := safedeserialize.NewGobStream(&conn,
+		safedeserialize.WithMaxSize(4<<10), // per message, not per connection
+		safedeserialize.WithMaxRecords(1000),
+	)
+	for {
+		var u User
+		err := stream.Decode(&u)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("Dropping connection: %v", err)
+			return
+		}
+		fmt.Printf("Received user: %s\n", u.Username)
+	}
+	fmt.Printf("Messages: %d\n\n", stream.Records())
+}
+
 func main() {
 	fmt.Println("safedeserialize Examples")
 	fmt.Println("========================")
@@ -333,6 +409,8 @@ func main() {
 	example6HTTPHandler()
 	example7ErrorHandling()
 	example8ConfigFile()
+	example9Policy()
+	example10GobStream()
 
 	fmt.Println("All examples completed.")
 }
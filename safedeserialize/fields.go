@@ -0,0 +1,73 @@
+package safedeserialize
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// checkDocumentKey applies the field policies in opts to a key found in the
+// input document at path; topLevel is set for keys of the root object
+func checkDocumentKey(key, path string, topLevel bool, opts *Options) error {
+	if topLevel && len(opts.AllowedFields) > 0 && !containsFold(opts.AllowedFields, key) {
+		return fmt.Errorf("%w: %q", ErrFieldNotAllowed, key)
+	}
+	if containsFold(opts.DeniedFields, key) {
+		return fmt.Errorf("%w: %q at %s", ErrDeniedField, key, path)
+	}
+	return nil
+}
+
+// needsKeyScan reports whether any option requires walking document keys
+func needsKeyScan(opts *Options) bool {
+	return len(opts.DeniedFields) > 0 || len(opts.AllowedFields) > 0
+}
+
+// needsMapScan reports whether any option limits the maps a document
+// decodes into, and target v can hold a map
+func needsMapScan(v any, opts *Options) bool {
+	return v != nil && (opts.MaxMapKeys > 0 || opts.MaxStringLength > 0) &&
+		typeReaches(reflect.TypeOf(v), isMapType, make(map[reflect.Type]bool))
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// displayPath formats a document path for error messages
+func displayPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
+
+// joinKeyPath appends an object key to a document path
+func joinKeyPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// joinIndexPath appends an array index to a document path
+func joinIndexPath(parent string, index int) string {
+	return fmt.Sprintf("%s[%d]", parent, index)
+}
+
+// hasTagFlag reports whether a comma-separated struct tag flag list
+// contains flag
+func hasTagFlag(flags, flag string) bool {
+	for _, f := range strings.Split(flags, ",") {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
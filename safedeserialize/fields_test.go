@@ -0,0 +1,134 @@
+package safedeserialize
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type Account struct {
+	Name    string   `json:"name" yaml:"name"`
+	IsAdmin bool     `json:"is_admin" yaml:"is_admin"`
+	Profile Profile  `json:"profile" yaml:"profile"`
+	Tags    []string `json:"tags" yaml:"tags"`
+}
+
+type Profile struct {
+	Bio      string    `json:"bio" yaml:"bio"`
+	Contacts []Contact `json:"contacts" yaml:"contacts"`
+}
+
+type Contact struct {
+	Email string `json:"email" yaml:"email"`
+	Role  string `json:"role" yaml:"role"`
+}
+
+func TestDeniedFields(t *testing.T) {
+	denied := WithDeniedFields("is_admin", "role", "password_hash")
+
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		target any
+		opts   []Option
+		path   string
+	}{
+		{name: "json clean", format: "json", data: `{"name": "a", "profile": {"bio": "role model"}}`},
+		{name: "json top level", format: "json", data: `{"name": "a", "is_admin": true}`, path: `"is_admin" at is_admin`},
+		{name: "json case-insensitive", format: "json", data: `{"name": "a", "IS_ADMIN": true}`, path: `"IS_ADMIN" at IS_ADMIN`},
+		{name: "json nested in array", format: "json", data: `{"profile": {"contacts": [{"email": "a"}, {"role": "root"}]}}`, path: `"role" at profile.contacts[1].role`},
+		{name: "json unknown to struct", format: "json", data: `{"name": "a", "password_hash": "x"}`, opts: []Option{WithStrictMode(false)}, path: `"password_hash" at password_hash`},
+		{name: "json string value", format: "json", data: `{"name": "is_admin", "tags": ["role"]}`},
+		{name: "json top-level array", format: "json", data: `[{"role": "x"}]`, target: &[]Contact{}, path: `"role" at [0].role`},
+		{name: "yaml clean", format: "yaml", data: "name: a\nprofile:\n  bio: role model"},
+		{name: "yaml top level", format: "yaml", data: "name: a\nis_admin: true", path: `"is_admin" at is_admin`},
+		{name: "yaml nested in sequence", format: "yaml", data: "profile:\n  contacts:\n    - email: a\n    - Role: root", path: `"Role" at profile.contacts[1].Role`},
+		{name: "yaml in anchor", format: "yaml", data: "base: &b\n  role: root\nprofile:\n  contacts:\n    - *b", opts: []Option{WithStrictMode(false)}, path: `"role" at base.role`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.target
+			if target == nil {
+				target = &Account{}
+			}
+			opts := append([]Option{denied}, tt.opts...)
+
+			var err error
+			if tt.format == "json" {
+				err = JSON([]byte(tt.data), target, opts...)
+			} else {
+				err = YAML([]byte(tt.data), target, opts...)
+			}
+
+			if tt.path == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrDeniedField) {
+				t.Fatalf("expected ErrDeniedField, got %v", err)
+			}
+			if !strings.HasSuffix(err.Error(), tt.path) {
+				t.Errorf("expected %s in %q", tt.path, err)
+			}
+		})
+	}
+}
+
+func TestAllowedFields(t *testing.T) {
+	allowed := WithAllowedFields("name", "tags")
+
+	tests := []struct {
+		name    string
+		format  string
+		data    string
+		target  any
+		opts    []Option
+		wantErr error
+		key     string
+	}{
+		{name: "json allowed", format: "json", data: `{"name": "a", "TAGS": ["x"]}`},
+		{name: "json unexpected", format: "json", data: `{"name": "a", "is_admin": true}`, wantErr: ErrFieldNotAllowed, key: `"is_admin"`},
+		{name: "json nested keys ignored", format: "json", data: `{"name": "a", "tags": []}`},
+		{name: "json checked before strict", format: "json", data: `{"name": "a", "bogus": 1}`, wantErr: ErrFieldNotAllowed, key: `"bogus"`},
+		{name: "json non-strict", format: "json", data: `{"profile": {"bio": "x"}}`, opts: []Option{WithStrictMode(false)}, wantErr: ErrFieldNotAllowed, key: `"profile"`},
+		{name: "json top-level array", format: "json", data: `[{"email": "a", "role": "b"}]`, target: &[]Contact{}},
+		{name: "json denied still applies", format: "json", data: `{"name": "a"}`, opts: []Option{WithAllowedFields("is_admin"), WithDeniedFields("name")}, wantErr: ErrDeniedField, key: `"name" at name`},
+		{name: "yaml allowed", format: "yaml", data: "name: a\ntags: [x]"},
+		{name: "yaml unexpected", format: "yaml", data: "name: a\nprofile:\n  bio: x", wantErr: ErrFieldNotAllowed, key: `"profile"`},
+		{name: "yaml top-level sequence", format: "yaml", data: "- email: a\n  role: b", target: &[]Contact{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.target
+			if target == nil {
+				target = &Account{}
+			}
+			opts := append([]Option{allowed}, tt.opts...)
+
+			var err error
+			if tt.format == "json" {
+				err = JSON([]byte(tt.data), target, opts...)
+			} else {
+				err = YAML([]byte(tt.data), target, opts...)
+			}
+
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if !strings.HasSuffix(err.Error(), tt.key) {
+				t.Errorf("expected %s in %q", tt.key, err)
+			}
+		})
+	}
+}
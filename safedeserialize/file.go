@@ -0,0 +1,119 @@
+package safedeserialize
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ravisastryk/go-safeinput/path"
+)
+
+// JSONFile safely decodes the JSON file at name. See DecodeFile.
+func JSONFile(name string, v any, opts ...Option) error {
+	return DecodeFile(FormatJSON, name, v, opts...)
+}
+
+// YAMLFile safely decodes the YAML file at name. See DecodeFile.
+func YAMLFile(name string, v any, opts ...Option) error {
+	return DecodeFile(FormatYAML, name, v, opts...)
+}
+
+// DecodeFile safely decodes the file at name in a built-in or registered
+// format. With WithPathSanitizer, name is validated (and resolved against
+// the sanitizer's base path) before the file is opened.
+//
+// MaxSize is enforced twice: against the size reported by stat, and while
+// reading, in case the file grows or is not a regular file. Failures to
+// validate, open or read the file wrap ErrFileAccess; problems with the
+// contents are reported exactly as by the in-memory decoders.
+func DecodeFile(format Format, name string, v any, opts ...Option) error {
+	options := DefaultOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+	return decodeFile(format, name, v, options)
+}
+
+func decodeFile(format Format, name string, v any, opts *Options) error {
+	name, err := resolveFilePath(name, opts)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrFileAccess, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrFileAccess, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%w: %s is a directory", ErrFileAccess, name)
+	}
+	if info.Mode().IsRegular() && info.Size() > opts.MaxSize {
+		return fmt.Errorf("%w: file %s is %d bytes, limit %d", ErrDataTooLarge, name, info.Size(), opts.MaxSize)
+	}
+
+	return decodeFormat(format, fileReader{f}, v, opts)
+}
+
+// resolveFilePath applies the PathSanitizer in opts to name. When the
+// sanitizer has a base path, relative names are joined to it and the result,
+// like any absolute name the sanitizer allows, must lie inside it.
+func resolveFilePath(name string, opts *Options) (string, error) {
+	if opts.PathSanitizer == nil {
+		return name, nil
+	}
+
+	cleaned, err := opts.PathSanitizer.Sanitize(name)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrFileAccess, err)
+	}
+	base := opts.PathSanitizer.BasePath()
+	if base == "" {
+		return cleaned, nil
+	}
+	if !filepath.IsAbs(cleaned) {
+		cleaned = filepath.Join(base, cleaned)
+	}
+	if err := checkWithinBase(base, cleaned); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrFileAccess, err)
+	}
+	return cleaned, nil
+}
+
+// checkWithinBase reports path.ErrOutsideBasePath unless name is base or
+// lies below it
+func checkWithinBase(base, name string) error {
+	absBase, err := filepath.Abs(base)
+	if err != nil {
+		return err
+	}
+	absName, err := filepath.Abs(name)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(absBase, absName)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%w: %s", path.ErrOutsideBasePath, name)
+	}
+	return nil
+}
+
+// fileReader marks read errors as file problems rather than content problems
+type fileReader struct {
+	r io.Reader
+}
+
+func (f fileReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("%w: %w", ErrFileAccess, err)
+	}
+	return n, err
+}
@@ -0,0 +1,114 @@
+package safedeserialize
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ravisastryk/go-safeinput/path"
+)
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestDecodeFile(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := writeTestFile(t, dir, "user.json", `{"id":1,"name":"alice","email":"alice@example.com"}`)
+	yamlFile := writeTestFile(t, dir, "user.yaml", "id: 1\nname: alice\nemail: alice@example.com\n")
+	want := SimpleUser{ID: 1, Name: "alice", Email: "alice@example.com"}
+
+	t.Run("valid", func(t *testing.T) {
+		var fromJSON, fromYAML, fromDecoder SimpleUser
+		if err := JSONFile(jsonFile, &fromJSON); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := YAMLFile(yamlFile, &fromYAML); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := NewDecoder().DecodeFile(FormatJSON, jsonFile, &fromDecoder); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if fromJSON != want || fromYAML != want || fromDecoder != want {
+			t.Errorf("unexpected result: %+v, %+v, %+v", fromJSON, fromYAML, fromDecoder)
+		}
+	})
+
+	t.Run("path sanitizer", func(t *testing.T) {
+		var got SimpleUser
+		if err := JSONFile("user.json", &got, WithPathSanitizer(path.New(dir))); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != want {
+			t.Errorf("unexpected result: %+v", got)
+		}
+	})
+
+	t.Run("absolute inside base", func(t *testing.T) {
+		var got SimpleUser
+		sanitizer := path.New(dir, path.WithAllowAbsolute(true))
+		if err := JSONFile(jsonFile, &got, WithPathSanitizer(sanitizer)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != want {
+			t.Errorf("unexpected result: %+v", got)
+		}
+	})
+
+	writeTestFile(t, dir, "extra.json", `{"id":1,"admin":true}`)
+	outside := writeTestFile(t, t.TempDir(), "outside.json", `{"id":2,"name":"mallory"}`)
+	allowAbsolute := path.New(dir, path.WithAllowAbsolute(true))
+	tests := []struct {
+		name        string
+		file        string
+		opts        []Option
+		wantErr     error
+		fileProblem bool
+	}{
+		{"missing", filepath.Join(dir, "missing.json"), nil, os.ErrNotExist, true},
+		{"directory", dir, nil, ErrFileAccess, true},
+		{"traversal", "../etc/passwd", []Option{WithPathSanitizer(path.New(dir))}, path.ErrPathTraversal, true},
+		{"absolute", jsonFile, []Option{WithPathSanitizer(path.New(dir))}, path.ErrAbsolutePath, true},
+		{"absolute outside base", outside, []Option{WithPathSanitizer(allowAbsolute)}, path.ErrOutsideBasePath, true},
+		{"too large", jsonFile, []Option{WithMaxSize(8)}, ErrDataTooLarge, false},
+		{"bad content", filepath.Join(dir, "extra.json"), nil, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got SimpleUser
+			err := JSONFile(tt.file, &got, tt.opts...)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+			if errors.Is(err, ErrFileAccess) != tt.fileProblem {
+				t.Errorf("file problem = %v, want %v: %v", !tt.fileProblem, tt.fileProblem, err)
+			}
+		})
+	}
+}
+
+func TestDecodeFileSpecial(t *testing.T) {
+	if _, err := os.Stat("/dev/zero"); err != nil {
+		t.Skip("/dev/zero not available")
+	}
+
+	// Special files report size 0 to stat, so the limited read must stop them
+	var got SimpleUser
+	err := JSONFile("/dev/zero", &got, WithMaxSize(1<<10))
+	if !errors.Is(err, ErrDataTooLarge) {
+		t.Errorf("expected ErrDataTooLarge, got %v", err)
+	}
+	if errors.Is(err, ErrFileAccess) {
+		t.Errorf("size limit reported as a file problem: %v", err)
+	}
+}
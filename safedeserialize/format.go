@@ -0,0 +1,196 @@
+package safedeserialize
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+)
+
+// Format names a serialization format supported by the package
+type Format string
+
+// Supported formats
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatXML  Format = "xml"
+	FormatGob  Format = "gob"
+)
+
+// Codec decodes a single serialization format.
+//
+// The package applies the same pipeline around every codec, built-in or
+// registered: format allow-listing, empty and MaxSize checks, target
+// validation before Unmarshal is called, and sanitization and validation
+// hooks after it returns. A registered codec receives a private copy of
+// the options, so changes it makes to opts have no effect.
+//
+// data is only valid until Unmarshal returns: the caller may modify or
+// reuse it afterwards, so a codec must copy any bytes it keeps, including
+// strings and byte slices in the decoded value.
+type Codec interface {
+	Unmarshal(data []byte, v any, opts *Options) error
+}
+
+// streamCodec is implemented by built-in codecs that decode directly from
+// a reader instead of buffering the input up to MaxSize
+type streamCodec interface {
+	decode(r io.Reader, v any, opts *Options) error
+}
+
+// codecFunc adapts an unmarshal function to the Codec interface
+type codecFunc func(data []byte, v any, opts *Options) error
+
+func (f codecFunc) Unmarshal(data []byte, v any, opts *Options) error {
+	return f(data, v, opts)
+}
+
+// gobCodec streams from readers, bounded by MaxSize
+type gobCodec struct{}
+
+func (gobCodec) Unmarshal(data []byte, v any, opts *Options) error {
+	return gobUnmarshal(data, v, opts)
+}
+
+func (gobCodec) decode(r io.Reader, v any, opts *Options) error {
+	return gobDecode(r, v, opts)
+}
+
+// builtinCodecs holds the formats shipped with the package. It is never
+// modified, so it is read without locking.
+var builtinCodecs = map[Format]Codec{
+	FormatJSON: codecFunc(jsonUnmarshal),
+	FormatYAML: codecFunc(yamlUnmarshal),
+	FormatXML:  codecFunc(xmlUnmarshal),
+	FormatGob:  gobCodec{},
+}
+
+// registeredCodecs holds the formats added with RegisterFormat
+var registeredCodecs = struct {
+	sync.RWMutex
+	m map[Format]Codec
+}{m: make(map[Format]Codec)}
+
+// RegisterFormat makes codec available to DecodeFormat, VerifyAndDecode and
+// WithAllowedFormats under the given name. It returns ErrFormatRegistered
+// if the name belongs to a built-in format or was already registered.
+// Formats are typically registered from an init function.
+func RegisterFormat(format Format, codec Codec) error {
+	if format == "" || codec == nil {
+		return fmt.Errorf("%w: RegisterFormat requires a name and a codec", ErrMalformedInput)
+	}
+	if _, ok := builtinCodecs[format]; ok {
+		return fmt.Errorf("%w: %q is a built-in format", ErrFormatRegistered, format)
+	}
+
+	registeredCodecs.Lock()
+	defer registeredCodecs.Unlock()
+	if _, ok := registeredCodecs.m[format]; ok {
+		return fmt.Errorf("%w: %q", ErrFormatRegistered, format)
+	}
+	registeredCodecs.m[format] = codec
+	return nil
+}
+
+// DecodeFormat safely decodes data in a built-in or registered format
+func DecodeFormat(format Format, data []byte, v any, opts ...Option) error {
+	options := DefaultOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+	return unmarshalFormat(format, data, v, options)
+}
+
+// lookupCodec returns the codec for format and whether it is a built-in
+func lookupCodec(format Format) (Codec, bool, error) {
+	if codec, ok := builtinCodecs[format]; ok {
+		return codec, true, nil
+	}
+
+	registeredCodecs.RLock()
+	codec, ok := registeredCodecs.m[format]
+	registeredCodecs.RUnlock()
+	if !ok {
+		return nil, false, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	return codec, false, nil
+}
+
+// unmarshalFormat decodes data in the given format with opts, reporting
+// the outcome to the metrics callback
+func unmarshalFormat(format Format, data []byte, v any, opts *Options) error {
+	return categorize(observe(format, data, v, opts, func() error {
+		return unmarshalBytes(format, data, v, opts)
+	}))
+}
+
+// decodeFormat decodes the contents of r in the given format with opts,
+// reporting the outcome to the metrics callback
+func decodeFormat(format Format, r io.Reader, v any, opts *Options) error {
+	return categorize(observeReader(format, r, v, opts, func(r io.Reader) error {
+		if err := checkFormatAllowed(format, opts); err != nil {
+			return err
+		}
+		if opts.ExpectedSize > opts.MaxSize {
+			return fmt.Errorf("%w: declared size %d exceeds limit %d", ErrDataTooLarge, opts.ExpectedSize, opts.MaxSize)
+		}
+		codec, _, err := lookupCodec(format)
+		if err != nil {
+			return err
+		}
+		// unmarshalBytes validates the target for the other codecs
+		if stream, ok := codec.(streamCodec); ok {
+			if err := validateTarget(v, opts); err != nil {
+				return err
+			}
+			return decodeInto(v, opts, func(target any) error {
+				return stream.decode(r, target, opts)
+			})
+		}
+		return readLimited(r, opts, func(data []byte) error {
+			if retainsInput(format, opts) {
+				data = bytes.Clone(data)
+			}
+			return unmarshalBytes(format, data, v, opts)
+		})
+	}))
+}
+
+// unmarshalBytes runs the decode pipeline shared by every codec
+func unmarshalBytes(format Format, data []byte, v any, opts *Options) error {
+	if err := checkFormatAllowed(format, opts); err != nil {
+		return err
+	}
+	codec, builtin, err := lookupCodec(format)
+	if err != nil {
+		return err
+	}
+
+	if len(data) == 0 {
+		return ErrEmptyData
+	}
+	if int64(len(data)) > opts.MaxSize {
+		return fmt.Errorf("%w: size %d exceeds limit %d", ErrDataTooLarge, len(data), opts.MaxSize)
+	}
+	if err := validateTarget(v, opts); err != nil {
+		return err
+	}
+
+	codecOpts := opts
+	if !builtin {
+		codecOpts = opts.clone()
+	}
+	return decodeInto(v, opts, func(target any) error {
+		return codec.Unmarshal(data, target, codecOpts)
+	})
+}
+
+// checkFormatAllowed rejects formats missing from a non-empty AllowedFormats
+func checkFormatAllowed(format Format, opts *Options) error {
+	if len(opts.AllowedFormats) > 0 && !slices.Contains(opts.AllowedFormats, format) {
+		return fmt.Errorf("%w: %s", ErrFormatNotAllowed, format)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package safedeserialize
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const formatCSV Format = "csv"
+
+// csvCodec decodes "id,name,email" into a *SimpleUser
+type csvCodec struct{}
+
+func (csvCodec) Unmarshal(data []byte, v any, opts *Options) error {
+	user, ok := v.(*SimpleUser)
+	if !ok {
+		return errors.New("csv: unsupported target")
+	}
+	fields := strings.Split(string(data), ",")
+	if len(fields) != 3 {
+		return errors.New("csv: expected 3 fields")
+	}
+	id, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return err
+	}
+	*user = SimpleUser{ID: id, Name: fields[1], Email: fields[2]}
+
+	// Codecs get a copy of the options; this must not leak to the caller
+	opts.MaxSize = 1
+	return nil
+}
+
+func init() {
+	if err := RegisterFormat(formatCSV, csvCodec{}); err != nil {
+		panic(err)
+	}
+}
+
+func TestRegisterFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		codec  Codec
+	}{
+		{"built-in json", FormatJSON, csvCodec{}},
+		{"built-in gob", FormatGob, csvCodec{}},
+		{"already registered", formatCSV, csvCodec{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := RegisterFormat(tt.format, tt.codec); !errors.Is(err, ErrFormatRegistered) {
+				t.Errorf("expected ErrFormatRegistered, got %v", err)
+			}
+		})
+	}
+
+	if err := RegisterFormat("", csvCodec{}); err == nil {
+		t.Error("expected error for empty format name")
+	}
+	if err := RegisterFormat("nil-codec", nil); err == nil {
+		t.Error("expected error for nil codec")
+	}
+}
+
+func TestDecodeFormat(t *testing.T) {
+	want := SimpleUser{ID: 1, Name: "alice", Email: "alice@example.com"}
+
+	t.Run("registered codec", func(t *testing.T) {
+		var got SimpleUser
+		if err := DecodeFormat(formatCSV, []byte("1,alice,alice@example.com"), &got); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != want {
+			t.Errorf("unexpected result: %+v", got)
+		}
+	})
+
+	t.Run("built-in codec", func(t *testing.T) {
+		var got SimpleUser
+		if err := DecodeFormat(FormatJSON, []byte(`{"id":1,"name":"alice","email":"alice@example.com"}`), &got); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != want {
+			t.Errorf("unexpected result: %+v", got)
+		}
+	})
+
+	t.Run("decoder", func(t *testing.T) {
+		decoder := NewDecoder(WithMaxSize(64))
+		var first, second SimpleUser
+		if err := decoder.DecodeFormat(formatCSV, []byte("1,alice,alice@example.com"), &first); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := decoder.DecodeFormat(formatCSV, []byte("1,alice,alice@example.com"), &second); err != nil {
+			t.Fatalf("codec changes to options leaked into the decoder: %v", err)
+		}
+		if decoder.Options().MaxSize != 64 {
+			t.Errorf("expected MaxSize 64, got %d", decoder.Options().MaxSize)
+		}
+	})
+
+	t.Run("shared pipeline", func(t *testing.T) {
+		var target any
+		errValidator := errors.New("rejected")
+		tests := []struct {
+			name    string
+			format  Format
+			data    []byte
+			v       any
+			opts    []Option
+			wantErr error
+		}{
+			{"empty", formatCSV, nil, &SimpleUser{}, nil, ErrEmptyData},
+			{"too large", formatCSV, []byte("1,alice,alice@example.com"), &SimpleUser{}, []Option{WithMaxSize(8)}, ErrDataTooLarge},
+			{"unsafe target", formatCSV, []byte("1,a,b"), &target, nil, ErrInterfaceTarget},
+			{"not allowed", formatCSV, []byte("1,a,b"), &SimpleUser{}, []Option{WithAllowedFormats(FormatJSON)}, ErrFormatNotAllowed},
+			{"validator", formatCSV, []byte("1,a,b"), &SimpleUser{}, []Option{WithValidator(func(any) error { return errValidator })}, errValidator},
+			{"unknown format", Format("toml"), []byte("a = 1"), &SimpleUser{}, nil, ErrUnknownFormat},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if err := DecodeFormat(tt.format, tt.data, tt.v, tt.opts...); !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+			})
+		}
+	})
+
+	t.Run("signed envelope", func(t *testing.T) {
+		key := []byte("0123456789abcdef0123456789abcdef")
+		var got SimpleUser
+		signed := Sign([]byte("1,alice,alice@example.com"), key, nil)
+		if err := VerifyAndDecode(formatCSV, signed, &got, WithHMAC(key, nil)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != want {
+			t.Errorf("unexpected result: %+v", got)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package safedeserialize
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// frameMaxSize is the MaxSize DecodeFrame and FrameDecoder apply unless
+// overridden by their options
+const frameMaxSize = 64 << 10
+
+// DecodeFrame safely decodes a single message frame, such as a WebSocket
+// message or a queue payload, in a built-in or registered format. Unless
+// opts say otherwise a frame may be at most 64KB.
+func DecodeFrame(format Format, frame []byte, v any, opts ...Option) error {
+	opts = append([]Option{WithMaxSize(frameMaxSize)}, opts...)
+	return DecodeFormat(format, frame, v, opts...)
+}
+
+// FrameDecoder decodes the frames received on one connection with preset
+// options, like DecodeFrame, and counts them so that a connection sending
+// oversized or malicious frames can be identified and dropped. It is safe
+// for concurrent use.
+type FrameDecoder struct {
+	decoder *Decoder
+
+	decoded          atomic.Int64
+	rejected         atomic.Int64
+	securityRejected atomic.Int64
+	bytes            atomic.Int64
+}
+
+// FrameStats holds the counters of a FrameDecoder
+type FrameStats struct {
+	// Decoded is the number of frames decoded successfully
+	Decoded int64
+
+	// Rejected is the number of frames that failed to decode for any reason
+	Rejected int64
+
+	// SecurityRejected is the number of rejected frames whose error matches
+	// ErrSecurityLimit, a subset of Rejected
+	SecurityRejected int64
+
+	// Bytes is the total size of every frame passed to Decode
+	Bytes int64
+}
+
+// NewFrameDecoder creates a frame decoder with the given options
+func NewFrameDecoder(opts ...Option) *FrameDecoder {
+	opts = append([]Option{WithMaxSize(frameMaxSize)}, opts...)
+	return &FrameDecoder{decoder: NewDecoder(opts...)}
+}
+
+// Decode decodes frame in format into v and updates the counters
+func (f *FrameDecoder) Decode(format Format, frame []byte, v any, opts ...Option) error {
+	err := f.decoder.DecodeFormat(format, frame, v, opts...)
+	f.bytes.Add(int64(len(frame)))
+	switch {
+	case err == nil:
+		f.decoded.Add(1)
+	case errors.Is(err, ErrSecurityLimit):
+		f.securityRejected.Add(1)
+		f.rejected.Add(1)
+	default:
+		f.rejected.Add(1)
+	}
+	return err
+}
+
+// Stats returns the counters so far. Each counter is read atomically, but
+// frames decoded concurrently with the call may be counted in some of them
+// and not yet in others.
+func (f *FrameDecoder) Stats() FrameStats {
+	return FrameStats{
+		Decoded:          f.decoded.Load(),
+		Rejected:         f.rejected.Load(),
+		SecurityRejected: f.securityRejected.Load(),
+		Bytes:            f.bytes.Load(),
+	}
+}
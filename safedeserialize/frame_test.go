@@ -0,0 +1,109 @@
+package safedeserialize
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDecodeFrame(t *testing.T) {
+	small := []byte(`{"id":1,"name":"alice"}`)
+	large := []byte(`{"id":1,"name":"` + strings.Repeat("x", 64<<10) + `"}`)
+
+	tests := []struct {
+		name    string
+		format  Format
+		frame   []byte
+		opts    []Option
+		wantErr error
+	}{
+		{"json", FormatJSON, small, nil, nil},
+		{"yaml", FormatYAML, []byte("id: 1\nname: alice\n"), nil, nil},
+		{"over default frame size", FormatJSON, large, nil, ErrDataTooLarge},
+		{"raised frame size", FormatJSON, large, []Option{WithMaxSize(128 << 10)}, nil},
+		{"lowered frame size", FormatJSON, small, []Option{WithMaxSize(8)}, ErrDataTooLarge},
+		{"empty frame", FormatJSON, nil, nil, ErrEmptyData},
+		{"format not allowed", FormatYAML, []byte("id: 1\n"), []Option{WithAllowedFormats(FormatJSON)}, ErrFormatNotAllowed},
+		{"unknown format", Format("msgpack"), small, nil, ErrUnknownFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got SimpleUser
+			err := DecodeFrame(tt.format, tt.frame, &got, tt.opts...)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got.ID != 1 {
+					t.Errorf("unexpected result %+v", got)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFrameDecoderStats(t *testing.T) {
+	dec := NewFrameDecoder(WithDeniedFields("is_admin"))
+	frames := []struct {
+		data    string
+		wantErr error
+	}{
+		{`{"id":1}`, nil},
+		{`{"id":2,"is_admin":true}`, ErrDeniedField},
+		{`{"id":`, ErrMalformedInput},
+		{`{"id":3}`, nil},
+	}
+
+	var total int64
+	for _, f := range frames {
+		var u SimpleUser
+		err := dec.Decode(FormatJSON, []byte(f.data), &u)
+		if f.wantErr == nil && err != nil {
+			t.Fatalf("%s: unexpected error: %v", f.data, err)
+		}
+		if f.wantErr != nil && !errors.Is(err, f.wantErr) {
+			t.Fatalf("%s: expected %v, got %v", f.data, f.wantErr, err)
+		}
+		total += int64(len(f.data))
+	}
+
+	want := FrameStats{Decoded: 2, Rejected: 2, SecurityRejected: 1, Bytes: total}
+	if got := dec.Stats(); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	// Per-call overrides apply to that frame only
+	var u SimpleUser
+	if err := dec.Decode(FormatJSON, []byte(`{"id":4}`), &u, WithMaxSize(4)); !errors.Is(err, ErrDataTooLarge) {
+		t.Fatalf("expected ErrDataTooLarge, got %v", err)
+	}
+	if err := dec.Decode(FormatJSON, []byte(`{"id":4}`), &u); err != nil {
+		t.Fatalf("unexpected error after override: %v", err)
+	}
+}
+
+func TestFrameDecoderConcurrent(t *testing.T) {
+	dec := NewFrameDecoder()
+	frame := []byte(`{"id":1}`)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				var u SimpleUser
+				_ = dec.Decode(FormatJSON, frame, &u)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := dec.Stats(); got.Decoded != 800 || got.Bytes != 800*int64(len(frame)) {
+		t.Errorf("unexpected stats %+v", got)
+	}
+}
@@ -0,0 +1,192 @@
+package safedeserialize
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// GobStream decodes a sequence of gob messages from a long-lived reader,
+// such as a network connection, applying the limits in its options to
+// every message: MaxSize bounds each message rather than the whole stream,
+// every target is validated, and MaxRecords bounds the number of messages.
+//
+// Type information sent earlier in the stream is shared by later messages,
+// as with a gob.Decoder. Once reading or decoding a message fails the
+// stream is out of sync, and every later call returns the same error. A
+// GobStream is not safe for concurrent use.
+type GobStream struct {
+	opts    *Options
+	reader  *gobStreamReader
+	decoder *gob.Decoder
+	records int
+	err     error
+}
+
+// NewGobStream returns a stream decoding gob messages from r
+func NewGobStream(r io.Reader, opts ...Option) *GobStream {
+	options := DefaultOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+	return newGobStream(r, options.clone())
+}
+
+func newGobStream(r io.Reader, opts *Options) *GobStream {
+	reader := &gobStreamReader{r: bufio.NewReader(r), framed: opts.LengthPrefix}
+	return &GobStream{opts: opts, reader: reader, decoder: gob.NewDecoder(reader)}
+}
+
+// Decode decodes the next message into v. It returns io.EOF, unwrapped,
+// once the stream ends cleanly between messages.
+func (s *GobStream) Decode(v any) error {
+	if s.err != nil {
+		return s.err
+	}
+	if err := checkFormatAllowed(FormatGob, s.opts); err != nil {
+		return categorize(err)
+	}
+
+	start := time.Now()
+	err := s.decode(v)
+	if err == io.EOF {
+		return err
+	}
+	err = categorize(err)
+	if s.err != nil {
+		s.err = err
+	}
+	if s.opts.MetricsCallback != nil || s.opts.AuditLogger != nil {
+		report(FormatGob, nil, int(s.reader.n), v, s.opts, err, time.Since(start))
+	}
+	return err
+}
+
+// Records returns the number of messages decoded so far
+func (s *GobStream) Records() int {
+	return s.records
+}
+
+func (s *GobStream) decode(v any) error {
+	if err := validateTarget(v, s.opts); err != nil {
+		return err
+	}
+	if err := checkGobOptions(v, s.opts); err != nil {
+		return err
+	}
+	if err := s.next(); err != nil {
+		if err != io.EOF {
+			s.err = err
+		}
+		return err
+	}
+
+	err := decodeInto(v, s.opts, func(target any) error {
+		if err := s.decoder.Decode(target); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			s.err = err
+			return err
+		}
+		if s.reader.framed && s.reader.remaining > 0 {
+			s.err = fmt.Errorf("gob frame holds %d bytes after the message", s.reader.remaining)
+			return s.err
+		}
+		s.records++
+		return nil
+	})
+	return err
+}
+
+// next prepares the reader for the next message, returning io.EOF if the
+// stream has ended
+func (s *GobStream) next() error {
+	s.reader.n = 0
+	if _, err := s.reader.r.Peek(1); err != nil {
+		return err
+	}
+	if s.opts.MaxRecords > 0 && s.records >= s.opts.MaxRecords {
+		return fmt.Errorf("%w: more than %d records", ErrTooManyRecords, s.opts.MaxRecords)
+	}
+	if !s.reader.framed {
+		s.reader.start(s.opts.MaxSize)
+		return nil
+	}
+
+	var prefix [4]byte
+	if _, err := io.ReadFull(s.reader.r, prefix[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	size := int64(binary.BigEndian.Uint32(prefix[:]))
+	if size == 0 {
+		return fmt.Errorf("%w: gob frame is empty", ErrEmptyData)
+	}
+	if size > s.opts.MaxSize {
+		return fmt.Errorf("%w: gob frame of %d bytes exceeds limit %d", ErrDataTooLarge, size, s.opts.MaxSize)
+	}
+	s.reader.start(size)
+	return nil
+}
+
+// gobStreamReader feeds a GobStream's gob.Decoder and bounds the bytes it
+// reads for the current message. It implements io.ByteReader, so the
+// decoder does not buffer ahead into the next message.
+type gobStreamReader struct {
+	r *bufio.Reader
+
+	// framed is set for length-prefixed frames, where remaining is the
+	// unread part of the frame rather than the budget for the message
+	framed    bool
+	remaining int64
+	limit     int64
+	n         int64
+}
+
+// start begins a message of at most limit bytes
+func (g *gobStreamReader) start(limit int64) {
+	g.remaining, g.limit, g.n = limit, limit, 0
+}
+
+// exhausted returns the error for reading past the current message
+func (g *gobStreamReader) exhausted() error {
+	if g.framed {
+		return errors.New("gob message overruns its frame")
+	}
+	return fmt.Errorf("%w: message exceeds limit %d", ErrDataTooLarge, g.limit)
+}
+
+func (g *gobStreamReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if g.remaining == 0 {
+		return 0, g.exhausted()
+	}
+	if int64(len(p)) > g.remaining {
+		p = p[:g.remaining]
+	}
+	n, err := g.r.Read(p)
+	g.remaining -= int64(n)
+	g.n += int64(n)
+	return n, err
+}
+
+func (g *gobStreamReader) ReadByte() (byte, error) {
+	if g.remaining == 0 {
+		return 0, g.exhausted()
+	}
+	b, err := g.r.ReadByte()
+	if err == nil {
+		g.remaining--
+		g.n++
+	}
+	return b, err
+}
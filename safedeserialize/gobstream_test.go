@@ -0,0 +1,171 @@
+package safedeserialize
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type streamMessage struct {
+	ID   int
+	Body string
+}
+
+// gobMessages encodes values as one unframed gob stream
+func gobMessages(t *testing.T, values ...streamMessage) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// gobFrames encodes values as a gob stream with each message in a
+// length-prefixed frame
+func gobFrames(t *testing.T, values ...streamMessage) []byte {
+	t.Helper()
+	var out, msg bytes.Buffer
+	enc := gob.NewEncoder(&msg)
+	for _, v := range values {
+		msg.Reset()
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		out.Write(binary.BigEndian.AppendUint32(nil, uint32(msg.Len())))
+		out.Write(msg.Bytes())
+	}
+	return out.Bytes()
+}
+
+// decodeStream decodes messages from s until it fails, returning the
+// messages and the final error
+func decodeStream(s *GobStream) ([]streamMessage, error) {
+	var got []streamMessage
+	for {
+		var m streamMessage
+		if err := s.Decode(&m); err != nil {
+			return got, err
+		}
+		got = append(got, m)
+	}
+}
+
+func TestGobStream(t *testing.T) {
+	body := strings.Repeat("x", 600)
+	values := []streamMessage{{1, body}, {2, body}, {3, body}}
+
+	tests := []struct {
+		name     string
+		data     func(t *testing.T) []byte
+		opts     []Option
+		wantN    int
+		wantErr  error
+		wantText string
+	}{
+		{"per message size", func(t *testing.T) []byte { return gobMessages(t, values...) }, []Option{WithMaxSize(1024)}, 3, io.EOF, ""},
+		{"message too large", func(t *testing.T) []byte {
+			return gobMessages(t, values[0], streamMessage{4, strings.Repeat("y", 2048)})
+		}, []Option{WithMaxSize(1024)}, 1, ErrDataTooLarge, "message exceeds limit 1024"},
+		{"records at limit", func(t *testing.T) []byte { return gobMessages(t, values[:2]...) }, []Option{WithMaxRecords(2)}, 2, io.EOF, ""},
+		{"too many records", func(t *testing.T) []byte { return gobMessages(t, values...) }, []Option{WithMaxRecords(2)}, 2, ErrTooManyRecords, "more than 2 records"},
+		{"truncated", func(t *testing.T) []byte { d := gobMessages(t, values...); return d[:len(d)-10] }, nil, 2, ErrMalformedInput, "unexpected EOF"},
+		{"empty", func(t *testing.T) []byte { return nil }, nil, 0, io.EOF, ""},
+		{"framed", func(t *testing.T) []byte { return gobFrames(t, values...) }, []Option{WithLengthPrefix(true), WithMaxSize(1024)}, 3, io.EOF, ""},
+		{"framed too large", func(t *testing.T) []byte { return gobFrames(t, values[0], streamMessage{4, strings.Repeat("y", 2048)}) }, []Option{WithLengthPrefix(true), WithMaxSize(1024)}, 1, ErrDataTooLarge, "exceeds limit 1024"},
+		{"framed huge prefix", func(t *testing.T) []byte { return []byte{0xff, 0xff, 0xff, 0xff} }, []Option{WithLengthPrefix(true)}, 0, ErrDataTooLarge, "4294967295 bytes"},
+		{"framed empty frame", func(t *testing.T) []byte { return []byte{0, 0, 0, 0} }, []Option{WithLengthPrefix(true)}, 0, ErrEmptyData, ""},
+		{"framed trailing bytes", func(t *testing.T) []byte {
+			d := gobFrames(t, values[0])
+			binary.BigEndian.PutUint32(d, binary.BigEndian.Uint32(d)+2)
+			return append(d, 0, 0)
+		}, []Option{WithLengthPrefix(true)}, 0, ErrMalformedInput, "2 bytes after the message"},
+		{"framed overrun", func(t *testing.T) []byte {
+			d := gobFrames(t, values[0], values[1])
+			binary.BigEndian.PutUint32(d, binary.BigEndian.Uint32(d)-1)
+			return d
+		}, []Option{WithLengthPrefix(true)}, 0, ErrMalformedInput, "overruns its frame"},
+		{"framed partial prefix", func(t *testing.T) []byte { return append(gobFrames(t, values[0]), 0, 0) }, []Option{WithLengthPrefix(true)}, 1, ErrMalformedInput, "unexpected EOF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewGobStream(bytes.NewReader(tt.data(t)), tt.opts...)
+			got, err := decodeStream(s)
+			if len(got) != tt.wantN || s.Records() != tt.wantN {
+				t.Fatalf("expected %d messages, got %d (Records %d)", tt.wantN, len(got), s.Records())
+			}
+			for i, m := range got {
+				if m != values[i] {
+					t.Errorf("message %d: got %+v", i, m)
+				}
+			}
+			if tt.wantErr == io.EOF {
+				if err != io.EOF {
+					t.Fatalf("expected io.EOF, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if !strings.Contains(err.Error(), tt.wantText) {
+				t.Errorf("expected %q in %q", tt.wantText, err)
+			}
+			// The stream is out of sync after a failed message
+			if again := s.Decode(&streamMessage{}); again != err {
+				t.Errorf("expected the same error again, got %v", again)
+			}
+		})
+	}
+}
+
+func TestGobStreamPerCallChecks(t *testing.T) {
+	data := gobMessages(t, streamMessage{1, "a"}, streamMessage{2, ""}, streamMessage{3, "c"})
+	validator := func(v any) error {
+		if v.(*streamMessage).Body == "" {
+			return errors.New("body is required")
+		}
+		return nil
+	}
+	s := NewDecoder(WithValidator(validator)).GobStream(bytes.NewReader(data))
+
+	// Rejected targets leave the stream untouched
+	var m streamMessage
+	if err := s.Decode(m); !errors.Is(err, ErrNotPointer) {
+		t.Fatalf("expected ErrNotPointer, got %v", err)
+	}
+	if err := s.Decode(&m); err != nil || m.ID != 1 {
+		t.Fatalf("unexpected result %+v, %v", m, err)
+	}
+
+	// A message that fails validation is consumed without breaking the stream
+	m = streamMessage{}
+	if err := s.Decode(&m); !errors.Is(err, ErrValidationFailed) {
+		t.Fatalf("expected ErrValidationFailed, got %v", err)
+	}
+	if err := s.Decode(&m); err != nil || m.ID != 3 {
+		t.Fatalf("unexpected result %+v, %v", m, err)
+	}
+	if s.Records() != 3 {
+		t.Errorf("expected 3 records, got %d", s.Records())
+	}
+
+	var outcomes []string
+	metrics := func(format, outcome string, size int, _ time.Duration) {
+		outcomes = append(outcomes, outcome)
+	}
+	s = NewGobStream(bytes.NewReader(data), WithMetricsCallback(metrics), WithMaxRecords(1))
+	_, _ = decodeStream(s)
+	if strings.Join(outcomes, ",") != OutcomeOK+","+OutcomeTooLarge {
+		t.Errorf("unexpected outcomes %v", outcomes)
+	}
+}
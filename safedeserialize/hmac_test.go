@@ -0,0 +1,62 @@
+package safedeserialize
+
+import (
+	"crypto/sha512"
+	"errors"
+	"testing"
+)
+
+func TestVerifyAndDecode(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	user := SimpleUser{ID: 1, Name: "alice", Email: "alice@example.com"}
+
+	signedJSON, err := SignedJSON(user, key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedGob, err := SignedGob(user, key, sha512.New)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		var fromJSON, fromGob SimpleUser
+		if err := VerifyAndDecode(FormatJSON, signedJSON, &fromJSON, WithHMAC(key, nil)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := NewDecoder(WithHMAC(key, sha512.New)).VerifyAndDecode(FormatGob, signedGob, &fromGob); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if fromJSON != user || fromGob != user {
+			t.Errorf("unexpected result: %+v, %+v", fromJSON, fromGob)
+		}
+	})
+
+	tampered := append([]byte(nil), signedJSON...)
+	tampered[len(tampered)-2] ^= 1
+
+	tests := []struct {
+		name    string
+		format  Format
+		data    []byte
+		opts    []Option
+		wantErr error
+	}{
+		{"tampered payload", FormatJSON, tampered, []Option{WithHMAC(key, nil)}, ErrInvalidSignature},
+		{"wrong key", FormatJSON, signedJSON, []Option{WithHMAC([]byte("other"), nil)}, ErrInvalidSignature},
+		{"wrong hash", FormatGob, signedGob, []Option{WithHMAC(key, nil)}, ErrInvalidSignature},
+		{"unsigned payload", FormatJSON, []byte(`{"id":1}`), []Option{WithHMAC(key, nil)}, ErrInvalidSignature},
+		{"no key", FormatJSON, signedJSON, nil, ErrHMACKeyRequired},
+		{"too large", FormatJSON, signedJSON, []Option{WithHMAC(key, nil), WithMaxSize(8)}, ErrDataTooLarge},
+		{"unknown format", Format("toml"), Sign([]byte("a = 1"), key, nil), []Option{WithHMAC(key, nil)}, ErrUnknownFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got SimpleUser
+			if err := VerifyAndDecode(tt.format, tt.data, &got, tt.opts...); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package safedeserialize
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Validatable is implemented by targets that check their own invariants.
+// Validate is called automatically after a successful decode.
+type Validatable interface {
+	Validate() error
+}
+
+// decodeInto runs decode and the post-decode hooks against the target v.
+// With AtomicDecode they run against a freshly allocated value instead,
+// which is copied into v only once both have succeeded.
+func decodeInto(v any, opts *Options, decode func(target any) error) error {
+	if !opts.AtomicDecode {
+		beforeDecode(v, opts)
+		if err := decode(v); err != nil {
+			return err
+		}
+		return afterDecode(v, opts)
+	}
+
+	dst := reflect.ValueOf(v).Elem()
+	fresh := reflect.New(dst.Type())
+	target := fresh.Interface()
+	if err := decode(target); err != nil {
+		return err
+	}
+	if err := afterDecode(target, opts); err != nil {
+		return err
+	}
+	dst.Set(fresh.Elem())
+	return nil
+}
+
+// beforeDecode prepares the validated target v for decoding according to
+// opts. With ZeroTarget, structs and other values are reset to their zero
+// value, maps are cleared and slices are truncated, so nothing from a
+// previous decode survives fields the new input omits.
+func beforeDecode(v any, opts *Options) {
+	if !opts.ZeroTarget {
+		return
+	}
+
+	elem := reflect.ValueOf(v).Elem()
+	switch elem.Kind() {
+	case reflect.Map:
+		if !elem.IsNil() {
+			elem.Clear()
+		}
+	case reflect.Slice:
+		if !elem.IsNil() {
+			elem.SetLen(0)
+		}
+	default:
+		elem.SetZero()
+	}
+}
+
+// afterDecode runs the post-decode hooks in opts against the populated
+// target v. It is only called once decoding has succeeded.
+func afterDecode(v any, opts *Options) error {
+	if opts.PostDecodeScan {
+		if err := scanInterfaces(v, opts); err != nil {
+			return err
+		}
+	}
+	if opts.Sanitizer != nil {
+		if err := sanitizeStrings(v, opts); err != nil {
+			return err
+		}
+	}
+	if opts.Defaults != nil {
+		opts.Defaults(v)
+	}
+	if target, ok := v.(Validatable); ok {
+		if err := target.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrValidationFailed, err)
+		}
+	}
+	if opts.Validator != nil {
+		if err := opts.Validator(v); err != nil {
+			return fmt.Errorf("%w: %w", ErrValidationFailed, err)
+		}
+	}
+	return nil
+}
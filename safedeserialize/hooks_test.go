@@ -0,0 +1,292 @@
+package safedeserialize
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// Order implements Validatable
+type Order struct {
+	ID       int `json:"id" yaml:"id" xml:"id"`
+	Quantity int `json:"quantity" yaml:"quantity" xml:"quantity"`
+}
+
+var errBadQuantity = errors.New("quantity must be positive")
+
+func (o *Order) Validate() error {
+	if o.Quantity <= 0 {
+		return errBadQuantity
+	}
+	return nil
+}
+
+func TestValidateMethod(t *testing.T) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(Order{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		decode func(v any) error
+	}{
+		{"json", func(v any) error { return JSON([]byte(`{"id":1,"quantity":0}`), v) }},
+		{"json reader", func(v any) error { return JSONReader(strings.NewReader(`{"id":1}`), v) }},
+		{"yaml", func(v any) error { return YAML([]byte("id: 1\nquantity: 0\n"), v) }},
+		{"xml", func(v any) error { return XML([]byte("<Order><id>1</id></Order>"), v) }},
+		{"gob", func(v any) error { return Gob(buf.Bytes(), v) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var order Order
+			err := tt.decode(&order)
+			if !errors.Is(err, ErrValidationFailed) {
+				t.Fatalf("expected ErrValidationFailed, got %v", err)
+			}
+			if !errors.Is(err, errBadQuantity) {
+				t.Errorf("expected the Validate error to be wrapped, got %v", err)
+			}
+			if order.ID != 1 {
+				t.Errorf("expected the target to be populated, got ID %d", order.ID)
+			}
+		})
+	}
+}
+
+func TestValidateMethodNotCalledOnParseError(t *testing.T) {
+	var order Order
+	err := JSON([]byte(`{"id":"one"}`), &order)
+	if err == nil || errors.Is(err, ErrValidationFailed) {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+}
+
+func TestWithValidator(t *testing.T) {
+	var seen *SimpleUser
+	validator := func(v any) error {
+		seen, _ = v.(*SimpleUser)
+		if seen == nil || seen.Email == "" {
+			return errors.New("email is required")
+		}
+		return nil
+	}
+
+	dec := NewDecoder(WithValidator(validator))
+
+	var user SimpleUser
+	if err := dec.JSON([]byte(`{"id":1,"name":"alice","email":"a@example.com"}`), &user); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if seen != &user {
+		t.Error("expected the validator to receive the decoded target itself")
+	}
+
+	var missing SimpleUser
+	err := dec.YAML([]byte("id: 2\nname: bob\n"), &missing)
+	if !errors.Is(err, ErrValidationFailed) {
+		t.Errorf("expected ErrValidationFailed, got %v", err)
+	}
+}
+
+func TestWithValidatorAfterValidateMethod(t *testing.T) {
+	called := false
+	validator := func(v any) error {
+		called = true
+		return nil
+	}
+
+	var order Order
+	err := JSON([]byte(`{"id":1,"quantity":0}`), &order, WithValidator(validator))
+	if !errors.Is(err, errBadQuantity) {
+		t.Fatalf("expected the Validate error, got %v", err)
+	}
+	if called {
+		t.Error("expected the validator to be skipped once Validate fails")
+	}
+}
+
+// reusedRequest is decoded repeatedly into the same value
+type reusedRequest struct {
+	ID    int               `json:"id" yaml:"id" xml:"id"`
+	Token string            `json:"token" yaml:"token" xml:"token"`
+	Tags  []string          `json:"tags" yaml:"tags" xml:"tag"`
+	Meta  map[string]string `json:"meta" yaml:"meta" xml:"-"`
+}
+
+func TestZeroTarget(t *testing.T) {
+	gobPayload := func(r reusedRequest) []byte {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(r); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	first := reusedRequest{ID: 1, Token: "secret", Tags: []string{"a"}, Meta: map[string]string{"k": "v"}}
+	firstGob, secondGob := gobPayload(first), gobPayload(reusedRequest{ID: 2})
+
+	tests := []struct {
+		name          string
+		first, second func(v any, opts ...Option) error
+		hasMeta       bool
+	}{
+		{
+			"json",
+			func(v any, opts ...Option) error {
+				return JSON([]byte(`{"id":1,"token":"secret","tags":["a"],"meta":{"k":"v"}}`), v, opts...)
+			},
+			func(v any, opts ...Option) error { return JSON([]byte(`{"id":2}`), v, opts...) },
+			true,
+		},
+		{
+			"json reader",
+			func(v any, opts ...Option) error {
+				return JSONReader(strings.NewReader(`{"id":1,"token":"secret","tags":["a"],"meta":{"k":"v"}}`), v, opts...)
+			},
+			func(v any, opts ...Option) error { return JSONReader(strings.NewReader(`{"id":2}`), v, opts...) },
+			true,
+		},
+		{
+			"yaml",
+			func(v any, opts ...Option) error {
+				return YAML([]byte("id: 1\ntoken: secret\ntags: [a]\nmeta: {k: v}\n"), v, opts...)
+			},
+			func(v any, opts ...Option) error { return YAML([]byte("id: 2\n"), v, opts...) },
+			true,
+		},
+		{
+			"xml",
+			func(v any, opts ...Option) error {
+				return XML([]byte("<r><id>1</id><token>secret</token><tag>a</tag></r>"), v, opts...)
+			},
+			func(v any, opts ...Option) error { return XML([]byte("<r><id>2</id></r>"), v, opts...) },
+			false,
+		},
+		{
+			"gob",
+			func(v any, opts ...Option) error { return Gob(firstGob, v, opts...) },
+			func(v any, opts ...Option) error { return Gob(secondGob, v, opts...) },
+			true,
+		},
+		{
+			"gob reader",
+			func(v any, opts ...Option) error { return GobReader(bytes.NewReader(firstGob), v, opts...) },
+			func(v any, opts ...Option) error { return GobReader(bytes.NewReader(secondGob), v, opts...) },
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req reusedRequest
+			if err := tt.first(&req, WithZeroTarget(true)); err != nil {
+				t.Fatalf("first decode: %v", err)
+			}
+			if req.Token != "secret" || len(req.Tags) != 1 || (tt.hasMeta && req.Meta["k"] != "v") {
+				t.Fatalf("first decode incomplete: %+v", req)
+			}
+			if err := tt.second(&req, WithZeroTarget(true)); err != nil {
+				t.Fatalf("second decode: %v", err)
+			}
+			if req.ID != 2 || req.Token != "" || len(req.Tags) != 0 || len(req.Meta) != 0 {
+				t.Errorf("values from the first payload leaked: %+v", req)
+			}
+
+			// Without the option, omitted fields keep their values
+			if err := tt.first(&req); err != nil {
+				t.Fatalf("first decode: %v", err)
+			}
+			if err := tt.second(&req); err != nil {
+				t.Fatalf("second decode: %v", err)
+			}
+			if req.Token != "secret" {
+				t.Errorf("expected merge semantics without WithZeroTarget, got %+v", req)
+			}
+		})
+	}
+}
+
+func TestZeroTargetContainers(t *testing.T) {
+	counts := map[string]int{"stale": 1}
+	alias := counts
+	if err := JSON([]byte(`{"fresh":2}`), &counts, WithZeroTarget(true)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(counts) != 1 || counts["fresh"] != 2 || len(alias) != 1 {
+		t.Errorf("map not cleared: %v", counts)
+	}
+
+	ids := make([]int, 3, 8)
+	if err := YAML([]byte("[7]"), &ids, WithZeroTarget(true)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != 7 {
+		t.Errorf("slice not truncated: %v", ids)
+	}
+}
+
+// rawBytes returns a copy of the memory backing *p
+func rawBytes[T any](p *T) []byte {
+	return bytes.Clone(unsafe.Slice((*byte)(unsafe.Pointer(p)), unsafe.Sizeof(*p)))
+}
+
+func TestAtomicDecode(t *testing.T) {
+	tests := []struct {
+		name   string
+		decode func(v any, opts ...Option) error
+	}{
+		{"json unknown field", func(v any, opts ...Option) error {
+			return JSON([]byte(`{"id":9,"quantity":9,"admin":true}`), v, opts...)
+		}},
+		{"json type error", func(v any, opts ...Option) error {
+			return JSON([]byte(`{"id":9,"quantity":"many"}`), v, opts...)
+		}},
+		{"json reader", func(v any, opts ...Option) error {
+			return JSONReader(strings.NewReader(`{"id":9,"quantity":9,"admin":true}`), v, opts...)
+		}},
+		{"yaml", func(v any, opts ...Option) error {
+			return YAML([]byte("id: 9\nquantity: 9\nadmin: true\n"), v, opts...)
+		}},
+		{"xml", func(v any, opts ...Option) error {
+			return XML([]byte("<Order><id>9</id><quantity>x</quantity></Order>"), v, opts...)
+		}},
+		{"validation", func(v any, opts ...Option) error {
+			return JSON([]byte(`{"id":9,"quantity":0}`), v, opts...)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := Order{ID: 1, Quantity: 2}
+			before := rawBytes(&order)
+			if err := tt.decode(&order, WithAtomicDecode(true)); err == nil {
+				t.Fatal("expected error")
+			}
+			if !bytes.Equal(rawBytes(&order), before) {
+				t.Errorf("target modified by failed decode: %+v", order)
+			}
+
+			// Without the option the same input leaves partial data behind
+			if err := tt.decode(&order); err == nil {
+				t.Fatal("expected error")
+			}
+			if order.ID != 9 {
+				t.Errorf("expected partial population without WithAtomicDecode, got %+v", order)
+			}
+		})
+	}
+
+	t.Run("success", func(t *testing.T) {
+		order := Order{ID: 1, Quantity: 2}
+		if err := JSON([]byte(`{"id":3,"quantity":4}`), &order, WithAtomicDecode(true)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if order != (Order{ID: 3, Quantity: 4}) {
+			t.Errorf("unexpected result: %+v", order)
+		}
+	})
+}
@@ -0,0 +1,405 @@
+package safedeserialize
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// jsonFieldCache maps struct types to their jsonFields.
+var jsonFieldCache sync.Map
+
+// scanJSON walks the first value in data token by token and applies the
+// document-level checks in opts before anything is decoded into the target.
+// It returns the data to decode, which differs from data only when a time
+// policy rewrote time.Time values to RFC 3339, and with DefaultTags the
+// struct fields present in the document. A nil v skips the checks that
+// depend on the target type.
+func scanJSON(data []byte, v any, opts *Options) ([]byte, fieldSet, error) {
+	checkMaps, checkTimes, defaults := needsMapScan(v, opts), needsTimeScan(v, opts), needsDefaultTags(v, opts)
+	if !needsKeyScan(opts) && !checkMaps && !checkTimes && !defaults {
+		return data, nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	scanner := &jsonScanner{opts: opts, data: data, checkTimes: checkTimes}
+	if checkMaps || checkTimes || defaults {
+		scanner.root = reflect.TypeOf(v)
+	}
+	if defaults {
+		scanner.present = make(fieldSet)
+	}
+	for {
+		scanner.offset = decoder.InputOffset()
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := scanner.token(tok, decoder.InputOffset()); err != nil {
+			return nil, nil, err
+		}
+		if len(scanner.stack) == 0 {
+			// Only the first value is decoded, so stop there
+			break
+		}
+	}
+	return scanner.rewritten(), scanner.present, nil
+}
+
+// jsonScanner holds the state of a single scanJSON pass
+type jsonScanner struct {
+	opts  *Options
+	root  reflect.Type // nil unless the target type is followed
+	stack []jsonFrame
+
+	// present collects the struct fields set by the document when
+	// DefaultTags applies
+	present fieldSet
+
+	// data is the input and offset the position where the current token's
+	// search began; edits replace time values when checkTimes is set
+	data       []byte
+	offset     int64
+	checkTimes bool
+	edits      []jsonEdit
+}
+
+// jsonEdit replaces data[start:end] with text
+type jsonEdit struct {
+	start, end int64
+	text       string
+}
+
+// jsonFrame is an open object or array
+type jsonFrame struct {
+	path      string
+	key       string
+	object    bool
+	expectKey bool
+	index     int
+
+	// typ is the Go type the container decodes into, or nil if unknown;
+	// keys counts the keys read so far when it is a map
+	typ  reflect.Type
+	keys int
+
+	// goPath is the Go path of the container for fieldSet; next and
+	// nextGoPath describe the value of the current key
+	goPath     string
+	next       reflect.Type
+	nextGoPath string
+}
+
+// token processes a single token from the decoder that ends at offset end
+func (s *jsonScanner) token(tok json.Token, end int64) error {
+	if top := s.top(); top != nil && top.object && top.expectKey {
+		if key, ok := tok.(string); ok {
+			top.key = key
+			top.expectKey = false
+			if err := checkDocumentKey(key, joinKeyPath(top.path, key), len(s.stack) == 1, s.opts); err != nil {
+				return err
+			}
+			s.keyType(top, key)
+			if isMapType(top.typ) {
+				top.keys++
+				return checkMapKey(key, top.keys, top.path, s.opts)
+			}
+			return nil
+		}
+	}
+
+	if delim, ok := tok.(json.Delim); ok && (delim == '}' || delim == ']') {
+		s.stack = s.stack[:len(s.stack)-1]
+		s.endValue()
+		return nil
+	}
+
+	path, typ := s.valuePath(), s.valueType()
+	if delim, ok := tok.(json.Delim); ok {
+		s.stack = append(s.stack, jsonFrame{
+			path: path, object: delim == '{', expectKey: delim == '{',
+			typ: typ, goPath: s.valueGoPath(),
+		})
+		return nil
+	}
+
+	if str, ok := tok.(string); ok {
+		if top := s.top(); top != nil && isMapType(top.typ) {
+			if err := checkMapValue(top.key, str, top.path, s.opts); err != nil {
+				return err
+			}
+		}
+	}
+	if s.checkTimes && typ == timeType {
+		if err := s.timeValue(tok, path, end); err != nil {
+			return err
+		}
+	}
+	s.endValue()
+	return nil
+}
+
+// timeValue applies the time policy to a scalar decoded into time.Time and
+// records its replacement by the equivalent RFC 3339 string
+func (s *jsonScanner) timeValue(tok json.Token, path string, end int64) error {
+	var t time.Time
+	var err error
+	switch v := tok.(type) {
+	case string:
+		t, err = parseTimeString(v, path, s.opts)
+	case json.Number:
+		t, err = parseEpochSeconds(v.String(), path, s.opts)
+	default:
+		return nil // null leaves the field unchanged, anything else fails to decode
+	}
+	if err != nil {
+		return err
+	}
+
+	start := s.offset
+	for start < end && strings.IndexByte(" \t\r\n,:", s.data[start]) >= 0 {
+		start++
+	}
+	s.edits = append(s.edits, jsonEdit{start: start, end: end, text: strconv.Quote(t.Format(time.RFC3339Nano))})
+	return nil
+}
+
+// rewritten returns the input with the recorded edits applied
+func (s *jsonScanner) rewritten() []byte {
+	if len(s.edits) == 0 {
+		return s.data
+	}
+	out := make([]byte, 0, len(s.data)+len(s.edits)*len(time.RFC3339Nano))
+	var last int64
+	for _, e := range s.edits {
+		out = append(out, s.data[last:e.start]...)
+		out = append(out, e.text...)
+		last = e.end
+	}
+	return append(out, s.data[last:]...)
+}
+
+// top returns the innermost open container, if any
+func (s *jsonScanner) top() *jsonFrame {
+	if len(s.stack) == 0 {
+		return nil
+	}
+	return &s.stack[len(s.stack)-1]
+}
+
+// valuePath returns the document path of the value that starts next
+func (s *jsonScanner) valuePath() string {
+	top := s.top()
+	switch {
+	case top == nil:
+		return ""
+	case top.object:
+		return joinKeyPath(top.path, top.key)
+	default:
+		return joinIndexPath(top.path, top.index)
+	}
+}
+
+// keyType records the type and Go path of the value of key in object top,
+// marking struct fields as present when DefaultTags applies
+func (s *jsonScanner) keyType(top *jsonFrame, key string) {
+	top.next, top.nextGoPath = nil, ""
+	switch {
+	case top.typ == nil:
+	case top.typ.Kind() == reflect.Map:
+		top.next, top.nextGoPath = top.typ.Elem(), top.goPath+"["+key+"]"
+	case top.typ.Kind() == reflect.Struct:
+		if index, ok := jsonFieldIndex(top.typ, key); ok {
+			top.next, top.nextGoPath = fieldAt(top.typ, top.goPath, index)
+			if s.present != nil {
+				s.present[top.nextGoPath] = true
+			}
+		}
+	}
+}
+
+// valueGoPath returns the Go path of the value that starts next
+func (s *jsonScanner) valueGoPath() string {
+	top := s.top()
+	switch {
+	case top == nil:
+		return ""
+	case top.object:
+		return top.nextGoPath
+	default:
+		return fmt.Sprintf("%s[%d]", top.goPath, top.index)
+	}
+}
+
+// valueType returns the Go type the value that starts next decodes into,
+// or nil if it is unknown or decoded by a custom unmarshaler
+func (s *jsonScanner) valueType() reflect.Type {
+	var t reflect.Type
+	top := s.top()
+	switch {
+	case top == nil:
+		t = s.root
+	case top.typ == nil:
+		return nil
+	case top.object:
+		t = top.next
+	case top.typ.Kind() == reflect.Slice || top.typ.Kind() == reflect.Array:
+		t = top.typ.Elem()
+	}
+	if t == nil {
+		return nil
+	}
+	t = derefType(t)
+	if t == timeType {
+		return t
+	}
+	if t.Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return nil
+	}
+	return t
+}
+
+// jsonFieldIndex returns the index of the field of struct t that an object
+// key sets, as encoding/json picks it: a field named exactly key, or else
+// the first whose name matches key case-insensitively, among the fields
+// jsonFields returns
+func jsonFieldIndex(t reflect.Type, key string) ([]int, bool) {
+	cached, ok := jsonFieldCache.Load(t)
+	if !ok {
+		cached, _ = jsonFieldCache.LoadOrStore(t, jsonFields(t))
+	}
+	fields := cached.([]jsonField)
+	for _, f := range fields {
+		if f.name == key {
+			return f.index, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, key) {
+			return f.index, true
+		}
+	}
+	return nil, false
+}
+
+// jsonField is a field of a struct that encoding/json decodes into.
+type jsonField struct {
+	name   string
+	tagged bool
+	index  []int
+}
+
+// jsonFields returns the fields of struct t that encoding/json decodes
+// into, in index order. Fields of embedded structs without a name in their
+// json tag are promoted. Of the fields with the same name, the shallowest
+// wins, or among several at that depth the one with a json tag name; if
+// that leaves more than one, none does.
+func jsonFields(t reflect.Type) []jsonField {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+	var all []jsonField
+	visited := map[reflect.Type]bool{}
+	for next := []embedded{{typ: t}}; len(next) > 0; {
+		// A struct embedded twice at one depth is walked twice, so that its
+		// fields hide each other as in encoding/json
+		level := slices.DeleteFunc(next, func(e embedded) bool { return visited[e.typ] })
+		next = nil
+		for _, e := range level {
+			visited[e.typ] = true
+		}
+		for _, e := range level {
+			for i := 0; i < e.typ.NumField(); i++ {
+				field := e.typ.Field(i)
+				ft := derefType(field.Type)
+				if !field.IsExported() && !(field.Anonymous && ft.Kind() == reflect.Struct) {
+					continue
+				}
+				tag := field.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, _, _ := strings.Cut(tag, ",")
+				index := append(slices.Clone(e.index), i)
+				if name == "" && field.Anonymous && ft.Kind() == reflect.Struct {
+					next = append(next, embedded{typ: ft, index: index})
+					continue
+				}
+				if !field.IsExported() {
+					continue
+				}
+				all = append(all, jsonField{name: cmp.Or(name, field.Name), tagged: name != "", index: index})
+			}
+		}
+	}
+
+	var fields []jsonField
+	for _, f := range all {
+		if dominantJSONField(all, f) {
+			fields = append(fields, f)
+		}
+	}
+	slices.SortFunc(fields, func(a, b jsonField) int { return slices.Compare(a.index, b.index) })
+	return fields
+}
+
+// dominantJSONField reports whether f, one of all, is the field
+// encoding/json decodes its name into
+func dominantJSONField(all []jsonField, f jsonField) bool {
+	for _, other := range all {
+		if other.name != f.name || slices.Equal(other.index, f.index) {
+			continue
+		}
+		switch {
+		case len(other.index) < len(f.index):
+			return false
+		case len(other.index) == len(f.index) && (other.tagged || !f.tagged):
+			return false
+		}
+	}
+	return true
+}
+
+// endValue records that a complete value was read in the current container
+func (s *jsonScanner) endValue() {
+	top := s.top()
+	switch {
+	case top == nil:
+	case top.object:
+		top.expectKey = true
+	default:
+		top.index++
+	}
+}
+
+// classifyJSONError maps encoding/json errors to sentinel errors where
+// opts asks for it
+func classifyJSONError(err error, opts *Options) error {
+	if err == nil || !opts.StrictNumbers {
+		return err
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && strings.HasPrefix(typeErr.Value, "number") {
+		return fmt.Errorf("%w: field %s value %s does not fit %s",
+			ErrInvalidNumber, typeErr.Field, strings.TrimPrefix(typeErr.Value, "number "), typeErr.Type)
+	}
+	return err
+}
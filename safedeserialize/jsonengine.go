@@ -0,0 +1,92 @@
+package safedeserialize
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// JSONEngine is a JSON implementation the package decodes with. Engines
+// only parse: size limits, target validation, key and depth checks and
+// post-decode hooks are applied by the package regardless of the engine.
+//
+// Unmarshal must reject data after the top-level value, like
+// encoding/json.Unmarshal. It must not keep data once it returns: the
+// caller may modify data afterwards, so decoded strings and byte slices
+// have to be copies rather than slices of it. Engines that alias their
+// input by default must be configured to copy. Engine authors should run
+// jsonenginetest.TestEngine against their implementation.
+type JSONEngine interface {
+	Unmarshal(data []byte, v any) error
+	NewDecoder(r io.Reader) JSONDecoder
+}
+
+// JSONDecoder is the streaming decoder of a JSONEngine. Once the input is
+// exhausted, Decode must return io.EOF.
+type JSONDecoder interface {
+	Decode(v any) error
+	DisallowUnknownFields()
+	UseNumber()
+}
+
+// StdJSONEngine is the encoding/json engine used unless another one is
+// configured
+var StdJSONEngine JSONEngine = stdJSONEngine{}
+
+type stdJSONEngine struct{}
+
+func (stdJSONEngine) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (stdJSONEngine) NewDecoder(r io.Reader) JSONDecoder {
+	return json.NewDecoder(r)
+}
+
+// jsonEngineBox keeps the dynamic type stored in defaultJSONEngine constant
+type jsonEngineBox struct {
+	engine JSONEngine
+}
+
+var defaultJSONEngine atomic.Pointer[jsonEngineBox]
+
+// SetDefaultJSONEngine sets the engine used by decodes without
+// WithJSONEngine. A nil engine restores encoding/json. It is safe to call
+// concurrently with decoding, but is meant to be called once at startup.
+func SetDefaultJSONEngine(e JSONEngine) {
+	if e == nil {
+		defaultJSONEngine.Store(nil)
+		return
+	}
+	defaultJSONEngine.Store(&jsonEngineBox{engine: e})
+}
+
+// jsonEngine returns the engine opts decodes JSON with
+func (o *Options) jsonEngine() JSONEngine {
+	if o.JSONEngine != nil {
+		return o.JSONEngine
+	}
+	if box := defaultJSONEngine.Load(); box != nil {
+		return box.engine
+	}
+	return StdJSONEngine
+}
+
+// decodeSingleJSON decodes the only value of a stream into v, rejecting
+// any further data so that decoders match Unmarshal
+func decodeSingleJSON(decoder JSONDecoder, v any) error {
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+
+	var extra json.RawMessage
+	switch err := decoder.Decode(&extra); err {
+	case io.EOF:
+		return nil
+	case nil:
+		return fmt.Errorf("%w: another value follows", ErrTrailingData)
+	default:
+		return fmt.Errorf("%w: %w", ErrTrailingData, err)
+	}
+}
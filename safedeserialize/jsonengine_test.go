@@ -0,0 +1,81 @@
+package safedeserialize
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+)
+
+// countingEngine wraps encoding/json and counts the calls routed to it
+type countingEngine struct {
+	calls *atomic.Int64
+}
+
+func (e countingEngine) Unmarshal(data []byte, v any) error {
+	e.calls.Add(1)
+	return json.Unmarshal(data, v)
+}
+
+func (e countingEngine) NewDecoder(r io.Reader) JSONDecoder {
+	e.calls.Add(1)
+	return json.NewDecoder(r)
+}
+
+func TestJSONEngine(t *testing.T) {
+	data := []byte(`{"id":1,"name":"alice"}`)
+
+	t.Run("option", func(t *testing.T) {
+		engine := countingEngine{calls: new(atomic.Int64)}
+		for _, strict := range []bool{true, false} {
+			var user SimpleUser
+			if err := JSON(data, &user, WithJSONEngine(engine), WithStrictMode(strict)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if user.ID != 1 {
+				t.Errorf("unexpected result: %+v", user)
+			}
+		}
+		if got := engine.calls.Load(); got != 2 {
+			t.Errorf("expected 2 engine calls, got %d", got)
+		}
+	})
+
+	t.Run("default", func(t *testing.T) {
+		engine := countingEngine{calls: new(atomic.Int64)}
+		SetDefaultJSONEngine(engine)
+		defer SetDefaultJSONEngine(nil)
+
+		var user SimpleUser
+		if err := NewDecoder().JSON(data, &user); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := engine.calls.Load(); got != 1 {
+			t.Errorf("expected 1 engine call, got %d", got)
+		}
+
+		SetDefaultJSONEngine(nil)
+		if err := JSON(data, &user); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := engine.calls.Load(); got != 1 {
+			t.Errorf("engine still used after reset: %d calls", got)
+		}
+	})
+
+	t.Run("trailing data", func(t *testing.T) {
+		engines := map[string]JSONEngine{
+			"std":     StdJSONEngine,
+			"wrapped": countingEngine{calls: new(atomic.Int64)},
+		}
+		for name, engine := range engines {
+			for _, in := range []string{`{"id":1}{"id":2}`, `{"id":1} x`} {
+				var user SimpleUser
+				if err := JSON([]byte(in), &user, WithJSONEngine(engine)); !errors.Is(err, ErrTrailingData) {
+					t.Errorf("%s %q: expected ErrTrailingData, got %v", name, in, err)
+				}
+			}
+		}
+	})
+}
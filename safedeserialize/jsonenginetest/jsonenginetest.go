@@ -0,0 +1,164 @@
+// Package jsonenginetest provides a conformance suite for implementations
+// of safedeserialize.JSONEngine.
+//
+// Engine authors should call TestEngine from a test in their own package:
+//
+//	func TestEngine(t *testing.T) {
+//		jsonenginetest.TestEngine(t, myEngine{})
+//	}
+package jsonenginetest
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ravisastryk/go-safeinput/safedeserialize"
+)
+
+type user struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// TestEngine checks that e provides the semantics safedeserialize relies
+// on: strict mode must reject unknown fields and trailing data, both the
+// Unmarshal and the streaming paths must decode the same documents, and
+// decoded values must not change when the input is modified afterwards.
+func TestEngine(t *testing.T, e safedeserialize.JSONEngine) {
+	t.Helper()
+
+	t.Run("decodes", func(t *testing.T) { testDecodes(t, e) })
+	t.Run("unknown fields", func(t *testing.T) { testUnknownFields(t, e) })
+	t.Run("trailing data", func(t *testing.T) { testTrailingData(t, e) })
+	t.Run("use number", func(t *testing.T) { testUseNumber(t, e) })
+	t.Run("malformed", func(t *testing.T) { testMalformed(t, e) })
+	t.Run("decoder eof", func(t *testing.T) { testDecoderEOF(t, e) })
+	t.Run("input not retained", func(t *testing.T) { testInputNotRetained(t, e) })
+}
+
+func testDecodes(t *testing.T, e safedeserialize.JSONEngine) {
+	data := []byte(`{"id":1,"name":"alice","email":"alice@example.com"}`)
+	want := user{ID: 1, Name: "alice", Email: "alice@example.com"}
+
+	for _, strict := range []bool{true, false} {
+		var got user
+		err := safedeserialize.JSON(data, &got,
+			safedeserialize.WithJSONEngine(e), safedeserialize.WithStrictMode(strict))
+		if err != nil {
+			t.Fatalf("strict=%v: unexpected error: %v", strict, err)
+		}
+		if got != want {
+			t.Errorf("strict=%v: got %+v, want %+v", strict, got, want)
+		}
+	}
+}
+
+func testUnknownFields(t *testing.T, e safedeserialize.JSONEngine) {
+	data := []byte(`{"id":1,"admin":true}`)
+
+	var got user
+	if err := safedeserialize.JSON(data, &got, safedeserialize.WithJSONEngine(e)); err == nil {
+		t.Error("strict mode accepted an unknown field")
+	}
+	if err := safedeserialize.JSON(data, &got,
+		safedeserialize.WithJSONEngine(e), safedeserialize.WithStrictMode(false)); err != nil {
+		t.Errorf("non-strict mode rejected an unknown field: %v", err)
+	}
+}
+
+func testTrailingData(t *testing.T, e safedeserialize.JSONEngine) {
+	inputs := []string{
+		`{"id":1}{"id":2}`,
+		`{"id":1} x`,
+		`{"id":1}}`,
+	}
+	modes := []struct {
+		name string
+		opts []safedeserialize.Option
+	}{
+		{"strict", nil},
+		{"strict use number", []safedeserialize.Option{safedeserialize.WithUseNumber(true)}},
+		{"non-strict", []safedeserialize.Option{safedeserialize.WithStrictMode(false)}},
+	}
+
+	for _, mode := range modes {
+		for _, in := range inputs {
+			var got user
+			opts := append([]safedeserialize.Option{safedeserialize.WithJSONEngine(e)}, mode.opts...)
+			if err := safedeserialize.JSON([]byte(in), &got, opts...); err == nil {
+				t.Errorf("%s: accepted trailing data in %q", mode.name, in)
+			}
+		}
+
+		var got user
+		opts := append([]safedeserialize.Option{safedeserialize.WithJSONEngine(e)}, mode.opts...)
+		if err := safedeserialize.JSON([]byte("{\"id\":1}\n\t "), &got, opts...); err != nil {
+			t.Errorf("%s: rejected trailing whitespace: %v", mode.name, err)
+		}
+	}
+}
+
+func testUseNumber(t *testing.T, e safedeserialize.JSONEngine) {
+	var got map[string]any
+	err := safedeserialize.JSON([]byte(`{"n":12345678901234567890}`), &got,
+		safedeserialize.WithJSONEngine(e),
+		safedeserialize.WithAllowMapStringInterface(true),
+		safedeserialize.WithUseNumber(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	n, ok := got["n"].(json.Number)
+	if !ok || n.String() != "12345678901234567890" {
+		t.Errorf("got %#v, want json.Number 12345678901234567890", got["n"])
+	}
+}
+
+func testMalformed(t *testing.T, e safedeserialize.JSONEngine) {
+	inputs := []string{`{"id":`, `{"id":"x"}`, `[1,2]`, `{id:1}`}
+	for _, in := range inputs {
+		for _, strict := range []bool{true, false} {
+			var got user
+			err := safedeserialize.JSON([]byte(in), &got,
+				safedeserialize.WithJSONEngine(e), safedeserialize.WithStrictMode(strict))
+			if err == nil {
+				t.Errorf("strict=%v: accepted malformed input %q", strict, in)
+			}
+		}
+	}
+}
+
+func testInputNotRetained(t *testing.T, e safedeserialize.JSONEngine) {
+	const doc = `{"id":1,"name":"alice","email":"alice@example.com"}`
+	want := user{ID: 1, Name: "alice", Email: "alice@example.com"}
+
+	for _, strict := range []bool{true, false} {
+		data := []byte(doc)
+		var got user
+		err := safedeserialize.JSON(data, &got,
+			safedeserialize.WithJSONEngine(e), safedeserialize.WithStrictMode(strict))
+		if err != nil {
+			t.Fatalf("strict=%v: unexpected error: %v", strict, err)
+		}
+		for i := range data {
+			data[i] = 'X'
+		}
+		if got != want {
+			t.Errorf("strict=%v: decoded values changed with the input: %+v", strict, got)
+		}
+	}
+}
+
+func testDecoderEOF(t *testing.T, e safedeserialize.JSONEngine) {
+	decoder := e.NewDecoder(strings.NewReader(`{"id":1} `))
+	var got user
+	if err := decoder.Decode(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := decoder.Decode(&got); !errors.Is(err, io.EOF) {
+		t.Errorf("Decode at end of input returned %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,29 @@
+package jsonenginetest
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/ravisastryk/go-safeinput/safedeserialize"
+)
+
+// wrappedEngine is a non-default engine backed by encoding/json, so the
+// suite also exercises the engine-agnostic decode path
+type wrappedEngine struct{}
+
+func (wrappedEngine) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (wrappedEngine) NewDecoder(r io.Reader) safedeserialize.JSONDecoder {
+	return json.NewDecoder(r)
+}
+
+func TestStdJSONEngine(t *testing.T) {
+	TestEngine(t, safedeserialize.StdJSONEngine)
+}
+
+func TestWrappedEngine(t *testing.T) {
+	TestEngine(t, wrappedEngine{})
+}
@@ -0,0 +1,129 @@
+package safedeserialize
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// Outcomes reported to the metrics callback
+const (
+	OutcomeOK               = "ok"
+	OutcomeEmpty            = "empty"
+	OutcomeTooLarge         = "too_large"
+	OutcomeTooDeep          = "too_deep"
+	OutcomeUnknownField     = "unknown_field"
+	OutcomeDeniedField      = "denied_field"
+	OutcomeUnsafeTarget     = "unsafe_target"
+	OutcomeUnsafeContent    = "unsafe_content"
+	OutcomeInvalidSignature = "invalid_signature"
+	OutcomeInvalid          = "invalid"
+	OutcomeError            = "error"
+)
+
+// MetricsFunc receives one call per decode with the format, the outcome,
+// the number of input bytes and the time spent
+type MetricsFunc func(format string, outcome string, bytes int, dur time.Duration)
+
+// observe runs decode on data and reports its outcome to the metrics
+// callback and, for rejections, the audit logger. Without either it only
+// runs decode.
+func observe(format Format, data []byte, v any, opts *Options, decode func() error) error {
+	if opts.MetricsCallback == nil && opts.AuditLogger == nil {
+		return decode()
+	}
+
+	start := time.Now()
+	err := decode()
+	report(format, data, len(data), v, opts, err, time.Since(start))
+	return err
+}
+
+// observeReader is observe for reader paths, counting the bytes read from r
+// and keeping a prefix for the audit excerpt
+func observeReader(format Format, r io.Reader, v any, opts *Options, decode func(io.Reader) error) error {
+	if opts.MetricsCallback == nil && opts.AuditLogger == nil {
+		return decode(r)
+	}
+
+	counter := &countingReader{r: r}
+	if opts.AuditLogger != nil {
+		counter.keep = auditExcerptBytes
+	}
+	start := time.Now()
+	err := decode(counter)
+	report(format, counter.prefix, counter.n, v, opts, err, time.Since(start))
+	return err
+}
+
+// report delivers the result of one decode to the configured callbacks
+func report(format Format, data []byte, size int, v any, opts *Options, err error, dur time.Duration) {
+	kind := outcome(err)
+	if opts.MetricsCallback != nil {
+		opts.MetricsCallback(string(format), kind, size, dur)
+	}
+	if err != nil && opts.AuditLogger != nil {
+		opts.AuditLogger(newAuditEvent(format, kind, err, data, size, v, opts))
+	}
+}
+
+// countingReader counts the bytes read through it, keeping the first keep
+// bytes in prefix
+type countingReader struct {
+	r      io.Reader
+	n      int
+	keep   int
+	prefix []byte
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	if missing := c.keep - len(c.prefix); missing > 0 {
+		c.prefix = append(c.prefix, p[:min(n, missing)]...)
+	}
+	return n, err
+}
+
+// outcome classifies a decode error for metrics
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeOK
+	case errors.Is(err, ErrEmptyData):
+		return OutcomeEmpty
+	case errors.Is(err, ErrDataTooLarge), errors.Is(err, ErrTooManyElements), errors.Is(err, ErrTooManyDocuments),
+		errors.Is(err, ErrTooManyAttributes), errors.Is(err, ErrStringTooLong), errors.Is(err, ErrTooManyMapKeys),
+		errors.Is(err, ErrTooManyRecords):
+		return OutcomeTooLarge
+	case errors.Is(err, ErrMaxDepthExceeded):
+		return OutcomeTooDeep
+	case errors.Is(err, ErrUnknownXMLElement), errors.Is(err, ErrUnknownXMLAttribute), isUnknownFieldError(err):
+		return OutcomeUnknownField
+	case errors.Is(err, ErrDeniedField), errors.Is(err, ErrFieldNotAllowed):
+		return OutcomeDeniedField
+	case errors.Is(err, ErrNilTarget), errors.Is(err, ErrNotPointer), errors.Is(err, ErrInterfaceTarget),
+		errors.Is(err, ErrMapInterface), errors.Is(err, ErrMapInterfaceKey), errors.Is(err, ErrSliceInterface),
+		errors.Is(err, ErrTypeNotAllowed), errors.Is(err, ErrUnmarshalerNotAllowed):
+		return OutcomeUnsafeTarget
+	case errors.Is(err, ErrDTDNotAllowed), errors.Is(err, ErrEntityNotAllowed), errors.Is(err, ErrCharsetNotAllowed),
+		errors.Is(err, ErrProcInstNotAllowed), errors.Is(err, ErrNamespaceNotAllowed), errors.Is(err, ErrNonFiniteNumber):
+		return OutcomeUnsafeContent
+	case errors.Is(err, ErrInvalidSignature):
+		return OutcomeInvalidSignature
+	case errors.Is(err, ErrInvalidNumber), errors.Is(err, ErrTrailingData), errors.Is(err, ErrValidationFailed),
+		errors.Is(err, ErrSanitizationFailed), errors.Is(err, ErrInvalidTime), errors.Is(err, ErrTimeOutOfRange):
+		return OutcomeInvalid
+	default:
+		return OutcomeError
+	}
+}
+
+// isUnknownFieldError reports whether err is encoding/json's or yaml.v3's
+// strict-mode rejection of an unmapped field
+func isUnknownFieldError(err error) bool {
+	msg := err.Error()
+	return strings.HasPrefix(msg, "json: unknown field ") ||
+		(strings.Contains(msg, "field ") && strings.Contains(msg, " not found in type "))
+}
@@ -0,0 +1,70 @@
+package safedeserialize
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type metricsRecord struct {
+	format, outcome string
+	bytes           int
+}
+
+func TestMetricsCallback(t *testing.T) {
+	var records []metricsRecord
+	decoder := NewDecoder(WithMetricsCallback(func(format, outcome string, bytes int, dur time.Duration) {
+		if dur < 0 {
+			t.Errorf("negative duration %v", dur)
+		}
+		records = append(records, metricsRecord{format, outcome, bytes})
+	}))
+
+	valid := `{"id":1,"name":"alice","email":"alice@example.com"}`
+	tests := []struct {
+		name   string
+		decode func() error
+		want   metricsRecord
+	}{
+		{"ok", func() error { return decoder.JSON([]byte(valid), &SimpleUser{}) },
+			metricsRecord{"json", OutcomeOK, len(valid)}},
+		{"reader", func() error { return decoder.JSONReader(strings.NewReader(valid), &SimpleUser{}) },
+			metricsRecord{"json", OutcomeOK, len(valid)}},
+		{"too large", func() error { return decoder.JSON([]byte(valid), &SimpleUser{}, WithMaxSize(8)) },
+			metricsRecord{"json", OutcomeTooLarge, len(valid)}},
+		{"too deep", func() error { return decoder.JSON([]byte(`{"id":[[[1]]]}`), &SimpleUser{}, WithMaxDepth(2)) },
+			metricsRecord{"json", OutcomeTooDeep, 14}},
+		{"unknown field", func() error { return decoder.YAML([]byte("nickname: al\n"), &SimpleUser{}) },
+			metricsRecord{"yaml", OutcomeUnknownField, 13}},
+		{"unsafe target", func() error { return decoder.XML([]byte("<a/>"), new(any)) },
+			metricsRecord{"xml", OutcomeUnsafeTarget, 4}},
+		{"syntax", func() error { return decoder.JSON([]byte(`{`), &SimpleUser{}) },
+			metricsRecord{"json", OutcomeError, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records = nil
+			err := tt.decode()
+			if (err == nil) != (tt.want.outcome == OutcomeOK) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(records) != 1 || records[0] != tt.want {
+				t.Errorf("expected one call %+v, got %+v", tt.want, records)
+			}
+		})
+	}
+}
+
+func BenchmarkJSONWithMetrics(b *testing.B) {
+	data := []byte(`{"id":1,"name":"alice","email":"alice@example.com"}`)
+	opt := WithMetricsCallback(func(string, string, int, time.Duration) {})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var user SimpleUser
+		if err := JSON(data, &user, opt); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
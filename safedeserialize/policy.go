@@ -0,0 +1,116 @@
+package safedeserialize
+
+import "fmt"
+
+// Bounds accepted for policy limits
+const (
+	maxPolicySize  = 1 << 30 // 1GB
+	maxPolicyDepth = 1024
+)
+
+// Policy declares deserialization limits in a reviewable config file.
+// Omitted settings keep the package defaults.
+type Policy struct {
+	// MaxSize is the maximum input size in bytes, 1 to 1GB; 0 keeps the
+	// default
+	MaxSize int64 `json:"max_size" yaml:"max_size"`
+
+	// MaxDepth is the maximum nesting depth, 1 to 1024, or 0 to keep the
+	// default; setting it makes gob decodes fail with ErrUnsupportedOption
+	MaxDepth int `json:"max_depth" yaml:"max_depth"`
+
+	// StrictMode defaults to true when omitted
+	StrictMode *bool `json:"strict_mode" yaml:"strict_mode"`
+
+	// AllowedTypes are type names as rendered by reflect.Type.String
+	AllowedTypes []string `json:"allowed_types" yaml:"allowed_types"`
+
+	// DeniedFields are keys rejected at any depth of JSON and YAML input
+	DeniedFields []string `json:"denied_fields" yaml:"denied_fields"`
+
+	// Formats enables or disables each format; omitted formats are enabled
+	Formats PolicyFormats `json:"formats" yaml:"formats"`
+}
+
+// PolicyFormats holds the per-format toggles of a Policy
+type PolicyFormats struct {
+	JSON *bool `json:"json" yaml:"json"`
+	YAML *bool `json:"yaml" yaml:"yaml"`
+	XML  *bool `json:"xml" yaml:"xml"`
+	Gob  *bool `json:"gob" yaml:"gob"`
+}
+
+// LoadPolicy parses a YAML (or JSON) policy document, rejecting unknown
+// keys and out-of-range values with ErrInvalidPolicy
+func LoadPolicy(data []byte) (*Policy, error) {
+	var p Policy
+	if err := YAML(data, &p, WithMaxSize(1<<16), WithStrictMode(true)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// validate checks that the limits of p are within range
+func (p *Policy) validate() error {
+	if p.MaxSize < 0 || p.MaxSize > maxPolicySize {
+		return fmt.Errorf("%w: max_size %d out of range 0..%d (0 keeps the default)", ErrInvalidPolicy, p.MaxSize, maxPolicySize)
+	}
+	if p.MaxDepth < 0 || p.MaxDepth > maxPolicyDepth {
+		return fmt.Errorf("%w: max_depth %d out of range 0..%d (0 keeps the default)", ErrInvalidPolicy, p.MaxDepth, maxPolicyDepth)
+	}
+	if len(p.allowedFormats()) == 0 {
+		return fmt.Errorf("%w: every format is disabled", ErrInvalidPolicy)
+	}
+	return nil
+}
+
+// allowedFormats returns the formats the policy enables
+func (p *Policy) allowedFormats() []Format {
+	var formats []Format
+	for _, f := range []struct {
+		format  Format
+		enabled *bool
+	}{
+		{FormatJSON, p.Formats.JSON},
+		{FormatYAML, p.Formats.YAML},
+		{FormatXML, p.Formats.XML},
+		{FormatGob, p.Formats.Gob},
+	} {
+		if f.enabled == nil || *f.enabled {
+			formats = append(formats, f.format)
+		}
+	}
+	return formats
+}
+
+// Options returns the options that apply the policy
+func (p *Policy) Options() []Option {
+	var opts []Option
+	if p.MaxSize > 0 {
+		opts = append(opts, WithMaxSize(p.MaxSize))
+	}
+	if p.MaxDepth > 0 {
+		opts = append(opts, WithMaxDepth(p.MaxDepth))
+	}
+	if p.StrictMode != nil {
+		opts = append(opts, WithStrictMode(*p.StrictMode))
+	}
+	if len(p.AllowedTypes) > 0 {
+		opts = append(opts, WithAllowedTypes(p.AllowedTypes...))
+	}
+	if len(p.DeniedFields) > 0 {
+		opts = append(opts, WithDeniedFields(p.DeniedFields...))
+	}
+	if formats := p.allowedFormats(); len(formats) < 4 {
+		opts = append(opts, WithAllowedFormats(formats...))
+	}
+	return opts
+}
+
+// Decoder returns a decoder configured by the policy
+func (p *Policy) Decoder() *Decoder {
+	return NewDecoder(p.Options()...)
+}
@@ -0,0 +1,104 @@
+package safedeserialize
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	policy, err := LoadPolicy([]byte(`
+max_size: 128
+max_depth: 4
+strict_mode: true
+allowed_types: [safedeserialize.SimpleUser]
+denied_fields: [role]
+formats:
+  xml: false
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	opts := policy.Decoder().Options()
+	if opts.MaxSize != 128 || opts.MaxDepth != 4 || !opts.StrictMode {
+		t.Errorf("unexpected limits: %+v", opts)
+	}
+
+	decoder := policy.Decoder()
+	tests := []struct {
+		name    string
+		decode  func() error
+		wantErr error
+	}{
+		{"allowed", func() error { return decoder.JSON([]byte(`{"id":1}`), &SimpleUser{}) }, nil},
+		{"type", func() error { return decoder.JSON([]byte(`{}`), &NestedConfig{}) }, ErrTypeNotAllowed},
+		{"denied field", func() error { return decoder.YAML([]byte("role: admin\n"), &SimpleUser{}) }, ErrDeniedField},
+		{"disabled format", func() error { return decoder.XML([]byte("<SimpleUser/>"), &SimpleUser{}) }, ErrFormatNotAllowed},
+		{"size", func() error { return decoder.JSON(make([]byte, 129), &SimpleUser{}) }, ErrDataTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.decode(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadPolicyDefaults(t *testing.T) {
+	policy, err := LoadPolicy([]byte(`{}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(policy.Options()) != 0 {
+		t.Errorf("expected an empty policy to keep the defaults")
+	}
+
+	policy, err = LoadPolicy([]byte("max_size: 0\nmax_depth: 0\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(policy.Options()) != 0 {
+		t.Errorf("expected zero limits to keep the defaults")
+	}
+}
+
+func TestLoadPolicyInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"unknown key", "max_sizes: 10\n"},
+		{"unknown format", "formats:\n  toml: true\n"},
+		{"negative size", "max_size: -1\n"},
+		{"size too large", "max_size: 4294967296\n"},
+		{"depth too large", "max_depth: 5000\n"},
+		{"negative depth", "max_depth: -1\n"},
+		{"all formats disabled", "formats: {json: false, yaml: false, xml: false, gob: false}\n"},
+		{"wrong type", "strict_mode: sometimes\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadPolicy([]byte(tt.data))
+			if !errors.Is(err, ErrInvalidPolicy) {
+				t.Errorf("expected ErrInvalidPolicy, got %v", err)
+			}
+			if err != nil && strings.Contains(err.Error(), "out of range 1..") {
+				t.Errorf("range excludes the 0 that keeps the default: %v", err)
+			}
+		})
+	}
+}
+
+func TestExamplePolicy(t *testing.T) {
+	data, err := os.ReadFile("examples/policy.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPolicy(data); err != nil {
+		t.Errorf("example policy does not load: %v", err)
+	}
+}
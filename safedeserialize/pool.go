@@ -0,0 +1,108 @@
+package safedeserialize
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// maxPooledBufferSize caps the buffers returned to bufferPool so that one
+// large payload does not pin its memory for the life of the process
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// readLimited reads r into a pooled buffer and passes its contents to
+// decode. Input longer than opts.MaxSize is rejected with ErrDataTooLarge
+// before decode is called. The bytes are only valid during decode.
+// Callers must have checked ExpectedSize against MaxSize.
+func readLimited(r io.Reader, opts *Options, decode func(data []byte) error) error {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBufferSize {
+			buf.Reset()
+			bufferPool.Put(buf)
+		}
+	}()
+
+	// Size the buffer for the declared length up front. A lying declaration
+	// only changes the initial capacity: the read below is still capped.
+	if opts.ExpectedSize > 0 {
+		buf.Grow(int(opts.ExpectedSize) + bytes.MinRead)
+	}
+
+	if _, err := buf.ReadFrom(io.LimitReader(r, opts.MaxSize)); err != nil {
+		return fmt.Errorf("safedeserialize: read error: %w", err)
+	}
+
+	// Reading stopped at the limit: probe for one more byte to tell a
+	// payload of exactly MaxSize from a larger one
+	if int64(buf.Len()) == opts.MaxSize {
+		var probe [1]byte
+		n, err := io.ReadFull(r, probe[:])
+		if n > 0 {
+			return fmt.Errorf("%w: input exceeds limit %d", ErrDataTooLarge, opts.MaxSize)
+		}
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("safedeserialize: read error: %w", err)
+		}
+	}
+	return decode(buf.Bytes())
+}
+
+// retainsInput reports whether the codec decoding format may keep slices
+// of its input after it returns, so that it must not be handed a pooled
+// buffer. Only the built-in codecs with encoding/json are known to copy.
+func retainsInput(format Format, opts *Options) bool {
+	if _, builtin := builtinCodecs[format]; !builtin {
+		return true
+	}
+	_, std := opts.jsonEngine().(stdJSONEngine)
+	return format == FormatJSON && !std
+}
+
+// strictJSONDecoder is a json.Decoder with DisallowUnknownFields set that
+// reads from a resettable in-memory reader, so it can be reused
+type strictJSONDecoder struct {
+	reader  bytes.Reader
+	decoder *json.Decoder
+}
+
+var strictJSONDecoderPool = sync.Pool{
+	New: func() any {
+		d := new(strictJSONDecoder)
+		d.decoder = json.NewDecoder(&d.reader)
+		d.decoder.DisallowUnknownFields()
+		return d
+	},
+}
+
+// decodeStrictJSON decodes data into v rejecting unknown fields and trailing
+// data, using a pooled decoder
+func decodeStrictJSON(data []byte, v any) error {
+	d := strictJSONDecoderPool.Get().(*strictJSONDecoder)
+	d.reader.Reset(data)
+
+	start := d.decoder.InputOffset()
+	if err := d.decoder.Decode(v); err != nil {
+		return err
+	}
+
+	// A decoder may only be reused once it has consumed all of data: after
+	// an error or with trailing bytes still buffered, its state would leak
+	// into the next call
+	consumed := d.decoder.InputOffset() - start
+	if consumed == int64(len(data)) {
+		d.reader.Reset(nil)
+		strictJSONDecoderPool.Put(d)
+		return nil
+	}
+	if rest := bytes.TrimLeft(data[consumed:], " \t\r\n"); len(rest) > 0 {
+		return fmt.Errorf("%w: at offset %d", ErrTrailingData, int64(len(data)-len(rest)))
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package safedeserialize
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPooledDecodersAreNotPoisoned(t *testing.T) {
+	inputs := []struct {
+		data    string
+		wantErr bool
+	}{
+		{`{"id":1,"name":"a"}`, false},
+		{`{"id":2,"name":`, true},
+		{`{"id":3} `, false},
+		{`{"id":4}{"id":5}`, true},
+		{`{"id":6,"extra":1}`, true},
+		{`{"id":7,"name":"g"}`, false},
+	}
+
+	for round := 0; round < 3; round++ {
+		for _, in := range inputs {
+			for _, reader := range []bool{false, true} {
+				var user SimpleUser
+				var err error
+				if reader {
+					err = JSONReader(strings.NewReader(in.data), &user)
+				} else {
+					err = JSON([]byte(in.data), &user)
+				}
+				if (err != nil) != in.wantErr {
+					t.Fatalf("%s (reader %v): unexpected error %v", in.data, reader, err)
+				}
+				if err == nil && user.ID == 0 {
+					t.Fatalf("%s (reader %v): target not populated", in.data, reader)
+				}
+			}
+		}
+	}
+}
+
+// retainingEngine keeps the input of the last Unmarshal in Raw, as engines
+// that avoid copying strings do
+type retainingEngine struct{}
+
+type retained struct {
+	Name string `json:"name"`
+	Raw  []byte `json:"-"`
+}
+
+func (retainingEngine) Unmarshal(data []byte, v any) error {
+	if r, ok := v.(*retained); ok {
+		r.Raw = data
+	}
+	return StdJSONEngine.Unmarshal(data, v)
+}
+
+func (retainingEngine) NewDecoder(r io.Reader) JSONDecoder {
+	return StdJSONEngine.NewDecoder(r)
+}
+
+func TestPooledBufferNotRetained(t *testing.T) {
+	decode := func(data string) retained {
+		var got retained
+		err := JSONReader(strings.NewReader(data), &got, WithJSONEngine(retainingEngine{}), WithStrictMode(false))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return got
+	}
+
+	first := decode(`{"name":"first-value"}`)
+	decode(`{"name":"XXXXXXXXXXXXXXXX"}`)
+	if string(first.Raw) != `{"name":"first-value"}` {
+		t.Errorf("engine input was reused: %q", first.Raw)
+	}
+}
+
+// readCounter counts the bytes read through it
+type readCounter struct {
+	r io.Reader
+	n int
+}
+
+func (c *readCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestExpectedSize(t *testing.T) {
+	data := `{"id":1,"name":"alice","email":"alice@example.com"}`
+
+	t.Run("declared too large", func(t *testing.T) {
+		readers := map[string]func(r io.Reader, opts ...Option) error{
+			"json": func(r io.Reader, opts ...Option) error { return JSONReader(r, &SimpleUser{}, opts...) },
+			"yaml": func(r io.Reader, opts ...Option) error { return YAMLReader(r, &SimpleUser{}, opts...) },
+			"gob":  func(r io.Reader, opts ...Option) error { return GobReader(r, &SimpleUser{}, opts...) },
+		}
+		for name, decode := range readers {
+			body := &readCounter{r: strings.NewReader(data)}
+			err := decode(body, WithMaxSize(1<<10), WithExpectedSize(50<<20))
+			if !errors.Is(err, ErrDataTooLarge) {
+				t.Errorf("%s: expected ErrDataTooLarge, got %v", name, err)
+			}
+			if body.n != 0 {
+				t.Errorf("%s: read %d bytes before rejecting", name, body.n)
+			}
+		}
+	})
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int64
+		wantErr  error
+	}{
+		{"exact", data, int64(len(data)), nil},
+		{"unknown", data, -1, nil},
+		{"understated", data, 4, nil},
+		{"overstated", data, 1 << 10, nil},
+		{"understated and too large", strings.Repeat(" ", 2<<10) + data, 4, ErrDataTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var user SimpleUser
+			err := JSONReader(strings.NewReader(tt.body), &user, WithMaxSize(1<<10), WithExpectedSize(tt.expected))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && user.ID != 1 {
+				t.Errorf("unexpected result: %+v", user)
+			}
+		})
+	}
+}
+
+func TestExpectedSizeHTTP(t *testing.T) {
+	var body *readCounter
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = &readCounter{r: r.Body}
+		var user SimpleUser
+		err := JSONReader(body, &user, WithMaxSize(1<<10), WithExpectedSize(r.ContentLength))
+		switch {
+		case errors.Is(err, ErrDataTooLarge):
+			w.Header().Set("Connection", "close")
+			http.Error(w, "Request too large", http.StatusRequestEntityTooLarge)
+		case err != nil:
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantRead bool
+	}{
+		{"declared too large", `{"id":1}` + strings.Repeat(" ", 4<<10), http.StatusRequestEntityTooLarge, false},
+		{"within limit", `{"id":1}`, http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d", tt.wantCode, rec.Code)
+			}
+			if read := body.n > 0; read != tt.wantRead {
+				t.Errorf("expected body read %v, read %d bytes", tt.wantRead, body.n)
+			}
+		})
+	}
+}
+
+// largeBody is a 512KB JSON document
+func largeBody() []byte {
+	return []byte(`{"id":1,"name":"` + strings.Repeat("x", 512<<10-len(`{"id":1,"name":""}`)) + `"}`)
+}
+
+func BenchmarkJSONReader512KB(b *testing.B) {
+	data := largeBody()
+	r := bytes.NewReader(data)
+	var u SimpleUser
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Reset(data)
+		if err := JSONReader(r, &u); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJSONReader512KBExpectedSize(b *testing.B) {
+	data := largeBody()
+	r := bytes.NewReader(data)
+	var u SimpleUser
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Reset(data)
+		if err := JSONReader(r, &u, WithExpectedSize(int64(len(data)))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
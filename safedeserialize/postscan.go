@@ -0,0 +1,84 @@
+package safedeserialize
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// interfaceScanner walks a decoded value looking for interface-typed
+// containers that the options do not permit
+type interfaceScanner struct {
+	opts    *Options
+	visited map[uintptr]bool
+}
+
+// scanInterfaces checks every map and slice reachable from the decoded
+// target v, including those held in interface values, against
+// AllowMapStringInterface, AllowMapInterfaceKey and AllowSliceInterface
+func scanInterfaces(v any, opts *Options) error {
+	rv := reflect.ValueOf(v).Elem()
+	s := &interfaceScanner{opts: opts, visited: make(map[uintptr]bool)}
+	return s.value(rv, rv.Type().String())
+}
+
+// value checks v, which was reached via path, and everything below it
+func (s *interfaceScanner) value(v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() || s.visited[v.Pointer()] {
+			return nil
+		}
+		s.visited[v.Pointer()] = true
+		return s.value(v.Elem(), path)
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return s.value(v.Elem(), path)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Interface && !s.opts.AllowSliceInterface {
+			return fmt.Errorf("%w: %s holds %s", ErrSliceInterface, path, v.Type())
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := s.value(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if err := s.checkMap(v.Type(), path); err != nil {
+			return err
+		}
+		if v.IsNil() || s.visited[v.Pointer()] {
+			return nil
+		}
+		s.visited[v.Pointer()] = true
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := s.value(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key())); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			if err := s.value(v.Field(i), path+"."+t.Field(i).Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkMap rejects map types with interface keys or values unless allowed
+func (s *interfaceScanner) checkMap(t reflect.Type, path string) error {
+	if t.Key().Kind() == reflect.Interface && !s.opts.AllowMapInterfaceKey {
+		return fmt.Errorf("%w: %s holds %s", ErrMapInterfaceKey, path, t)
+	}
+	if t.Elem().Kind() == reflect.Interface && !s.opts.AllowMapStringInterface {
+		return fmt.Errorf("%w: %s holds %s", ErrMapInterface, path, t)
+	}
+	return nil
+}
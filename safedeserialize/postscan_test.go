@@ -0,0 +1,78 @@
+package safedeserialize
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// looseEnvelope has an any-typed field, which only non-strict mode accepts
+type looseEnvelope struct {
+	Kind string `json:"kind" yaml:"kind"`
+	Data any    `json:"data" yaml:"data"`
+}
+
+// scanNode can be made cyclic
+type scanNode struct {
+	Name string    `json:"name"`
+	Next *scanNode `json:"next"`
+}
+
+func TestPostDecodeScan(t *testing.T) {
+	loose := []Option{WithStrictMode(false), WithPostDecodeScan(true)}
+
+	tests := []struct {
+		name     string
+		decode   func() error
+		wantErr  error
+		wantPath string
+	}{
+		{"scalar in interface", func() error {
+			return JSON([]byte(`{"kind":"a","data":"x"}`), &looseEnvelope{}, loose...)
+		}, nil, ""},
+		{"map in interface", func() error {
+			return JSON([]byte(`{"kind":"a","data":{"admin":true}}`), &looseEnvelope{}, loose...)
+		}, ErrMapInterface, "looseEnvelope.Data"},
+		{"slice in interface", func() error {
+			return JSON([]byte(`{"kind":"a","data":[1,2]}`), &looseEnvelope{}, loose...)
+		}, ErrSliceInterface, "looseEnvelope.Data"},
+		{"yaml map in interface", func() error {
+			return YAML([]byte("kind: a\ndata:\n  admin: true\n"), &looseEnvelope{}, loose...)
+		}, ErrMapInterface, "looseEnvelope.Data"},
+		{"nested slice in allowed map", func() error {
+			var m map[string]any
+			return JSON([]byte(`{"a":{"b":[1]}}`), &m, WithAllowMapStringInterface(true), WithPostDecodeScan(true))
+		}, ErrSliceInterface, "[a][b]"},
+		{"everything allowed", func() error {
+			var m map[string]any
+			return JSON([]byte(`{"a":{"b":[1]}}`), &m, WithAllowMapStringInterface(true),
+				WithAllowSliceInterface(true), WithPostDecodeScan(true))
+		}, nil, ""},
+		{"scan off", func() error {
+			return JSON([]byte(`{"kind":"a","data":{"admin":true}}`), &looseEnvelope{}, WithStrictMode(false))
+		}, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.decode()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.wantPath) {
+				t.Errorf("expected path %q in %v", tt.wantPath, err)
+			}
+		})
+	}
+}
+
+func TestPostDecodeScanCycle(t *testing.T) {
+	n := &scanNode{Name: "a"}
+	n.Next = n
+	if err := JSON([]byte(`{"name":"b"}`), n, WithPostDecodeScan(true)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n.Name != "b" || n.Next != n {
+		t.Errorf("unexpected result: %+v", n)
+	}
+}
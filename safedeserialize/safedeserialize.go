@@ -32,15 +32,17 @@ package safedeserialize
 import (
 	"bytes"
 	"encoding/gob"
-	"encoding/json"
-	"encoding/xml"
-	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"reflect"
 	"slices"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/ravisastryk/go-safeinput"
+	"github.com/ravisastryk/go-safeinput/path"
 	"gopkg.in/yaml.v3"
 )
 
@@ -49,38 +51,162 @@ const Version = "1.0.0"
 
 // Default configuration values
 const (
-	DefaultMaxSize  = 1 << 20 // 1MB
-	DefaultMaxDepth = 32
+	DefaultMaxSize          = 1 << 20 // 1MB
+	DefaultMaxDepth         = 32
+	DefaultMaxYAMLDocuments = 1
 )
 
 // Common errors returned by safedeserialize functions
 var (
 	// ErrDataTooLarge is returned when input data exceeds MaxSize
-	ErrDataTooLarge = errors.New("safedeserialize: data exceeds maximum allowed size")
+	ErrDataTooLarge = securityError("safedeserialize: data exceeds maximum allowed size")
 
 	// ErrNilTarget is returned when the deserialization target is nil
-	ErrNilTarget = errors.New("safedeserialize: target cannot be nil")
+	ErrNilTarget = securityError("safedeserialize: target cannot be nil")
 
 	// ErrNotPointer is returned when target is not a pointer
-	ErrNotPointer = errors.New("safedeserialize: target must be a pointer")
+	ErrNotPointer = securityError("safedeserialize: target must be a pointer")
 
 	// ErrInterfaceTarget is returned when deserializing into any
-	ErrInterfaceTarget = errors.New("safedeserialize: cannot deserialize into any type - use concrete struct")
+	ErrInterfaceTarget = securityError("safedeserialize: cannot deserialize into any type - use concrete struct")
 
 	// ErrMapInterface is returned when deserializing into map[string]any
-	ErrMapInterface = errors.New("safedeserialize: cannot deserialize into map with any values")
+	ErrMapInterface = securityError("safedeserialize: cannot deserialize into map with any values")
+
+	// ErrMapInterfaceKey is returned when deserializing into a map with any keys
+	ErrMapInterfaceKey = securityError("safedeserialize: cannot deserialize into map with any keys")
 
 	// ErrSliceInterface is returned when deserializing into []any
-	ErrSliceInterface = errors.New("safedeserialize: cannot deserialize into slice of any")
+	ErrSliceInterface = securityError("safedeserialize: cannot deserialize into slice of any")
 
 	// ErrTypeNotAllowed is returned when type is not in the allowed list
-	ErrTypeNotAllowed = errors.New("safedeserialize: type not in allowed types list")
+	ErrTypeNotAllowed = securityError("safedeserialize: type not in allowed types list")
 
 	// ErrMaxDepthExceeded is returned when nesting depth exceeds MaxDepth
-	ErrMaxDepthExceeded = errors.New("safedeserialize: maximum nesting depth exceeded")
+	ErrMaxDepthExceeded = securityError("safedeserialize: maximum nesting depth exceeded")
 
 	// ErrEmptyData is returned when input data is empty
-	ErrEmptyData = errors.New("safedeserialize: input data is empty")
+	ErrEmptyData = malformedError("safedeserialize: input data is empty")
+
+	// ErrInvalidNumber is returned in StrictNumbers mode when a numeric value
+	// is fractional or out of range for its target field
+	ErrInvalidNumber = malformedError("safedeserialize: number does not fit target field")
+
+	// ErrInvalidTime is returned when a time.Time value does not match the
+	// time policy's layouts or epoch setting
+	ErrInvalidTime = malformedError("safedeserialize: time value not allowed")
+
+	// ErrTimeOutOfRange is returned when a time.Time value falls outside
+	// TimeMin and TimeMax
+	ErrTimeOutOfRange = securityError("safedeserialize: time value out of range")
+
+	// ErrNonFiniteNumber is returned when input contains a NaN or infinite number
+	ErrNonFiniteNumber = securityError("safedeserialize: non-finite number not allowed")
+
+	// ErrDeniedField is returned when input contains a key listed in DeniedFields
+	ErrDeniedField = securityError("safedeserialize: denied field in input")
+
+	// ErrFieldNotAllowed is returned when a top-level key is not listed in AllowedFields
+	ErrFieldNotAllowed = securityError("safedeserialize: field not in allowed fields list")
+
+	// ErrDTDNotAllowed is returned when XML input contains a DOCTYPE or DTD declaration
+	ErrDTDNotAllowed = securityError("safedeserialize: XML DTD declarations are not allowed")
+
+	// ErrEntityNotAllowed is returned when XML input references a non-predefined entity
+	ErrEntityNotAllowed = securityError("safedeserialize: XML entity reference not allowed")
+
+	// ErrCharsetNotAllowed is returned when XML input declares a charset that is not allowed
+	ErrCharsetNotAllowed = securityError("safedeserialize: XML charset not allowed")
+
+	// ErrProcInstNotAllowed is returned when XML input contains a processing
+	// instruction other than the XML declaration
+	ErrProcInstNotAllowed = securityError("safedeserialize: XML processing instruction not allowed")
+
+	// ErrUnknownXMLElement is returned in strict mode when XML input contains
+	// an element the target struct does not map
+	ErrUnknownXMLElement = malformedError("safedeserialize: unknown XML element")
+
+	// ErrUnknownXMLAttribute is returned in strict mode when XML input contains
+	// an attribute the target struct does not map
+	ErrUnknownXMLAttribute = malformedError("safedeserialize: unknown XML attribute")
+
+	// ErrTooManyElements is returned when input contains more elements than MaxElements
+	ErrTooManyElements = securityError("safedeserialize: element count exceeds limit")
+
+	// ErrTooManyDocuments is returned when YAML input holds more documents
+	// than MaxYAMLDocuments
+	ErrTooManyDocuments = securityError("safedeserialize: YAML document count exceeds limit")
+
+	// ErrTooManyRecords is returned when a stream carries more messages
+	// than MaxRecords
+	ErrTooManyRecords = securityError("safedeserialize: stream record count exceeds limit")
+
+	// ErrTooManyAttributes is returned when an XML element has more attributes than MaxAttributes
+	ErrTooManyAttributes = securityError("safedeserialize: attribute count exceeds limit")
+
+	// ErrStringTooLong is returned when a string value exceeds MaxStringLength
+	ErrStringTooLong = securityError("safedeserialize: string length exceeds limit")
+
+	// ErrTooManyMapKeys is returned when an object decoded into a map has
+	// more keys than MaxMapKeys
+	ErrTooManyMapKeys = securityError("safedeserialize: map key count exceeds limit")
+
+	// ErrNamespaceNotAllowed is returned when an XML element is not in an allowed namespace
+	ErrNamespaceNotAllowed = securityError("safedeserialize: XML namespace not allowed")
+
+	// ErrUnmarshalerNotAllowed is returned in strict mode when a type reachable
+	// from the target has a custom unmarshaler that has not been allowed
+	ErrUnmarshalerNotAllowed = securityError("safedeserialize: custom unmarshaler not allowed")
+
+	// ErrSanitizationFailed is returned when a decoded string cannot be
+	// sanitized, or would be changed in SanitizeReject mode
+	ErrSanitizationFailed = securityError("safedeserialize: string sanitization failed")
+
+	// ErrInvalidSignature is returned when a signed envelope fails HMAC verification
+	ErrInvalidSignature = securityError("safedeserialize: invalid signature")
+
+	// ErrHMACKeyRequired is returned by VerifyAndDecode when no key was set with WithHMAC
+	ErrHMACKeyRequired = securityError("safedeserialize: HMAC key not configured")
+
+	// ErrUnknownFormat is returned when a format name is not recognized
+	ErrUnknownFormat = malformedError("safedeserialize: unknown format")
+
+	// ErrFormatRegistered is returned by RegisterFormat when the name is
+	// already taken by a built-in or registered format
+	ErrFormatRegistered = malformedError("safedeserialize: format already registered")
+
+	// ErrRegistryFrozen is the panic value when a frozen TypeRegistry is modified
+	ErrRegistryFrozen = securityError("safedeserialize: type registry is frozen")
+
+	// ErrFormatNotAllowed is returned when a format is not in AllowedFormats
+	ErrFormatNotAllowed = securityError("safedeserialize: format not allowed")
+
+	// ErrUnsupportedOption is returned when a limit was configured that the
+	// format being decoded cannot enforce
+	ErrUnsupportedOption = securityError("safedeserialize: option not supported for format")
+
+	// ErrInvalidPolicy is returned by LoadPolicy for malformed or out-of-range policies
+	ErrInvalidPolicy = malformedError("safedeserialize: invalid policy")
+
+	// ErrValidationFailed wraps errors returned by Validate methods and the
+	// Validator option, distinguishing them from parse failures
+	ErrValidationFailed = malformedError("safedeserialize: validation failed")
+
+	// ErrTrailingData is returned when JSON input continues after the
+	// top-level value
+	ErrTrailingData = malformedError("safedeserialize: unexpected data after top-level value")
+
+	// ErrFileAccess is returned by DecodeFile when the path is rejected or
+	// the file cannot be opened or read, as opposed to invalid contents
+	ErrFileAccess = malformedError("safedeserialize: cannot read file")
+
+	// ErrInvalidOptions is returned by NewDecoderStrict for conflicting or
+	// pointless option combinations
+	ErrInvalidOptions = malformedError("safedeserialize: invalid options")
+
+	// ErrInvalidDefault is returned with DefaultTags when a default:"..."
+	// tag cannot be converted to its field's type
+	ErrInvalidDefault = malformedError("safedeserialize: invalid default tag")
 )
 
 // Options configures the behavior of safe deserialization
@@ -89,27 +215,250 @@ type Options struct {
 	// Default: 1MB (1 << 20)
 	MaxSize int64
 
-	// MaxDepth is the maximum allowed nesting depth
+	// ExpectedSize is the input length declared by the caller, typically an
+	// HTTP Content-Length. Reader paths reject input declared larger than
+	// MaxSize before reading anything, and otherwise size their read buffer
+	// for it. The declaration is not trusted: reads stay capped at MaxSize.
+	// Default: 0 (unknown)
+	ExpectedSize int64
+
+	// MaxDepth is the maximum allowed nesting depth, enforced for JSON, YAML
+	// and XML in strict and non-strict mode. JSON and YAML count nested
+	// objects and arrays, XML counts nested elements. Gob cannot enforce it,
+	// so gob decodes fail with ErrUnsupportedOption once WithMaxDepth is used.
 	// Default: 32
 	MaxDepth int
 
-	// AllowedTypes is an optional whitelist of type names
+	// AllowedTypes is an optional whitelist of type names as rendered by
+	// reflect.Type.String. Names are ambiguous: models.User matches every
+	// package named models, so prefer Registry, which compares identities.
 	// If empty, all concrete (non-interface) types are allowed
 	// Example: []string{"main.User", "main.Config"}
 	AllowedTypes []string
 
+	// Registry is an optional whitelist of types compared by identity,
+	// set by TypeRegistry.Option; it is consulted on every decode
+	// Default: nil
+	Registry *TypeRegistry
+
+	// AllowedPackages is an optional whitelist of package prefixes: a named
+	// type is allowed when PkgPath()+"."+Name() starts with one of them.
+	// End a prefix with "." to match a single package.
+	// Example: []string{"github.com/acme/api/types."}
+	AllowedPackages []string
+
+	// RequireRegisteredGraph requires every named struct type reachable from
+	// the target (fields, slice/array elements, map keys and values) to be
+	// allowed by Registry, AllowedTypes or AllowedPackages, not just the
+	// target itself. Intended for Gob.
+	// Default: false
+	RequireRegisteredGraph bool
+
 	// StrictMode enables additional validation:
-	// - JSON: DisallowUnknownFields
+	// - JSON: DisallowUnknownFields and reject data after the top-level value
+	// - YAML: KnownFields
+	// - XML: Reject elements and attributes the target struct does not map
 	// - Depth checking before parsing
 	StrictMode bool
 
+	// UseNumber decodes JSON numbers into any-typed values as json.Number
+	// instead of float64, preserving the precision of large integers
+	// Default: false
+	UseNumber bool
+
+	// JSONEngine decodes JSON instead of the default engine
+	// Default: nil (the engine set with SetDefaultJSONEngine, encoding/json
+	// unless changed)
+	JSONEngine JSONEngine
+
+	// StrictNumbers rejects fractional values targeted at integer fields and
+	// integers exceeding the range of their target field with ErrInvalidNumber
+	// Default: false
+	StrictNumbers bool
+
+	// AllowNonFiniteNumbers permits NaN and ±Inf values such as YAML's .nan and .inf
+	// Default: false (blocked, they poison comparisons and re-serialization)
+	AllowNonFiniteNumbers bool
+
+	// TimeLayouts lists the layouts time.Time values in JSON and YAML input
+	// must match; setting it, EpochSeconds or a time range replaces the
+	// decoders' own time parsing for every time.Time in the target
+	// Default: empty (RFC 3339 once a time policy is set)
+	TimeLayouts []string
+
+	// EpochSeconds accepts whole Unix seconds for time.Time values
+	// Default: false
+	EpochSeconds bool
+
+	// TimeMin and TimeMax reject time.Time values outside the range with
+	// ErrTimeOutOfRange; a zero bound is open
+	// Default: zero (unbounded)
+	TimeMin time.Time
+	TimeMax time.Time
+
+	// DeniedFields lists keys that are rejected at any nesting level of
+	// JSON and YAML input, regardless of the target type or StrictMode
+	// Matching is case-insensitive, mirroring encoding/json
+	// Example: []string{"is_admin", "role", "password_hash"}
+	DeniedFields []string
+
+	// AllowedFields is an optional whitelist of top-level keys in JSON and
+	// YAML input, checked before StrictMode's unknown field detection
+	// If empty, or if the document's top level is not an object, all keys
+	// are allowed; matching is case-insensitive
+	AllowedFields []string
+
+	// AllowedUnmarshalers lists types whose custom unmarshalers (UnmarshalJSON,
+	// UnmarshalYAML, UnmarshalText, ...) may run in strict mode, in addition
+	// to standard library types such as time.Time
+	// Default: empty (other custom unmarshalers are rejected in strict mode)
+	AllowedUnmarshalers []reflect.Type
+
+	// AllowedFormats is an optional whitelist of formats that may be decoded
+	// If empty, all formats are allowed
+	AllowedFormats []Format
+
 	// AllowMapStringInterface permits map[string]any targets
 	// Default: false (blocked for security)
 	AllowMapStringInterface bool
 
+	// AllowMapInterfaceKey permits maps keyed by any, such as map[any]string
+	// Default: false (blocked for security)
+	AllowMapInterfaceKey bool
+
 	// AllowSliceInterface permits []any targets
 	// Default: false (blocked for security)
 	AllowSliceInterface bool
+
+	// AllowDTD permits DOCTYPE and DTD declarations in XML input
+	// Default: false (blocked to prevent entity expansion attacks)
+	AllowDTD bool
+
+	// MaxElements is the maximum number of elements (nodes) a document may contain
+	// Currently enforced for XML; 0 means unlimited
+	// Default: 0
+	MaxElements int
+
+	// MaxYAMLDocuments is the maximum number of documents YAML input may
+	// hold; only the first is decoded into the target, and 0 means the default
+	// Default: 1
+	MaxYAMLDocuments int
+
+	// MaxRecords is the maximum number of messages a GobStream decodes;
+	// MaxSize applies to each message separately, and 0 means unlimited
+	// Default: 0
+	MaxRecords int
+
+	// LengthPrefix makes a GobStream read each message as a frame preceded
+	// by its length as a 4-byte big-endian integer, so frames larger than
+	// MaxSize are rejected before any of them is read
+	// Default: false
+	LengthPrefix bool
+
+	// MaxAttributes is the maximum number of attributes a single XML element may carry
+	// 0 means unlimited
+	// Default: 0
+	MaxAttributes int
+
+	// MaxStringLength is the maximum length in bytes of a single string value
+	// Enforced for XML attribute values and for the text and CDATA directly
+	// inside an element, added up across sections, and for the keys and
+	// string values of JSON and YAML objects decoded into a Go map;
+	// 0 means unlimited
+	// Default: 0
+	MaxStringLength int
+
+	// MaxMapKeys is the maximum number of keys a JSON object or YAML mapping
+	// decoded into a Go map may hold; 0 means unlimited
+	// Default: 0
+	MaxMapKeys int
+
+	// AllowedXMLNamespaces is an optional whitelist of XML namespace URIs
+	// If empty, elements in any namespace are allowed; include "" to
+	// permit elements without a namespace
+	AllowedXMLNamespaces []string
+
+	// AllowedCharsets lists additional charsets XML input may declare
+	// UTF-8 and ASCII are always allowed; listed charsets are read as-is,
+	// so only add labels whose documents are valid UTF-8
+	AllowedCharsets []string
+
+	// Sanitizer, if set, runs every string decoded into the target through
+	// SanitizeContext; fields tagged sanitize:"html" and similar use that
+	// context instead, and sanitize:"-" leaves a field unchanged
+	// Default: nil
+	Sanitizer       *safeinput.Sanitizer
+	SanitizeContext safeinput.Context
+
+	// SanitizeMode selects whether strings the sanitizer would change are
+	// replaced or rejected with ErrSanitizationFailed
+	// Default: SanitizeReplace
+	SanitizeMode SanitizeMode
+
+	// HMACKey and HMACHash authenticate signed envelopes in VerifyAndDecode
+	// before their payload is parsed; a nil HMACHash selects SHA-256
+	// Default: nil
+	HMACKey  []byte
+	HMACHash func() hash.Hash
+
+	// MetricsCallback, if set, is called synchronously once per decode with
+	// the outcome; it runs on the decode path, so it should return quickly
+	// Default: nil
+	MetricsCallback MetricsFunc
+
+	// AuditLogger, if set, is called synchronously with an AuditEvent for
+	// every rejected decode; it is never called on success
+	// Default: nil
+	AuditLogger func(event AuditEvent)
+
+	// ZeroTarget resets the target before decoding, so values left in a
+	// reused target do not survive fields the new input omits: structs
+	// are zeroed, maps cleared and slices truncated
+	// Default: false
+	ZeroTarget bool
+
+	// AtomicDecode decodes into a freshly allocated value of the target's
+	// type and copies it into the target only after decoding and the
+	// post-decode hooks succeed, so a failed decode leaves the target
+	// untouched. It costs one extra allocation and copy of the value per
+	// decode, and fields the input omits end up zero as with ZeroTarget.
+	// Default: false
+	AtomicDecode bool
+
+	// PostDecodeScan walks the decoded value and rejects any reachable map
+	// or slice with interface keys, values or elements that the Allow
+	// options do not permit, including those held in interface values. It
+	// catches what the static target checks cannot see, such as any-typed
+	// fields in non-strict mode, at the cost of a reflection walk per decode.
+	// Default: false
+	PostDecodeScan bool
+
+	// PathSanitizer, if set, validates file paths passed to DecodeFile and
+	// the per-format file helpers before they are opened
+	// Default: nil
+	PathSanitizer *path.Sanitizer
+
+	// Validator is called with the decoded target after every successful
+	// decode, after the target's own Validate method if it has one
+	// Default: nil
+	Validator func(v any) error
+
+	// Defaults is called with the decoded target after every successful
+	// decode, before Validate and Validator, to fill in unset fields
+	// Default: nil
+	Defaults func(v any)
+
+	// DefaultTags sets struct fields tagged default:"..." that are zero
+	// and absent from JSON and YAML input to the tag's value
+	// Default: false
+	DefaultTags bool
+
+	// rejected records option values that were ignored, for NewDecoderStrict
+	rejected []string
+
+	// maxDepthSet records that MaxDepth was set with WithMaxDepth, so
+	// formats that cannot enforce it refuse to decode
+	maxDepthSet bool
 }
 
 // Option is a function that modifies Options
@@ -120,17 +469,38 @@ func DefaultOptions() *Options {
 	return &Options{
 		MaxSize:                 DefaultMaxSize,
 		MaxDepth:                DefaultMaxDepth,
+		MaxYAMLDocuments:        DefaultMaxYAMLDocuments,
 		StrictMode:              true,
 		AllowMapStringInterface: false,
 		AllowSliceInterface:     false,
 	}
 }
 
+// clone returns a copy of o whose slices do not share storage with o, so
+// options that append to them cannot affect the original
+func (o *Options) clone() *Options {
+	c := *o
+	c.AllowedTypes = slices.Clone(o.AllowedTypes)
+	c.AllowedPackages = slices.Clone(o.AllowedPackages)
+	c.DeniedFields = slices.Clone(o.DeniedFields)
+	c.AllowedFields = slices.Clone(o.AllowedFields)
+	c.AllowedUnmarshalers = slices.Clone(o.AllowedUnmarshalers)
+	c.AllowedXMLNamespaces = slices.Clone(o.AllowedXMLNamespaces)
+	c.AllowedCharsets = slices.Clone(o.AllowedCharsets)
+	c.AllowedFormats = slices.Clone(o.AllowedFormats)
+	c.TimeLayouts = slices.Clone(o.TimeLayouts)
+	c.HMACKey = slices.Clone(o.HMACKey)
+	c.rejected = slices.Clone(o.rejected)
+	return &c
+}
+
 // WithMaxSize sets the maximum allowed data size
 func WithMaxSize(size int64) Option {
 	return func(o *Options) {
 		if size > 0 {
 			o.MaxSize = size
+		} else {
+			o.reject("WithMaxSize(%d): size must be positive", size)
 		}
 	}
 }
@@ -140,17 +510,137 @@ func WithMaxDepth(depth int) Option {
 	return func(o *Options) {
 		if depth > 0 {
 			o.MaxDepth = depth
+			o.maxDepthSet = true
+		} else {
+			o.reject("WithMaxDepth(%d): depth must be positive", depth)
 		}
 	}
 }
 
-// WithAllowedTypes sets the whitelist of allowed type names
+// WithExpectedSize declares the input length for reader paths, e.g.
+// r.ContentLength; n <= 0 means unknown
+func WithExpectedSize(n int64) Option {
+	return func(o *Options) {
+		o.ExpectedSize = max(n, 0)
+	}
+}
+
+// WithMaxElements sets the maximum number of elements a document may contain
+func WithMaxElements(n int) Option {
+	return func(o *Options) {
+		if n > 0 {
+			o.MaxElements = n
+		} else {
+			o.reject("WithMaxElements(%d): n must be positive", n)
+		}
+	}
+}
+
+// WithMaxYAMLDocuments sets the maximum number of documents YAML input may hold
+func WithMaxYAMLDocuments(n int) Option {
+	return func(o *Options) {
+		if n > 0 {
+			o.MaxYAMLDocuments = n
+		} else {
+			o.reject("WithMaxYAMLDocuments(%d): n must be positive", n)
+		}
+	}
+}
+
+// yamlDocumentLimit returns MaxYAMLDocuments, or the default if it is unset
+func (o *Options) yamlDocumentLimit() int {
+	if o.MaxYAMLDocuments > 0 {
+		return o.MaxYAMLDocuments
+	}
+	return DefaultMaxYAMLDocuments
+}
+
+// WithMaxRecords sets the maximum number of messages a GobStream decodes
+func WithMaxRecords(n int) Option {
+	return func(o *Options) {
+		if n > 0 {
+			o.MaxRecords = n
+		} else {
+			o.reject("WithMaxRecords(%d): n must be positive", n)
+		}
+	}
+}
+
+// WithLengthPrefix makes a GobStream read length-prefixed frames
+func WithLengthPrefix(enable bool) Option {
+	return func(o *Options) {
+		o.LengthPrefix = enable
+	}
+}
+
+// WithMaxAttributes sets the maximum number of attributes per XML element
+func WithMaxAttributes(n int) Option {
+	return func(o *Options) {
+		if n > 0 {
+			o.MaxAttributes = n
+		} else {
+			o.reject("WithMaxAttributes(%d): n must be positive", n)
+		}
+	}
+}
+
+// WithMaxStringLength sets the maximum length in bytes of a single string value
+func WithMaxStringLength(n int) Option {
+	return func(o *Options) {
+		if n > 0 {
+			o.MaxStringLength = n
+		} else {
+			o.reject("WithMaxStringLength(%d): n must be positive", n)
+		}
+	}
+}
+
+// WithMaxMapKeys sets the maximum number of keys of an object decoded into a map
+func WithMaxMapKeys(n int) Option {
+	return func(o *Options) {
+		if n > 0 {
+			o.MaxMapKeys = n
+		} else {
+			o.reject("WithMaxMapKeys(%d): n must be positive", n)
+		}
+	}
+}
+
+// WithAllowedTypes sets the whitelist of allowed type names.
+// Type names are ambiguous across packages; prefer TypeRegistry.Option.
 func WithAllowedTypes(types ...string) Option {
 	return func(o *Options) {
 		o.AllowedTypes = types
 	}
 }
 
+// WithDefaultRegistry validates targets against DefaultRegistry, rejecting
+// types that were not registered with Register
+func WithDefaultRegistry(use bool) Option {
+	return func(o *Options) {
+		if use {
+			o.Registry = DefaultRegistry
+		} else if o.Registry == DefaultRegistry {
+			o.Registry = nil
+		}
+	}
+}
+
+// WithAllowedPackages adds package prefixes to the type whitelist
+func WithAllowedPackages(prefixes ...string) Option {
+	return func(o *Options) {
+		o.AllowedPackages = append(o.AllowedPackages, prefixes...)
+	}
+}
+
+// WithRequireRegisteredGraph requires every named struct type reachable
+// from the target to be in the allowed types list
+func WithRequireRegisteredGraph(require bool) Option {
+	return func(o *Options) {
+		o.RequireRegisteredGraph = require
+	}
+}
+
 // WithStrictMode enables or disables strict parsing
 func WithStrictMode(strict bool) Option {
 	return func(o *Options) {
@@ -158,6 +648,93 @@ func WithStrictMode(strict bool) Option {
 	}
 }
 
+// WithUseNumber decodes JSON numbers as json.Number instead of float64
+func WithUseNumber(use bool) Option {
+	return func(o *Options) {
+		o.UseNumber = use
+	}
+}
+
+// WithJSONEngine decodes JSON with e instead of the default engine
+func WithJSONEngine(e JSONEngine) Option {
+	return func(o *Options) {
+		o.JSONEngine = e
+	}
+}
+
+// WithStrictNumbers rejects fractional and out-of-range values for integer fields
+func WithStrictNumbers(strict bool) Option {
+	return func(o *Options) {
+		o.StrictNumbers = strict
+	}
+}
+
+// WithAllowNonFiniteNumbers permits NaN and ±Inf values in input
+func WithAllowNonFiniteNumbers(allow bool) Option {
+	return func(o *Options) {
+		o.AllowNonFiniteNumbers = allow
+	}
+}
+
+// WithTimeLayouts sets the layouts time.Time values must match, in the
+// notation of time.Parse
+func WithTimeLayouts(layouts ...string) Option {
+	return func(o *Options) {
+		o.TimeLayouts = append(o.TimeLayouts, layouts...)
+	}
+}
+
+// WithEpochSeconds accepts whole Unix seconds for time.Time values
+func WithEpochSeconds(allow bool) Option {
+	return func(o *Options) {
+		o.EpochSeconds = allow
+	}
+}
+
+// WithTimeRange rejects time.Time values before min or after max; a zero
+// bound leaves that side open
+func WithTimeRange(min, max time.Time) Option {
+	return func(o *Options) {
+		if !min.IsZero() && !max.IsZero() && min.After(max) {
+			o.reject("WithTimeRange(%s, %s): min is after max", min.Format(time.RFC3339), max.Format(time.RFC3339))
+			return
+		}
+		o.TimeMin, o.TimeMax = min, max
+	}
+}
+
+// WithDeniedFields rejects input containing any of the given keys
+func WithDeniedFields(names ...string) Option {
+	return func(o *Options) {
+		o.DeniedFields = append(o.DeniedFields, names...)
+	}
+}
+
+// WithAllowedFields sets the whitelist of top-level keys input may contain
+func WithAllowedFields(names ...string) Option {
+	return func(o *Options) {
+		o.AllowedFields = append(o.AllowedFields, names...)
+	}
+}
+
+// WithAllowedUnmarshalers trusts the custom unmarshalers of the types of the
+// given values; pointers are dereferenced, so both T{} and &T{} work
+// Use with caution - the unmarshalers run on untrusted input
+func WithAllowedUnmarshalers(types ...any) Option {
+	return func(o *Options) {
+		for _, v := range types {
+			o.AllowedUnmarshalers = append(o.AllowedUnmarshalers, derefType(reflect.TypeOf(v)))
+		}
+	}
+}
+
+// WithAllowedFormats sets the whitelist of formats that may be decoded
+func WithAllowedFormats(formats ...Format) Option {
+	return func(o *Options) {
+		o.AllowedFormats = append(o.AllowedFormats, formats...)
+	}
+}
+
 // WithAllowMapStringInterface permits map[string]any targets
 // Use with caution - this reduces security
 func WithAllowMapStringInterface(allow bool) Option {
@@ -166,6 +743,14 @@ func WithAllowMapStringInterface(allow bool) Option {
 	}
 }
 
+// WithAllowMapInterfaceKey permits maps keyed by any
+// Use with caution - this reduces security
+func WithAllowMapInterfaceKey(allow bool) Option {
+	return func(o *Options) {
+		o.AllowMapInterfaceKey = allow
+	}
+}
+
 // WithAllowSliceInterface permits []any targets
 // Use with caution - this reduces security
 func WithAllowSliceInterface(allow bool) Option {
@@ -174,13 +759,128 @@ func WithAllowSliceInterface(allow bool) Option {
 	}
 }
 
+// WithAllowDTD permits DOCTYPE and DTD declarations in XML input
+// Use with caution - internal entity declarations enable expansion attacks
+func WithAllowDTD(allow bool) Option {
+	return func(o *Options) {
+		o.AllowDTD = allow
+	}
+}
+
+// WithAllowedXMLNamespaces extends the whitelist of namespace URIs XML elements may use
+func WithAllowedXMLNamespaces(uris ...string) Option {
+	return func(o *Options) {
+		o.AllowedXMLNamespaces = append(o.AllowedXMLNamespaces, uris...)
+	}
+}
+
+// WithAllowedCharsets extends the charsets XML input may declare
+func WithAllowedCharsets(charsets ...string) Option {
+	return func(o *Options) {
+		o.AllowedCharsets = append(o.AllowedCharsets, charsets...)
+	}
+}
+
+// WithStringSanitizer runs every decoded string through s for ctx
+func WithStringSanitizer(s *safeinput.Sanitizer, ctx safeinput.Context) Option {
+	return func(o *Options) {
+		o.Sanitizer = s
+		o.SanitizeContext = ctx
+	}
+}
+
+// WithSanitizeMode sets whether unsafe strings are replaced or rejected
+func WithSanitizeMode(mode SanitizeMode) Option {
+	return func(o *Options) {
+		o.SanitizeMode = mode
+	}
+}
+
+// WithHMAC sets the key and hash function VerifyAndDecode authenticates with
+func WithHMAC(key []byte, hashFn func() hash.Hash) Option {
+	return func(o *Options) {
+		o.HMACKey = key
+		o.HMACHash = hashFn
+	}
+}
+
+// WithMetricsCallback reports the format, outcome, input size and duration
+// of every decode to fn. fn is called synchronously and must not block.
+func WithMetricsCallback(fn MetricsFunc) Option {
+	return func(o *Options) {
+		o.MetricsCallback = fn
+	}
+}
+
+// WithAuditLogger records every rejected decode, including a sanitized
+// excerpt of the payload, with fn
+func WithAuditLogger(fn func(event AuditEvent)) Option {
+	return func(o *Options) {
+		o.AuditLogger = fn
+	}
+}
+
+// WithZeroTarget resets the target to its zero value before decoding
+func WithZeroTarget(zero bool) Option {
+	return func(o *Options) {
+		o.ZeroTarget = zero
+	}
+}
+
+// WithAtomicDecode leaves the target unchanged unless the whole decode,
+// including post-decode validation, succeeds
+func WithAtomicDecode(atomic bool) Option {
+	return func(o *Options) {
+		o.AtomicDecode = atomic
+	}
+}
+
+// WithPostDecodeScan rejects decoded values that hold interface-typed maps
+// or slices not permitted by the Allow options
+func WithPostDecodeScan(scan bool) Option {
+	return func(o *Options) {
+		o.PostDecodeScan = scan
+	}
+}
+
+// WithPathSanitizer validates file paths passed to DecodeFile with s,
+// rejecting traversal and paths outside its base directory
+func WithPathSanitizer(s *path.Sanitizer) Option {
+	return func(o *Options) {
+		o.PathSanitizer = s
+	}
+}
+
+// WithValidator sets a function that checks every successfully decoded target
+func WithValidator(fn func(v any) error) Option {
+	return func(o *Options) {
+		o.Validator = fn
+	}
+}
+
+// WithDefaults sets a function that fills in unset fields of every
+// successfully decoded target before it is validated
+func WithDefaults(fn func(v any)) Option {
+	return func(o *Options) {
+		o.Defaults = fn
+	}
+}
+
+// WithDefaultTags applies default:"..." struct tags to fields that JSON
+// and YAML input leaves unset
+func WithDefaultTags(enable bool) Option {
+	return func(o *Options) {
+		o.DefaultTags = enable
+	}
+}
+
 // JSON safely unmarshals JSON data into a concrete type
 func JSON(data []byte, v any, opts ...Option) error {
 	options := DefaultOptions()
 	for _, opt := range opts {
 		opt(options)
 	}
-	return jsonUnmarshal(data, v, options)
+	return unmarshalFormat(FormatJSON, data, v, options)
 }
 
 // JSONReader safely decodes JSON from an io.Reader
@@ -189,7 +889,7 @@ func JSONReader(r io.Reader, v any, opts ...Option) error {
 	for _, opt := range opts {
 		opt(options)
 	}
-	return jsonDecode(r, v, options)
+	return decodeFormat(FormatJSON, r, v, options)
 }
 
 // YAML safely unmarshals YAML data into a concrete type
@@ -198,7 +898,7 @@ func YAML(data []byte, v any, opts ...Option) error {
 	for _, opt := range opts {
 		opt(options)
 	}
-	return yamlUnmarshal(data, v, options)
+	return unmarshalFormat(FormatYAML, data, v, options)
 }
 
 // YAMLReader safely decodes YAML from an io.Reader
@@ -207,7 +907,7 @@ func YAMLReader(r io.Reader, v any, opts ...Option) error {
 	for _, opt := range opts {
 		opt(options)
 	}
-	return yamlDecode(r, v, options)
+	return decodeFormat(FormatYAML, r, v, options)
 }
 
 // XML safely unmarshals XML data into a concrete type
@@ -216,7 +916,7 @@ func XML(data []byte, v any, opts ...Option) error {
 	for _, opt := range opts {
 		opt(options)
 	}
-	return xmlUnmarshal(data, v, options)
+	return unmarshalFormat(FormatXML, data, v, options)
 }
 
 // XMLReader safely decodes XML from an io.Reader
@@ -225,16 +925,18 @@ func XMLReader(r io.Reader, v any, opts ...Option) error {
 	for _, opt := range opts {
 		opt(options)
 	}
-	return xmlDecode(r, v, options)
+	return decodeFormat(FormatXML, r, v, options)
 }
 
-// Gob safely decodes Gob data into a concrete type
+// Gob safely decodes Gob data into a concrete type.
+// For payloads produced by your own services, prefer VerifyAndDecode with
+// FormatGob so that only authenticated bytes reach the gob decoder.
 func Gob(data []byte, v any, opts ...Option) error {
 	options := DefaultOptions()
 	for _, opt := range opts {
 		opt(options)
 	}
-	return gobDecode(bytes.NewReader(data), v, options)
+	return unmarshalFormat(FormatGob, data, v, options)
 }
 
 // GobReader safely decodes Gob from an io.Reader
@@ -243,133 +945,153 @@ func GobReader(r io.Reader, v any, opts ...Option) error {
 	for _, opt := range opts {
 		opt(options)
 	}
-	return gobDecode(r, v, options)
+	return decodeFormat(FormatGob, r, v, options)
 }
 
-// Internal implementations
+// Internal implementations. Format allow-listing, size limits, target
+// validation and post-decode hooks are applied around these by the codec
+// pipeline in format.go.
 
 func jsonUnmarshal(data []byte, v any, opts *Options) error {
-	if len(data) == 0 {
-		return ErrEmptyData
-	}
-
-	if int64(len(data)) > opts.MaxSize {
-		return fmt.Errorf("%w: size %d exceeds limit %d", ErrDataTooLarge, len(data), opts.MaxSize)
+	if err := checkDepth(measureJSONDepth(data), opts); err != nil {
+		return err
 	}
 
-	if err := validateTarget(v, opts); err != nil {
+	data, present, err := scanJSON(data, v, opts)
+	if err != nil {
 		return err
 	}
 
-	if opts.StrictMode {
-		if depth := measureJSONDepth(data); depth > opts.MaxDepth {
-			return fmt.Errorf("%w: depth %d exceeds limit %d", ErrMaxDepthExceeded, depth, opts.MaxDepth)
-		}
+	if err := classifyJSONError(decodeJSON(data, v, opts), opts); err != nil {
+		return err
 	}
-
-	if opts.StrictMode {
-		decoder := json.NewDecoder(bytes.NewReader(data))
-		decoder.DisallowUnknownFields()
-		return decoder.Decode(v)
+	if present != nil {
+		return applyDefaultTags(v, present)
 	}
-
-	return json.Unmarshal(data, v)
+	return nil
 }
 
-func jsonDecode(r io.Reader, v any, opts *Options) error {
-	if err := validateTarget(v, opts); err != nil {
-		return err
+func decodeJSON(data []byte, v any, opts *Options) error {
+	engine := opts.jsonEngine()
+	if _, std := engine.(stdJSONEngine); std && opts.StrictMode && !opts.UseNumber {
+		return decodeStrictJSON(data, v)
 	}
 
-	limitedReader := io.LimitReader(r, opts.MaxSize+1)
-	data, err := io.ReadAll(limitedReader)
-	if err != nil {
-		return fmt.Errorf("safedeserialize: read error: %w", err)
+	if opts.StrictMode || opts.UseNumber {
+		decoder := engine.NewDecoder(bytes.NewReader(data))
+		if opts.StrictMode {
+			decoder.DisallowUnknownFields()
+		}
+		if opts.UseNumber {
+			decoder.UseNumber()
+		}
+		return decodeSingleJSON(decoder, v)
 	}
 
-	return jsonUnmarshal(data, v, opts)
+	return engine.Unmarshal(data, v)
 }
 
 func yamlUnmarshal(data []byte, v any, opts *Options) error {
-	if len(data) == 0 {
-		return ErrEmptyData
-	}
-
-	if int64(len(data)) > opts.MaxSize {
-		return fmt.Errorf("%w: size %d exceeds limit %d", ErrDataTooLarge, len(data), opts.MaxSize)
-	}
-
-	if err := validateTarget(v, opts); err != nil {
+	data, root, err := scanYAML(data, v, opts)
+	if err != nil {
 		return err
 	}
 
 	if opts.StrictMode {
 		decoder := yaml.NewDecoder(bytes.NewReader(data))
 		decoder.KnownFields(true)
-		return decoder.Decode(v)
+		err = decoder.Decode(v)
+	} else {
+		err = yaml.Unmarshal(data, v)
 	}
-
-	return yaml.Unmarshal(data, v)
-}
-
-func yamlDecode(r io.Reader, v any, opts *Options) error {
-	if err := validateTarget(v, opts); err != nil {
+	if err != nil || !needsDefaultTags(v, opts) {
 		return err
 	}
 
-	limitedReader := io.LimitReader(r, opts.MaxSize+1)
-	data, err := io.ReadAll(limitedReader)
-	if err != nil {
-		return fmt.Errorf("safedeserialize: read error: %w", err)
+	present := make(fieldSet)
+	if root != nil {
+		yamlPresence(root, reflect.TypeOf(v), "", present)
 	}
-
-	return yamlUnmarshal(data, v, opts)
+	return applyDefaultTags(v, present)
 }
 
 func xmlUnmarshal(data []byte, v any, opts *Options) error {
-	if len(data) == 0 {
-		return ErrEmptyData
+	if needsTimeScan(v, opts) {
+		return fmt.Errorf("%w: the time policy cannot be enforced for XML", ErrUnsupportedOption)
 	}
-
-	if int64(len(data)) > opts.MaxSize {
-		return fmt.Errorf("%w: size %d exceeds limit %d", ErrDataTooLarge, len(data), opts.MaxSize)
+	if needsDefaultTags(v, opts) {
+		return fmt.Errorf("%w: default tags cannot be applied for XML", ErrUnsupportedOption)
 	}
-
-	if err := validateTarget(v, opts); err != nil {
+	if err := scanXML(data, v, opts); err != nil {
 		return err
 	}
 
-	if opts.StrictMode {
-		decoder := xml.NewDecoder(bytes.NewReader(data))
-		decoder.Strict = true
-		return decoder.Decode(v)
-	}
+	return newXMLDecoder(data, opts).Decode(v)
+}
 
-	return xml.Unmarshal(data, v)
+func gobUnmarshal(data []byte, v any, opts *Options) error {
+	return gobDecode(bytes.NewReader(data), v, opts)
 }
 
-func xmlDecode(r io.Reader, v any, opts *Options) error {
-	if err := validateTarget(v, opts); err != nil {
+func gobDecode(r io.Reader, v any, opts *Options) error {
+	if err := checkGobOptions(v, opts); err != nil {
+		return err
+	}
+	limitedReader := &maxBytesReader{r: r, remaining: opts.MaxSize, limit: opts.MaxSize}
+	decoder := gob.NewDecoder(limitedReader)
+	if err := decoder.Decode(v); err != nil {
+		// A fresh gob decoder only reports a bare io.EOF when the stream
+		// ended before the first message
+		if err == io.EOF {
+			return ErrEmptyData
+		}
 		return err
 	}
+	return nil
+}
 
-	limitedReader := io.LimitReader(r, opts.MaxSize+1)
-	data, err := io.ReadAll(limitedReader)
-	if err != nil {
-		return fmt.Errorf("safedeserialize: read error: %w", err)
+// checkGobOptions rejects limits that gob streams cannot enforce for
+// target v; a nil v skips the checks that depend on the target type
+func checkGobOptions(v any, opts *Options) error {
+	if opts.maxDepthSet {
+		return fmt.Errorf("%w: MaxDepth cannot be enforced for gob", ErrUnsupportedOption)
+	}
+	if needsTimeScan(v, opts) {
+		return fmt.Errorf("%w: the time policy cannot be enforced for gob", ErrUnsupportedOption)
+	}
+	if needsDefaultTags(v, opts) {
+		return fmt.Errorf("%w: default tags cannot be applied for gob", ErrUnsupportedOption)
 	}
+	return nil
+}
 
-	return xmlUnmarshal(data, v, opts)
+// maxBytesReader reads from r and fails with ErrDataTooLarge once more
+// than limit bytes have been read, instead of truncating the stream
+type maxBytesReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+	err       error
 }
 
-func gobDecode(r io.Reader, v any, opts *Options) error {
-	if err := validateTarget(v, opts); err != nil {
-		return err
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	if int64(len(p)) > m.remaining+1 {
+		p = p[:m.remaining+1]
 	}
 
-	limitedReader := io.LimitReader(r, opts.MaxSize)
-	decoder := gob.NewDecoder(limitedReader)
-	return decoder.Decode(v)
+	n, err := m.r.Read(p)
+	if int64(n) <= m.remaining {
+		m.remaining -= int64(n)
+		return n, err
+	}
+
+	n = int(m.remaining)
+	m.remaining = 0
+	m.err = fmt.Errorf("%w: stream exceeds limit %d", ErrDataTooLarge, m.limit)
+	return n, m.err
 }
 
 // validateTarget ensures the deserialization target is safe
@@ -394,13 +1116,69 @@ func validateTarget(v any, opts *Options) error {
 		return err
 	}
 
+	if opts.RequireRegisteredGraph {
+		if err := validateRegisteredGraph(elem.Type(), elem.Type().String(), opts, make(map[reflect.Type]bool)); err != nil {
+			return err
+		}
+	}
+
+	if opts.StrictMode {
+		return validateTargetType(elem.Type(), opts)
+	}
+
+	return nil
+}
+
+// strictTypeKey identifies a target type together with the options that
+// affect its strict-mode type check
+type strictTypeKey struct {
+	t                       reflect.Type
+	allowMapStringInterface bool
+	allowMapInterfaceKey    bool
+	allowSliceInterface     bool
+}
+
+// validTypes caches the strict-mode type checks that passed, as the result
+// depends only on the type and the options in strictTypeKey
+var validTypes = struct {
+	sync.RWMutex
+	m map[strictTypeKey]struct{}
+}{m: make(map[strictTypeKey]struct{})}
+
+// validateTargetType runs the strict-mode checks on target type t
+func validateTargetType(t reflect.Type, opts *Options) error {
+	key := strictTypeKey{t, opts.AllowMapStringInterface, opts.AllowMapInterfaceKey, opts.AllowSliceInterface}
+	// AllowedUnmarshalers is not part of the key, so only the default
+	// configuration is cached
+	cacheable := len(opts.AllowedUnmarshalers) == 0
+	if cacheable {
+		validTypes.RLock()
+		_, ok := validTypes.m[key]
+		validTypes.RUnlock()
+		if ok {
+			return nil
+		}
+	}
+
+	// A target with a custom unmarshaler decodes itself, so its
+	// fields are not walked
+	custom, err := checkUnmarshaler(t, fieldRef{path: t.String()}, opts)
+	if err != nil {
+		return err
+	}
+
 	// Recursively check struct fields for any types
-	if opts.StrictMode && elem.Kind() == reflect.Struct {
-		if err := validateStructFields(elem.Type(), opts, make(map[reflect.Type]bool)); err != nil {
+	if !custom && t.Kind() == reflect.Struct {
+		if err := validateStructFields(t, opts, make(map[reflect.Type]bool)); err != nil {
 			return err
 		}
 	}
 
+	if cacheable {
+		validTypes.Lock()
+		validTypes.m[key] = struct{}{}
+		validTypes.Unlock()
+	}
 	return nil
 }
 
@@ -431,6 +1209,9 @@ func validatePointerAndValue(v any) (reflect.Value, error) {
 func validateContainerTypes(elem reflect.Value, opts *Options) error {
 	switch elem.Kind() {
 	case reflect.Map:
+		if elem.Type().Key().Kind() == reflect.Interface && !opts.AllowMapInterfaceKey {
+			return ErrMapInterfaceKey
+		}
 		if elem.Type().Elem().Kind() == reflect.Interface && !opts.AllowMapStringInterface {
 			return ErrMapInterface
 		}
@@ -444,13 +1225,74 @@ func validateContainerTypes(elem reflect.Value, opts *Options) error {
 
 // validateTypeWhitelist checks if the type is allowed per the whitelist
 func validateTypeWhitelist(elem reflect.Value, opts *Options) error {
-	if len(opts.AllowedTypes) == 0 {
+	if opts.Registry == nil && len(opts.AllowedTypes) == 0 && len(opts.AllowedPackages) == 0 {
 		return nil
 	}
 
-	typeName := elem.Type().String()
-	if !slices.Contains(opts.AllowedTypes, typeName) {
-		return fmt.Errorf("%w: %s", ErrTypeNotAllowed, typeName)
+	if !isAllowedType(elem.Type(), opts) {
+		return fmt.Errorf("%w: %s", ErrTypeNotAllowed, elem.Type().String())
+	}
+
+	return nil
+}
+
+// isAllowedType reports whether t is allowed by the registry, listed in
+// AllowedTypes or a named type in one of AllowedPackages. Unnamed types
+// such as anonymous structs have no package, so they can only be allowed
+// by AllowedTypes.
+func isAllowedType(t reflect.Type, opts *Options) bool {
+	if opts.Registry != nil && opts.Registry.allows(t) {
+		return true
+	}
+	return slices.Contains(opts.AllowedTypes, t.String()) || matchesPackage(t, opts.AllowedPackages)
+}
+
+// matchesPackage reports whether named type t has a PkgPath()+"."+Name()
+// starting with one of prefixes
+func matchesPackage(t reflect.Type, prefixes []string) bool {
+	if t.Name() == "" || t.PkgPath() == "" || len(prefixes) == 0 {
+		return false
+	}
+
+	qualified := t.PkgPath() + "." + t.Name()
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(qualified, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRegisteredGraph checks that every named struct type reachable
+// from t is in the allowed types list
+func validateRegisteredGraph(t reflect.Type, path string, opts *Options, visited map[reflect.Type]bool) error {
+	t = derefType(t)
+	if visited[t] {
+		return nil
+	}
+	visited[t] = true
+
+	switch t.Kind() {
+	case reflect.Map:
+		if err := validateRegisteredGraph(t.Key(), path+"[key]", opts, visited); err != nil {
+			return err
+		}
+		return validateRegisteredGraph(t.Elem(), path+"[]", opts, visited)
+	case reflect.Slice, reflect.Array:
+		return validateRegisteredGraph(t.Elem(), path+"[]", opts, visited)
+	case reflect.Struct:
+		if t.Name() != "" && !isAllowedType(t, opts) {
+			return fmt.Errorf("%w: %s (reached via %s)", ErrTypeNotAllowed, t.String(), path)
+		}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if err := validateRegisteredGraph(field.Type, path+"."+field.Name, opts, visited); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -458,6 +1300,13 @@ func validateTypeWhitelist(elem reflect.Value, opts *Options) error {
 
 // validateStructFields checks struct fields for unsafe types
 func validateStructFields(t reflect.Type, opts *Options, visited map[reflect.Type]bool) error {
+	return validateStructFieldsAt(t, t.Name(), opts, visited)
+}
+
+// validateStructFieldsAt checks the fields of struct t, reached via path.
+// A type is only walked the first time it is reached, which cuts cycles in
+// recursive types; errors report the path of that first occurrence.
+func validateStructFieldsAt(t reflect.Type, path string, opts *Options, visited map[reflect.Type]bool) error {
 	if visited[t] {
 		return nil // Prevent infinite recursion
 	}
@@ -465,43 +1314,111 @@ func validateStructFields(t reflect.Type, opts *Options, visited map[reflect.Typ
 
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		fieldType := field.Type
 
 		// Skip unexported fields
 		if !field.IsExported() {
 			continue
 		}
 
-		// Dereference pointers
-		for fieldType.Kind() == reflect.Pointer {
-			fieldType = fieldType.Elem()
+		f := fieldRef{path: path + "." + field.Name, tag: fieldTagName(field)}
+		if err := validateFieldType(field.Type, f, opts, visited); err != nil {
+			return err
 		}
+	}
 
-		switch fieldType.Kind() {
-		case reflect.Interface:
-			return fmt.Errorf("safedeserialize: struct field %s.%s is any type", t.Name(), field.Name)
-		case reflect.Map:
-			if fieldType.Elem().Kind() == reflect.Interface && !opts.AllowMapStringInterface {
-				return fmt.Errorf("safedeserialize: struct field %s.%s contains map with any values", t.Name(), field.Name)
-			}
-		case reflect.Slice:
-			elemType := fieldType.Elem()
-			for elemType.Kind() == reflect.Pointer {
-				elemType = elemType.Elem()
-			}
-			if elemType.Kind() == reflect.Interface && !opts.AllowSliceInterface {
-				return fmt.Errorf("safedeserialize: struct field %s.%s is []any type", t.Name(), field.Name)
+	return nil
+}
+
+// fieldRef identifies a struct field in validation errors by its path from
+// the target type and the serialized name from its struct tag
+type fieldRef struct {
+	path string
+	tag  string
+}
+
+// String formats the field as Type.Field.Sub[] (tag "name")
+func (f fieldRef) String() string {
+	if f.tag == "" {
+		return f.path
+	}
+	return fmt.Sprintf("%s (tag %q)", f.path, f.tag)
+}
+
+// elem returns the reference for an element of a slice, array or map field
+func (f fieldRef) elem() fieldRef {
+	return fieldRef{path: f.path + "[]", tag: f.tag}
+}
+
+// fieldTagName returns the serialized name of a field from the first
+// json, yaml or xml tag that names it
+func fieldTagName(field reflect.StructField) string {
+	for _, key := range []string{"json", "yaml", "xml"} {
+		name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return ""
+}
+
+// validateFieldType checks a field type for unsafe types and custom
+// unmarshalers, descending into pointers, slice and array elements, map
+// values and nested structs
+func validateFieldType(t reflect.Type, f fieldRef, opts *Options, visited map[reflect.Type]bool) error {
+	t = derefType(t)
+
+	if custom, err := checkUnmarshaler(t, f, opts); custom || err != nil {
+		return err
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return fmt.Errorf("%w: struct field %s is any type", ErrInterfaceTarget, f)
+	case reflect.Map:
+		if derefType(t.Key()).Kind() == reflect.Interface && !opts.AllowMapInterfaceKey {
+			return fmt.Errorf("%w: struct field %s", ErrMapInterfaceKey, f)
+		}
+		if _, err := checkUnmarshaler(derefType(t.Key()), f, opts); err != nil {
+			return err
+		}
+		if derefType(t.Elem()).Kind() == reflect.Interface {
+			if opts.AllowMapStringInterface {
+				return nil
 			}
-		case reflect.Struct:
-			if err := validateStructFields(fieldType, opts, visited); err != nil {
-				return err
+			return fmt.Errorf("%w: struct field %s contains map with any values", ErrMapInterface, f)
+		}
+		return validateFieldType(t.Elem(), f.elem(), opts, visited)
+	case reflect.Slice, reflect.Array:
+		if derefType(t.Elem()).Kind() == reflect.Interface {
+			if opts.AllowSliceInterface {
+				return nil
 			}
+			return fmt.Errorf("%w: struct field %s is []any type", ErrSliceInterface, f)
 		}
+		return validateFieldType(t.Elem(), f.elem(), opts, visited)
+	case reflect.Struct:
+		return validateStructFieldsAt(t, f.path, opts, visited)
 	}
 
 	return nil
 }
 
+// derefType strips any number of pointer indirections from t
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// checkDepth rejects a document whose nesting depth exceeds MaxDepth
+func checkDepth(depth int, opts *Options) error {
+	if depth > opts.MaxDepth {
+		return fmt.Errorf("%w: depth %d exceeds limit %d", ErrMaxDepthExceeded, depth, opts.MaxDepth)
+	}
+	return nil
+}
+
 // measureJSONDepth estimates the nesting depth of JSON data
 func measureJSONDepth(data []byte) int {
 	maxDepth := 0
@@ -543,33 +1460,101 @@ func measureJSONDepth(data []byte) int {
 	return maxDepth
 }
 
-// TypeRegistry provides a thread-safe whitelist of allowed types
+// TypeRegistry provides a thread-safe whitelist of allowed types.
+// Types are compared by identity, so two types that share a name such as
+// models.User but live in different packages are distinct.
 type TypeRegistry struct {
-	mu    sync.RWMutex
-	types map[string]reflect.Type
+	mu       sync.RWMutex
+	types    map[reflect.Type]struct{}
+	packages []string
+	frozen   bool
+}
+
+// DefaultRegistry is the registry filled by the package-level Register
+// and consulted by decodes using WithDefaultRegistry(true)
+var DefaultRegistry = NewTypeRegistry()
+
+// Register adds a type to DefaultRegistry, so that packages defining
+// message types can register them from init like gob.Register
+func Register(v any) {
+	DefaultRegistry.Register(v)
 }
 
 // NewTypeRegistry creates a new type registry
 func NewTypeRegistry() *TypeRegistry {
 	return &TypeRegistry{
-		types: make(map[string]reflect.Type),
+		types: make(map[reflect.Type]struct{}),
 	}
 }
 
 // Register adds a type to the registry
 // Pass a zero value or pointer: registry.Register(User{}) or registry.Register(&User{})
+// Register panics with ErrRegistryFrozen once the registry is frozen
 func (r *TypeRegistry) Register(v any) *TypeRegistry {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.mustNotBeFrozen("Register")
+
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	r.types[t] = struct{}{}
+	return r
+}
+
+// RegisterPackage allows every named type whose PkgPath()+"."+Name()
+// starts with prefix, e.g. "github.com/acme/api/types."
+// RegisterPackage panics with ErrRegistryFrozen once the registry is frozen
+func (r *TypeRegistry) RegisterPackage(prefix string) *TypeRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mustNotBeFrozen("RegisterPackage")
+
+	if !slices.Contains(r.packages, prefix) {
+		r.packages = append(r.packages, prefix)
+	}
+	return r
+}
+
+// Unregister removes a type from the registry
+// Unregister panics with ErrRegistryFrozen once the registry is frozen
+func (r *TypeRegistry) Unregister(v any) *TypeRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mustNotBeFrozen("Unregister")
 
 	t := reflect.TypeOf(v)
 	if t.Kind() == reflect.Pointer {
 		t = t.Elem()
 	}
-	r.types[t.String()] = t
+	delete(r.types, t)
 	return r
 }
 
+// Freeze makes the registry read-only; later calls to Register or
+// Unregister panic. Freezing an already frozen registry is a no-op.
+func (r *TypeRegistry) Freeze() *TypeRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frozen = true
+	return r
+}
+
+// IsFrozen reports whether Freeze has been called
+func (r *TypeRegistry) IsFrozen() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.frozen
+}
+
+// mustNotBeFrozen panics if the registry is frozen; r.mu must be held
+func (r *TypeRegistry) mustNotBeFrozen(op string) {
+	if r.frozen {
+		panic(fmt.Errorf("%w: %s called after Freeze", ErrRegistryFrozen, op))
+	}
+}
+
 // RegisterMultiple adds multiple types to the registry
 func (r *TypeRegistry) RegisterMultiple(values ...any) *TypeRegistry {
 	for _, v := range values {
@@ -587,28 +1572,52 @@ func (r *TypeRegistry) IsRegistered(v any) bool {
 	if t.Kind() == reflect.Pointer {
 		t = t.Elem()
 	}
-	_, ok := r.types[t.String()]
+	_, ok := r.types[t]
 	return ok
 }
 
-// TypeNames returns the list of registered type names
+// allows reports whether t is registered or is a named type in one of the
+// registered packages
+func (r *TypeRegistry) allows(t reflect.Type) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.types[t]; ok {
+		return true
+	}
+	return matchesPackage(t, r.packages)
+}
+
+// TypeNames returns the sorted list of registered type names
 func (r *TypeRegistry) TypeNames() []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	names := make([]string, 0, len(r.types))
-	for name := range r.types {
-		names = append(names, name)
+	for t := range r.types {
+		names = append(names, t.String())
 	}
+	slices.Sort(names)
 	return names
 }
 
-// Option returns an Option that uses this registry for type validation
+// Option returns an Option that validates targets against this registry.
+// The registry is consulted on every decode, so types registered later are
+// allowed too; call Freeze to make the allowed set final.
 func (r *TypeRegistry) Option() Option {
-	return WithAllowedTypes(r.TypeNames()...)
+	return func(o *Options) {
+		o.Registry = r
+	}
 }
 
-// Decoder provides a reusable decoder with preset options
+// Decoder provides a reusable decoder with preset options.
+//
+// A Decoder's options are fixed at construction: they are copied from the
+// applied Options, so later changes to slices passed to an Option do not
+// affect it. A Decoder is therefore safe for concurrent use by multiple
+// goroutines, including calls with per-call overrides. A TypeRegistry set
+// with its Option is shared rather than copied, and is itself safe for
+// concurrent use.
 type Decoder struct {
 	opts *Options
 }
@@ -619,45 +1628,108 @@ func NewDecoder(opts ...Option) *Decoder {
 	for _, opt := range opts {
 		opt(options)
 	}
-	return &Decoder{opts: options}
+	return &Decoder{opts: options.clone()}
+}
+
+// NewDecoderStrict is like NewDecoder, but returns an error wrapping
+// ErrInvalidOptions, listing every problem, when an option value was
+// ignored or the options conflict with or make each other pointless.
+func NewDecoderStrict(opts ...Option) (*Decoder, error) {
+	options := DefaultOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+	if err := options.checkConfig(); err != nil {
+		return nil, err
+	}
+	return &Decoder{opts: options.clone()}, nil
+}
+
+// Clone returns a new decoder with the options of d plus overrides. The
+// options are copied first, so d is never affected.
+func (d *Decoder) Clone(opts ...Option) *Decoder {
+	options := d.opts.clone()
+	for _, opt := range opts {
+		opt(options)
+	}
+	return &Decoder{opts: options.clone()}
+}
+
+// Options returns a copy of the options the decoder was constructed with
+func (d *Decoder) Options() Options {
+	return *d.opts.clone()
+}
+
+// options returns the options for a single call, applying per-call
+// overrides to a copy so the shared options are never mutated
+func (d *Decoder) options(overrides []Option) *Options {
+	if len(overrides) == 0 {
+		return d.opts
+	}
+	return d.Clone(overrides...).opts
 }
 
 // JSON decodes JSON data
-func (d *Decoder) JSON(data []byte, v any) error {
-	return jsonUnmarshal(data, v, d.opts)
+func (d *Decoder) JSON(data []byte, v any, opts ...Option) error {
+	return unmarshalFormat(FormatJSON, data, v, d.options(opts))
 }
 
 // JSONReader decodes JSON from a reader
-func (d *Decoder) JSONReader(r io.Reader, v any) error {
-	return jsonDecode(r, v, d.opts)
+func (d *Decoder) JSONReader(r io.Reader, v any, opts ...Option) error {
+	return decodeFormat(FormatJSON, r, v, d.options(opts))
 }
 
 // YAML decodes YAML data
-func (d *Decoder) YAML(data []byte, v any) error {
-	return yamlUnmarshal(data, v, d.opts)
+func (d *Decoder) YAML(data []byte, v any, opts ...Option) error {
+	return unmarshalFormat(FormatYAML, data, v, d.options(opts))
 }
 
 // YAMLReader decodes YAML from a reader
-func (d *Decoder) YAMLReader(r io.Reader, v any) error {
-	return yamlDecode(r, v, d.opts)
+func (d *Decoder) YAMLReader(r io.Reader, v any, opts ...Option) error {
+	return decodeFormat(FormatYAML, r, v, d.options(opts))
 }
 
 // XML decodes XML data
-func (d *Decoder) XML(data []byte, v any) error {
-	return xmlUnmarshal(data, v, d.opts)
+func (d *Decoder) XML(data []byte, v any, opts ...Option) error {
+	return unmarshalFormat(FormatXML, data, v, d.options(opts))
 }
 
 // XMLReader decodes XML from a reader
-func (d *Decoder) XMLReader(r io.Reader, v any) error {
-	return xmlDecode(r, v, d.opts)
+func (d *Decoder) XMLReader(r io.Reader, v any, opts ...Option) error {
+	return decodeFormat(FormatXML, r, v, d.options(opts))
 }
 
 // Gob decodes Gob data
-func (d *Decoder) Gob(data []byte, v any) error {
-	return gobDecode(bytes.NewReader(data), v, d.opts)
+func (d *Decoder) Gob(data []byte, v any, opts ...Option) error {
+	return unmarshalFormat(FormatGob, data, v, d.options(opts))
 }
 
 // GobReader decodes Gob from a reader
-func (d *Decoder) GobReader(r io.Reader, v any) error {
-	return gobDecode(r, v, d.opts)
+func (d *Decoder) GobReader(r io.Reader, v any, opts ...Option) error {
+	return decodeFormat(FormatGob, r, v, d.options(opts))
+}
+
+// GobStream returns a stream decoding gob messages from r
+func (d *Decoder) GobStream(r io.Reader, opts ...Option) *GobStream {
+	return newGobStream(r, d.options(opts).clone())
+}
+
+// DecodeFormat decodes data in a built-in or registered format
+func (d *Decoder) DecodeFormat(format Format, data []byte, v any, opts ...Option) error {
+	return unmarshalFormat(format, data, v, d.options(opts))
+}
+
+// Valid checks data against the decoder's options without decoding it
+func (d *Decoder) Valid(format Format, data []byte, opts ...Option) error {
+	return valid(format, data, d.options(opts))
+}
+
+// DecodeFile decodes the file at name in a built-in or registered format
+func (d *Decoder) DecodeFile(format Format, name string, v any, opts ...Option) error {
+	return decodeFile(format, name, v, d.options(opts))
+}
+
+// VerifyAndDecode authenticates a signed envelope and decodes its payload
+func (d *Decoder) VerifyAndDecode(format Format, data []byte, v any, opts ...Option) error {
+	return verifyAndDecode(format, data, v, d.options(opts))
 }
@@ -3,7 +3,14 @@ package safedeserialize
 import (
 	"bytes"
 	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -46,6 +53,15 @@ type SliceInterfaceStruct struct {
 	Items []any  `json:"items"`
 }
 
+type DeepItem struct {
+	SKU  string `json:"sku"`
+	Meta any    `json:"meta"`
+}
+
+type SafeItem struct {
+	SKU string `json:"sku"`
+}
+
 // ============================================================================
 // Table-Driven JSON Tests
 // ============================================================================
@@ -163,6 +179,72 @@ func TestReaders(t *testing.T) {
 	}
 }
 
+// chunkedReader returns its data a few bytes at a time, interleaving empty
+// reads, and fails with err once the data is exhausted
+type chunkedReader struct {
+	data  []byte
+	chunk int
+	err   error
+	empty bool
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if r.empty = !r.empty; r.empty {
+		return 0, nil
+	}
+	if len(r.data) == 0 {
+		return 0, r.err
+	}
+	n := copy(p[:min(len(p), r.chunk)], r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestReaderSizeLimitBoundary(t *testing.T) {
+	valid := `{"id":1,"name":"alice"}`
+	limit := int64(len(valid))
+	// Cut mid-token when truncated at the limit, which must not surface
+	// as a syntax error
+	oversized := `{"id":1,"name":"alice","email":"a@b.c"}`
+
+	var gobData bytes.Buffer
+	if err := gob.NewEncoder(&gobData).Encode(SimpleUser{ID: 1, Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	readers := map[string]func(data string) io.Reader{
+		"chunked":     func(data string) io.Reader { return &chunkedReader{data: []byte(data), chunk: 3, err: io.EOF} },
+		"single byte": func(data string) io.Reader { return &chunkedReader{data: []byte(data), chunk: 1, err: io.EOF} },
+		"fails at end": func(data string) io.Reader {
+			return &chunkedReader{data: []byte(data), chunk: 5, err: io.ErrClosedPipe}
+		},
+		"strings.Reader": func(data string) io.Reader { return strings.NewReader(data) },
+	}
+
+	for name, newReader := range readers {
+		t.Run(name, func(t *testing.T) {
+			if err := JSONReader(newReader(oversized), &SimpleUser{}, WithMaxSize(limit)); !errors.Is(err, ErrDataTooLarge) {
+				t.Errorf("oversized: expected ErrDataTooLarge, got %v", err)
+			}
+			if err := YAMLReader(newReader("id: 1\nname: alice\n"), &SimpleUser{}, WithMaxSize(10)); !errors.Is(err, ErrDataTooLarge) {
+				t.Errorf("oversized YAML: expected ErrDataTooLarge, got %v", err)
+			}
+			if err := GobReader(newReader(gobData.String()), &SimpleUser{}, WithMaxSize(10)); !errors.Is(err, ErrDataTooLarge) {
+				t.Errorf("oversized Gob: expected ErrDataTooLarge, got %v", err)
+			}
+
+			err := JSONReader(newReader(valid), &SimpleUser{}, WithMaxSize(limit))
+			if name == "fails at end" {
+				if err == nil || errors.Is(err, ErrDataTooLarge) {
+					t.Errorf("exact size: expected the read error, got %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("exact size: unexpected error: %v", err)
+			}
+		})
+	}
+}
+
 // ============================================================================
 // YAML, XML, Gob Tests
 // ============================================================================
@@ -193,6 +275,104 @@ func TestYAML(t *testing.T) {
 	}
 }
 
+func TestYAMLMapInterfaceKey(t *testing.T) {
+	type Labels struct {
+		Name   string         `yaml:"name"`
+		Labels map[any]string `yaml:"labels"`
+	}
+	type NestedLabels struct {
+		Items []map[string]map[any]int `yaml:"items"`
+	}
+
+	data := []byte("name: a\nlabels:\n  1: one\n  true: yes\n  key: value")
+
+	tests := []struct {
+		name    string
+		data    []byte
+		target  any
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "top-level key", data: []byte("1: one\nkey: value"), target: &map[any]string{}, wantErr: true},
+		{name: "top-level key allowed", data: []byte("1: one\nkey: value"), target: &map[any]string{}, opts: []Option{WithAllowMapInterfaceKey(true)}},
+		{name: "struct field", data: data, target: &Labels{}, wantErr: true},
+		{name: "struct field allowed", data: data, target: &Labels{}, opts: []Option{WithAllowMapInterfaceKey(true)}},
+		{name: "nested field", data: []byte("items:\n  - a:\n      1: 2"), target: &NestedLabels{}, wantErr: true},
+		{name: "string keys", data: []byte("a: one"), target: &map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := YAML(tt.data, tt.target, tt.opts...)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrMapInterfaceKey) {
+				t.Errorf("expected ErrMapInterfaceKey, got %v", err)
+			}
+		})
+	}
+}
+
+func TestYAMLDocuments(t *testing.T) {
+	user := "id: 1\nname: a\nemail: a@b.c\n"
+	tests := []struct {
+		name    string
+		data    string
+		opts    []Option
+		wantErr error
+	}{
+		{name: "single", data: user},
+		{name: "explicit start", data: "---\n" + user},
+		{name: "second document", data: user + "---\n" + user, wantErr: ErrTooManyDocuments},
+		{name: "trailing separator", data: user + "---\n", wantErr: ErrTooManyDocuments},
+		{name: "separator flood", data: user + strings.Repeat("---\n", 10000), wantErr: ErrTooManyDocuments},
+		{name: "within limit", data: user + "---\nid: 2\n---\nid: 3\n", opts: []Option{WithMaxYAMLDocuments(3)}},
+		{name: "over limit", data: strings.Repeat("---\n"+user, 4), opts: []Option{WithMaxYAMLDocuments(3)}, wantErr: ErrTooManyDocuments},
+		{name: "later document checked", data: user + "---\nid: [", opts: []Option{WithMaxYAMLDocuments(2)}, wantErr: ErrMalformedInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := map[string]func() error{
+				"YAML":       func() error { return YAML([]byte(tt.data), &SimpleUser{}, tt.opts...) },
+				"YAMLReader": func() error { return YAMLReader(strings.NewReader(tt.data), &SimpleUser{}, tt.opts...) },
+				"Valid":      func() error { return Valid(FormatYAML, []byte(tt.data), tt.opts...) },
+			}
+			for name, check := range checks {
+				err := check()
+				if tt.wantErr == nil && err != nil {
+					t.Errorf("%s: unexpected error: %v", name, err)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("%s: expected %v, got %v", name, tt.wantErr, err)
+				}
+				if errors.Is(err, ErrTooManyDocuments) && (errors.Is(err, ErrMaxDepthExceeded) || errors.Is(err, ErrDataTooLarge)) {
+					t.Errorf("%s: document limit not distinguishable: %v", name, err)
+				}
+			}
+		})
+	}
+
+	var got SimpleUser
+	if err := YAML([]byte(user+"---\nid: 2\n"), &got, WithMaxYAMLDocuments(2)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.ID != 1 {
+		t.Errorf("expected the first document to be decoded, got ID %d", got.ID)
+	}
+
+	if err := YAML([]byte(user+"---\n"+user), &SimpleUser{}, WithMaxYAMLDocuments(2)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := NewDecoderStrict(WithMaxYAMLDocuments(0)); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("expected ErrInvalidOptions, got %v", err)
+	}
+}
+
 func TestXML(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -250,6 +430,222 @@ func TestGob(t *testing.T) {
 	}
 }
 
+func TestGobSizeLimit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&SimpleUser{ID: 1, Name: strings.Repeat("a", 500), Email: "a@b.c"}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	t.Run("Gob", func(t *testing.T) {
+		if err := Gob(data, &SimpleUser{}, WithMaxSize(100)); !errors.Is(err, ErrDataTooLarge) {
+			t.Errorf("expected ErrDataTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("GobReader", func(t *testing.T) {
+		if err := GobReader(bytes.NewReader(data), &SimpleUser{}, WithMaxSize(100)); !errors.Is(err, ErrDataTooLarge) {
+			t.Errorf("expected ErrDataTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("GobReader exact size", func(t *testing.T) {
+		if err := GobReader(bytes.NewReader(data), &SimpleUser{}, WithMaxSize(int64(len(data)))); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("GobReader trailing data", func(t *testing.T) {
+		r := io.MultiReader(bytes.NewReader(data), strings.NewReader(strings.Repeat("x", 1<<16)))
+		if err := GobReader(r, &SimpleUser{}, WithMaxSize(int64(len(data)))); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestGobEmptyAndStrict(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      func() error
+		wantErr error
+	}{
+		{name: "Gob empty", fn: func() error { return Gob([]byte{}, &SimpleUser{}) }, wantErr: ErrEmptyData},
+		{name: "Gob nil", fn: func() error { return Gob(nil, &SimpleUser{}) }, wantErr: ErrEmptyData},
+		{name: "GobReader empty", fn: func() error { return GobReader(strings.NewReader(""), &SimpleUser{}) }, wantErr: ErrEmptyData},
+		{name: "Decoder.Gob empty", fn: func() error { return NewDecoder().Gob([]byte{}, &SimpleUser{}) }, wantErr: ErrEmptyData},
+		{name: "Decoder.GobReader empty", fn: func() error { return NewDecoder().GobReader(&bytes.Buffer{}, &SimpleUser{}) }, wantErr: ErrEmptyData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fn(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("any field rejected like JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(&UnsafeStruct{Name: "a"}); err != nil {
+			t.Fatal(err)
+		}
+
+		jsonErr := JSON([]byte(`{"name": "a"}`), &UnsafeStruct{})
+		gobErr := Gob(buf.Bytes(), &UnsafeStruct{})
+		gobReaderErr := GobReader(bytes.NewReader(buf.Bytes()), &UnsafeStruct{})
+		if jsonErr == nil || gobErr == nil || gobReaderErr == nil {
+			t.Fatalf("expected all formats to reject: json=%v gob=%v gobReader=%v", jsonErr, gobErr, gobReaderErr)
+		}
+		if gobErr.Error() != jsonErr.Error() || gobReaderErr.Error() != jsonErr.Error() {
+			t.Errorf("errors differ: json=%v gob=%v gobReader=%v", jsonErr, gobErr, gobReaderErr)
+		}
+	})
+}
+
+// ============================================================================
+// Number Handling Tests
+// ============================================================================
+
+func TestUseNumber(t *testing.T) {
+	data := []byte(`{"id": 9007199254740993}`)
+
+	for _, strict := range []bool{true, false} {
+		m := map[string]any{}
+		err := JSON(data, &m, WithAllowMapStringInterface(true), WithUseNumber(true), WithStrictMode(strict))
+		if err != nil {
+			t.Fatalf("strict=%v: unexpected error: %v", strict, err)
+		}
+		if n, ok := m["id"].(json.Number); !ok || n.String() != "9007199254740993" {
+			t.Errorf("strict=%v: expected json.Number 9007199254740993, got %#v", strict, m["id"])
+		}
+	}
+
+	m := map[string]any{}
+	if err := JSON(data, &m, WithAllowMapStringInterface(true)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["id"].(float64); !ok {
+		t.Errorf("expected float64 without UseNumber, got %T", m["id"])
+	}
+}
+
+func TestNonFiniteNumbers(t *testing.T) {
+	type Reading struct {
+		Value   float64            `yaml:"value"`
+		Samples []float32          `yaml:"samples"`
+		ByName  map[string]float64 `yaml:"by_name"`
+		Label   string             `yaml:"label"`
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		opts    []Option
+		wantErr string
+	}{
+		{name: "finite", data: "value: 1.5\nsamples: [1, 2.5]"},
+		{name: "nan", data: "value: .nan", wantErr: "field value value .nan"},
+		{name: "inf", data: "value: .inf", wantErr: "field value value .inf"},
+		{name: "negative inf", data: "value: -.Inf", wantErr: "field value value -.Inf"},
+		{name: "in sequence", data: "samples: [1, .NaN]", wantErr: "field samples[1] value .NaN"},
+		{name: "in map", data: "by_name:\n  a: +.INF", wantErr: "field by_name.a value +.INF"},
+		{name: "explicit tag overflow", data: "value: !!float 1e999", wantErr: "field value value 1e999"},
+		{name: "quoted string", data: "label: '.nan'"},
+		{name: "non-strict", data: "value: .nan", opts: []Option{WithStrictMode(false)}, wantErr: "field value value .nan"},
+		{name: "allowed", data: "value: .nan\nsamples: [.inf]", opts: []Option{WithAllowNonFiniteNumbers(true)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r Reading
+			err := YAML([]byte(tt.data), &r, tt.opts...)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrNonFiniteNumber) {
+				t.Fatalf("expected ErrNonFiniteNumber, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected %q in %q", tt.wantErr, err)
+			}
+			if r.Value != 0 || len(r.Samples) != 0 || len(r.ByName) != 0 {
+				t.Errorf("target was populated: %+v", r)
+			}
+		})
+	}
+}
+
+func TestStrictNumbers(t *testing.T) {
+	type Limits struct {
+		Count  int                `json:"count" yaml:"count"`
+		Small  int8               `json:"small" yaml:"small"`
+		Size   uint16             `json:"size" yaml:"size"`
+		Ratio  float64            `json:"ratio" yaml:"ratio"`
+		Ports  []uint8            `json:"ports" yaml:"ports"`
+		Quotas map[string]int32   `json:"quotas" yaml:"quotas"`
+		Nested struct{ ID int64 } `json:"nested" yaml:"nested"`
+	}
+
+	tests := []struct {
+		name    string
+		format  string
+		data    string
+		wantErr string
+	}{
+		{name: "json valid", format: "json", data: `{"count": 3, "small": -128, "size": 65535, "ratio": 1.5}`},
+		{name: "json fractional", format: "json", data: `{"count": 1.9}`, wantErr: "field count value 1.9"},
+		{name: "json exponent", format: "json", data: `{"count": 1e2}`, wantErr: "field count value 1e2"},
+		{name: "json overflow", format: "json", data: `{"small": 300}`, wantErr: "field small value 300"},
+		{name: "json negative unsigned", format: "json", data: `{"ports": [80, -1]}`, wantErr: "value -1"},
+		{name: "yaml valid", format: "yaml", data: "count: 3\nsmall: -128\nsize: 0xffff\nratio: 1.5\nnested:\n  id: 2.0"},
+		{name: "yaml fractional", format: "yaml", data: "count: 1.9", wantErr: "field count value 1.9 does not fit int (not an integer)"},
+		{name: "yaml overflow", format: "yaml", data: "small: 300", wantErr: "field small value 300 does not fit int8 (out of range)"},
+		{name: "yaml float overflow", format: "yaml", data: "size: 7e4", wantErr: "field size value 7e4 does not fit uint16 (out of range)"},
+		{name: "yaml non-finite", format: "yaml", data: "count: .inf", wantErr: "(not an integer)"},
+		{name: "yaml sequence", format: "yaml", data: "ports: [80, 256]", wantErr: "field ports[1] value 256"},
+		{name: "yaml map value", format: "yaml", data: "quotas:\n  a: 0.5", wantErr: "field quotas.a value 0.5"},
+		{name: "yaml nested", format: "yaml", data: "nested:\n  id: 1.5", wantErr: "field nested.id value 1.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Cover both the strict decoder and the plain Unmarshal paths
+			for _, strict := range []bool{true, false} {
+				var l Limits
+				var err error
+				if tt.format == "json" {
+					err = JSON([]byte(tt.data), &l, WithStrictNumbers(true), WithStrictMode(strict))
+				} else {
+					err = YAML([]byte(tt.data), &l, WithStrictNumbers(true), WithStrictMode(strict), WithAllowNonFiniteNumbers(true))
+				}
+
+				if tt.wantErr == "" {
+					if err != nil {
+						t.Errorf("strict=%v: unexpected error: %v", strict, err)
+					}
+					continue
+				}
+				if !errors.Is(err, ErrInvalidNumber) {
+					t.Fatalf("strict=%v: expected ErrInvalidNumber, got %v", strict, err)
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("strict=%v: expected %q in %q", strict, tt.wantErr, err)
+				}
+			}
+		})
+	}
+
+	t.Run("yaml truncates without option", func(t *testing.T) {
+		var l Limits
+		if err := YAML([]byte("count: 1.9"), &l); err != nil || l.Count != 1 {
+			t.Errorf("expected silent truncation to 1, got %d (%v)", l.Count, err)
+		}
+	})
+}
+
 // ============================================================================
 // Decoder Tests
 // ============================================================================
@@ -317,6 +713,102 @@ func TestDecoder(t *testing.T) {
 	})
 }
 
+func TestDecoderClone(t *testing.T) {
+	data := []byte(`{"id":1,"name":"alice","email":"alice@example.com"}`)
+	parent := NewDecoder(WithMaxSize(1<<20), WithDeniedFields("role"))
+
+	small := parent.Clone(WithMaxSize(16), WithDeniedFields("email"))
+	if err := small.JSON(data, &SimpleUser{}); !errors.Is(err, ErrDataTooLarge) {
+		t.Errorf("expected ErrDataTooLarge from clone, got %v", err)
+	}
+	if err := parent.JSON(data, &SimpleUser{}); err != nil {
+		t.Errorf("parent decoder was affected by Clone: %v", err)
+	}
+	if parent.opts.MaxSize != 1<<20 || len(parent.opts.DeniedFields) != 1 {
+		t.Errorf("parent options changed: MaxSize %d, DeniedFields %v", parent.opts.MaxSize, parent.opts.DeniedFields)
+	}
+	if got := small.opts.DeniedFields; len(got) != 2 {
+		t.Errorf("expected clone to extend DeniedFields, got %v", got)
+	}
+}
+
+func TestDecoderPerCallOptions(t *testing.T) {
+	data := []byte(`{"id":1,"name":"alice","email":"alice@example.com"}`)
+	decoder := NewDecoder()
+
+	if err := decoder.JSON(data, &SimpleUser{}, WithMaxSize(16)); !errors.Is(err, ErrDataTooLarge) {
+		t.Errorf("expected ErrDataTooLarge, got %v", err)
+	}
+	if err := decoder.JSONReader(strings.NewReader(string(data)), &SimpleUser{}); err != nil {
+		t.Errorf("per-call option leaked into the decoder: %v", err)
+	}
+	if decoder.opts.MaxSize != DefaultMaxSize {
+		t.Errorf("expected MaxSize %d, got %d", DefaultMaxSize, decoder.opts.MaxSize)
+	}
+}
+
+func TestDecoderOptions(t *testing.T) {
+	types := []string{"safedeserialize.SimpleUser"}
+	decoder := NewDecoder(WithMaxSize(4096), WithAllowedTypes(types...))
+
+	opts := decoder.Options()
+	if opts.MaxSize != 4096 || !opts.StrictMode {
+		t.Errorf("unexpected options: MaxSize %d, StrictMode %v", opts.MaxSize, opts.StrictMode)
+	}
+
+	// Neither the returned copy nor the caller's slice reach the decoder
+	opts.MaxSize = 1
+	opts.AllowedTypes[0] = "other.Type"
+	types[0] = "other.Type"
+
+	if got := decoder.Options(); got.MaxSize != 4096 || got.AllowedTypes[0] != "safedeserialize.SimpleUser" {
+		t.Errorf("decoder options were mutated: %+v", got)
+	}
+	if err := decoder.JSON([]byte(`{"id":1}`), &SimpleUser{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDecoderConcurrentUse(t *testing.T) {
+	decoder := NewDecoder(WithDeniedFields("role"))
+	data := []byte(`{"id":1,"name":"alice","email":"alice@example.com"}`)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				var user SimpleUser
+				var err error
+				switch (i + j) % 4 {
+				case 0:
+					err = decoder.JSON(data, &user)
+				case 1:
+					err = decoder.JSON(data, &user, WithDeniedFields("email"))
+					if !errors.Is(err, ErrDeniedField) {
+						t.Errorf("expected ErrDeniedField, got %v", err)
+					}
+					continue
+				case 2:
+					err = decoder.Clone(WithMaxSize(1<<10)).JSONReader(bytes.NewReader(data), &user)
+				case 3:
+					err = decoder.YAML([]byte("id: 1\nname: alice\n"), &user)
+				}
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				_ = decoder.Options()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := decoder.Options().DeniedFields; len(got) != 1 {
+		t.Errorf("expected shared DeniedFields to be unchanged, got %v", got)
+	}
+}
+
 // ============================================================================
 // TypeRegistry Tests
 // ============================================================================
@@ -346,6 +838,294 @@ func TestTypeRegistry(t *testing.T) {
 	}
 }
 
+func TestRequireRegisteredGraph(t *testing.T) {
+	type Attachment struct {
+		Name string
+	}
+	type Envelope struct {
+		ID          int
+		Attachments []Attachment
+		Link        url.URL
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&Envelope{ID: 1, Attachments: []Attachment{{Name: "a"}}}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	t.Run("off by default", func(t *testing.T) {
+		r := NewTypeRegistry().Register(Envelope{})
+		if err := Gob(data, &Envelope{}, r.Option()); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("unregistered nested type", func(t *testing.T) {
+		r := NewTypeRegistry().Register(Envelope{}).Register(url.Userinfo{})
+		err := Gob(data, &Envelope{}, r.Option(), WithRequireRegisteredGraph(true))
+		if !errors.Is(err, ErrTypeNotAllowed) {
+			t.Fatalf("expected ErrTypeNotAllowed, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "safedeserialize.Attachment (reached via safedeserialize.Envelope.Attachments[])") {
+			t.Errorf("error does not name the type and path: %v", err)
+		}
+	})
+
+	t.Run("unregistered third-party type", func(t *testing.T) {
+		r := NewTypeRegistry().Register(Envelope{}).Register(Attachment{})
+		err := GobReader(bytes.NewReader(data), &Envelope{}, r.Option(), WithRequireRegisteredGraph(true))
+		if !errors.Is(err, ErrTypeNotAllowed) {
+			t.Fatalf("expected ErrTypeNotAllowed, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "url.URL") {
+			t.Errorf("error does not name url.URL: %v", err)
+		}
+	})
+
+	t.Run("fully registered", func(t *testing.T) {
+		r := NewTypeRegistry().RegisterMultiple(Envelope{}, Attachment{}, url.URL{}, url.Userinfo{})
+		if err := Gob(data, &Envelope{}, r.Option(), WithRequireRegisteredGraph(true)); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("no registry", func(t *testing.T) {
+		if err := Gob(data, &Envelope{}, WithRequireRegisteredGraph(true)); !errors.Is(err, ErrTypeNotAllowed) {
+			t.Errorf("expected ErrTypeNotAllowed, got %v", err)
+		}
+	})
+}
+
+func TestTypeRegistryUnregister(t *testing.T) {
+	r := NewTypeRegistry().RegisterMultiple(SimpleUser{}, NestedConfig{})
+	r.Unregister(&SimpleUser{})
+
+	if r.IsRegistered(SimpleUser{}) || !r.IsRegistered(NestedConfig{}) {
+		t.Errorf("unexpected registry contents: %v", r.TypeNames())
+	}
+	if err := JSON([]byte(`{"id":1}`), &SimpleUser{}, r.Option()); !errors.Is(err, ErrTypeNotAllowed) {
+		t.Errorf("expected ErrTypeNotAllowed, got %v", err)
+	}
+}
+
+func TestTypeRegistryFreeze(t *testing.T) {
+	r := NewTypeRegistry().Register(SimpleUser{})
+	if r.IsFrozen() {
+		t.Fatal("new registry should not be frozen")
+	}
+	r.Freeze()
+	if !r.IsFrozen() {
+		t.Fatal("expected registry to be frozen")
+	}
+
+	expectFrozenPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			err, _ := recover().(error)
+			if !errors.Is(err, ErrRegistryFrozen) {
+				t.Errorf("%s: expected ErrRegistryFrozen panic, got %v", name, err)
+			}
+		}()
+		fn()
+	}
+	expectFrozenPanic("Register", func() { r.Register(NestedConfig{}) })
+	expectFrozenPanic("RegisterMultiple", func() { r.RegisterMultiple(NestedConfig{}) })
+	expectFrozenPanic("Unregister", func() { r.Unregister(SimpleUser{}) })
+
+	if !r.IsRegistered(SimpleUser{}) || r.IsRegistered(NestedConfig{}) {
+		t.Errorf("frozen registry changed: %v", r.TypeNames())
+	}
+}
+
+func TestTypeRegistryFreezeConcurrent(t *testing.T) {
+	r := NewTypeRegistry().Register(SimpleUser{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = r.IsRegistered(SimpleUser{})
+				_ = r.IsFrozen()
+				_ = r.Option()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			r.Freeze()
+		}()
+	}
+	wg.Wait()
+
+	if !r.IsFrozen() {
+		t.Error("expected registry to be frozen")
+	}
+}
+
+// URL shares its name with net/url.URL
+type URL struct {
+	Raw string `json:"raw"`
+}
+
+func TestTypeRegistryPackage(t *testing.T) {
+	self := reflect.TypeOf(SimpleUser{}).PkgPath() + "."
+
+	tests := []struct {
+		name    string
+		r       *TypeRegistry
+		target  any
+		data    string
+		wantErr bool
+	}{
+		{"allowed package", NewTypeRegistry().RegisterPackage(self), &SimpleUser{}, `{"id":1}`, false},
+		{"other package", NewTypeRegistry().RegisterPackage(self), &url.URL{}, `{}`, true},
+		{"stdlib package", NewTypeRegistry().RegisterPackage("net/url."), &url.URL{}, `{}`, false},
+		{"same name in other package", NewTypeRegistry().RegisterPackage("net/url."), &URL{}, `{"raw":"x"}`, true},
+		{"exact registration alongside", NewTypeRegistry().RegisterPackage("net/url.").Register(URL{}), &URL{}, `{"raw":"x"}`, false},
+		{"anonymous struct not matched by package", NewTypeRegistry().RegisterPackage(self), &struct {
+			ID int `json:"id"`
+		}{}, `{"id":1}`, true},
+		{"anonymous struct registered by structure", NewTypeRegistry().Register(struct {
+			ID int `json:"id"`
+		}{}), &struct {
+			ID int `json:"id"`
+		}{}, `{"id":1}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := JSON([]byte(tt.data), tt.target, tt.r.Option())
+			if tt.wantErr && !errors.Is(err, ErrTypeNotAllowed) {
+				t.Errorf("expected ErrTypeNotAllowed, got %v", err)
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRequireRegisteredGraphPackage(t *testing.T) {
+	type Attachment struct {
+		Name string
+	}
+	type Envelope struct {
+		Attachments []Attachment
+		Link        url.URL
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&Envelope{Attachments: []Attachment{{Name: "a"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	self := reflect.TypeOf(Envelope{}).PkgPath() + "."
+	r := NewTypeRegistry().RegisterPackage(self)
+	if err := Gob(buf.Bytes(), &Envelope{}, r.Option(), WithRequireRegisteredGraph(true)); !errors.Is(err, ErrTypeNotAllowed) {
+		t.Errorf("expected url.URL to be rejected, got %v", err)
+	}
+
+	r.RegisterPackage("net/url.")
+	if err := Gob(buf.Bytes(), &Envelope{}, r.Option(), WithRequireRegisteredGraph(true)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func itemTypeA() any {
+	type Item struct {
+		ID int `json:"id"`
+	}
+	return &Item{}
+}
+
+func itemTypeB() any {
+	type Item struct {
+		ID int `json:"id"`
+	}
+	return &Item{}
+}
+
+func TestTypeRegistryIdentity(t *testing.T) {
+	a, b := itemTypeA(), itemTypeB()
+	if reflect.TypeOf(a).String() != reflect.TypeOf(b).String() {
+		t.Fatal("test types should share a name")
+	}
+	data := []byte(`{"id":1}`)
+
+	r := NewTypeRegistry().Register(a)
+	if err := JSON(data, itemTypeA(), r.Option()); err != nil {
+		t.Errorf("unexpected error for registered type: %v", err)
+	}
+	if err := JSON(data, b, r.Option()); !errors.Is(err, ErrTypeNotAllowed) {
+		t.Errorf("expected same-named type to be rejected, got %v", err)
+	}
+
+	// Names cannot tell the two apart
+	names := WithAllowedTypes(reflect.TypeOf(a).Elem().String())
+	if err := JSON(data, b, names); err != nil {
+		t.Errorf("expected WithAllowedTypes to match by name, got %v", err)
+	}
+}
+
+func TestTypeRegistryOptionIsLive(t *testing.T) {
+	r := NewTypeRegistry()
+	decoder := NewDecoder(r.Option())
+
+	if err := decoder.JSON([]byte(`{"id":1}`), &SimpleUser{}); !errors.Is(err, ErrTypeNotAllowed) {
+		t.Errorf("expected empty registry to reject, got %v", err)
+	}
+	r.Register(SimpleUser{}).Freeze()
+	if err := decoder.JSON([]byte(`{"id":1}`), &SimpleUser{}); err != nil {
+		t.Errorf("unexpected error after Register: %v", err)
+	}
+}
+
+// selfRegistered is added to DefaultRegistry the way a library would
+// register its message types
+type selfRegistered struct {
+	ID int `json:"id"`
+}
+
+func init() {
+	Register(selfRegistered{})
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	data := []byte(`{"id":1}`)
+
+	if err := JSON(data, &SimpleUser{}); err != nil {
+		t.Errorf("unregistered type rejected without WithDefaultRegistry: %v", err)
+	}
+	if err := JSON(data, &SimpleUser{}, WithDefaultRegistry(true)); !errors.Is(err, ErrTypeNotAllowed) {
+		t.Errorf("expected ErrTypeNotAllowed, got %v", err)
+	}
+	if err := JSON(data, &selfRegistered{}, WithDefaultRegistry(true)); err != nil {
+		t.Errorf("unexpected error for registered type: %v", err)
+	}
+	if err := JSON(data, &SimpleUser{}, WithDefaultRegistry(true), WithDefaultRegistry(false)); err != nil {
+		t.Errorf("WithDefaultRegistry(false) did not disable the registry: %v", err)
+	}
+	if !slices.Contains(DefaultRegistry.TypeNames(), "safedeserialize.selfRegistered") {
+		t.Errorf("expected selfRegistered in %v", DefaultRegistry.TypeNames())
+	}
+}
+
+func TestTypeNamesSorted(t *testing.T) {
+	r := NewTypeRegistry().RegisterMultiple(SimpleUser{}, NestedConfig{}, DatabaseConfig{}, ServerConfig{})
+	want := []string{
+		"safedeserialize.DatabaseConfig",
+		"safedeserialize.NestedConfig",
+		"safedeserialize.ServerConfig",
+		"safedeserialize.SimpleUser",
+	}
+	for i := 0; i < 5; i++ {
+		if got := r.TypeNames(); !slices.Equal(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
 // ============================================================================
 // Validation and Options Tests
 // ============================================================================
@@ -373,6 +1153,99 @@ func TestValidation(t *testing.T) {
 	}
 }
 
+func TestValidateDeepFields(t *testing.T) {
+	type SliceOrder struct{ Items []DeepItem }
+	type PtrSliceOrder struct{ Items []*DeepItem }
+	type ArrayOrder struct{ Items [4]DeepItem }
+	type MapOrder struct{ Items map[string]DeepItem }
+	type MapPtrOrder struct{ Items map[string]*DeepItem }
+	type NestedSliceOrder struct{ Batches [][]DeepItem }
+	type SliceMapOrder struct{ Items []map[string]DeepItem }
+	type ArrayAnyOrder struct{ Items [2]any }
+	type SafeOrder struct {
+		Items  []SafeItem
+		ByID   map[string]*SafeItem
+		Fixed  [2]SafeItem
+		Nested [][]SafeItem
+	}
+	type Recursive struct {
+		Children []Recursive
+		Parent   *Recursive
+	}
+
+	tests := []struct {
+		name   string
+		target any
+		path   string
+	}{
+		{name: "slice of struct", target: &SliceOrder{}, path: "SliceOrder.Items[].Meta"},
+		{name: "slice of pointer", target: &PtrSliceOrder{}, path: "PtrSliceOrder.Items[].Meta"},
+		{name: "array of struct", target: &ArrayOrder{}, path: "ArrayOrder.Items[].Meta"},
+		{name: "map of struct", target: &MapOrder{}, path: "MapOrder.Items[].Meta"},
+		{name: "map of pointer to struct", target: &MapPtrOrder{}, path: "MapPtrOrder.Items[].Meta"},
+		{name: "nested slices", target: &NestedSliceOrder{}, path: "NestedSliceOrder.Batches[][].Meta"},
+		{name: "slice of maps", target: &SliceMapOrder{}, path: "SliceMapOrder.Items[][].Meta"},
+		{name: "array of any", target: &ArrayAnyOrder{}, path: "ArrayAnyOrder.Items"},
+		{name: "safe containers", target: &SafeOrder{}},
+		{name: "recursive", target: &Recursive{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTarget(tt.target, DefaultOptions())
+			if tt.path == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), "field "+tt.path+" ") {
+				t.Errorf("expected path %s in %q", tt.path, err)
+			}
+		})
+	}
+}
+
+func TestValidateFieldPath(t *testing.T) {
+	type Gateway struct {
+		Name    string `json:"name"`
+		Options any    `json:"options,omitempty"`
+	}
+	type Payment struct {
+		Gateway Gateway `yaml:"gateway"`
+	}
+	type CreateOrderRequest struct {
+		Payment Payment
+	}
+	type Node struct {
+		Children []*Node          `json:"children"`
+		Attrs    map[string]any   `json:"attrs"`
+		Parent   *Node            `json:"-"`
+		Index    map[string]*Node `xml:"index"`
+	}
+
+	tests := []struct {
+		name   string
+		target any
+		want   string
+	}{
+		{name: "deep field with tag", target: &CreateOrderRequest{}, want: `struct field CreateOrderRequest.Payment.Gateway.Options (tag "options") is any type`},
+		{name: "recursive type reports first occurrence", target: &Node{}, want: `struct field Node.Attrs (tag "attrs") contains map with any values`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTarget(tt.target, DefaultOptions())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
 func TestOptions(t *testing.T) {
 	opts := DefaultOptions()
 	if opts.MaxSize != DefaultMaxSize || opts.MaxDepth != DefaultMaxDepth || !opts.StrictMode {
@@ -432,6 +1305,8 @@ func TestMeasureJSONDepth(t *testing.T) {
 func BenchmarkJSON(b *testing.B) {
 	data := []byte(`{"id": 1, "name": "John", "email": "john@example.com"}`)
 	var u SimpleUser
+	assertAllocsOverJSON(b, func() { _ = JSON(data, &u) }, data)
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		_ = JSON(data, &u)
@@ -442,8 +1317,34 @@ func BenchmarkDecoder(b *testing.B) {
 	decoder := NewDecoder()
 	data := []byte(`{"id": 1, "name": "John", "email": "john@example.com"}`)
 	var u SimpleUser
+	assertAllocsOverJSON(b, func() { _ = decoder.JSON(data, &u) }, data)
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		_ = decoder.JSON(data, &u)
 	}
 }
+
+func BenchmarkDecoderReader(b *testing.B) {
+	decoder := NewDecoder()
+	data := []byte(`{"id": 1, "name": "John", "email": "john@example.com"}`)
+	var u SimpleUser
+	r := bytes.NewReader(data)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Reset(data)
+		_ = decoder.JSONReader(r, &u)
+	}
+}
+
+// assertAllocsOverJSON fails the benchmark if decode allocates more than
+// two objects per call beyond what encoding/json needs for data
+func assertAllocsOverJSON(b *testing.B, decode func(), data []byte) {
+	b.Helper()
+	var u SimpleUser
+	base := testing.AllocsPerRun(100, func() { _ = json.Unmarshal(data, &u) })
+	if got := testing.AllocsPerRun(100, decode); got > base+2 {
+		b.Fatalf("%.0f allocs/op, encoding/json needs %.0f", got, base)
+	}
+}
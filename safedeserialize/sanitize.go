@@ -0,0 +1,52 @@
+package safedeserialize
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/ravisastryk/go-safeinput"
+)
+
+// SanitizeMode selects what happens to a decoded string the sanitizer
+// would change
+type SanitizeMode int
+
+const (
+	// SanitizeReplace replaces each string with its sanitized form
+	SanitizeReplace SanitizeMode = iota
+	// SanitizeReject fails the decode if any string would be changed
+	SanitizeReject
+)
+
+// sanitizeStrings sanitizes every string reachable from the decoded
+// target v in place. Strings without a sanitize tag use SanitizeContext.
+func sanitizeStrings(v any, opts *Options) error {
+	root := reflect.TypeOf(v).Elem().String()
+	err := safeinput.WalkStrings(v, func(f safeinput.StringField, value string) (string, error) {
+		ctx := opts.SanitizeContext
+		if f.Tagged {
+			ctx = f.Context
+		}
+		ref := fieldRef{path: root + f.Path, tag: fieldTagName(f.Field)}
+		return sanitizeString(value, ref, ctx, opts)
+	})
+	// The walk itself only fails on an unknown sanitize tag
+	var fe *safeinput.FieldError
+	if !errors.Is(err, ErrSanitizationFailed) && errors.As(err, &fe) {
+		return fmt.Errorf("%w: field %s: %w", ErrSanitizationFailed, root+fe.Key, fe.Err)
+	}
+	return err
+}
+
+// sanitizeString sanitizes a single string according to the sanitize mode
+func sanitizeString(value string, f fieldRef, ctx safeinput.Context, opts *Options) (string, error) {
+	sanitized, err := opts.Sanitizer.Sanitize(value, ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w: field %s: %w", ErrSanitizationFailed, f, err)
+	}
+	if sanitized != value && opts.SanitizeMode == SanitizeReject {
+		return "", fmt.Errorf("%w: field %s is not safe for %s", ErrSanitizationFailed, f, ctx)
+	}
+	return sanitized, nil
+}
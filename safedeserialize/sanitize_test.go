@@ -0,0 +1,108 @@
+package safedeserialize
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ravisastryk/go-safeinput"
+)
+
+type Comment struct {
+	Author   string            `json:"author" yaml:"author"`
+	Body     string            `json:"body" yaml:"body"`
+	Tags     []string          `json:"tags" yaml:"tags"`
+	Meta     map[string]string `json:"meta" yaml:"meta"`
+	Reply    *Comment          `json:"reply" yaml:"reply"`
+	Filename string            `json:"filename" yaml:"filename" sanitize:"shell"`
+	Raw      string            `json:"raw" yaml:"raw" sanitize:"-"`
+}
+
+func TestStringSanitizer(t *testing.T) {
+	data := []byte(`{
+		"author": "<b>eve</b>",
+		"body": "hi<script>x</script>",
+		"tags": ["<i>go</i>"],
+		"meta": {"ref": "<a href=x>r</a>"},
+		"reply": {"author": "<b>bob</b>"},
+		"filename": "a b;rm",
+		"raw": "<b>kept</b>"
+	}`)
+
+	var c Comment
+	err := JSON(data, &c, WithStringSanitizer(safeinput.Default(), safeinput.HTMLBody))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name, got, want string
+	}{
+		{"field", c.Author, "eve"},
+		{"script", c.Body, "hi"},
+		{"slice", c.Tags[0], "go"},
+		{"map", c.Meta["ref"], "r"},
+		{"nested", c.Reply.Author, "bob"},
+		{"tag", c.Filename, "abrm"},
+		{"skip", c.Raw, "<b>kept</b>"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestStringSanitizerReject(t *testing.T) {
+	opts := []Option{
+		WithStringSanitizer(safeinput.Default(), safeinput.HTMLBody),
+		WithSanitizeMode(SanitizeReject),
+	}
+
+	var clean Comment
+	if err := YAML([]byte("author: eve\ntags: [go]\n"), &clean, opts...); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	var dirty Comment
+	err := YAML([]byte("author: eve\ntags: [go, '<i>x</i>']\n"), &dirty, opts...)
+	if !errors.Is(err, ErrSanitizationFailed) {
+		t.Fatalf("expected ErrSanitizationFailed, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `Comment.Tags[] (tag "tags")`) {
+		t.Errorf("error does not name the field: %v", err)
+	}
+}
+
+func TestStringSanitizerErrors(t *testing.T) {
+	type Upload struct {
+		Path string `json:"path" sanitize:"path"`
+	}
+	type BadTag struct {
+		Name string `json:"name" sanitize:"unknown"`
+	}
+
+	err := JSON([]byte(`{"path":"../etc/passwd"}`), &Upload{}, WithStringSanitizer(safeinput.Default(), safeinput.HTMLBody))
+	if !errors.Is(err, ErrSanitizationFailed) {
+		t.Errorf("expected ErrSanitizationFailed, got %v", err)
+	}
+
+	err = JSON([]byte(`{"name":"x"}`), &BadTag{}, WithStringSanitizer(safeinput.Default(), safeinput.HTMLBody))
+	if !errors.Is(err, safeinput.ErrUnknownContext) {
+		t.Errorf("expected ErrUnknownContext, got %v", err)
+	}
+}
+
+func TestStringSanitizerMapInterface(t *testing.T) {
+	var m map[string]any
+	err := JSON([]byte(`{"a":"<b>x</b>","b":["<i>y</i>"],"c":1}`), &m,
+		WithAllowMapStringInterface(true),
+		WithStringSanitizer(safeinput.Default(), safeinput.HTMLBody),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m["a"] != "x" || m["b"].([]any)[0] != "y" || m["c"] != float64(1) {
+		t.Errorf("unexpected result: %v", m)
+	}
+}
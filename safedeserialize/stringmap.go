@@ -0,0 +1,89 @@
+package safedeserialize
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Limits StringMap applies unless overridden by its options
+const (
+	stringMapMaxKeys         = 256
+	stringMapMaxStringLength = 4 << 10
+)
+
+// StringMap safely decodes a flat JSON object of string values, such as
+// labels or annotations. Unless opts say otherwise it allows at most 256
+// keys and 4KB per key or value.
+func StringMap(data []byte, opts ...Option) (map[string]string, error) {
+	opts = append([]Option{WithMaxMapKeys(stringMapMaxKeys), WithMaxStringLength(stringMapMaxStringLength)}, opts...)
+	var m map[string]string
+	if err := JSON(data, &m, opts...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// isMapType reports whether t is a map type
+func isMapType(t reflect.Type) bool {
+	return t != nil && t.Kind() == reflect.Map
+}
+
+// typeReaches reports whether a value of type t can hold a value whose type
+// matches, directly or through fields and elements; seen guards against
+// recursive types
+func typeReaches(t reflect.Type, match func(reflect.Type) bool, seen map[reflect.Type]bool) bool {
+	if match(t) {
+		return true
+	}
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return typeReaches(t.Elem(), match, seen)
+	case reflect.Map:
+		return typeReaches(t.Elem(), match, seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if typeReaches(t.Field(i).Type, match, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkMapKey applies MaxMapKeys and MaxStringLength to the count-th key of
+// the map at path
+func checkMapKey(key string, count int, path string, opts *Options) error {
+	if opts.MaxMapKeys > 0 && count > opts.MaxMapKeys {
+		return fmt.Errorf("%w: map %s has more than %d keys, first extra key %s",
+			ErrTooManyMapKeys, displayPath(path), opts.MaxMapKeys, quoteKey(key))
+	}
+	if opts.MaxStringLength > 0 && len(key) > opts.MaxStringLength {
+		return fmt.Errorf("%w: key %s of map %s is %d bytes, limit %d",
+			ErrStringTooLong, quoteKey(key), displayPath(path), len(key), opts.MaxStringLength)
+	}
+	return nil
+}
+
+// checkMapValue applies MaxStringLength to a string value of the map at path
+func checkMapValue(key, value, path string, opts *Options) error {
+	if opts.MaxStringLength > 0 && len(value) > opts.MaxStringLength {
+		return fmt.Errorf("%w: value of key %s of map %s is %d bytes, limit %d",
+			ErrStringTooLong, quoteKey(key), displayPath(path), len(value), opts.MaxStringLength)
+	}
+	return nil
+}
+
+// quoteKey quotes a key for an error message, shortening long keys
+func quoteKey(key string) string {
+	const maxShown = 64
+	if len(key) > maxShown {
+		return strconv.Quote(key[:maxShown]) + "..."
+	}
+	return strconv.Quote(key)
+}
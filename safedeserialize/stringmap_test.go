@@ -0,0 +1,132 @@
+package safedeserialize
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// jsonObject returns a JSON object with n keys k0..kn-1 holding value
+func jsonObject(n int, value string) string {
+	pairs := make([]string, n)
+	for i := range pairs {
+		pairs[i] = fmt.Sprintf(`"k%d":%q`, i, value)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// yamlMapping returns a YAML mapping with n keys k0..kn-1 holding value
+func yamlMapping(n int, value string) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "k%d: %q\n", i, value)
+	}
+	return b.String()
+}
+
+func TestMapLimits(t *testing.T) {
+	type labeled struct {
+		Name   string            `json:"name" yaml:"name"`
+		Labels map[string]string `json:"labels" yaml:"labels"`
+	}
+	limits := []Option{WithMaxMapKeys(3), WithMaxStringLength(8)}
+
+	tests := []struct {
+		name    string
+		format  Format
+		data    string
+		target  func() any
+		wantErr error
+		detail  string
+	}{
+		{"json at limit", FormatJSON, jsonObject(3, "12345678"), func() any { return &map[string]string{} }, nil, ""},
+		{"json too many keys", FormatJSON, jsonObject(4, "v"), func() any { return &map[string]string{} }, ErrTooManyMapKeys, `first extra key "k3"`},
+		{"json long value", FormatJSON, `{"a":"123456789"}`, func() any { return &map[string]string{} }, ErrStringTooLong, `value of key "a" of map (root) is 9 bytes`},
+		{"json long key", FormatJSON, `{"123456789":"v"}`, func() any { return &map[string]string{} }, ErrStringTooLong, `key "123456789" of map (root) is 9 bytes`},
+		{"json field map", FormatJSON, `{"name":"n","labels":` + jsonObject(4, "v") + `}`, func() any { return &labeled{} }, ErrTooManyMapKeys, "map labels"},
+		{"json field name case", FormatJSON, `{"LABELS":{"a":"123456789"}}`, func() any { return &labeled{} }, ErrStringTooLong, "map LABELS"},
+		{"json nested maps", FormatJSON, `{"a":{"b":"123456789"}}`, func() any { return &map[string]map[string]string{} }, ErrStringTooLong, `key "b" of map a`},
+		{"json slice of maps", FormatJSON, `[{"a":"v"},` + jsonObject(4, "v") + `]`, func() any { return &[]map[string]string{} }, ErrTooManyMapKeys, "map [1]"},
+		{"json struct not limited", FormatJSON, `{"id":1,"name":"a long name","email":"a@example.com"}`, func() any { return &SimpleUser{} }, nil, ""},
+		{"yaml at limit", FormatYAML, yamlMapping(3, "12345678"), func() any { return &map[string]string{} }, nil, ""},
+		{"yaml too many keys", FormatYAML, yamlMapping(4, "v"), func() any { return &map[string]string{} }, ErrTooManyMapKeys, `first extra key "k3"`},
+		{"yaml long value", FormatYAML, "a: '123456789'\n", func() any { return &map[string]string{} }, ErrStringTooLong, `value of key "a" of map (root) is 9 bytes`},
+		{"yaml field map", FormatYAML, "name: n\nlabels:\n  a: '123456789'\n", func() any { return &labeled{} }, ErrStringTooLong, "map labels"},
+		{"yaml merge keys", FormatYAML, "base: &b {a: 1, b: 2}\nlabels: {<<: *b, c: 3, d: 4}\n", func() any { return &labeled{} }, ErrTooManyMapKeys, `first extra key "d"`},
+		{"yaml aliased value", FormatYAML, "name: &n '123456789'\nlabels: {a: *n}\n", func() any { return &labeled{} }, ErrStringTooLong, `value of key "a" of map labels`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.target()
+			err := DecodeFormat(tt.format, []byte(tt.data), target, append(limits, WithStrictMode(false))...)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if !strings.Contains(err.Error(), tt.detail) {
+				t.Errorf("expected %q in %q", tt.detail, err)
+			}
+		})
+	}
+}
+
+func TestMapLimitsLongKeyShortened(t *testing.T) {
+	key := strings.Repeat("k", 10000)
+	err := JSON([]byte(`{"`+key+`":"v"}`), &map[string]string{}, WithMaxStringLength(100))
+	if !errors.Is(err, ErrStringTooLong) {
+		t.Fatalf("expected ErrStringTooLong, got %v", err)
+	}
+	if len(err.Error()) > 300 {
+		t.Errorf("error message not shortened: %d bytes", len(err.Error()))
+	}
+}
+
+func TestStringMap(t *testing.T) {
+	got, err := StringMap([]byte(`{"app":"web","tier":"frontend"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got["app"] != "web" || got["tier"] != "frontend" {
+		t.Errorf("unexpected result: %v", got)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		opts    []Option
+		wantErr error
+	}{
+		{"default key limit", jsonObject(256, "v"), nil, nil},
+		{"over default key limit", jsonObject(257, "v"), nil, ErrTooManyMapKeys},
+		{"default value limit", jsonObject(1, strings.Repeat("x", 4<<10)), nil, nil},
+		{"over default value limit", jsonObject(1, strings.Repeat("x", 4<<10+1)), nil, ErrStringTooLong},
+		{"raised key limit", jsonObject(300, "v"), []Option{WithMaxMapKeys(512)}, nil},
+		{"lowered value limit", jsonObject(1, "12345"), []Option{WithMaxStringLength(4)}, ErrStringTooLong},
+		{"non-string value", `{"a":1}`, nil, ErrMalformedInput},
+		{"nested object", `{"a":{"b":"c"}}`, nil, ErrMalformedInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := StringMap([]byte(tt.data), tt.opts...)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if m != nil {
+				t.Errorf("expected nil map on error, got %v", m)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package safedeserialize
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// hasTimePolicy reports whether any time option is set
+func (o *Options) hasTimePolicy() bool {
+	return len(o.TimeLayouts) > 0 || o.EpochSeconds || !o.TimeMin.IsZero() || !o.TimeMax.IsZero()
+}
+
+// needsTimeScan reports whether a time policy is set and target v can
+// hold a time.Time
+func needsTimeScan(v any, opts *Options) bool {
+	return v != nil && opts.hasTimePolicy() &&
+		typeReaches(reflect.TypeOf(v), isTimeType, make(map[reflect.Type]bool))
+}
+
+// isTimeType reports whether t is time.Time
+func isTimeType(t reflect.Type) bool {
+	return t == timeType
+}
+
+// parseTimeString parses a textual time.Time value at path with the
+// configured layouts, RFC 3339 if none are set
+func parseTimeString(s, path string, opts *Options) (time.Time, error) {
+	layouts := opts.TimeLayouts
+	if len(layouts) == 0 {
+		layouts = []string{time.RFC3339}
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return checkTimeRange(t, path, opts)
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: field %s value %q matches none of the allowed layouts",
+		ErrInvalidTime, displayPath(path), s)
+}
+
+// parseEpochSeconds parses a numeric time.Time value at path as Unix seconds
+func parseEpochSeconds(s, path string, opts *Options) (time.Time, error) {
+	if !opts.EpochSeconds {
+		return time.Time{}, fmt.Errorf("%w: field %s value %s is a number, epoch seconds are not allowed",
+			ErrInvalidTime, displayPath(path), s)
+	}
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: field %s value %s is not whole epoch seconds",
+			ErrInvalidTime, displayPath(path), s)
+	}
+	return checkTimeRange(time.Unix(sec, 0).UTC(), path, opts)
+}
+
+// checkTimeRange rejects t if it falls outside TimeMin and TimeMax
+func checkTimeRange(t time.Time, path string, opts *Options) (time.Time, error) {
+	if (!opts.TimeMin.IsZero() && t.Before(opts.TimeMin)) || (!opts.TimeMax.IsZero() && t.After(opts.TimeMax)) {
+		return time.Time{}, fmt.Errorf("%w: field %s value %s", ErrTimeOutOfRange, displayPath(path), t.Format(time.RFC3339))
+	}
+	return t, nil
+}
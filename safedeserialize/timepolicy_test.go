@@ -0,0 +1,138 @@
+package safedeserialize
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type timedEvent struct {
+	Name   string               `json:"name" yaml:"name" xml:"name"`
+	At     time.Time            `json:"at" yaml:"at" xml:"at"`
+	Ends   *time.Time           `json:"ends,omitempty" yaml:"ends,omitempty" xml:"ends,omitempty"`
+	Seen   []time.Time          `json:"seen,omitempty" yaml:"seen,omitempty" xml:"seen,omitempty"`
+	ByName map[string]time.Time `json:"by_name,omitempty" yaml:"by_name,omitempty" xml:"-"`
+}
+
+func TestTimePolicy(t *testing.T) {
+	rfc := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	epoch := time.Unix(1700000000, 0).UTC()
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	inRange := WithTimeRange(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name    string
+		json    string
+		yaml    string
+		opts    []Option
+		want    time.Time
+		wantErr error
+		detail  string
+	}{
+		{name: "rfc3339", json: `{"at":"2024-03-01T12:30:00Z"}`, yaml: "at: 2024-03-01T12:30:00Z\n", opts: []Option{WithEpochSeconds(true)}, want: rfc},
+		{name: "rfc3339 offset", json: `{"at":"2024-03-01T14:30:00+02:00"}`, yaml: "at: '2024-03-01T14:30:00+02:00'\n", opts: []Option{WithEpochSeconds(true)}, want: rfc},
+		{name: "epoch", json: `{"at":1700000000}`, yaml: "at: 1700000000\n", opts: []Option{WithEpochSeconds(true)}, want: epoch},
+		{name: "epoch not allowed", json: `{"at":1700000000}`, yaml: "at: 1700000000\n", opts: []Option{WithTimeLayouts(time.RFC3339)}, wantErr: ErrInvalidTime, detail: "field at value 1700000000 is a number"},
+		{name: "fractional epoch", json: `{"at":1700000000.5}`, yaml: "at: 1700000000.5\n", opts: []Option{WithEpochSeconds(true)}, wantErr: ErrInvalidTime, detail: "not whole epoch seconds"},
+		{name: "ambiguous format", json: `{"at":"03/01/2024"}`, yaml: "at: 03/01/2024\n", opts: []Option{WithEpochSeconds(true)}, wantErr: ErrInvalidTime, detail: `field at value "03/01/2024" matches none`},
+		{name: "yaml space separated", json: `{"at":"2024-03-01 12:30:00"}`, yaml: "at: 2024-03-01 12:30:00\n", opts: []Option{WithEpochSeconds(true)}, wantErr: ErrInvalidTime, detail: "matches none"},
+		{name: "custom layout", json: `{"at":"2024-03-01"}`, yaml: "at: 2024-03-01\n", opts: []Option{WithTimeLayouts(time.DateOnly)}, want: day},
+		{name: "layout excludes rfc3339", json: `{"at":"2024-03-01T12:30:00Z"}`, yaml: "at: 2024-03-01T12:30:00Z\n", opts: []Option{WithTimeLayouts(time.DateOnly)}, wantErr: ErrInvalidTime},
+		{name: "in range", json: `{"at":"2024-03-01T12:30:00Z"}`, yaml: "at: 2024-03-01T12:30:00Z\n", opts: []Option{inRange}, want: rfc},
+		{name: "year 9999", json: `{"at":"9999-12-31T23:59:59Z"}`, yaml: "at: 9999-12-31T23:59:59Z\n", opts: []Option{inRange}, wantErr: ErrTimeOutOfRange, detail: "field at value 9999-12-31T23:59:59Z"},
+		{name: "epoch out of range", json: `{"at":253402300799}`, yaml: "at: 253402300799\n", opts: []Option{inRange, WithEpochSeconds(true)}, wantErr: ErrTimeOutOfRange},
+		{name: "pointer field", json: `{"ends":"bad"}`, yaml: "ends: bad\n", opts: []Option{WithEpochSeconds(true)}, wantErr: ErrInvalidTime, detail: "field ends"},
+		{name: "slice element", json: `{"seen":["2024-03-01T12:30:00Z","bad"]}`, yaml: "seen: [2024-03-01T12:30:00Z, bad]\n", opts: []Option{WithEpochSeconds(true)}, wantErr: ErrInvalidTime, detail: "field seen[1]"},
+		{name: "map value", json: `{"by_name":{"x":"bad"}}`, yaml: "by_name: {x: bad}\n", opts: []Option{WithEpochSeconds(true)}, wantErr: ErrInvalidTime, detail: "field by_name.x"},
+		{name: "null", json: `{"at":null}`, yaml: "at: null\n", opts: []Option{WithEpochSeconds(true)}},
+	}
+
+	for _, tt := range tests {
+		for _, format := range []Format{FormatJSON, FormatYAML} {
+			t.Run(tt.name+"/"+string(format), func(t *testing.T) {
+				data := tt.json
+				if format == FormatYAML {
+					data = tt.yaml
+				}
+				var got timedEvent
+				err := DecodeFormat(format, []byte(data), &got, tt.opts...)
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("expected %v, got %v", tt.wantErr, err)
+					}
+					if !strings.Contains(err.Error(), tt.detail) {
+						t.Errorf("expected %q in %q", tt.detail, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !got.At.Equal(tt.want) {
+					t.Errorf("expected %v, got %v", tt.want, got.At)
+				}
+			})
+		}
+	}
+}
+
+func TestTimePolicyRewrite(t *testing.T) {
+	// Values around a rewritten time keep their exact encoding
+	data := []byte(`{"name":"a \"quoted\" é name", "at" : 1700000000 ,"seen":[1700000000,"2024-03-01T12:30:00Z"]}`)
+	var got timedEvent
+	if err := JSON(data, &got, WithEpochSeconds(true)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Name != `a "quoted" é name` {
+		t.Errorf("unexpected name %q", got.Name)
+	}
+	if len(got.Seen) != 2 || got.Seen[0].Unix() != 1700000000 || got.Seen[1].Unix() != 1709296200 {
+		t.Errorf("unexpected seen %v", got.Seen)
+	}
+
+	// Aliased YAML values are rewritten once and decode everywhere they appear
+	yamlData := []byte("at: &t 1700000000\nseen: [*t, *t]\n")
+	got = timedEvent{}
+	if err := YAML(yamlData, &got, WithEpochSeconds(true)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.At.Unix() != 1700000000 || len(got.Seen) != 2 || !got.Seen[1].Equal(got.At) {
+		t.Errorf("unexpected result %+v", got)
+	}
+}
+
+func TestTimePolicyScope(t *testing.T) {
+	// Without a policy the decoders' own parsing applies
+	var got timedEvent
+	if err := YAML([]byte("at: 2024-03-01\n"), &got); err != nil {
+		t.Errorf("unexpected error without a policy: %v", err)
+	}
+
+	// Targets without time.Time are unaffected by the policy
+	if err := JSON([]byte(`{"id":1}`), &SimpleUser{}, WithEpochSeconds(true)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := XML([]byte(`<SimpleUser><id>1</id></SimpleUser>`), &SimpleUser{}, WithEpochSeconds(true)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Formats that cannot apply it refuse time targets
+	if err := XML([]byte(`<e><at>2024-03-01T12:30:00Z</at></e>`), &timedEvent{}, WithEpochSeconds(true)); !errors.Is(err, ErrUnsupportedOption) {
+		t.Errorf("XML: expected ErrUnsupportedOption, got %v", err)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(timedEvent{At: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Gob(buf.Bytes(), &timedEvent{}, WithEpochSeconds(true)); !errors.Is(err, ErrUnsupportedOption) {
+		t.Errorf("Gob: expected ErrUnsupportedOption, got %v", err)
+	}
+
+	end := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := NewDecoderStrict(WithTimeRange(end.AddDate(1, 0, 0), end)); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("expected ErrInvalidOptions for an inverted range, got %v", err)
+	}
+}
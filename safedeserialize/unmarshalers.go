@@ -0,0 +1,71 @@
+package safedeserialize
+
+import (
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// unmarshalerInterfaces are the hooks through which a decoder hands
+// control to code on the target type
+var unmarshalerInterfaces = []struct {
+	name string
+	typ  reflect.Type
+}{
+	{"json.Unmarshaler", jsonUnmarshalerType},
+	{"yaml.Unmarshaler", yamlUnmarshalerType},
+	{"xml.Unmarshaler", xmlUnmarshalerType},
+	{"xml.UnmarshalerAttr", reflect.TypeOf((*xml.UnmarshalerAttr)(nil)).Elem()},
+	{"encoding.TextUnmarshaler", reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()},
+	{"encoding.BinaryUnmarshaler", reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()},
+	{"gob.GobDecoder", reflect.TypeOf((*gob.GobDecoder)(nil)).Elem()},
+}
+
+// defaultAllowedUnmarshalers are standard library types whose custom
+// unmarshalers are trusted without configuration
+var defaultAllowedUnmarshalers = []reflect.Type{
+	reflect.TypeOf(time.Time{}),
+	reflect.TypeOf(big.Int{}),
+	reflect.TypeOf(big.Float{}),
+	reflect.TypeOf(big.Rat{}),
+	reflect.TypeOf(net.IP{}),
+	reflect.TypeOf(netip.Addr{}),
+	reflect.TypeOf(netip.AddrPort{}),
+	reflect.TypeOf(netip.Prefix{}),
+	reflect.TypeOf(url.URL{}),
+	reflect.TypeOf(json.RawMessage{}),
+}
+
+// customUnmarshaler returns the name of the first unmarshaler interface
+// implemented by t or *t, or "" if decoding t runs no custom code
+func customUnmarshaler(t reflect.Type) string {
+	for _, u := range unmarshalerInterfaces {
+		if t.Implements(u.typ) || reflect.PointerTo(t).Implements(u.typ) {
+			return u.name
+		}
+	}
+	return ""
+}
+
+// checkUnmarshaler reports whether t decodes itself through a custom
+// unmarshaler, and rejects it unless the type is allowed. Callers stop
+// walking t when it is custom, as its fields are not populated directly.
+func checkUnmarshaler(t reflect.Type, f fieldRef, opts *Options) (bool, error) {
+	name := customUnmarshaler(t)
+	if name == "" {
+		return false, nil
+	}
+	if slices.Contains(defaultAllowedUnmarshalers, t) || slices.Contains(opts.AllowedUnmarshalers, t) {
+		return true, nil
+	}
+	return true, fmt.Errorf("%w: %s implements %s (field %s)", ErrUnmarshalerNotAllowed, t, name, f)
+}
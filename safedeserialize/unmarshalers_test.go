@@ -0,0 +1,95 @@
+package safedeserialize
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Cents decodes "12.34" style amounts through a custom unmarshaler
+type Cents int64
+
+func (c *Cents) UnmarshalJSON(data []byte) error {
+	f, err := strconv.ParseFloat(strings.Trim(string(data), `"`), 64)
+	if err != nil {
+		return err
+	}
+	*c = Cents(f * 100)
+	return nil
+}
+
+// Currency is used as a map key through encoding.TextUnmarshaler
+type Currency string
+
+func (c *Currency) UnmarshalText(text []byte) error {
+	*c = Currency(strings.ToUpper(string(text)))
+	return nil
+}
+
+type Invoice struct {
+	Issued time.Time `json:"issued"`
+	Lines  []struct {
+		Total Cents `json:"total"`
+	} `json:"lines"`
+}
+
+type Rates struct {
+	ByCurrency map[Currency]float64 `json:"by_currency"`
+}
+
+func TestUnmarshalerGuard(t *testing.T) {
+	invoice := []byte(`{"issued":"2024-01-02T03:04:05Z","lines":[{"total":"12.34"}]}`)
+
+	t.Run("rejected in strict mode", func(t *testing.T) {
+		err := JSON(invoice, &Invoice{})
+		if !errors.Is(err, ErrUnmarshalerNotAllowed) {
+			t.Fatalf("expected ErrUnmarshalerNotAllowed, got %v", err)
+		}
+		for _, want := range []string{"safedeserialize.Cents", "json.Unmarshaler", `Invoice.Lines[].Total (tag "total")`} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error %q does not contain %q", err, want)
+			}
+		}
+	})
+
+	t.Run("explicitly allowed", func(t *testing.T) {
+		for _, allowed := range []any{Cents(0), new(Cents)} {
+			var inv Invoice
+			if err := JSON(invoice, &inv, WithAllowedUnmarshalers(allowed)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if inv.Lines[0].Total != 1234 || inv.Issued.Year() != 2024 {
+				t.Errorf("unexpected result: %+v", inv)
+			}
+		}
+	})
+
+	t.Run("allowed without strict mode", func(t *testing.T) {
+		if err := JSON(invoice, &Invoice{}, WithStrictMode(false)); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("map key", func(t *testing.T) {
+		data := []byte(`{"by_currency":{"usd":1}}`)
+		if err := JSON(data, &Rates{}); !errors.Is(err, ErrUnmarshalerNotAllowed) {
+			t.Errorf("expected ErrUnmarshalerNotAllowed, got %v", err)
+		}
+		if err := JSON(data, &Rates{}, WithAllowedUnmarshalers(Currency(""))); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("target", func(t *testing.T) {
+		var c Cents
+		if err := JSON([]byte(`"1.50"`), &c); !errors.Is(err, ErrUnmarshalerNotAllowed) {
+			t.Errorf("expected ErrUnmarshalerNotAllowed, got %v", err)
+		}
+		var ts time.Time
+		if err := JSON([]byte(`"2024-01-02T03:04:05Z"`), &ts); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
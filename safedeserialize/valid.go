@@ -0,0 +1,118 @@
+package safedeserialize
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// ValidatingCodec is implemented by registered codecs that support Valid
+type ValidatingCodec interface {
+	Codec
+
+	// Valid checks data without decoding it into a target. opts is a
+	// private copy, as for Unmarshal.
+	Valid(data []byte, opts *Options) error
+}
+
+// builtinValidators check documents in the built-in formats
+var builtinValidators = map[Format]func(data []byte, opts *Options) error{
+	FormatJSON: validJSON,
+	FormatYAML: validYAML,
+	FormatXML:  validXML,
+	FormatGob:  validGob,
+}
+
+// Valid reports whether data would pass the document-level checks of a
+// decode in the given format, without decoding it into a target: format
+// allow-listing, size and depth limits, denied and allowed fields, the
+// format's content restrictions and syntax. Errors are the ones the full
+// decode returns for the same input.
+//
+// Checks that depend on the target type, such as unknown fields in strict
+// mode or numbers that do not fit a field, are not applied, so a payload
+// accepted by Valid can still be rejected when it is decoded.
+func Valid(format Format, data []byte, opts ...Option) error {
+	options := DefaultOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+	return valid(format, data, options)
+}
+
+func valid(format Format, data []byte, opts *Options) error {
+	return categorize(checkValid(format, data, opts))
+}
+
+func checkValid(format Format, data []byte, opts *Options) error {
+	if err := checkFormatAllowed(format, opts); err != nil {
+		return err
+	}
+	codec, builtin, err := lookupCodec(format)
+	if err != nil {
+		return err
+	}
+
+	if len(data) == 0 {
+		return ErrEmptyData
+	}
+	if int64(len(data)) > opts.MaxSize {
+		return fmt.Errorf("%w: size %d exceeds limit %d", ErrDataTooLarge, len(data), opts.MaxSize)
+	}
+
+	if builtin {
+		return builtinValidators[format](data, opts)
+	}
+	if c, ok := codec.(ValidatingCodec); ok {
+		return c.Valid(data, opts.clone())
+	}
+	return fmt.Errorf("safedeserialize: format %q does not support Valid", format)
+}
+
+func validJSON(data []byte, opts *Options) error {
+	if err := checkDepth(measureJSONDepth(data), opts); err != nil {
+		return err
+	}
+
+	if _, _, err := scanJSON(data, nil, opts); err != nil {
+		return err
+	}
+
+	if json.Valid(data) {
+		return nil
+	}
+
+	// Reproduce the error the decode would report for the same input
+	var raw json.RawMessage
+	if opts.StrictMode && !opts.UseNumber {
+		return decodeStrictJSON(data, &raw)
+	}
+	if opts.StrictMode || opts.UseNumber {
+		return decodeSingleJSON(json.NewDecoder(bytes.NewReader(data)), &raw)
+	}
+	return json.Unmarshal(data, &raw)
+}
+
+func validYAML(data []byte, opts *Options) error {
+	root, err := parseYAML(data, opts)
+	if err != nil || root == nil {
+		return err
+	}
+	return walkYAML(root, "", opts)
+}
+
+func validXML(data []byte, opts *Options) error {
+	return scanXML(data, nil, opts)
+}
+
+// validGob reads the first value of a gob stream and discards it, which
+// checks the stream's type descriptors and encoding
+func validGob(data []byte, opts *Options) error {
+	if err := checkGobOptions(nil, opts); err != nil {
+		return err
+	}
+	limitedReader := &maxBytesReader{r: bytes.NewReader(data), remaining: opts.MaxSize, limit: opts.MaxSize}
+	return gob.NewDecoder(limitedReader).DecodeValue(reflect.Value{})
+}
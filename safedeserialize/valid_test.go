@@ -0,0 +1,163 @@
+package safedeserialize
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	var gobData bytes.Buffer
+	if err := gob.NewEncoder(&gobData).Encode(SimpleUser{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	deep := []byte(strings.Repeat(`{"a":`, 40) + "1" + strings.Repeat("}", 40))
+
+	tests := []struct {
+		name    string
+		format  Format
+		data    []byte
+		opts    []Option
+		wantErr error
+	}{
+		{"json", FormatJSON, []byte(`{"id":1,"name":"alice"}`), nil, nil},
+		{"json empty", FormatJSON, nil, nil, ErrEmptyData},
+		{"json too large", FormatJSON, []byte(`{"id":1}`), []Option{WithMaxSize(4)}, ErrDataTooLarge},
+		{"json too deep", FormatJSON, deep, nil, ErrMaxDepthExceeded},
+		{"json denied field", FormatJSON, []byte(`{"id":1,"x":{"__proto__":1}}`), []Option{WithDeniedFields("__proto__")}, ErrDeniedField},
+		{"json field not allowed", FormatJSON, []byte(`{"id":1,"admin":true}`), []Option{WithAllowedFields("id")}, ErrFieldNotAllowed},
+		{"json trailing data", FormatJSON, []byte(`{"id":1}{"id":2}`), nil, ErrTrailingData},
+		{"yaml", FormatYAML, []byte("id: 1\nname: alice\n"), nil, nil},
+		{"yaml denied field", FormatYAML, []byte("id: 1\nadmin: true\n"), []Option{WithDeniedFields("admin")}, ErrDeniedField},
+		{"yaml non-finite", FormatYAML, []byte("id: .inf\n"), nil, ErrNonFiniteNumber},
+		{"xml", FormatXML, []byte("<SimpleUser><id>1</id></SimpleUser>"), nil, nil},
+		{"xml dtd", FormatXML, []byte(`<!DOCTYPE x [<!ENTITY e "x">]><SimpleUser/>`), nil, ErrDTDNotAllowed},
+		{"xml entity", FormatXML, []byte("<SimpleUser><id>&e;</id></SimpleUser>"), nil, ErrEntityNotAllowed},
+		{"xml too many elements", FormatXML, []byte("<SimpleUser><id>1</id><name>a</name></SimpleUser>"), []Option{WithMaxElements(2)}, ErrTooManyElements},
+		{"gob", FormatGob, gobData.Bytes(), nil, nil},
+		{"gob too large", FormatGob, gobData.Bytes(), []Option{WithMaxSize(4)}, ErrDataTooLarge},
+		{"format not allowed", FormatXML, []byte("<a/>"), []Option{WithAllowedFormats(FormatJSON)}, ErrFormatNotAllowed},
+		{"unknown format", Format("toml"), []byte("a = 1"), nil, ErrUnknownFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Valid(tt.format, tt.data, tt.opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			// Valid must report what the full decode reports
+			decodeErr := DecodeFormat(tt.format, tt.data, &SimpleUser{}, tt.opts...)
+			if fmt.Sprint(err) != fmt.Sprint(decodeErr) {
+				t.Errorf("Valid returned %v, decode returned %v", err, decodeErr)
+			}
+		})
+	}
+}
+
+func TestValidSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		data   string
+		opts   []Option
+	}{
+		{"json truncated", FormatJSON, `{"id":`, nil},
+		{"json bad token", FormatJSON, `{"id":1,}`, nil},
+		{"json use number", FormatJSON, `{"id":1} x`, []Option{WithUseNumber(true)}},
+		{"json non-strict", FormatJSON, `{"id":1} x`, []Option{WithStrictMode(false)}},
+		{"yaml", FormatYAML, "id: [1\n", nil},
+		{"xml", FormatXML, "<SimpleUser><id>1</SimpleUser>", nil},
+		{"gob", FormatGob, "\x03garbage", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Valid(tt.format, []byte(tt.data), tt.opts...)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			decodeErr := DecodeFormat(tt.format, []byte(tt.data), &SimpleUser{}, tt.opts...)
+			if err.Error() != fmt.Sprint(decodeErr) {
+				t.Errorf("Valid returned %v, decode returned %v", err, decodeErr)
+			}
+		})
+	}
+}
+
+// csvValidatingCodec adds Valid to csvCodec
+type csvValidatingCodec struct {
+	csvCodec
+}
+
+func (csvValidatingCodec) Valid(data []byte, opts *Options) error {
+	if strings.Count(string(data), ",") != 2 {
+		return errors.New("csv: expected 3 fields")
+	}
+	return nil
+}
+
+func init() {
+	if err := RegisterFormat("csv-valid", csvValidatingCodec{}); err != nil {
+		panic(err)
+	}
+}
+
+func TestValidRegisteredFormat(t *testing.T) {
+	if err := Valid("csv-valid", []byte("1,alice,alice@example.com")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := NewDecoder().Valid("csv-valid", []byte("1,alice")); err == nil {
+		t.Error("expected error from codec Valid")
+	}
+	if err := Valid(formatCSV, []byte("1,alice,alice@example.com")); err == nil {
+		t.Error("expected error for codec without Valid")
+	}
+}
+
+// largeUsers is a payload of many records, where decoding cost dominates
+type largeUsers struct {
+	Users []SimpleUser `json:"users"`
+}
+
+func largeJSONPayload() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"users":[`)
+	for i := 0; i < 5000; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `{"id":%d,"name":"user %d","email":"user%d@example.com"}`, i, i, i)
+	}
+	buf.WriteString(`]}`)
+	return buf.Bytes()
+}
+
+func BenchmarkValidLarge(b *testing.B) {
+	data := largeJSONPayload()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Valid(FormatJSON, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJSONLarge(b *testing.B) {
+	data := largeJSONPayload()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var v largeUsers
+		if err := JSON(data, &v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
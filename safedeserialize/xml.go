@@ -0,0 +1,387 @@
+package safedeserialize
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// defaultXMLCharsets are the non-UTF-8 charset labels accepted without
+// configuration; all of them are byte-compatible with UTF-8
+var defaultXMLCharsets = []string{"utf8", "us-ascii", "ascii"}
+
+// newXMLDecoder returns an encoding/xml decoder hardened according to opts.
+// Rather than relying on encoding/xml defaults, it pins strict parsing,
+// disables custom entities and restricts the charsets a document may declare.
+func newXMLDecoder(data []byte, opts *Options) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+	decoder.Entity = map[string]string{}
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		if !isAllowedCharset(charset, opts.AllowedCharsets) {
+			return nil, ErrCharsetNotAllowed
+		}
+		return input, nil
+	}
+	return decoder
+}
+
+// isAllowedCharset reports whether charset is one of the defaults or was
+// explicitly allowed
+func isAllowedCharset(charset string, allowed []string) bool {
+	for _, name := range defaultXMLCharsets {
+		if strings.EqualFold(charset, name) {
+			return true
+		}
+	}
+	for _, name := range allowed {
+		if strings.EqualFold(charset, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanXML walks the token stream of data and rejects constructs that are
+// not permitted by opts. It runs before Decode so that nothing is
+// instantiated from a hostile document. A nil v skips the checks that
+// depend on the target type.
+func scanXML(data []byte, v any, opts *Options) error {
+	scanner := &xmlScanner{opts: opts}
+	if opts.StrictMode && v != nil {
+		scanner.root = xmlSchemaFor(reflect.TypeOf(v))
+	}
+
+	decoder := newXMLDecoder(data, opts)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return classifyXMLError(err)
+		}
+		if err := scanner.token(tok); err != nil {
+			return err
+		}
+	}
+}
+
+// xmlScanner holds the state of a single scanXML pass
+type xmlScanner struct {
+	opts *Options
+	root *xmlNode // nil unless unknown elements are rejected
+
+	// stack holds the schema node of each open element; nil entries are
+	// subtrees that are not checked
+	stack []*xmlNode
+	path  []string
+
+	// text holds the character data seen so far directly inside each open
+	// element
+	text []xmlText
+
+	elements int
+}
+
+// token validates a single token against the scanner's options
+func (s *xmlScanner) token(tok xml.Token) error {
+	switch t := tok.(type) {
+	case xml.StartElement:
+		return s.startElement(t)
+	case xml.CharData:
+		return s.charData(t)
+	case xml.EndElement:
+		s.stack = s.stack[:len(s.stack)-1]
+		s.path = s.path[:len(s.path)-1]
+		s.text = s.text[:len(s.text)-1]
+	case xml.Directive:
+		if !s.opts.AllowDTD && isDTDDirective(t) {
+			return ErrDTDNotAllowed
+		}
+	case xml.ProcInst:
+		if t.Target != "xml" {
+			return fmt.Errorf("%w: %q", ErrProcInstNotAllowed, t.Target)
+		}
+	}
+	return nil
+}
+
+// startElement pushes an element and checks it against the target schema
+func (s *xmlScanner) startElement(t xml.StartElement) error {
+	s.path = append(s.path, t.Name.Local)
+	if len(s.text) > 0 {
+		s.text[len(s.text)-1].pending = 0
+	}
+	s.text = append(s.text, xmlText{})
+
+	if err := checkDepth(len(s.path), s.opts); err != nil {
+		return fmt.Errorf("%w at element %s", err, s.elementPath())
+	}
+	s.elements++
+	if s.opts.MaxElements > 0 && s.elements > s.opts.MaxElements {
+		return fmt.Errorf("%w: reached %d elements, limit %d", ErrTooManyElements, s.elements, s.opts.MaxElements)
+	}
+	if err := s.checkAttrLimits(t); err != nil {
+		return err
+	}
+	if len(s.opts.AllowedXMLNamespaces) > 0 && !slices.Contains(s.opts.AllowedXMLNamespaces, t.Name.Space) {
+		return fmt.Errorf("%w: %q on element %s", ErrNamespaceNotAllowed, t.Name.Space, s.elementPath())
+	}
+
+	node := s.root
+	if len(s.stack) > 0 {
+		parent := s.stack[len(s.stack)-1]
+		node = nil
+		if parent != nil {
+			child, ok := parent.child(t.Name.Local)
+			if !ok {
+				return fmt.Errorf("%w: %s", ErrUnknownXMLElement, s.elementPath())
+			}
+			node = child
+		}
+	}
+	s.stack = append(s.stack, node)
+
+	if node != nil && !node.anyAttr {
+		for _, attr := range t.Attr {
+			if isNamespaceDecl(attr) || node.attrs[attr.Name.Local] {
+				continue
+			}
+			return fmt.Errorf("%w: %s/@%s", ErrUnknownXMLAttribute, s.elementPath(), attr.Name.Local)
+		}
+	}
+	return nil
+}
+
+// checkAttrLimits enforces MaxAttributes and MaxStringLength on the
+// attributes of an element
+func (s *xmlScanner) checkAttrLimits(t xml.StartElement) error {
+	if s.opts.MaxAttributes > 0 && len(t.Attr) > s.opts.MaxAttributes {
+		return fmt.Errorf("%w: element %s has %d attributes, limit %d",
+			ErrTooManyAttributes, s.elementPath(), len(t.Attr), s.opts.MaxAttributes)
+	}
+	if s.opts.MaxStringLength > 0 {
+		for _, attr := range t.Attr {
+			if len(attr.Value) > s.opts.MaxStringLength {
+				return fmt.Errorf("%w: attribute %s of element %s is %d bytes, limit %d",
+					ErrStringTooLong, attr.Name.Local, s.elementPath(), len(attr.Value), s.opts.MaxStringLength)
+			}
+		}
+	}
+	return nil
+}
+
+// charData enforces MaxStringLength on the character data of an element.
+// Text and CDATA sections arrive as separate tokens, so their lengths are
+// added up per element before anything is decoded. Whitespace-only tokens
+// count too, unless a child element follows them, so indentation between
+// child elements does not add up.
+func (s *xmlScanner) charData(t xml.CharData) error {
+	if s.opts.MaxStringLength <= 0 || len(s.text) == 0 {
+		return nil
+	}
+	text := &s.text[len(s.text)-1]
+	if len(bytes.Trim(t, " \t\r\n")) == 0 {
+		text.pending += len(t)
+	} else {
+		text.total += text.pending + len(t)
+		text.pending = 0
+	}
+	if n := text.total + text.pending; n > s.opts.MaxStringLength {
+		return fmt.Errorf("%w: character data in element %s reached %d bytes, limit %d",
+			ErrStringTooLong, s.elementPath(), n, s.opts.MaxStringLength)
+	}
+	return nil
+}
+
+// xmlText counts the character data directly inside an element. Whitespace
+// is held in pending until it is known not to be indentation before a child
+// element.
+type xmlText struct {
+	total   int
+	pending int
+}
+
+// elementPath returns the slash-separated path of the current element
+func (s *xmlScanner) elementPath() string {
+	return "/" + strings.Join(s.path, "/")
+}
+
+// isNamespaceDecl reports whether attr is an xmlns declaration
+func isNamespaceDecl(attr xml.Attr) bool {
+	return attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns")
+}
+
+// isDTDDirective reports whether a directive declares a DTD or any of its
+// markup declarations
+func isDTDDirective(d xml.Directive) bool {
+	upper := bytes.ToUpper(d)
+	return bytes.Contains(upper, []byte("DOCTYPE")) || bytes.Contains(upper, []byte("ENTITY"))
+}
+
+// classifyXMLError maps encoding/xml errors caused by hardening to the
+// matching sentinel error
+func classifyXMLError(err error) error {
+	const entityPrefix = "invalid character entity "
+
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) && strings.HasPrefix(syntaxErr.Msg, entityPrefix+"&") &&
+		!strings.HasPrefix(syntaxErr.Msg, entityPrefix+"&#") {
+		return fmt.Errorf("%w: %s (line %d)", ErrEntityNotAllowed,
+			strings.TrimPrefix(syntaxErr.Msg, entityPrefix), syntaxErr.Line)
+	}
+	return err
+}
+
+// xmlNode describes which child elements and attributes an element may
+// contain when decoded into a given Go type
+type xmlNode struct {
+	children map[string]*xmlNode
+	attrs    map[string]bool
+
+	// open permits unknown child elements (",any" fields) and raw skips
+	// checking the subtree entirely (",innerxml" fields and types with a
+	// custom UnmarshalXML)
+	open    bool
+	raw     bool
+	anyAttr bool
+}
+
+var xmlUnmarshalerType = reflect.TypeOf((*xml.Unmarshaler)(nil)).Elem()
+
+// child returns the schema node for a child element and whether the
+// element is permitted at all
+func (n *xmlNode) child(name string) (*xmlNode, bool) {
+	if n.raw {
+		return nil, true
+	}
+	if c, ok := n.children[name]; ok {
+		return c, true
+	}
+	return nil, n.open
+}
+
+// xmlSchemaFor builds the schema for a decode target, returning nil when
+// the target is not a struct and so has no fixed set of elements
+func xmlSchemaFor(t reflect.Type) *xmlNode {
+	base := xmlElemType(t)
+	if base.Kind() != reflect.Struct {
+		return nil
+	}
+	return buildXMLNode(t, make(map[reflect.Type]*xmlNode))
+}
+
+// xmlElemType strips pointers and repeated-element slices from t
+func xmlElemType(t reflect.Type) reflect.Type {
+	for {
+		switch {
+		case t.Kind() == reflect.Pointer:
+			t = t.Elem()
+		case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+// buildXMLNode builds the schema node for elements decoded into t
+func buildXMLNode(t reflect.Type, cache map[reflect.Type]*xmlNode) *xmlNode {
+	t = xmlElemType(t)
+	if node, ok := cache[t]; ok {
+		return node
+	}
+
+	node := &xmlNode{children: make(map[string]*xmlNode), attrs: make(map[string]bool)}
+	cache[t] = node
+
+	if t.Implements(xmlUnmarshalerType) || reflect.PointerTo(t).Implements(xmlUnmarshalerType) {
+		node.raw = true
+		node.anyAttr = true
+		return node
+	}
+	if t.Kind() == reflect.Struct {
+		addXMLFields(node, t, cache)
+	}
+	return node
+}
+
+// addXMLFields adds the elements and attributes mapped by the fields of
+// struct type t to node, following encoding/xml's tag rules
+func addXMLFields(node *xmlNode, t reflect.Type, cache map[reflect.Type]*xmlNode) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("xml")
+		if tag == "-" || field.Name == "XMLName" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		name, flags, _ := strings.Cut(tag, ",")
+		if _, local, ok := strings.Cut(name, " "); ok {
+			name = local
+		}
+
+		if field.Anonymous && name == "" {
+			if ft := xmlElemType(field.Type); ft.Kind() == reflect.Struct {
+				addXMLFields(node, ft, cache)
+				continue
+			}
+		}
+
+		switch {
+		case hasTagFlag(flags, "attr"):
+			if hasTagFlag(flags, "any") {
+				node.anyAttr = true
+			} else {
+				node.attrs[xmlFieldName(name, field)] = true
+			}
+		case hasTagFlag(flags, "innerxml"):
+			node.raw = true
+		case hasTagFlag(flags, "any"):
+			node.open = true
+		case hasTagFlag(flags, "chardata"), hasTagFlag(flags, "cdata"), hasTagFlag(flags, "comment"):
+		default:
+			addXMLChild(node, xmlFieldName(name, field), buildXMLNode(field.Type, cache))
+		}
+	}
+}
+
+// addXMLChild registers child under a possibly nested "a>b>c" path
+func addXMLChild(node *xmlNode, path string, child *xmlNode) {
+	parts := strings.Split(path, ">")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := node.children[part]
+		if !ok {
+			next = &xmlNode{children: make(map[string]*xmlNode), attrs: make(map[string]bool)}
+			node.children[part] = next
+		}
+		node = next
+	}
+	node.children[parts[len(parts)-1]] = child
+}
+
+// xmlFieldName returns the element or attribute name a field maps to,
+// honouring the XMLName tag of untagged struct fields
+func xmlFieldName(name string, field reflect.StructField) string {
+	if name != "" {
+		return name
+	}
+	if ft := xmlElemType(field.Type); ft.Kind() == reflect.Struct {
+		if xmlName, ok := ft.FieldByName("XMLName"); ok {
+			tagName, _, _ := strings.Cut(xmlName.Tag.Get("xml"), ",")
+			if _, local, ok := strings.Cut(tagName, " "); ok {
+				tagName = local
+			}
+			if tagName != "" {
+				return tagName
+			}
+		}
+	}
+	return field.Name
+}
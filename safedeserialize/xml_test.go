@@ -0,0 +1,443 @@
+package safedeserialize
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const billionLaughs = `<?xml version="1.0"?>
+<!DOCTYPE lolz [
+ <!ENTITY lol "lol">
+ <!ENTITY lol2 "&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;">
+ <!ENTITY lol3 "&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;">
+ <!ENTITY lol4 "&lol3;&lol3;&lol3;&lol3;&lol3;&lol3;&lol3;&lol3;&lol3;&lol3;">
+ <!ENTITY lol5 "&lol4;&lol4;&lol4;&lol4;&lol4;&lol4;&lol4;&lol4;&lol4;&lol4;">
+ <!ENTITY lol6 "&lol5;&lol5;&lol5;&lol5;&lol5;&lol5;&lol5;&lol5;&lol5;&lol5;">
+ <!ENTITY lol7 "&lol6;&lol6;&lol6;&lol6;&lol6;&lol6;&lol6;&lol6;&lol6;&lol6;">
+ <!ENTITY lol8 "&lol7;&lol7;&lol7;&lol7;&lol7;&lol7;&lol7;&lol7;&lol7;&lol7;">
+ <!ENTITY lol9 "&lol8;&lol8;&lol8;&lol8;&lol8;&lol8;&lol8;&lol8;&lol8;&lol8;">
+]>
+<SimpleUser><id>1</id><name>&lol9;</name><email>a@b.c</email></SimpleUser>`
+
+func TestXMLDTD(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		opts    []Option
+		wantErr error
+	}{
+		{name: "billion laughs", data: billionLaughs, wantErr: ErrDTDNotAllowed},
+		{name: "billion laughs non-strict", data: billionLaughs, opts: []Option{WithStrictMode(false)}, wantErr: ErrDTDNotAllowed},
+		{name: "empty doctype", data: `<!DOCTYPE SimpleUser><SimpleUser><id>1</id></SimpleUser>`, wantErr: ErrDTDNotAllowed},
+		{name: "external doctype", data: `<!DOCTYPE SimpleUser SYSTEM "file:///etc/passwd"><SimpleUser><id>1</id></SimpleUser>`, wantErr: ErrDTDNotAllowed},
+		{name: "lowercase doctype", data: `<!doctype SimpleUser><SimpleUser><id>1</id></SimpleUser>`, wantErr: ErrDTDNotAllowed},
+		{name: "allowed doctype", data: `<!DOCTYPE SimpleUser><SimpleUser><id>1</id></SimpleUser>`, opts: []Option{WithAllowDTD(true)}},
+		{name: "no doctype", data: `<?xml version="1.0"?><SimpleUser><id>1</id></SimpleUser>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var u SimpleUser
+			err := XML([]byte(tt.data), &u, tt.opts...)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+			if u != (SimpleUser{}) {
+				t.Errorf("target was populated: %+v", u)
+			}
+		})
+	}
+}
+
+func TestXMLHardening(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		opts    []Option
+		wantErr error
+	}{
+		{name: "undeclared entity", data: `<SimpleUser><name>&xxe;</name></SimpleUser>`, wantErr: ErrEntityNotAllowed},
+		{name: "undeclared entity non-strict", data: `<SimpleUser><name>&xxe;</name></SimpleUser>`, opts: []Option{WithStrictMode(false)}, wantErr: ErrEntityNotAllowed},
+		{name: "predefined entity", data: `<SimpleUser><name>&lt;a&amp;b&gt;</name></SimpleUser>`},
+		{name: "numeric entity", data: `<SimpleUser><name>&#65;&#x42;</name></SimpleUser>`},
+		{name: "utf-16 charset", data: `<?xml version="1.0" encoding="UTF-16"?><SimpleUser/>`, wantErr: ErrCharsetNotAllowed},
+		{name: "latin1 charset", data: `<?xml version="1.0" encoding="ISO-8859-1"?><SimpleUser/>`, wantErr: ErrCharsetNotAllowed},
+		{name: "latin1 charset allowed", data: `<?xml version="1.0" encoding="ISO-8859-1"?><SimpleUser/>`, opts: []Option{WithAllowedCharsets("iso-8859-1")}},
+		{name: "utf-8 charset", data: `<?xml version="1.0" encoding="UTF-8"?><SimpleUser/>`},
+		{name: "ascii charset", data: `<?xml version="1.0" encoding="US-ASCII"?><SimpleUser/>`},
+		{name: "stylesheet", data: `<?xml-stylesheet href="x.xsl"?><SimpleUser/>`, wantErr: ErrProcInstNotAllowed},
+		{name: "nested pi", data: `<SimpleUser><?php echo 1; ?></SimpleUser>`, wantErr: ErrProcInstNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var u SimpleUser
+			err := XML([]byte(tt.data), &u, tt.opts...)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestXMLXXECorpus(t *testing.T) {
+	want := map[string]error{
+		"billion_laughs.xml":      ErrDTDNotAllowed,
+		"entity_in_attribute.xml": ErrEntityNotAllowed,
+		"external_dtd.xml":        ErrDTDNotAllowed,
+		"external_entity.xml":     ErrDTDNotAllowed,
+		"nested_pi.xml":           ErrProcInstNotAllowed,
+		"parameter_entity.xml":    ErrDTDNotAllowed,
+		"quadratic_blowup.xml":    ErrDTDNotAllowed,
+		"stylesheet_pi.xml":       ErrProcInstNotAllowed,
+		"undeclared_entity.xml":   ErrEntityNotAllowed,
+		"utf16_charset.xml":       ErrCharsetNotAllowed,
+	}
+
+	files, err := filepath.Glob(filepath.Join("testdata", "xxe", "*.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(want) {
+		t.Fatalf("corpus has %d files, expectations cover %d", len(files), len(want))
+	}
+
+	for _, file := range files {
+		name := filepath.Base(file)
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantErr, ok := want[name]
+			if !ok {
+				t.Fatalf("no expectation for %s", name)
+			}
+			var u SimpleUser
+			if err := XML(data, &u); !errors.Is(err, wantErr) {
+				t.Errorf("expected %v, got %v", wantErr, err)
+			}
+			if u != (SimpleUser{}) {
+				t.Errorf("target was populated: %+v", u)
+			}
+		})
+	}
+}
+
+type xmlOrder struct {
+	XMLName  xml.Name    `xml:"order"`
+	ID       string      `xml:"id,attr"`
+	Customer xmlCustomer `xml:"customer"`
+	Items    []xmlItem   `xml:"items>item"`
+	Note     string      `xml:",chardata"`
+	Shipping *xmlAddress
+	Extra    xmlExtensions `xml:"extensions"`
+	Raw      xmlRaw        `xml:"raw"`
+	xmlAudit
+}
+
+type xmlCustomer struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+type xmlItem struct {
+	SKU string `xml:"sku,attr"`
+	Qty int    `xml:"qty"`
+}
+
+type xmlAddress struct {
+	XMLName xml.Name `xml:"address"`
+	City    string   `xml:"city"`
+}
+
+type xmlExtensions struct {
+	Known string     `xml:"known"`
+	Any   []xml.Name `xml:",any"`
+}
+
+type xmlRaw struct {
+	Inner string `xml:",innerxml"`
+}
+
+type xmlAudit struct {
+	Created string `xml:"created"`
+}
+
+func TestXMLStrictUnknownElements(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		opts    []Option
+		wantErr error
+		path    string
+	}{
+		{name: "all known", data: `<order id="1"><customer><name>a</name></customer><items><item sku="x"><qty>1</qty></item></items><address><city>c</city></address><created>now</created></order>`},
+		{name: "unknown top level", data: `<order><admin>true</admin></order>`, wantErr: ErrUnknownXMLElement, path: "/order/admin"},
+		{name: "unknown nested", data: `<order><customer><name>a</name><role>root</role></customer></order>`, wantErr: ErrUnknownXMLElement, path: "/order/customer/role"},
+		{name: "unknown in path", data: `<order><items><bogus/></items></order>`, wantErr: ErrUnknownXMLElement, path: "/order/items/bogus"},
+		{name: "unknown in slice element", data: `<order><items><item><qty>1</qty><price>0</price></item></items></order>`, wantErr: ErrUnknownXMLElement, path: "/order/items/item/price"},
+		{name: "child of leaf", data: `<order><customer><name><first>a</first></name></customer></order>`, wantErr: ErrUnknownXMLElement, path: "/order/customer/name/first"},
+		{name: "unknown attribute", data: `<order id="1" admin="true"></order>`, wantErr: ErrUnknownXMLAttribute, path: "/order/@admin"},
+		{name: "unknown nested attribute", data: `<order><items><item sku="x" price="0"/></items></order>`, wantErr: ErrUnknownXMLAttribute, path: "/order/items/item/@price"},
+		{name: "namespace declarations", data: `<order xmlns="urn:a" xmlns:b="urn:b" id="1"></order>`},
+		{name: "any suppresses", data: `<order><extensions><known>k</known><custom><deep>x</deep></custom></extensions></order>`},
+		{name: "innerxml suppresses", data: `<order><raw><whatever a="1"><nested/></whatever></raw></order>`},
+		{name: "embedded struct", data: `<order><created>now</created></order>`},
+		{name: "non-strict ignores", data: `<order><admin>true</admin></order>`, opts: []Option{WithStrictMode(false)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var o xmlOrder
+			err := XML([]byte(tt.data), &o, tt.opts...)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if !strings.HasSuffix(err.Error(), tt.path) {
+				t.Errorf("expected path %s in %q", tt.path, err)
+			}
+		})
+	}
+}
+
+func TestXMLStrictNonStructTarget(t *testing.T) {
+	var s string
+	if err := XML([]byte(`<v>text<child/></v>`), &s); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestXMLMaxElements(t *testing.T) {
+	type list struct {
+		Items []string `xml:"i"`
+	}
+
+	tests := []struct {
+		name    string
+		count   int
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "unlimited", count: 1000},
+		{name: "under limit", count: 5, opts: []Option{WithMaxElements(10)}},
+		{name: "at limit", count: 9, opts: []Option{WithMaxElements(10)}},
+		{name: "over limit", count: 10, opts: []Option{WithMaxElements(10)}, wantErr: true},
+		{name: "ignores invalid", count: 1000, opts: []Option{WithMaxElements(0), WithMaxElements(-1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := "<list>" + strings.Repeat("<i/>", tt.count) + "</list>"
+			var l list
+			err := XML([]byte(data), &l, tt.opts...)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrTooManyElements) {
+				t.Fatalf("expected ErrTooManyElements, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "reached 11 elements") {
+				t.Errorf("expected count in error, got %q", err)
+			}
+		})
+	}
+}
+
+// BenchmarkXMLMaxElements shows that rejecting a wide document costs time
+// proportional to the limit rather than to the document size
+func BenchmarkXMLMaxElements(b *testing.B) {
+	type list struct {
+		Items []string `xml:"i"`
+	}
+
+	for _, size := range []int{1_000, 10_000, 100_000} {
+		data := []byte("<list>" + strings.Repeat("<i/>", size) + "</list>")
+		b.Run(fmt.Sprintf("elements=%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var l list
+				_ = XML(data, &l, WithMaxElements(100))
+			}
+		})
+	}
+}
+
+func TestXMLAttributeLimits(t *testing.T) {
+	type item struct {
+		Attrs []xml.Attr `xml:",any,attr"`
+		Value string     `xml:",chardata"`
+	}
+
+	manyAttrs := func(n int) string {
+		var b strings.Builder
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&b, ` a%d="v"`, i)
+		}
+		return b.String()
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		opts    []Option
+		wantErr error
+		detail  string
+	}{
+		{name: "attributes unlimited", data: `<item` + manyAttrs(1000) + `/>`},
+		{name: "attributes at limit", data: `<item` + manyAttrs(5) + `/>`, opts: []Option{WithMaxAttributes(5)}},
+		{name: "attributes over limit", data: `<item` + manyAttrs(6) + `/>`, opts: []Option{WithMaxAttributes(5)}, wantErr: ErrTooManyAttributes, detail: "element /item has 6 attributes, limit 5"},
+		{name: "attribute value at limit", data: `<item a="` + strings.Repeat("x", 8) + `"/>`, opts: []Option{WithMaxStringLength(8)}},
+		{name: "attribute value over limit", data: `<item a="` + strings.Repeat("x", 9) + `"/>`, opts: []Option{WithMaxStringLength(8)}, wantErr: ErrStringTooLong, detail: "attribute a of element /item is 9 bytes"},
+		{name: "chardata at limit", data: `<item>` + strings.Repeat("x", 8) + `</item>`, opts: []Option{WithMaxStringLength(8)}},
+		{name: "chardata over limit", data: `<item>` + strings.Repeat("x", 9) + `</item>`, opts: []Option{WithMaxStringLength(8)}, wantErr: ErrStringTooLong, detail: "character data in element /item reached 9 bytes"},
+		{name: "cdata over limit", data: `<item><![CDATA[` + strings.Repeat("x", 9) + `]]></item>`, opts: []Option{WithMaxStringLength(8)}, wantErr: ErrStringTooLong, detail: "character data in element /item reached 9 bytes"},
+		{name: "split cdata over limit", data: `<item>xxx<![CDATA[xxx]]><!-- -->xxx</item>`, opts: []Option{WithMaxStringLength(8)}, wantErr: ErrStringTooLong, detail: "character data in element /item reached 9 bytes"},
+		{name: "split cdata at limit", data: `<item>xx<![CDATA[xxx]]>xxx</item>`, opts: []Option{WithMaxStringLength(8)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var it item
+			err := XML([]byte(tt.data), &it, tt.opts...)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if !strings.Contains(err.Error(), tt.detail) {
+				t.Errorf("expected %q in %q", tt.detail, err)
+			}
+			if it.Value != "" || len(it.Attrs) != 0 {
+				t.Errorf("target was populated: %+v", it)
+			}
+		})
+	}
+}
+
+func TestXMLCharDataLimit(t *testing.T) {
+	type doc struct {
+		Name  string   `xml:"name"`
+		Items []string `xml:"item"`
+	}
+
+	t.Run("large cdata", func(t *testing.T) {
+		data := []byte(`<doc><name><![CDATA[` + strings.Repeat("x", 900<<10) + `]]></name></doc>`)
+		var got doc
+		err := XML(data, &got, WithMaxStringLength(4<<10))
+		if !errors.Is(err, ErrStringTooLong) {
+			t.Fatalf("expected ErrStringTooLong, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "element /doc/name") {
+			t.Errorf("expected the element path in %q", err)
+		}
+		if got.Name != "" {
+			t.Errorf("target was populated with %d bytes", len(got.Name))
+		}
+		if err := Valid(FormatXML, data, WithMaxStringLength(4<<10)); !errors.Is(err, ErrStringTooLong) {
+			t.Errorf("Valid: expected ErrStringTooLong, got %v", err)
+		}
+	})
+
+	t.Run("many small sections", func(t *testing.T) {
+		data := []byte(`<doc><name>` + strings.Repeat(`<![CDATA[`+strings.Repeat("x", 512)+`]]>`, 16) + `</name></doc>`)
+		if err := XML(data, &doc{}, WithMaxStringLength(4<<10)); !errors.Is(err, ErrStringTooLong) {
+			t.Fatalf("expected ErrStringTooLong, got %v", err)
+		}
+	})
+
+	t.Run("whitespace sections between text", func(t *testing.T) {
+		section := "x<![CDATA[" + strings.Repeat(" ", 4000) + "]]>"
+		data := []byte(`<doc><name>` + strings.Repeat(section, 200) + `</name></doc>`)
+		var got doc
+		if err := XML(data, &got, WithMaxStringLength(4<<10)); !errors.Is(err, ErrStringTooLong) {
+			t.Fatalf("expected ErrStringTooLong, got %v (decoded %d bytes)", err, len(got.Name))
+		}
+	})
+
+	t.Run("indentation between children", func(t *testing.T) {
+		data := []byte("<doc>\n" + strings.Repeat("    <item>x</item>\n", 2000) + "</doc>")
+		var got doc
+		if err := XML(data, &got, WithMaxStringLength(64)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got.Items) != 2000 {
+			t.Errorf("expected 2000 items, got %d", len(got.Items))
+		}
+	})
+}
+
+func TestXMLAllowedNamespaces(t *testing.T) {
+	type envelope struct {
+		Body struct {
+			Value string `xml:"value"`
+		} `xml:"body"`
+	}
+
+	const ns = "urn:partner:v1"
+
+	tests := []struct {
+		name    string
+		data    string
+		opts    []Option
+		wantErr bool
+		detail  string
+	}{
+		{name: "no allowlist", data: `<envelope xmlns:x="urn:other"><x:body><value>v</value></x:body></envelope>`},
+		{name: "default namespace allowed", data: `<envelope xmlns="` + ns + `"><body><value>v</value></body></envelope>`, opts: []Option{WithAllowedXMLNamespaces(ns)}},
+		{name: "empty namespace rejected", data: `<envelope><body/></envelope>`, opts: []Option{WithAllowedXMLNamespaces(ns)}, wantErr: true, detail: `"" on element /envelope`},
+		{name: "empty namespace allowed", data: `<envelope><body/></envelope>`, opts: []Option{WithAllowedXMLNamespaces(ns, "")}},
+		{name: "options add up", data: `<envelope xmlns="` + ns + `"><body xmlns="urn:other"/></envelope>`, opts: []Option{WithAllowedXMLNamespaces(ns), WithAllowedXMLNamespaces("urn:other")}},
+		{name: "foreign namespace", data: `<envelope xmlns="` + ns + `" xmlns:x="urn:other"><x:body><value>v</value></x:body></envelope>`, opts: []Option{WithAllowedXMLNamespaces(ns)}, wantErr: true, detail: `"urn:other" on element /envelope/body`},
+		{name: "nested override", data: `<envelope xmlns="` + ns + `"><body xmlns="urn:other"/></envelope>`, opts: []Option{WithAllowedXMLNamespaces(ns)}, wantErr: true, detail: `"urn:other" on element /envelope/body`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e envelope
+			err := XML([]byte(tt.data), &e, tt.opts...)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrNamespaceNotAllowed) {
+				t.Fatalf("expected ErrNamespaceNotAllowed, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.detail) {
+				t.Errorf("expected %q in %q", tt.detail, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,479 @@
+package safedeserialize
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// scanYAML parses data into a yaml.Node tree and applies the
+// document-level checks in opts before anything is decoded into the target.
+// It returns the data to decode, which differs from data only when a time
+// policy rewrote time.Time values to RFC 3339, and the parsed tree, which
+// is nil if data holds no document.
+func scanYAML(data []byte, v any, opts *Options) ([]byte, *yaml.Node, error) {
+	root, err := parseYAML(data, opts)
+	if err != nil || root == nil {
+		return data, nil, err
+	}
+	checkMaps, checkTimes := needsMapScan(v, opts), needsTimeScan(v, opts)
+	if !needsKeyScan(opts) && !checkMaps && !checkTimes && !opts.StrictNumbers && opts.AllowNonFiniteNumbers {
+		return data, root, nil
+	}
+
+	if err := walkYAML(root, "", opts); err != nil {
+		return nil, nil, err
+	}
+	if opts.StrictNumbers {
+		if err := checkYAMLNumbers(root, reflect.TypeOf(v), ""); err != nil {
+			return nil, nil, err
+		}
+	}
+	if !checkMaps && !checkTimes {
+		return data, root, nil
+	}
+
+	checker := &yamlTypeChecker{opts: opts, maps: checkMaps, times: checkTimes, seen: make(map[yamlTypedNode]bool)}
+	if err := checker.check(root, reflect.TypeOf(v), ""); err != nil {
+		return nil, nil, err
+	}
+	if checker.rewritten {
+		data, err = yaml.Marshal(root)
+		return data, root, err
+	}
+	return data, root, nil
+}
+
+// parseYAML parses the first document of data, rejecting it if it nests
+// deeper than MaxDepth, and rejects data holding more than MaxYAMLDocuments
+// documents. Later documents are parsed only to count them, and parsing
+// stops once the limit is passed. It returns a nil node if data holds no
+// document.
+func parseYAML(data []byte, opts *Options) (*yaml.Node, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	var root yaml.Node
+	if err := decoder.Decode(&root); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := checkDepth(yamlDepth(&root, make(map[*yaml.Node]int)), opts); err != nil {
+		return nil, err
+	}
+
+	limit := opts.yamlDocumentLimit()
+	for count := 1; ; count++ {
+		var next yaml.Node
+		err := decoder.Decode(&next)
+		if errors.Is(err, io.EOF) {
+			return &root, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if count+1 > limit {
+			return nil, fmt.Errorf("%w: reached %d documents, limit %d", ErrTooManyDocuments, count+1, limit)
+		}
+	}
+}
+
+// yamlDepth returns the nesting depth of the mappings and sequences in n.
+// Aliases count as the node they refer to, since decoding expands them;
+// heights memoizes anchored nodes so each is measured once.
+func yamlDepth(n *yaml.Node, heights map[*yaml.Node]int) int {
+	if n.Kind == yaml.AliasNode {
+		if h, ok := heights[n.Alias]; ok {
+			return h
+		}
+		heights[n.Alias] = 0 // an anchor that contains itself adds nothing
+		h := yamlDepth(n.Alias, heights)
+		heights[n.Alias] = h
+		return h
+	}
+
+	depth := 0
+	for _, child := range n.Content {
+		depth = max(depth, yamlDepth(child, heights))
+	}
+	if n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode {
+		depth++
+	}
+	return depth
+}
+
+// walkYAML applies the checks in opts to n and its descendants. Alias
+// nodes are not followed: their anchors are checked where they are defined.
+func walkYAML(n *yaml.Node, path string, opts *Options) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, child := range n.Content {
+			if err := walkYAML(child, path, opts); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			keyPath := joinKeyPath(path, key.Value)
+			if err := checkDocumentKey(key.Value, keyPath, path == "", opts); err != nil {
+				return err
+			}
+			if err := walkYAML(value, keyPath, opts); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, child := range n.Content {
+			if err := walkYAML(child, joinIndexPath(path, i), opts); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if !opts.AllowNonFiniteNumbers && isNonFiniteYAML(n) {
+			return fmt.Errorf("%w: field %s value %s", ErrNonFiniteNumber, displayPath(path), n.Value)
+		}
+	}
+	return nil
+}
+
+// isNonFiniteYAML reports whether n is a float scalar holding NaN or ±Inf,
+// including the .nan/.inf spellings and literals that overflow float64
+func isNonFiniteYAML(n *yaml.Node) bool {
+	if n.ShortTag() != "!!float" {
+		return false
+	}
+
+	value := strings.ReplaceAll(n.Value, "_", "")
+	sign, rest := "", value
+	if strings.HasPrefix(rest, "+") || strings.HasPrefix(rest, "-") {
+		sign, rest = rest[:1], rest[1:]
+	}
+	if lower := strings.ToLower(rest); lower == ".inf" || lower == ".nan" {
+		return true
+	}
+
+	f, _ := strconv.ParseFloat(sign+rest, 64)
+	return math.IsInf(f, 0) || math.IsNaN(f)
+}
+
+// yamlTypeChecker walks a document alongside the Go type it decodes into.
+// With maps set it applies MaxMapKeys and MaxStringLength to mappings that
+// decode into a Go map; with times set it applies the time policy to
+// time.Time values and rewrites them to RFC 3339 in place.
+type yamlTypeChecker struct {
+	opts      *Options
+	maps      bool
+	times     bool
+	rewritten bool
+
+	// seen holds aliased nodes already checked against a type, so that
+	// repeated aliases are checked once
+	seen map[yamlTypedNode]bool
+}
+
+// yamlTypedNode is a node paired with the Go type it decodes into
+type yamlTypedNode struct {
+	n *yaml.Node
+	t reflect.Type
+}
+
+// check walks n alongside the Go type it decodes into
+func (c *yamlTypeChecker) check(n *yaml.Node, t reflect.Type, path string) error {
+	t = derefType(t)
+	if t.Implements(yamlUnmarshalerType) || reflect.PointerTo(t).Implements(yamlUnmarshalerType) {
+		return nil
+	}
+
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if c.times && t == timeType {
+			return c.checkTime(n, path)
+		}
+	case yaml.AliasNode:
+		key := yamlTypedNode{n.Alias, t}
+		if c.seen[key] {
+			return nil
+		}
+		c.seen[key] = true
+		return c.check(n.Alias, t, path)
+	case yaml.DocumentNode:
+		for _, child := range n.Content {
+			if err := c.check(child, t, path); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		if t.Kind() == reflect.Map {
+			return c.checkMap(n, t, path)
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			valueType, ok := yamlValueType(t, key.Value)
+			if !ok {
+				continue
+			}
+			if err := c.check(value, valueType, joinKeyPath(path, key.Value)); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return nil
+		}
+		for i, child := range n.Content {
+			if err := c.check(child, t.Elem(), joinIndexPath(path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkMap checks the keys and values of a mapping decoded into map type t,
+// including keys pulled in with merge keys
+func (c *yamlTypeChecker) checkMap(n *yaml.Node, t reflect.Type, path string) error {
+	keys := 0
+	stringValues := c.maps && derefType(t.Elem()).Kind() == reflect.String
+	return yamlMapEntries(n, make(map[*yaml.Node]bool), func(key, value *yaml.Node) error {
+		keys++
+		if c.maps {
+			if err := checkMapKey(key.Value, keys, path, c.opts); err != nil {
+				return err
+			}
+		}
+		if scalar := resolveYAMLAlias(value); stringValues && scalar.Kind == yaml.ScalarNode {
+			if err := checkMapValue(key.Value, scalar.Value, path, c.opts); err != nil {
+				return err
+			}
+		}
+		return c.check(value, t.Elem(), joinKeyPath(path, key.Value))
+	})
+}
+
+// checkTime applies the time policy to a scalar decoded into time.Time
+func (c *yamlTypeChecker) checkTime(n *yaml.Node, path string) error {
+	var t time.Time
+	var err error
+	switch n.ShortTag() {
+	case "!!null":
+		return nil
+	case "!!int", "!!float":
+		t, err = parseEpochSeconds(n.Value, path, c.opts)
+	default:
+		t, err = parseTimeString(n.Value, path, c.opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	n.Value, n.Tag, n.Style = t.Format(time.RFC3339Nano), "!!timestamp", 0
+	c.rewritten = true
+	return nil
+}
+
+// yamlMapEntries calls visit for each key and value of mapping n, expanding
+// merge keys the way yaml.v3 does when decoding into a map. merging guards
+// against mappings that merge themselves.
+func yamlMapEntries(n *yaml.Node, merging map[*yaml.Node]bool, visit func(key, value *yaml.Node) error) error {
+	if merging[n] {
+		return nil
+	}
+	merging[n] = true
+	defer delete(merging, n)
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if key.Kind != yaml.ScalarNode || key.ShortTag() != "!!merge" {
+			if err := visit(key, value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		merged := []*yaml.Node{value}
+		if value = resolveYAMLAlias(value); value.Kind == yaml.SequenceNode {
+			merged = value.Content
+		}
+		for _, m := range merged {
+			if m = resolveYAMLAlias(m); m.Kind == yaml.MappingNode {
+				if err := yamlMapEntries(m, merging, visit); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// resolveYAMLAlias returns the node an alias refers to, or n itself
+func resolveYAMLAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+// checkYAMLNumbers walks n alongside the Go type it decodes into and
+// rejects numeric scalars that yaml.v3 would truncate or that overflow
+// an integer target
+func checkYAMLNumbers(n *yaml.Node, t reflect.Type, path string) error {
+	t = derefType(t)
+	if t.Implements(yamlUnmarshalerType) || reflect.PointerTo(t).Implements(yamlUnmarshalerType) {
+		return nil
+	}
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, child := range n.Content {
+			if err := checkYAMLNumbers(child, t, path); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			valueType, ok := yamlValueType(t, key.Value)
+			if !ok {
+				continue
+			}
+			if err := checkYAMLNumbers(value, valueType, joinKeyPath(path, key.Value)); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return nil
+		}
+		for i, child := range n.Content {
+			if err := checkYAMLNumbers(child, t.Elem(), joinIndexPath(path, i)); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		return checkYAMLIntScalar(n, t, path)
+	}
+	return nil
+}
+
+// checkYAMLIntScalar rejects a scalar that does not fit integer type t
+func checkYAMLIntScalar(n *yaml.Node, t reflect.Type, path string) error {
+	var signed bool
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		signed = true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+	default:
+		return nil
+	}
+
+	var problem string
+	switch n.ShortTag() {
+	case "!!float":
+		f, err := strconv.ParseFloat(strings.ReplaceAll(n.Value, "_", ""), 64)
+		if err != nil || f != math.Trunc(f) {
+			problem = "not an integer"
+		} else if !floatFitsInt(f, t.Bits(), signed) {
+			problem = "out of range"
+		}
+	case "!!int":
+		var err error
+		if signed {
+			_, err = strconv.ParseInt(n.Value, 0, t.Bits())
+		} else {
+			_, err = strconv.ParseUint(n.Value, 0, t.Bits())
+		}
+		if err != nil {
+			problem = "out of range"
+		}
+	}
+
+	if problem != "" {
+		return fmt.Errorf("%w: field %s value %s does not fit %s (%s)", ErrInvalidNumber, path, n.Value, t, problem)
+	}
+	return nil
+}
+
+// floatFitsInt reports whether integral value f fits an integer of the
+// given width and signedness
+func floatFitsInt(f float64, bits int, signed bool) bool {
+	if signed {
+		limit := math.Ldexp(1, bits-1)
+		return f >= -limit && f < limit
+	}
+	return f >= 0 && f < math.Ldexp(1, bits)
+}
+
+// yamlValueType returns the type a mapping value with the given key decodes
+// into, following yaml.v3's field naming rules for structs
+func yamlValueType(t reflect.Type, key string) (reflect.Type, bool) {
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Elem(), true
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, flags, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if name == "-" {
+				continue
+			}
+			if hasTagFlag(flags, "inline") {
+				if ft, ok := yamlValueType(derefType(field.Type), key); ok {
+					return ft, true
+				}
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			if name == key {
+				return field.Type, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// yamlFieldIndex returns the index of the field of struct t that a mapping
+// key sets, following yaml.v3's field naming rules and descending into
+// inlined structs
+func yamlFieldIndex(t reflect.Type, key string) ([]int, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		name, flags, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if hasTagFlag(flags, "inline") {
+			if ft := derefType(field.Type); ft.Kind() == reflect.Struct {
+				if index, ok := yamlFieldIndex(ft, key); ok {
+					return append([]int{i}, index...), true
+				}
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		if name == key {
+			return []int{i}, true
+		}
+	}
+	return nil, false
+}
@@ -4,14 +4,28 @@
 //   - CWE-89: SQL Injection
 //   - CWE-22: Path Traversal
 //   - CWE-78: OS Command Injection
+//   - CWE-90: LDAP Injection
+//   - CWE-93/CWE-113: CRLF Injection and HTTP Response Splitting
+//   - CWE-117: Log Injection
+//   - CWE-91: XML Injection
+//   - CWE-601: Open Redirect
+//   - CWE-918: Server-Side Request Forgery (SSRF)
+//   - CWE-1236: CSV Injection
+//   - CWE-1333: Inefficient Regular Expression Complexity
+//   - CWE-1336: Server-Side Template Injection
 package safeinput
 
 import (
+	"fmt"
+	"maps"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/ravisastryk/go-safeinput/html"
 	"github.com/ravisastryk/go-safeinput/path"
 	"github.com/ravisastryk/go-safeinput/sql"
+	"golang.org/x/text/unicode/norm"
 )
 
 // Context defines the output context for sanitization.
@@ -28,32 +42,124 @@ const (
 	SQLValue
 	// FilePath sanitizes filesystem paths (CWE-22).
 	FilePath
-	// URLPath sanitizes URL path components.
+	// URLPath percent-encodes URL paths or path segments, or in strict mode
+	// rejects paths that would break out of the path, rejecting traversal
+	// either way.
 	URLPath
-	// URLQuery sanitizes URL query parameters.
+	// URLQuery percent-encodes URL query parameter values, or in strict
+	// mode rejects values that would break out of a parameter.
 	URLQuery
 	// ShellArg sanitizes shell command arguments (CWE-78).
 	ShellArg
+	// LDAPFilter escapes values for LDAP search filters (CWE-90).
+	LDAPFilter
+	// LDAPDN escapes attribute values for LDAP distinguished names (CWE-90).
+	LDAPDN
+	// HTTPHeader sanitizes HTTP header values (CWE-93, CWE-113).
+	HTTPHeader
+	// LogLine escapes values interpolated into log lines (CWE-117).
+	LogLine
+	// JSONString escapes values for inclusion inside a JSON string literal.
+	JSONString
+	// URL validates absolute URLs against scheme and host allowlists (CWE-601).
+	URL
+	// RegexLiteral escapes regular expression metacharacters so input matches literally.
+	RegexLiteral
+	// RegexPattern validates user-supplied regular expressions (CWE-1333).
+	RegexPattern
+	// CSVField neutralizes spreadsheet formulas and quotes CSV fields (CWE-1236).
+	CSVField
+	// XMLText escapes XML character data (CWE-91).
+	XMLText
+	// XMLAttr escapes quoted XML attribute values (CWE-91).
+	XMLAttr
+	// Hostname validates and normalizes host names (CWE-918).
+	Hostname
+	// IPAddress validates and normalizes IP addresses (CWE-918).
+	IPAddress
+	// UUID validates and normalizes UUIDs.
+	UUID
+	// CookieName validates HTTP cookie names (CWE-113).
+	CookieName
+	// CookieValue validates HTTP cookie values (CWE-113).
+	CookieValue
+	// TemplateLiteral escapes values included in server-side template text (CWE-1336).
+	TemplateLiteral
+	// Filename sanitizes a single filename component (CWE-22).
+	Filename
+	// ShellArgQuoted quotes shell command arguments for a POSIX shell (CWE-78).
+	ShellArgQuoted
+	// ShellArgWindows quotes command arguments for a Windows command line run through cmd.exe (CWE-78).
+	ShellArgWindows
 )
 
-// String returns a human-readable name for the context.
+// contextNames holds the names of the built-in contexts, indexed by value.
+var contextNames = []string{
+	"HTMLBody", "HTMLAttribute", "SQLIdentifier", "SQLValue",
+	"FilePath", "URLPath", "URLQuery", "ShellArg",
+	"LDAPFilter", "LDAPDN", "HTTPHeader", "LogLine",
+	"JSONString", "URL", "RegexLiteral", "RegexPattern",
+	"CSVField", "XMLText", "XMLAttr", "Hostname",
+	"IPAddress", "UUID", "CookieName", "CookieValue",
+	"TemplateLiteral", "Filename", "ShellArgQuoted", "ShellArgWindows",
+}
+
+// String returns a human-readable name for the context, the registered
+// name for contexts created by RegisterContext.
 func (c Context) String() string {
-	names := []string{
-		"HTMLBody", "HTMLAttribute", "SQLIdentifier", "SQLValue",
-		"FilePath", "URLPath", "URLQuery", "ShellArg",
+	if int(c) >= 0 && int(c) < len(contextNames) {
+		return contextNames[c]
 	}
-	if int(c) >= 0 && int(c) < len(names) {
-		return names[c]
+	if name, ok := customContextName(c); ok {
+		return name
 	}
 	return "Unknown"
 }
 
-// Sanitizer provides the main sanitization interface.
+// ParseContext returns the built-in context with the given name. Matching
+// is case-insensitive and accepts both the names Context.String reports,
+// such as "HTMLBody", and the short forms used in sanitize struct tags,
+// such as "html" or "sql_identifier". Unknown names fail with
+// ErrUnknownContext. Contexts created by RegisterContext are not parsed,
+// since their names are only unique per Sanitizer.
+func ParseContext(name string) (Context, error) {
+	lower := strings.ToLower(name)
+	for i, n := range contextNames {
+		if strings.ToLower(n) == lower {
+			return Context(i), nil
+		}
+	}
+	if ctx, ok := tagContexts[lower]; ok {
+		return ctx, nil
+	}
+	return 0, fmt.Errorf("%w %q", ErrUnknownContext, name)
+}
+
+// AllContexts returns every built-in context in order of value.
+func AllContexts() []Context {
+	contexts := make([]Context, len(contextNames))
+	for i := range contexts {
+		contexts[i] = Context(i)
+	}
+	return contexts
+}
+
+// Sanitizer provides the main sanitization interface. A Sanitizer is safe
+// for concurrent use: its configuration is fixed by New and its HTML, SQL
+// and path sanitizers are private to it, so nothing can change its
+// behavior while it is shared. WithConfig and With derive a Sanitizer with
+// different settings, leaving the original untouched.
 type Sanitizer struct {
 	html   *html.Sanitizer
 	sql    *sql.Sanitizer
 	path   *path.Sanitizer
 	config Config
+
+	// shellExtra holds the characters ShellArg allows beyond the base set
+	shellExtra string
+
+	customMu sync.RWMutex
+	custom   map[Context]ContextFunc
 }
 
 // Config holds sanitizer configuration options.
@@ -63,6 +169,74 @@ type Config struct {
 	BasePath        string
 	StrictMode      bool
 	StripNullBytes  bool
+	MaxHeaderLength int
+	MaxLogLength    int
+	MaxRegexLength  int
+	CSVDelimiter    rune
+	StripCSVFormula bool
+
+	// URLPathSegment makes URLPath treat input as a single path segment,
+	// encoding slashes rather than keeping them.
+	URLPathSegment bool
+
+	// LegacyURLQueryEscaping makes URLQuery HTML-escape input as it used
+	// to, rather than percent-encoding it.
+	//
+	// Deprecated: HTML escaping changes the meaning of a query value and
+	// does not stop parameter injection. This option will be removed in the
+	// next release.
+	LegacyURLQueryEscaping bool
+
+	AllowedURLSchemes []string
+	AllowedURLHosts   []string
+	DeniedURLHosts    []string
+
+	// AllowedHTMLURLSchemes replaces html.DefaultURLSchemes as the schemes
+	// HTMLBody allows in links and other URL attributes.
+	AllowedHTMLURLSchemes []string
+
+	RejectIDNHostnames bool
+	RejectIPHostnames  bool
+	BlockInternalHosts bool
+	DeniedHostnames    []string
+
+	RejectPrivateIPs   bool
+	RejectLoopbackIPs  bool
+	RejectLinkLocalIPs bool
+	RejectMulticastIPs bool
+
+	AllowWrappedUUIDs bool
+	UUIDVersions      []int
+
+	EncodeCookies   bool
+	MaxCookieLength int
+
+	TemplateLeftDelim  string
+	TemplateRightDelim string
+
+	MaxFilenameLength int
+	AllowDotFiles     bool
+
+	ShellExtraAllowed string
+	ShellAllowSpace   bool
+
+	ValidateKeys bool
+	MaxKeyLength int
+
+	DefaultParamContext Context
+
+	MaxLengths map[Context]int
+	LengthUnit LengthUnit
+
+	NormalizeUnicode bool
+
+	RejectBidiControls bool
+	StripBidiControls  bool
+
+	RejectInvisible bool
+	StripInvisible  bool
+
+	RejectMixedScript bool
 }
 
 // New creates a new Sanitizer with the given configuration.
@@ -70,57 +244,313 @@ func New(cfg Config) *Sanitizer {
 	if cfg.MaxInputLength == 0 {
 		cfg.MaxInputLength = 10000
 	}
+	if cfg.MaxHeaderLength == 0 {
+		cfg.MaxHeaderLength = defaultMaxHeaderLength
+	}
+	if cfg.MaxRegexLength == 0 {
+		cfg.MaxRegexLength = defaultMaxRegexLength
+	}
+	if cfg.MaxCookieLength == 0 {
+		cfg.MaxCookieLength = defaultMaxCookieLength
+	}
+	cfg = cfg.clone()
+	cfg.MaxLengths = maxLengths(cfg)
+	shellExtra := shellExtraAllowed(cfg.ShellExtraAllowed)
+	if cfg.ShellAllowSpace {
+		shellExtra += " "
+	}
 	return &Sanitizer{
-		html:   html.New(cfg.AllowedHTMLTags),
-		sql:    sql.New(),
-		path:   path.New(cfg.BasePath),
-		config: cfg,
+		html:       html.New(cfg.AllowedHTMLTags, htmlOptions(cfg)...),
+		sql:        sql.New(),
+		path:       path.New(cfg.BasePath),
+		config:     cfg,
+		shellExtra: shellExtra,
+	}
+}
+
+// htmlOptions returns the options for the HTML sanitizer of cfg.
+func htmlOptions(cfg Config) []html.Option {
+	if len(cfg.AllowedHTMLURLSchemes) == 0 {
+		return nil
+	}
+	return []html.Option{html.WithURLSchemes(cfg.AllowedHTMLURLSchemes...)}
+}
+
+// clone returns a copy of c that shares no slices or maps with it.
+func (c Config) clone() Config {
+	c.AllowedHTMLTags = slices.Clone(c.AllowedHTMLTags)
+	c.AllowedHTMLURLSchemes = slices.Clone(c.AllowedHTMLURLSchemes)
+	c.AllowedURLSchemes = slices.Clone(c.AllowedURLSchemes)
+	c.AllowedURLHosts = slices.Clone(c.AllowedURLHosts)
+	c.DeniedURLHosts = slices.Clone(c.DeniedURLHosts)
+	c.DeniedHostnames = slices.Clone(c.DeniedHostnames)
+	c.UUIDVersions = slices.Clone(c.UUIDVersions)
+	c.MaxLengths = maps.Clone(c.MaxLengths)
+	return c
+}
+
+// WithConfig returns a new Sanitizer configured by cfg, with its own HTML,
+// SQL and path sanitizers and the custom contexts registered on s.
+// Contexts registered on either Sanitizer afterward are not shared.
+func (s *Sanitizer) WithConfig(cfg Config) *Sanitizer {
+	derived := New(cfg)
+	s.customMu.RLock()
+	derived.custom = maps.Clone(s.custom)
+	s.customMu.RUnlock()
+	return derived
+}
+
+// Option changes a setting for With.
+type Option func(*Config)
+
+// With returns a new Sanitizer with the configuration of s changed by
+// opts, as WithConfig does.
+func (s *Sanitizer) With(opts ...Option) *Sanitizer {
+	cfg := s.GetConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return s.WithConfig(cfg)
+}
+
+// WithStrictMode sets Config.StrictMode.
+func WithStrictMode(strict bool) Option {
+	return func(cfg *Config) { cfg.StrictMode = strict }
+}
+
+// WithMaxInputLength sets Config.MaxInputLength.
+func WithMaxInputLength(n int) Option {
+	return func(cfg *Config) { cfg.MaxInputLength = n }
+}
+
+// WithAllowedHTMLTags sets Config.AllowedHTMLTags.
+func WithAllowedHTMLTags(tags ...string) Option {
+	return func(cfg *Config) { cfg.AllowedHTMLTags = tags }
+}
+
+// WithBasePath sets Config.BasePath.
+func WithBasePath(basePath string) Option {
+	return func(cfg *Config) { cfg.BasePath = basePath }
+}
+
+// maxLengths returns a copy of cfg.MaxLengths with the default limit for
+// Filename filled in. Filenames within the limit but longer than
+// MaxFilenameLength are truncated rather than rejected.
+func maxLengths(cfg Config) map[Context]int {
+	lengths := make(map[Context]int, len(cfg.MaxLengths)+1)
+	for ctx, n := range cfg.MaxLengths {
+		if n > 0 {
+			lengths[ctx] = n
+		}
+	}
+	if _, ok := lengths[Filename]; !ok {
+		lengths[Filename] = path.DefaultMaxFilenameLength
+	}
+	return lengths
+}
+
+// maxLength returns the MaxLengths entry for ctx, or else MaxInputLength.
+func (s *Sanitizer) maxLength(ctx Context) int {
+	if limit, ok := s.config.MaxLengths[ctx]; ok {
+		return limit
 	}
+	return s.config.MaxInputLength
+}
+
+// checkLength returns ErrInputTooLong, naming the context, the limit and
+// the length of input, if input exceeds the length limit for ctx.
+func (s *Sanitizer) checkLength(input string, ctx Context) error {
+	limit, unit := s.maxLength(ctx), s.config.LengthUnit
+	if len(input) <= limit {
+		// no unit counts more than the bytes
+		return nil
+	}
+	if maxBytes := s.maxBytes(ctx); len(input) > maxBytes {
+		return s.tooLong(ctx, len(input), maxBytes, Bytes)
+	}
+	if n := unit.count(input); n > limit {
+		return s.tooLong(ctx, n, limit, unit)
+	}
+	return nil
+}
+
+// tooLong returns ErrInputTooLong naming ctx, its length limit and the
+// length of the input.
+func (s *Sanitizer) tooLong(ctx Context, length, limit int, unit LengthUnit) error {
+	return fmt.Errorf("%w: %s input is limited to %d %s, got %d", ErrInputTooLong, ctx, limit, unit, length)
+}
+
+// normalize applies NFC normalization to input if NormalizeUnicode is set.
+// NFC shortens a string to no less than a third of its length, so input
+// more than three times the most bytes allowed for ctx is rejected without
+// normalizing it.
+func (s *Sanitizer) normalize(input string, ctx Context) (string, error) {
+	if !s.config.NormalizeUnicode || norm.NFC.IsNormalString(input) {
+		return input, nil
+	}
+	if maxBytes := s.maxBytes(ctx); len(input)/3 > maxBytes {
+		return "", s.tooLong(ctx, len(input), maxBytes, Bytes)
+	}
+	return norm.NFC.String(input), nil
 }
 
 // Default returns a Sanitizer with secure default settings.
 func Default() *Sanitizer {
 	return New(Config{
-		MaxInputLength: 10000,
-		StrictMode:     true,
-		StripNullBytes: true,
+		MaxInputLength:   10000,
+		StrictMode:       true,
+		StripNullBytes:   true,
+		NormalizeUnicode: true,
+		StripInvisible:   true,
 	})
 }
 
-// Sanitize processes input for the specified context.
+// Sanitize processes input for the specified context. When input is
+// rejected the error is a *SanitizeError locating the offending text.
 func (s *Sanitizer) Sanitize(input string, ctx Context) (string, error) {
-	if len(input) > s.config.MaxInputLength {
-		return "", ErrInputTooLong
-	}
+	return s.sanitize(input, ctx, nil)
+}
 
-	if strings.ContainsRune(input, 0) {
-		if s.config.StripNullBytes {
-			input = StripNullBytes(input)
-		} else {
-			return "", ErrNullByte
+// sanitize implements Sanitize and SanitizeDetailed, returning rejections
+// as a *SanitizeError. If findings is not nil, contexts that can explain
+// their changes append to it, with offsets into input after normalization
+// and after null bytes are stripped.
+func (s *Sanitizer) sanitize(input string, ctx Context, findings *[]Finding) (string, error) {
+	input, err := s.normalize(input, ctx)
+	if err == nil {
+		var out string
+		if out, err = s.sanitizeNormalized(input, ctx, findings); err == nil {
+			return out, nil
 		}
 	}
+	return "", s.sanitizeError(input, ctx, err)
+}
+
+// sanitizeNormalized implements sanitize for normalized input.
+func (s *Sanitizer) sanitizeNormalized(input string, ctx Context, findings *[]Finding) (string, error) {
+	if err := s.checkLength(input, ctx); err != nil {
+		return "", err
+	}
+
+	input, err := s.checkCharacters(input, ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkMixedScript(input, ctx); err != nil {
+		return "", err
+	}
 
 	switch ctx {
 	case HTMLBody:
+		if findings != nil {
+			return s.htmlBodyDetailed(input, findings), nil
+		}
 		return s.html.SanitizeBody(input), nil
 	case HTMLAttribute:
 		return s.html.SanitizeAttribute(input), nil
 	case SQLIdentifier:
-		return s.sql.SanitizeIdentifier(input)
+		out, err := s.sql.SanitizeIdentifier(input)
+		return out, sqlError(input, err, s.sql.MaxIdentifierLength())
 	case SQLValue:
-		return s.sql.ValidateValue(input)
+		out, err := s.sql.ValidateValue(input)
+		return out, sqlError(input, err, 0)
 	case FilePath:
-		return s.path.Sanitize(input)
-	case URLPath, URLQuery:
-		return s.html.SanitizeAttribute(input), nil
+		out, err := s.path.Sanitize(input)
+		return out, pathError(input, err)
+	case URLPath:
+		return sanitizeURLPath(input, s.config.URLPathSegment, s.config.StrictMode)
+	case URLQuery:
+		if s.config.LegacyURLQueryEscaping {
+			return s.html.SanitizeAttribute(input), nil
+		}
+		return sanitizeURLQuery(input, s.config.StrictMode)
 	case ShellArg:
-		return SanitizeShellArg(input), nil
+		return sanitizeShellArg(input, s.shellExtra, findings), nil
+	case ShellArgQuoted:
+		return QuoteShellArg(input), nil
+	case ShellArgWindows:
+		return sanitizeWindowsArg(input)
+	case LDAPFilter:
+		return EscapeLDAPFilter(input), nil
+	case LDAPDN:
+		return EscapeLDAPDN(input), nil
+	case HTTPHeader:
+		return sanitizeHeaderValue(input, s.config.MaxHeaderLength, s.config.StrictMode)
+	case LogLine:
+		return sanitizeLogLine(input, s.config.MaxLogLength), nil
+	case JSONString:
+		return EscapeJSONString(input), nil
+	case URL:
+		return sanitizeURL(input, s.config.AllowedURLSchemes, s.config.AllowedURLHosts, s.config.DeniedURLHosts)
+	case RegexLiteral:
+		return EscapeRegex(input), nil
+	case RegexPattern:
+		return validateRegexPattern(input, s.config.MaxRegexLength)
+	case CSVField:
+		return sanitizeCSVField(input, s.config.CSVDelimiter, s.config.StripCSVFormula), nil
+	case XMLText:
+		return escapeXML(input, false, s.config.StrictMode)
+	case XMLAttr:
+		return escapeXML(input, true, s.config.StrictMode)
+	case Hostname:
+		return sanitizeHostname(input, hostnamePolicy{
+			rejectIDN:     s.config.RejectIDNHostnames,
+			rejectIP:      s.config.RejectIPHostnames,
+			blockInternal: s.config.BlockInternalHosts,
+			denied:        s.config.DeniedHostnames,
+		})
+	case IPAddress:
+		return sanitizeIPAddress(input, ipPolicy{
+			rejectPrivate:   s.config.RejectPrivateIPs,
+			rejectLoopback:  s.config.RejectLoopbackIPs,
+			rejectLinkLocal: s.config.RejectLinkLocalIPs,
+			rejectMulticast: s.config.RejectMulticastIPs,
+		})
+	case UUID:
+		return sanitizeUUID(input, s.config.AllowWrappedUUIDs, s.config.UUIDVersions)
+	case CookieName:
+		return sanitizeCookieName(input, s.cookieMode(), s.config.MaxCookieLength)
+	case CookieValue:
+		return sanitizeCookie(input, isCookieValueByte, s.cookieMode(), s.config.MaxCookieLength)
+	case TemplateLiteral:
+		return sanitizeTemplateLiteral(input, s.config.TemplateLeftDelim, s.config.TemplateRightDelim, s.config.StrictMode)
+	case Filename:
+		return path.SanitizeFilename(input, path.FilenameOptions{
+			MaxLength:       s.config.MaxFilenameLength,
+			AllowLeadingDot: s.config.AllowDotFiles,
+		})
 	default:
+		if fn := s.customContext(ctx); fn != nil {
+			return fn(input, s.config)
+		}
 		return "", ErrUnknownContext
 	}
 }
 
+// checkCharacters strips or rejects the null bytes, bidi controls and
+// invisible characters in input, as configured for ctx.
+func (s *Sanitizer) checkCharacters(input string, ctx Context) (string, error) {
+	if strings.ContainsRune(input, 0) {
+		if !s.stripsNullBytes(ctx) {
+			return "", ErrNullByte
+		}
+		input = StripNullBytes(input)
+	}
+	input, err := s.checkBidi(input)
+	if err != nil {
+		return "", err
+	}
+	return s.checkInvisible(input)
+}
+
+// stripsNullBytes reports whether Sanitize strips null bytes from input for
+// ctx rather than rejecting it. The quoting shell contexts always reject
+// them, since an argument cut short at a null byte can change the meaning
+// of a command.
+func (s *Sanitizer) stripsNullBytes(ctx Context) bool {
+	return s.config.StripNullBytes && ctx != ShellArgQuoted && ctx != ShellArgWindows
+}
+
 // MustSanitize panics on error.
 func (s *Sanitizer) MustSanitize(input string, ctx Context) string {
 	result, err := s.Sanitize(input, ctx)
@@ -130,15 +560,16 @@ func (s *Sanitizer) MustSanitize(input string, ctx Context) string {
 	return result
 }
 
-// IsValid checks if input is valid for the given context.
+// IsValid checks if input is valid for the given context, as reported by
+// Validate.
 func (s *Sanitizer) IsValid(input string, ctx Context) bool {
-	_, err := s.Sanitize(input, ctx)
-	return err == nil
+	return s.Validate(input, ctx) == nil
 }
 
-// GetConfig returns a copy of the configuration.
+// GetConfig returns a copy of the configuration. Changing its slices or
+// maps does not affect s.
 func (s *Sanitizer) GetConfig() Config {
-	return s.config
+	return s.config.clone()
 }
 
 // StripNullBytes removes null bytes from a string.
@@ -156,14 +587,41 @@ func StripNullBytes(s string) string {
 // SanitizeShellArg sanitizes shell command arguments (CWE-78).
 // Only allows alphanumeric characters, dash, underscore, period, and forward slash.
 func SanitizeShellArg(input string) string {
-	var b strings.Builder
-	b.Grow(len(input))
-	for _, r := range input {
-		if isAllowedShellChar(r) {
-			b.WriteRune(r)
+	return sanitizeShellArg(input, "", nil)
+}
+
+// sanitizeShellArg implements SanitizeShellArg and SanitizeShellArgAllowing,
+// also keeping the characters in extra, and appends a finding for each
+// dropped character if findings is not nil.
+func sanitizeShellArg(input, extra string, findings *[]Finding) string {
+	if !strings.ContainsFunc(input, func(r rune) bool { return !isAllowedShellRune(r, extra) }) {
+		return input
+	}
+	return string(appendShellArg(make([]byte, 0, len(input)), input, extra, findings))
+}
+
+// appendShellArg appends input sanitized as sanitizeShellArg describes to
+// dst.
+func appendShellArg[T text](dst []byte, input T, extra string, findings *[]Finding) []byte {
+	last := 0
+	for i := 0; i < len(input); {
+		r, size := decodeRune(input[i:])
+		if !isAllowedShellRune(r, extra) {
+			dst = append(dst, input[last:i]...)
+			if findings != nil {
+				*findings = append(*findings, Finding{Kind: FindingRemovedCharacter, Text: string(input[i : i+size]), Offset: i})
+			}
+			last = i + size
 		}
+		i += size
 	}
-	return b.String()
+	return append(dst, input[last:]...)
+}
+
+// isAllowedShellRune reports whether r is in the base ShellArg allowlist or
+// in extra.
+func isAllowedShellRune(r rune, extra string) bool {
+	return isAllowedShellChar(r) || strings.ContainsRune(extra, r)
 }
 
 func isAllowedShellChar(r rune) bool {
@@ -172,3 +630,47 @@ func isAllowedShellChar(r rune) bool {
 		(r >= '0' && r <= '9') ||
 		r == '-' || r == '_' || r == '.' || r == '/'
 }
+
+// hexDigits are the lowercase digits used in RFC 4515 escapes.
+const hexDigits = "0123456789abcdef"
+
+// EscapeLDAPFilter escapes a value for use in an LDAP search filter (CWE-90).
+// Asterisks, parentheses, backslashes and NUL are escaped as in RFC 4515.
+func EscapeLDAPFilter(input string) string {
+	var b strings.Builder
+	b.Grow(len(input))
+	for i := 0; i < len(input); i++ {
+		switch c := input[i]; c {
+		case '*', '(', ')', '\\', 0:
+			b.WriteByte('\\')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0x0f])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// EscapeLDAPDN escapes an attribute value for use in an LDAP distinguished
+// name (CWE-90). Special characters, a leading space or '#' and a trailing
+// space are escaped with a backslash and NUL as \00, as in RFC 4514.
+func EscapeLDAPDN(input string) string {
+	var b strings.Builder
+	b.Grow(len(input))
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case c == 0:
+			b.WriteString(`\00`)
+		case c == '"' || c == '+' || c == ',' || c == ';' || c == '<' || c == '>' || c == '\\',
+			i == 0 && (c == ' ' || c == '#'),
+			i == len(input)-1 && c == ' ':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
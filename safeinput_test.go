@@ -1,8 +1,14 @@
 package safeinput
 
 import (
+	"errors"
+	"net/url"
 	"strings"
+	"sync"
 	"testing"
+
+	"github.com/ravisastryk/go-safeinput/path"
+	"github.com/ravisastryk/go-safeinput/sql"
 )
 
 func TestDefault(t *testing.T) {
@@ -31,6 +37,96 @@ func TestNew_DefaultMaxLength(t *testing.T) {
 	}
 }
 
+func TestWithConfig(t *testing.T) {
+	s := New(Config{StrictMode: true, AllowedHTMLTags: []string{"b"}})
+	derived := s.WithConfig(Config{BasePath: "/srv/data"})
+
+	if got := s.MustSanitize("<b>x</b>", HTMLBody); got != "<b>x</b>" {
+		t.Errorf("original HTMLBody = %q", got)
+	}
+	if got := derived.MustSanitize("<b>x</b>", HTMLBody); got != "x" {
+		t.Errorf("derived HTMLBody = %q", got)
+	}
+	if s.GetConfig().BasePath != "" || derived.GetConfig().MaxInputLength != 10000 {
+		t.Errorf("unexpected configs %+v, %+v", s.GetConfig(), derived.GetConfig())
+	}
+}
+
+func TestWith(t *testing.T) {
+	s := Default()
+	derived := s.With(WithMaxInputLength(5), WithAllowedHTMLTags("i"), WithStrictMode(false), WithBasePath("/tmp"))
+
+	if _, err := s.Sanitize("abcdefgh", LogLine); err != nil {
+		t.Errorf("original rejected input: %v", err)
+	}
+	if _, err := derived.Sanitize("abcdefgh", LogLine); !errors.Is(err, ErrInputTooLong) {
+		t.Errorf("expected ErrInputTooLong, got %v", err)
+	}
+	cfg := derived.GetConfig()
+	if cfg.StrictMode || cfg.BasePath != "/tmp" || len(cfg.AllowedHTMLTags) != 1 || !cfg.NormalizeUnicode {
+		t.Errorf("unexpected derived config %+v", cfg)
+	}
+	if !s.GetConfig().StrictMode {
+		t.Error("With changed the original config")
+	}
+
+	// Custom contexts carry over to derived sanitizers
+	ticket, err := s.RegisterContext("Ticket", sanitizeTicket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.With().Sanitize("ops-1", ticket); err != nil || got != "OPS-1" {
+		t.Errorf("derived Sanitize = %q, %v", got, err)
+	}
+}
+
+func TestGetConfig_Copy(t *testing.T) {
+	tags := []string{"b"}
+	s := New(Config{AllowedHTMLTags: tags, UUIDVersions: []int{4}, MaxLengths: map[Context]int{LogLine: 5}})
+	tags[0] = "script"
+
+	cfg := s.GetConfig()
+	cfg.UUIDVersions[0] = 7
+	cfg.MaxLengths[LogLine] = 1
+
+	cfg = s.GetConfig()
+	if cfg.AllowedHTMLTags[0] != "b" || cfg.UUIDVersions[0] != 4 || cfg.MaxLengths[LogLine] != 5 {
+		t.Errorf("configuration was changed from outside: %+v", cfg)
+	}
+}
+
+func TestWith_Concurrent(t *testing.T) {
+	s := Default()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if got := s.MustSanitize("<b>x</b>", HTMLBody); got != "x" {
+					t.Errorf("HTMLBody = %q", got)
+					return
+				}
+				if _, err := s.Sanitize("users", SQLIdentifier); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				derived := s.With(WithStrictMode(i%2 == 0), WithAllowedHTMLTags("b"))
+				if got := derived.MustSanitize("<b>x</b>", HTMLBody); got != "<b>x</b>" {
+					t.Errorf("derived HTMLBody = %q", got)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
 func TestSanitize_HTMLBody(t *testing.T) {
 	s := Default()
 	tests := []struct {
@@ -57,6 +153,19 @@ func TestSanitize_HTMLBody(t *testing.T) {
 	}
 }
 
+func TestSanitize_HTMLBodyURLSchemes(t *testing.T) {
+	s := New(Config{AllowedHTMLTags: []string{"a"}})
+	input := `<a href="javascript:alert(1)">x</a><a href="mailto:a@example.com">y</a>`
+	if got, _ := s.Sanitize(input, HTMLBody); got != `<a>x</a><a href="mailto:a@example.com">y</a>` {
+		t.Errorf("Sanitize(%q) = %q", input, got)
+	}
+
+	s = New(Config{AllowedHTMLTags: []string{"a"}, AllowedHTMLURLSchemes: []string{"https"}})
+	if got, _ := s.Sanitize(input, HTMLBody); got != "<a>x</a><a>y</a>" {
+		t.Errorf("Sanitize(%q) with https only = %q", input, got)
+	}
+}
+
 func TestSanitize_HTMLAttribute(t *testing.T) {
 	s := Default()
 	tests := []struct {
@@ -92,6 +201,37 @@ func TestSanitize_FilePath(t *testing.T) {
 	}
 }
 
+func TestSanitize_Filename(t *testing.T) {
+	s := Default()
+	tests := []struct {
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"invoice.pdf", "invoice.pdf", nil},
+		{"what?.txt", "what_.txt", nil},
+		{".bashrc", "bashrc", nil},
+		{"subdir/file.txt", "", path.ErrPathSeparator},
+		{"..", "", path.ErrPathTraversal},
+		{"CON.txt", "", path.ErrReservedName},
+	}
+	for _, tt := range tests {
+		got, err := s.Sanitize(tt.input, Filename)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Sanitize(%q, Filename) error = %v, want %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Sanitize(%q, Filename) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	s = New(Config{AllowDotFiles: true, MaxFilenameLength: 8})
+	if got, err := s.Sanitize(".profile.sh", Filename); err != nil || got != ".prof.sh" {
+		t.Errorf("configured: got %q, %v", got, err)
+	}
+}
+
 func TestSanitize_SQLIdentifier(t *testing.T) {
 	s := Default()
 	valid := []string{"users", "user_data", "_private", "Table123"}
@@ -129,30 +269,352 @@ func TestSanitize_ShellArg(t *testing.T) {
 	}
 }
 
-func TestSanitize_URLPath(t *testing.T) {
+func TestSanitize_LDAPFilter(t *testing.T) {
 	s := Default()
-	if _, err := s.Sanitize("path/to/file", URLPath); err != nil {
-		t.Errorf("URLPath error: %v", err)
+	payloads := []string{
+		"*)(uid=*))(|(uid=*",
+		"admin)(&)",
+		"*",
+		"x)(|(objectClass=*)",
+	}
+	for _, p := range payloads {
+		got, err := s.Sanitize(p, LDAPFilter)
+		if err != nil {
+			t.Errorf("Sanitize(%q, LDAPFilter) error = %v", p, err)
+		}
+		if strings.ContainsAny(got, "*()") {
+			t.Errorf("Sanitize(%q, LDAPFilter) = %q, still contains filter syntax", p, got)
+		}
+		filter := "(&(uid=" + got + ")(objectClass=person))"
+		if strings.Count(filter, "(") != 3 || strings.Count(filter, ")") != 3 {
+			t.Errorf("escaped value changed the filter structure: %s", filter)
+		}
 	}
 }
 
-func TestSanitize_URLQuery(t *testing.T) {
+func TestSanitize_LDAPDN(t *testing.T) {
 	s := Default()
-	if _, err := s.Sanitize("key=value", URLQuery); err != nil {
-		t.Errorf("URLQuery error: %v", err)
+	got, err := s.Sanitize("admin,ou=admins,dc=example,dc=com", LDAPDN)
+	if err != nil {
+		t.Fatalf("Sanitize error = %v", err)
+	}
+	if got != `admin\,ou=admins\,dc=example\,dc=com` {
+		t.Errorf("LDAPDN should escape commas: %q", got)
+	}
+}
+
+func TestSanitize_URLPath(t *testing.T) {
+	tests := []struct {
+		input   string
+		segment bool
+		want    string
+	}{
+		{"docs/intro", false, "docs/intro"},
+		{"/files/my report.pdf", false, "/files/my%20report.pdf"},
+		{"a?admin=1#top", false, "a%3Fadmin=1%23top"},
+		{"100%/done", false, "100%25/done"},
+		{"café/ü", false, "caf%C3%A9/%C3%BC"},
+		{"javascript:alert(1)", false, "javascript%3Aalert%281%29"},
+		{`a\b`, false, "a%5Cb"},
+		{"//evil.com/x", false, "/%2Fevil.com/x"},
+		{"///evil.com", false, "/%2F/evil.com"},
+		{"/\\evil.com", false, "/%5Cevil.com"},
+		{"a/b", true, "a%2Fb"},
+		{"my file?.txt", true, "my%20file%3F.txt"},
+		{"", false, ""},
+	}
+	for _, tt := range tests {
+		s := New(Config{URLPathSegment: tt.segment})
+		got, err := s.Sanitize(tt.input, URLPath)
+		if err != nil || got != tt.want {
+			t.Errorf("Sanitize(%q, segment %v) = %q, %v, want %q", tt.input, tt.segment, got, err, tt.want)
+			continue
+		}
+
+		if u, err := url.Parse(got); err != nil || u.Host != "" {
+			t.Errorf("url.Parse(%q) = %#v, %v, want no host", got, u, err)
+		}
+		u, err := url.Parse("https://example.com/base/" + got)
+		if err != nil || u.RawQuery != "" || u.Fragment != "" || u.Opaque != "" || u.Host != "example.com" {
+			t.Errorf("url.Parse of %q = %#v, %v", got, u, err)
+			continue
+		}
+		want := "/base/" + tt.input
+		if tt.segment {
+			want = "/base/" + url.PathEscape(tt.input)
+			if u.EscapedPath() != "/base/"+got {
+				t.Errorf("EscapedPath of %q = %q", got, u.EscapedPath())
+			}
+			if seg, _ := url.PathUnescape(got); seg != tt.input {
+				t.Errorf("PathUnescape(%q) = %q, want %q", got, seg, tt.input)
+			}
+			continue
+		}
+		if u.Path != want {
+			t.Errorf("url.Parse(%q).Path = %q, want %q", got, u.Path, want)
+		}
+	}
+}
+
+func TestSanitize_URLPathStrict(t *testing.T) {
+	s := New(Config{StrictMode: true})
+	for _, input := range []string{"docs/intro", "/files/my%20report.pdf", "caf%C3%A9", "a/b:c", ""} {
+		got, err := s.Sanitize(input, URLPath)
+		if err != nil || got != input {
+			t.Errorf("Sanitize(%q) = %q, %v, want it unchanged", input, got, err)
+		}
+	}
+
+	tests := []struct {
+		input   string
+		segment bool
+		offset  int
+	}{
+		{"a?admin=1", false, 1},
+		{"docs#top", false, 4},
+		{`a\b`, false, 1},
+		{"javascript:alert(1)", false, 10},
+		{"//evil.com/x", false, 1},
+		{`/\evil.com`, false, 1},
+		{"a/b", true, 1},
+		{"12:30", true, 2},
+		{"100%", false, 3},
+		{"a%zz", false, 1},
+	}
+	for _, tt := range tests {
+		s := New(Config{StrictMode: true, URLPathSegment: tt.segment})
+		_, err := s.Sanitize(tt.input, URLPath)
+		var se *SanitizeError
+		if !errors.Is(err, ErrInvalidURLPath) || !errors.As(err, &se) || se.Offset != tt.offset || se.Kind != ErrorKindInvalidChar {
+			t.Errorf("Sanitize(%q, segment %v) = %v, want ErrInvalidURLPath at %d", tt.input, tt.segment, err, tt.offset)
+		}
+	}
+}
+
+func TestSanitize_URLPathRejects(t *testing.T) {
+	// the traversal corpus of the path package's tests
+	attacks := []string{
+		"../etc/passwd", "../../etc/passwd", "../../../etc",
+		"foo/../../../etc", "..\\..\\windows",
+		"..%2f..%2f", "..%5c..%5c", "%2e%2e/",
+		".%2e/", "%2e./", "..%252f", "....//",
+		"files/..%2F..%2Fetc", "x/%2E%2E/",
+	}
+	for _, input := range attacks {
+		if !path.IsTraversal(input) {
+			t.Fatalf("path.IsTraversal(%q) = false", input)
+		}
+		for name, cfg := range map[string]Config{"path": {}, "strict": {StrictMode: true}, "segment": {URLPathSegment: true}} {
+			_, err := New(cfg).Sanitize(input, URLPath)
+			var se *SanitizeError
+			if !errors.Is(err, path.ErrPathTraversal) || !errors.As(err, &se) || se.Kind != ErrorKindTraversal {
+				t.Errorf("%s: Sanitize(%q) = %v, want ErrPathTraversal", name, input, err)
+			}
+		}
+	}
+
+	for _, input := range []string{"a\nb", "tab\there", "del\x7f", "a\rb"} {
+		if _, err := Default().Sanitize(input, URLPath); !errors.Is(err, path.ErrInvalidCharacter) {
+			t.Errorf("Sanitize(%q) = %v, want ErrInvalidCharacter", input, err)
+		}
+	}
+}
+
+func TestSanitize_URLQuery(t *testing.T) {
+	s := New(Config{StripNullBytes: true})
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"value", "value"},
+		{"a&b=c", "a%26b%3Dc"},
+		{"x&admin=true", "x%26admin%3Dtrue"},
+		{"a b+c", "a+b%2Bc"},
+		{"100%#top;x", "100%25%23top%3Bx"},
+		{"café\r\n", "caf%C3%A9%0D%0A"},
+		{"<script>", "%3Cscript%3E"},
+	}
+	for _, tt := range tests {
+		got, err := s.Sanitize(tt.input, URLQuery)
+		if err != nil || got != tt.want {
+			t.Errorf("Sanitize(%q, URLQuery) = %q, %v, want %q", tt.input, got, err, tt.want)
+			continue
+		}
+		values, err := url.ParseQuery("q=" + got + "&admin=false")
+		if err != nil || len(values["q"]) != 1 || values.Get("q") != tt.input || values.Get("admin") != "false" {
+			t.Errorf("ParseQuery(%q) = %v, %v, want q=%q", got, values, err, tt.input)
+		}
+	}
+
+	legacy := New(Config{LegacyURLQueryEscaping: true})
+	if got, _ := legacy.Sanitize("a&b=c", URLQuery); got != "a&amp;b=c" {
+		t.Errorf("legacy URLQuery = %q, want HTML escaping", got)
+	}
+}
+
+func TestSanitize_URLQueryStrict(t *testing.T) {
+	s := New(Config{StrictMode: true})
+	for _, input := range []string{"value", "caf%C3%A9", "a+b", "a%26b", ""} {
+		got, err := s.Sanitize(input, URLQuery)
+		if err != nil || got != input {
+			t.Errorf("Sanitize(%q) = %q, %v, want it unchanged", input, got, err)
+		}
+		if _, err := url.ParseQuery("q=" + got); err != nil {
+			t.Errorf("ParseQuery(%q): %v", got, err)
+		}
+	}
+
+	tests := []struct {
+		input  string
+		offset int
+		text   string
+	}{
+		{"a&b", 1, "&"},
+		{"key=value", 3, "="},
+		{"x#frag", 1, "#"},
+		{"a;b", 1, ";"},
+		{"line\nbreak", 4, "\n"},
+		{"del\x7f", 3, "\x7f"},
+		{"100%", 3, "%"},
+		{"50%zz", 2, "%zz"},
+	}
+	for _, tt := range tests {
+		_, err := s.Sanitize(tt.input, URLQuery)
+		var se *SanitizeError
+		if !errors.Is(err, ErrInvalidQueryValue) || !errors.As(err, &se) || se.Offset != tt.offset || se.Text != tt.text || se.Kind != ErrorKindInvalidChar {
+			t.Errorf("Sanitize(%q) = %v, want ErrInvalidQueryValue at %d", tt.input, err, tt.offset)
+		}
 	}
 }
 
 func TestSanitize_MaxLength(t *testing.T) {
 	s := New(Config{MaxInputLength: 10})
-	if _, err := s.Sanitize("this is too long", HTMLBody); err != ErrInputTooLong {
+	if _, err := s.Sanitize("this is too long", HTMLBody); !errors.Is(err, ErrInputTooLong) {
 		t.Errorf("Expected ErrInputTooLong, got %v", err)
 	}
 }
 
+func TestSanitize_MaxLengths(t *testing.T) {
+	long := strings.Repeat("a", 300)
+
+	s := Default()
+	if _, err := s.Sanitize(long, HTMLBody); err != nil {
+		t.Errorf("HTMLBody: unexpected error %v", err)
+	}
+	_, err := s.Sanitize(long, Filename)
+	if !errors.Is(err, ErrInputTooLong) || !strings.Contains(err.Error(), "Filename input is limited to 255 bytes") {
+		t.Errorf("Filename: expected ErrInputTooLong naming the limit, got %v", err)
+	}
+	if err := s.Validate(long, Filename); !errors.Is(err, ErrInputTooLong) {
+		t.Errorf("Validate: expected ErrInputTooLong, got %v", err)
+	}
+
+	limits := map[Context]int{SQLIdentifier: 64, HTMLBody: 20000, Filename: 400, LogLine: 0}
+	s = New(Config{MaxInputLength: 100, MaxLengths: limits})
+	tests := []struct {
+		ctx   Context
+		size  int
+		valid bool
+	}{
+		{SQLIdentifier, 64, true},
+		{SQLIdentifier, 65, false},
+		{HTMLBody, 15000, true},
+		{Filename, 300, true},
+		{LogLine, 100, true}, // zero falls back to MaxInputLength
+		{LogLine, 101, false},
+		{ShellArg, 101, false},
+	}
+	for _, tt := range tests {
+		_, err := s.Sanitize(strings.Repeat("a", tt.size), tt.ctx)
+		if tt.valid != (err == nil) || (err != nil && !errors.Is(err, ErrInputTooLong)) {
+			t.Errorf("%v with %d bytes: got %v", tt.ctx, tt.size, err)
+		}
+	}
+
+	// The sanitizer keeps its own copy of the limits
+	limits[ShellArg] = 1000
+	if _, err := s.Sanitize(strings.Repeat("a", 101), ShellArg); !errors.Is(err, ErrInputTooLong) {
+		t.Errorf("expected the configured limits to be copied, got %v", err)
+	}
+}
+
+func TestSanitize_NormalizeUnicode(t *testing.T) {
+	const composed, decomposed = "caf\u00e9", "cafe\u0301"
+
+	s := Default()
+	tests := []struct {
+		input   string
+		ctx     Context
+		want    string
+		wantErr error
+	}{
+		{decomposed, HTMLBody, composed, nil},
+		{composed, HTMLBody, composed, nil},
+		{"\u1100\u1161\u11a8", LogLine, "\uac01", nil},
+		{decomposed + "/menu.txt", FilePath, composed + "/menu.txt", nil},
+		{"menus/" + decomposed + ".txt", Filename, "", path.ErrPathSeparator},
+		{decomposed + ".txt", Filename, composed + ".txt", nil},
+		{decomposed, SQLIdentifier, "", sql.ErrInvalidIdentifier},
+		{"user_" + decomposed, SQLIdentifier, "", sql.ErrInvalidIdentifier},
+	}
+	for _, tt := range tests {
+		got, err := s.Sanitize(tt.input, tt.ctx)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("Sanitize(%q, %v) = %q, %v, want %q, %v", tt.input, tt.ctx, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	// Decomposed and composed paths now name the same file
+	a, _ := s.Sanitize("docs/"+decomposed, FilePath)
+	b, _ := s.Sanitize("docs/"+composed, FilePath)
+	if a != b {
+		t.Errorf("paths differ after normalization: %q, %q", a, b)
+	}
+
+	// Off unless configured
+	if got, _ := New(Config{}).Sanitize(decomposed, HTMLBody); got != decomposed {
+		t.Errorf("normalized without NormalizeUnicode: %q", got)
+	}
+
+	if err := s.Validate(decomposed, HTMLBody); !errors.Is(err, ErrUnsafeInput) {
+		t.Errorf("Validate(decomposed, HTMLBody) = %v, want ErrUnsafeInput", err)
+	}
+	if err := s.Validate(decomposed+".txt", FilePath); err != nil {
+		t.Errorf("Validate(decomposed, FilePath) = %v", err)
+	}
+	res, err := s.SanitizeDetailed(decomposed, HTMLBody)
+	if err != nil || res.Output != composed || !res.Modified {
+		t.Errorf("SanitizeDetailed(decomposed) = %+v, %v", res, err)
+	}
+}
+
+// MaxInputLength applies to the normalized input
+func TestSanitize_NormalizeUnicodeLength(t *testing.T) {
+	decomposed := strings.Repeat("e\u0301", 4) // 12 bytes, 8 once composed
+
+	s := New(Config{MaxInputLength: 8, NormalizeUnicode: true})
+	if got, err := s.Sanitize(decomposed, HTMLBody); err != nil || got != strings.Repeat("\u00e9", 4) {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if _, err := s.Sanitize(decomposed+"e\u0301", HTMLBody); !errors.Is(err, ErrInputTooLong) {
+		t.Errorf("expected ErrInputTooLong over the limit, got %v", err)
+	}
+	if _, err := New(Config{MaxInputLength: 8}).Sanitize(decomposed, HTMLBody); !errors.Is(err, ErrInputTooLong) {
+		t.Errorf("expected ErrInputTooLong without normalization, got %v", err)
+	}
+
+	// Hangul jamo compose to a third of their length
+	if got, err := s.Sanitize(strings.Repeat("\u1100\u1161\u11a8", 2), HTMLBody); err != nil || got != "\uac01\uac01" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if _, err := s.Sanitize(strings.Repeat("\u1100\u1161\u11a8", 3), HTMLBody); !errors.Is(err, ErrInputTooLong) {
+		t.Errorf("expected ErrInputTooLong for 27 bytes, got %v", err)
+	}
+}
+
 func TestSanitize_NullByte(t *testing.T) {
 	s := New(Config{StripNullBytes: false, MaxInputLength: 1000})
-	if _, err := s.Sanitize("file\x00.txt", HTMLBody); err != ErrNullByte {
+	if _, err := s.Sanitize("file\x00.txt", HTMLBody); !errors.Is(err, ErrNullByte) {
 		t.Errorf("Expected ErrNullByte, got %v", err)
 	}
 }
@@ -212,6 +674,24 @@ func TestContext_String(t *testing.T) {
 		{URLPath, "URLPath"},
 		{URLQuery, "URLQuery"},
 		{ShellArg, "ShellArg"},
+		{LDAPFilter, "LDAPFilter"},
+		{LDAPDN, "LDAPDN"},
+		{HTTPHeader, "HTTPHeader"},
+		{LogLine, "LogLine"},
+		{JSONString, "JSONString"},
+		{URL, "URL"},
+		{RegexLiteral, "RegexLiteral"},
+		{RegexPattern, "RegexPattern"},
+		{CSVField, "CSVField"},
+		{XMLText, "XMLText"},
+		{XMLAttr, "XMLAttr"},
+		{Hostname, "Hostname"},
+		{IPAddress, "IPAddress"},
+		{UUID, "UUID"},
+		{CookieName, "CookieName"},
+		{CookieValue, "CookieValue"},
+		{TemplateLiteral, "TemplateLiteral"},
+		{Filename, "Filename"},
 		{Context(999), "Unknown"},
 		{Context(-1), "Unknown"},
 	}
@@ -222,6 +702,60 @@ func TestContext_String(t *testing.T) {
 	}
 }
 
+func TestParseContext(t *testing.T) {
+	tests := []struct {
+		name string
+		want Context
+	}{
+		{"HTMLBody", HTMLBody},
+		{"htmlbody", HTMLBody},
+		{"html", HTMLBody},
+		{"HTML_ATTR", HTMLAttribute},
+		{"sql_identifier", SQLIdentifier},
+		{"shell", ShellArg},
+		{"path", FilePath},
+		{"Filename", Filename},
+		{"uuid", UUID},
+	}
+	for _, tt := range tests {
+		got, err := ParseContext(tt.name)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseContext(%q) = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+
+	for _, name := range []string{"", "Unknown", "htmlx"} {
+		if _, err := ParseContext(name); !errors.Is(err, ErrUnknownContext) {
+			t.Errorf("ParseContext(%q) error = %v, want ErrUnknownContext", name, err)
+		}
+	}
+}
+
+func TestAllContexts_RoundTrip(t *testing.T) {
+	contexts := AllContexts()
+	if len(contexts) != int(ShellArgWindows)+1 {
+		t.Fatalf("AllContexts() returned %d contexts, want %d", len(contexts), int(ShellArgWindows)+1)
+	}
+	for i, ctx := range contexts {
+		if ctx != Context(i) {
+			t.Errorf("AllContexts()[%d] = %d", i, int(ctx))
+		}
+		name := ctx.String()
+		if name == "Unknown" {
+			t.Errorf("Context(%d) has no name", int(ctx))
+		}
+		if got, err := ParseContext(name); err != nil || got != ctx {
+			t.Errorf("ParseContext(%q) = %v, %v, want %v", name, got, err, ctx)
+		}
+	}
+	// Every struct tag alias names a context that AllContexts lists
+	for tag, ctx := range tagContexts {
+		if got, err := ParseContext(tag); err != nil || got != ctx || int(ctx) >= len(contexts) {
+			t.Errorf("ParseContext(%q) = %v, %v, want %v", tag, got, err, ctx)
+		}
+	}
+}
+
 func TestStripNullBytes(t *testing.T) {
 	tests := []struct {
 		input string
@@ -258,6 +792,45 @@ func TestSanitizeShellArg(t *testing.T) {
 	}
 }
 
+func TestEscapeLDAPFilter(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"alice", "alice"},
+		{"*)(uid=*))(|(uid=*", `\2a\29\28uid=\2a\29\29\28|\28uid=\2a`},
+		{`a\b`, `a\5cb`},
+		{"nul\x00byte", `nul\00byte`},
+		{"Lučić", "Lučić"},
+	}
+	for _, tt := range tests {
+		if got := EscapeLDAPFilter(tt.input); got != tt.want {
+			t.Errorf("EscapeLDAPFilter(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeLDAPDN(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"alice", "alice"},
+		{"Smith, John", `Smith\, John`},
+		{`a+b"c;d<e>f\g`, `a\+b\"c\;d\<e\>f\\g`},
+		{"#admin", `\#admin`},
+		{" padded ", `\ padded\ `},
+		{"a#b c", "a#b c"},
+		{"nul\x00byte", `nul\00byte`},
+		{"cn=x,dc=evil", `cn=x\,dc=evil`},
+	}
+	for _, tt := range tests {
+		if got := EscapeLDAPDN(tt.input); got != tt.want {
+			t.Errorf("EscapeLDAPDN(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
 func TestIsAllowedShellChar(t *testing.T) {
 	allowed := []rune{'a', 'Z', '0', '-', '_', '.', '/'}
 	for _, r := range allowed {
@@ -0,0 +1,278 @@
+package safeinput
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ravisastryk/go-safeinput/path"
+	"github.com/ravisastryk/go-safeinput/sql"
+)
+
+// ErrorKind classifies a SanitizeError.
+type ErrorKind string
+
+// Kinds of errors reported by SanitizeError.
+const (
+	// ErrorKindInvalid is input that is malformed for its context, such as
+	// a URL that does not parse. It is the kind of any error not covered
+	// by the others.
+	ErrorKindInvalid ErrorKind = "invalid"
+	// ErrorKindTooLong is input over a length limit.
+	ErrorKindTooLong ErrorKind = "too_long"
+	// ErrorKindInvalidChar is a character the context does not allow,
+	// such as a null byte, a control character in a path or CR or LF in a
+	// header value.
+	ErrorKindInvalidChar ErrorKind = "invalid_char"
+	// ErrorKindTraversal is a path traversal sequence or a path escaping
+	// the base directory.
+	ErrorKindTraversal ErrorKind = "traversal"
+	// ErrorKindSuspiciousPattern is text matching an injection pattern,
+	// such as a SQL comment or a template action.
+	ErrorKindSuspiciousPattern ErrorKind = "suspicious_pattern"
+	// ErrorKindNotAllowed is input that is well formed but rejected by
+	// policy, such as an absolute path, a SQL reserved word or a denied
+	// host.
+	ErrorKindNotAllowed ErrorKind = "not_allowed"
+)
+
+// Severity grades how likely input that Sanitize changed or rejected is an
+// attack, from SeverityNone to SeverityMalicious, so that callers can tell a
+// mistake to report back from an attempt to alert on.
+type Severity int
+
+// Severity levels, in increasing order.
+const (
+	// SeverityNone is input Sanitize accepts as it is, or only escapes.
+	SeverityNone Severity = iota
+	// SeverityInfo is input that is malformed or over a limit, as a user
+	// can produce by mistake.
+	SeverityInfo
+	// SeveritySuspicious is input with characters or values that have
+	// little use other than attacks, such as null bytes, bidi controls or
+	// internal host names, but that are not an attack on their own.
+	SeveritySuspicious
+	// SeverityMalicious is input matching an attack pattern, such as path
+	// traversal, SQL injection or a script element.
+	SeverityMalicious
+)
+
+var severityNames = map[Severity]string{
+	SeverityNone:       "none",
+	SeverityInfo:       "info",
+	SeveritySuspicious: "suspicious",
+	SeverityMalicious:  "malicious",
+}
+
+// String returns the name of the severity, such as "suspicious".
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// errorKinds maps the errors Sanitize can return to their kinds and
+// severities, checked in order with errors.Is. Errors not in the table are
+// ErrorKindInvalid and SeverityInfo.
+var errorKinds = []struct {
+	err      error
+	kind     ErrorKind
+	severity Severity
+}{
+	{ErrInputTooLong, ErrorKindTooLong, SeverityInfo},
+	{sql.ErrIdentifierTooLong, ErrorKindTooLong, SeverityInfo},
+	{ErrNullByte, ErrorKindInvalidChar, SeveritySuspicious},
+	{ErrBidiControl, ErrorKindInvalidChar, SeveritySuspicious},
+	{ErrInvisibleCharacter, ErrorKindInvalidChar, SeverityInfo},
+	{ErrHeaderInjection, ErrorKindInvalidChar, SeveritySuspicious},
+	{ErrInvalidXMLChar, ErrorKindInvalidChar, SeverityInfo},
+	{ErrInvalidCookie, ErrorKindInvalidChar, SeverityInfo},
+	{ErrUnquotableShellArg, ErrorKindInvalidChar, SeveritySuspicious},
+	{ErrInvalidQueryValue, ErrorKindInvalidChar, SeveritySuspicious},
+	{ErrInvalidURLPath, ErrorKindInvalidChar, SeveritySuspicious},
+	{path.ErrInvalidCharacter, ErrorKindInvalidChar, SeverityInfo},
+	{path.ErrPathSeparator, ErrorKindInvalidChar, SeverityInfo},
+	{sql.ErrInvalidIdentifier, ErrorKindInvalidChar, SeverityInfo},
+	{path.ErrPathTraversal, ErrorKindTraversal, SeverityMalicious},
+	{path.ErrOutsideBasePath, ErrorKindTraversal, SeverityMalicious},
+	{sql.ErrSuspiciousPattern, ErrorKindSuspiciousPattern, SeverityMalicious},
+	{ErrTemplateAction, ErrorKindSuspiciousPattern, SeveritySuspicious},
+	{ErrUnsafeRegex, ErrorKindSuspiciousPattern, SeveritySuspicious},
+	{path.ErrAbsolutePath, ErrorKindNotAllowed, SeveritySuspicious},
+	{path.ErrReservedName, ErrorKindNotAllowed, SeverityInfo},
+	{sql.ErrReservedWord, ErrorKindNotAllowed, SeverityInfo},
+	{ErrURLCredentials, ErrorKindNotAllowed, SeveritySuspicious},
+	{ErrURLSchemeNotAllowed, ErrorKindNotAllowed, SeveritySuspicious},
+	{ErrURLHostNotAllowed, ErrorKindNotAllowed, SeveritySuspicious},
+	{ErrHostnameNotAllowed, ErrorKindNotAllowed, SeveritySuspicious},
+	{ErrIPAddressNotAllowed, ErrorKindNotAllowed, SeveritySuspicious},
+	{ErrUUIDVersionNotAllowed, ErrorKindNotAllowed, SeverityInfo},
+	{ErrMixedScript, ErrorKindNotAllowed, SeveritySuspicious},
+}
+
+// maxErrorText is the number of bytes of offending text SanitizeError.Error
+// includes.
+const maxErrorText = 32
+
+// SanitizeError describes why Sanitize rejected input. errors.Is matches
+// it against Err, so callers can keep testing for sentinels such as
+// path.ErrPathTraversal or ErrNullByte.
+type SanitizeError struct {
+	Context Context
+	Kind    ErrorKind
+	// Offset is the byte offset of Text in the input, or -1 if the error
+	// is not tied to a position. With NormalizeUnicode set, it is an
+	// offset into the NFC form of the input.
+	Offset int
+	// Text is the offending character or sequence, if there is one.
+	Text string
+	// Pattern is the suspicious pattern Text matched, for SQLValue.
+	Pattern string
+	// Err is the underlying error.
+	Err error
+	// Severity grades the rejection, from the rule that made it.
+	Severity Severity
+}
+
+// Error describes the error and, if there is one, the position and the
+// offending text, quoted so that control characters are escaped and
+// shortened if it is long.
+func (e *SanitizeError) Error() string {
+	if e.Offset < 0 {
+		return e.Err.Error()
+	}
+	text, more := e.Text, ""
+	if len(text) > maxErrorText {
+		text, more = text[:maxErrorText], "..."
+	}
+	return fmt.Sprintf("%v at offset %d: %q%s", e.Err, e.Offset, text, more)
+}
+
+// Unwrap returns the underlying error.
+func (e *SanitizeError) Unwrap() error {
+	return e.Err
+}
+
+// errorKind returns the kind of err.
+func errorKind(err error) ErrorKind {
+	for _, k := range errorKinds {
+		if errors.Is(err, k.err) {
+			return k.kind
+		}
+	}
+	return ErrorKindInvalid
+}
+
+// ErrorSeverity returns the severity of err from Sanitize or Validate: the
+// Severity of a *SanitizeError, or else that of the rule it comes from.
+// It is SeverityNone for nil and ErrUnsafeInput, and SeverityInfo for
+// errors that are not about the input, such as ErrUnknownContext.
+func ErrorSeverity(err error) Severity {
+	var se *SanitizeError
+	switch {
+	case err == nil || errors.Is(err, ErrUnsafeInput):
+		return SeverityNone
+	case errors.As(err, &se):
+		return se.Severity
+	}
+	for _, k := range errorKinds {
+		if errors.Is(err, k.err) {
+			return k.severity
+		}
+	}
+	return SeverityInfo
+}
+
+// sanitizeError returns err from sanitizing input for ctx as a
+// *SanitizeError. input is normalized but still holds the characters
+// Sanitize strips before dispatching on the context; a *SanitizeError from
+// a context has offsets without them, which are mapped back to input.
+// ErrUnknownContext is returned as is, since it is not about input.
+func (s *Sanitizer) sanitizeError(input string, ctx Context, err error) error {
+	if errors.Is(err, ErrUnknownContext) {
+		return err
+	}
+	var contextErr *SanitizeError
+	if errors.As(err, &contextErr) {
+		se := *contextErr
+		se.Context = ctx
+		se.Severity = ErrorSeverity(se.Err)
+		if se.Offset >= 0 {
+			se.Offset = s.unstrippedOffset(input, ctx, se.Offset)
+		}
+		return &se
+	}
+
+	se := &SanitizeError{Context: ctx, Kind: errorKind(err), Offset: -1, Err: err, Severity: ErrorSeverity(err)}
+	switch {
+	case errors.Is(err, ErrNullByte):
+		se.Offset = strings.IndexByte(input, 0)
+	case errors.Is(err, ErrBidiControl):
+		se.Offset = IndexBidiControl(input)
+	case errors.Is(err, ErrInvisibleCharacter):
+		se.Offset = strings.IndexFunc(input, isInvisibleRune)
+	}
+	if se.Offset >= 0 {
+		se.Text = runeAt(input, se.Offset)
+	}
+	return se
+}
+
+// unstrippedOffset maps offset, into input with the characters
+// strippedCharacters reports removed, back to an offset into input.
+func (s *Sanitizer) unstrippedOffset(input string, ctx Context, offset int) int {
+	for _, r := range s.strippedCharacters(input, ctx) {
+		if r.Offset <= offset {
+			offset += len(r.Text)
+		}
+	}
+	return offset
+}
+
+// sqlError returns err from the sql package as a *SanitizeError locating
+// what made it reject input, or nil if err is nil.
+func sqlError(input string, err error, maxLength int) error {
+	if err == nil {
+		return nil
+	}
+	se := &SanitizeError{Kind: errorKind(err), Offset: -1, Err: err}
+	switch {
+	case errors.Is(err, sql.ErrSuspiciousPattern):
+		se.Pattern, se.Text, se.Offset = sql.FindSuspiciousPattern(input)
+	case errors.Is(err, sql.ErrIdentifierTooLong):
+		se.Text, se.Offset = input[maxLength:], maxLength
+	case errors.Is(err, sql.ErrReservedWord):
+		se.Text, se.Offset = input, 0
+	case errors.Is(err, sql.ErrInvalidIdentifier) && input != "":
+		se.Offset = strings.IndexFunc(input, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9') && r != '_'
+		})
+		if se.Offset < 0 {
+			se.Offset = 0 // a leading digit
+		}
+		se.Text = runeAt(input, se.Offset)
+	}
+	return se
+}
+
+// pathError returns err from the path package as a *SanitizeError locating
+// what made it reject input, or nil if err is nil.
+func pathError(input string, err error) error {
+	if err == nil {
+		return nil
+	}
+	se := &SanitizeError{Kind: errorKind(err), Offset: -1, Err: err}
+	switch {
+	case errors.Is(err, path.ErrPathTraversal):
+		se.Text, se.Offset = path.FindTraversal(input)
+	case errors.Is(err, path.ErrInvalidCharacter):
+		se.Offset = strings.IndexFunc(input, func(r rune) bool { return (r < 0x20 && r != '\t') || r == 0x7f })
+		if se.Offset >= 0 {
+			se.Text = runeAt(input, se.Offset)
+		}
+	case errors.Is(err, path.ErrAbsolutePath):
+		se.Text, se.Offset = input, 0
+	}
+	return se
+}
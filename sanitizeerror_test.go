@@ -0,0 +1,150 @@
+package safeinput
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ravisastryk/go-safeinput/path"
+	"github.com/ravisastryk/go-safeinput/sql"
+)
+
+func TestSanitizeError_Traversal(t *testing.T) {
+	input := strings.Repeat("dir/", 50) + "../" + strings.Repeat("sub/", 20) + "file.txt"
+	_, err := Default().Sanitize(input, FilePath)
+	if !errors.Is(err, path.ErrPathTraversal) {
+		t.Fatalf("expected ErrPathTraversal, got %v", err)
+	}
+	var se *SanitizeError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected *SanitizeError, got %T", err)
+	}
+	want := SanitizeError{Context: FilePath, Kind: ErrorKindTraversal, Offset: 200, Text: "../", Err: path.ErrPathTraversal, Severity: SeverityMalicious}
+	if *se != want {
+		t.Errorf("got %+v, want %+v", *se, want)
+	}
+	if got := se.Error(); got != `path traversal detected at offset 200: "../"` {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestSanitizeError_StrippedOffsets(t *testing.T) {
+	// Offsets count the null bytes and invisible characters Sanitize strips
+	_, err := Default().Sanitize("a\x00b\u200b/../c", FilePath)
+	var se *SanitizeError
+	if !errors.As(err, &se) || se.Offset != 7 || se.Text != "../" {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestSanitizeError_Kinds(t *testing.T) {
+	s := New(Config{StrictMode: true, RejectBidiControls: true})
+	tests := []struct {
+		name   string
+		input  string
+		ctx    Context
+		kind   ErrorKind
+		offset int
+		text   string
+		err    error
+	}{
+		{"null byte", "ab\x00", LogLine, ErrorKindInvalidChar, 2, "\x00", ErrNullByte},
+		{"bidi", "abc\u202e", LogLine, ErrorKindInvalidChar, 3, "\u202e", ErrBidiControl},
+		{"too long", strings.Repeat("a", 10001), LogLine, ErrorKindTooLong, -1, "", ErrInputTooLong},
+		{"path control", "a/\x01", FilePath, ErrorKindInvalidChar, 2, "\x01", path.ErrInvalidCharacter},
+		{"absolute", "/etc/passwd", FilePath, ErrorKindNotAllowed, 0, "/etc/passwd", path.ErrAbsolutePath},
+		{"sql comment", "name -- x", SQLValue, ErrorKindSuspiciousPattern, 5, "--", sql.ErrSuspiciousPattern},
+		{"reserved word", "drop", SQLIdentifier, ErrorKindNotAllowed, 0, "drop", sql.ErrReservedWord},
+		{"identifier", "ab-c", SQLIdentifier, ErrorKindInvalidChar, 2, "-", sql.ErrInvalidIdentifier},
+		{"header", "a\r\nb", HTTPHeader, ErrorKindInvalidChar, -1, "", ErrHeaderInjection},
+		{"url", "javascript:alert(1)", URL, ErrorKindNotAllowed, -1, "", ErrURLSchemeNotAllowed},
+		{"uuid", "not-a-uuid", UUID, ErrorKindInvalid, -1, "", ErrInvalidUUID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Sanitize(tt.input, tt.ctx)
+			var se *SanitizeError
+			if !errors.As(err, &se) || !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want *SanitizeError wrapping %v", err, tt.err)
+			}
+			if se.Context != tt.ctx || se.Kind != tt.kind || se.Offset != tt.offset || se.Text != tt.text {
+				t.Errorf("got %+v", *se)
+			}
+		})
+	}
+
+	if _, err := s.Sanitize("x", Context(999)); err != ErrUnknownContext {
+		t.Errorf("expected bare ErrUnknownContext, got %v", err)
+	}
+}
+
+func TestSanitizeError_Error(t *testing.T) {
+	se := &SanitizeError{Offset: 3, Text: "\x1b[31m" + strings.Repeat("x", 40), Err: path.ErrInvalidCharacter}
+	want := `invalid character in path at offset 3: "\x1b[31m` + strings.Repeat("x", 27) + `"...`
+	if got := se.Error(); got != want {
+		t.Errorf("Error() = %s, want %s", got, want)
+	}
+	se = &SanitizeError{Offset: -1, Err: ErrHeaderInjection}
+	if got := se.Error(); got != ErrHeaderInjection.Error() {
+		t.Errorf("Error() = %s", got)
+	}
+}
+
+func TestSanitizeError_CustomContext(t *testing.T) {
+	s := Default()
+	ctx, err := s.RegisterContext("Code", func(input string, _ Config) (string, error) {
+		if i := strings.IndexByte(input, '!'); i >= 0 {
+			return "", &SanitizeError{Kind: ErrorKindInvalidChar, Offset: i, Text: "!", Err: ErrUnsafeInput}
+		}
+		return input, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Sanitize("a\u200bb!", ctx)
+	var se *SanitizeError
+	if !errors.As(err, &se) || se.Context != ctx || se.Offset != 5 || !errors.Is(err, ErrUnsafeInput) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestErrorSeverity(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Severity
+	}{
+		{nil, SeverityNone},
+		{ErrUnsafeInput, SeverityNone},
+		{ErrInputTooLong, SeverityInfo},
+		{fmt.Errorf("%w: HTMLBody input is limited", ErrInputTooLong), SeverityInfo},
+		{ErrInvalidUUID, SeverityInfo},
+		{ErrUnknownContext, SeverityInfo},
+		{ErrNullByte, SeveritySuspicious},
+		{ErrURLHostNotAllowed, SeveritySuspicious},
+		{path.ErrPathTraversal, SeverityMalicious},
+		{sql.ErrSuspiciousPattern, SeverityMalicious},
+		{&SanitizeError{Err: ErrInputTooLong, Severity: SeverityMalicious}, SeverityMalicious},
+	}
+	for _, tt := range tests {
+		if got := ErrorSeverity(tt.err); got != tt.want {
+			t.Errorf("ErrorSeverity(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+
+	for _, k := range errorKinds {
+		if k.severity < SeverityInfo || k.severity > SeverityMalicious {
+			t.Errorf("%v: severity %v out of range", k.err, k.severity)
+		}
+		if k.kind == ErrorKindTraversal && k.severity != SeverityMalicious {
+			t.Errorf("%v: traversal should be malicious, got %v", k.err, k.severity)
+		}
+		if k.kind == ErrorKindTooLong && k.severity != SeverityInfo {
+			t.Errorf("%v: length limits should be info, got %v", k.err, k.severity)
+		}
+	}
+
+	if got := Severity(7).String(); got != "Severity(7)" {
+		t.Errorf("String() = %q", got)
+	}
+}
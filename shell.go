@@ -0,0 +1,131 @@
+package safeinput
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// shellMetacharacters are the characters that can never be added to the
+// ShellArg allowlist.
+const shellMetacharacters = ";|&$`<>()\\\"'*?[]{}~!#^"
+
+// SanitizeShellArgAllowing sanitizes a shell command argument like
+// SanitizeShellArg, also keeping the characters in extra, such as "=@:,"
+// for --flag=value arguments or email addresses. Shell metacharacters
+// (; | & $ ` < > ( ) \ " ' * ? [ ] { } ~ ! # ^) and control characters in
+// extra are ignored, so they are always dropped.
+func SanitizeShellArgAllowing(input, extra string) string {
+	return sanitizeShellArg(input, shellExtraAllowed(extra), nil)
+}
+
+// shellExtraAllowed returns the characters of extra that may be added to
+// the ShellArg allowlist.
+func shellExtraAllowed(extra string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(shellMetacharacters, r) || !unicode.IsPrint(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, extra)
+}
+
+// QuoteShellArg quotes input as a single POSIX shell word (CWE-78). The
+// value is wrapped in single quotes, inside which the shell gives no
+// character a special meaning. Each single quote in input closes the
+// quoting, adds an escaped quote and reopens it:
+//
+//	it's -> 'it'\''s'
+//
+// Unlike SanitizeShellArg, spaces, parentheses and any other characters
+// survive.
+//
+// No quoting can carry a NUL byte through exec, which ends the argument
+// there; the ShellArgQuoted context rejects input containing one. See
+// QuoteWindowsArg for Windows command lines.
+func QuoteShellArg(input string) string {
+	var b strings.Builder
+	b.Grow(len(input) + 2)
+	b.WriteByte('\'')
+	for {
+		i := strings.IndexByte(input, '\'')
+		if i < 0 {
+			break
+		}
+		b.WriteString(input[:i])
+		b.WriteString(`'\''`)
+		input = input[i+1:]
+	}
+	b.WriteString(input)
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// cmdMetacharacters are the characters cmd.exe interprets on a command
+// line. QuoteWindowsArg escapes the double quote too, so that cmd.exe never
+// enters a quoted region in which some of the others would be literal.
+const cmdMetacharacters = "()%!^\"<>&|"
+
+// QuoteWindowsArg quotes input as a single argument on a Windows command
+// line run through cmd.exe (CWE-78). The argument is first quoted as
+// CommandLineToArgvW and the Microsoft C runtime parse it: left alone if
+// it is non-empty with no whitespace or double quotes, and otherwise
+// wrapped in double quotes with each double quote escaped by a backslash
+// and the backslashes before it or before the closing quote doubled. Then
+// each cmd.exe metacharacter, ( ) % ! ^ " < > & and |, is escaped with a
+// caret.
+//
+// cmd.exe ends a command at CR or LF and no escaping carries a NUL byte;
+// the ShellArgWindows context rejects input containing them. Use
+// ShellArgWindows or QuoteWindowsArg whatever the GOOS of the process
+// preparing the command, since only the target system matters.
+func QuoteWindowsArg(input string) string {
+	argv := quoteWindowsArgv(input)
+	var b strings.Builder
+	b.Grow(len(argv) + 2)
+	for i := 0; i < len(argv); i++ {
+		if strings.IndexByte(cmdMetacharacters, argv[i]) >= 0 {
+			b.WriteByte('^')
+		}
+		b.WriteByte(argv[i])
+	}
+	return b.String()
+}
+
+// quoteWindowsArgv quotes input for CommandLineToArgvW.
+func quoteWindowsArgv(input string) string {
+	if input != "" && !strings.ContainsAny(input, " \t\n\v\"") {
+		return input
+	}
+	var b strings.Builder
+	b.Grow(len(input) + 2)
+	b.WriteByte('"')
+	for i := 0; ; i++ {
+		backslashes := 0
+		for ; i < len(input) && input[i] == '\\'; i++ {
+			backslashes++
+		}
+		if i == len(input) {
+			// Double them so the closing quote is not escaped
+			b.WriteString(strings.Repeat(`\`, 2*backslashes))
+			break
+		}
+		if input[i] == '"' {
+			b.WriteString(strings.Repeat(`\`, 2*backslashes+1))
+		} else {
+			b.WriteString(strings.Repeat(`\`, backslashes))
+		}
+		b.WriteByte(input[i])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// sanitizeWindowsArg implements the ShellArgWindows context, rejecting CR
+// and LF, which end a cmd.exe command.
+func sanitizeWindowsArg(input string) (string, error) {
+	if i := strings.IndexAny(input, "\r\n"); i >= 0 {
+		return "", &SanitizeError{Kind: ErrorKindInvalidChar, Offset: i, Text: input[i : i+1], Err: ErrUnquotableShellArg}
+	}
+	return QuoteWindowsArg(input), nil
+}
@@ -0,0 +1,41 @@
+//go:build integration
+
+package safeinput
+
+import (
+	"os/exec"
+	"testing"
+)
+
+// TestQuoteShellArg_RoundTrip passes quoted values through sh and checks
+// that the shell sees exactly the original value.
+func TestQuoteShellArg_RoundTrip(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+	inputs := []string{
+		"",
+		"my file (final).pdf",
+		"it's",
+		"''''",
+		`"double" \back\slash\`,
+		"$(id) `id` ${HOME} $HOME",
+		"a; rm -rf / && b || c | d & e",
+		"< > >> 2>&1",
+		"*.go ? [a-z] ~",
+		"line\nbreak\ttab",
+		"-n",
+		"ünïcödé ✓",
+	}
+	for _, input := range inputs {
+		out, err := exec.Command(sh, "-c", "printf %s "+QuoteShellArg(input)).Output()
+		if err != nil {
+			t.Errorf("sh failed for %q: %v", input, err)
+			continue
+		}
+		if string(out) != input {
+			t.Errorf("sh printed %q, want %q", out, input)
+		}
+	}
+}
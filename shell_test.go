@@ -0,0 +1,197 @@
+package safeinput
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestQuoteShellArg(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", "''"},
+		{"file.txt", "'file.txt'"},
+		{"my file (final).pdf", "'my file (final).pdf'"},
+		{"it's", `'it'\''s'`},
+		{"'", `''\'''`},
+		{"$(rm -rf /); `id` | x > y", "'$(rm -rf /); `id` | x > y'"},
+		{"a\nb", "'a\nb'"},
+	}
+	for _, tt := range tests {
+		if got := QuoteShellArg(tt.input); got != tt.want {
+			t.Errorf("QuoteShellArg(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSanitize_ShellArgQuoted(t *testing.T) {
+	s := Default()
+	got, err := s.Sanitize("my file (final).pdf", ShellArgQuoted)
+	if err != nil || got != "'my file (final).pdf'" {
+		t.Errorf("Sanitize = %q, %v", got, err)
+	}
+
+	// Null bytes are rejected even though Default strips them elsewhere
+	if _, err := s.Sanitize("a\x00b", ShellArgQuoted); !errors.Is(err, ErrNullByte) {
+		t.Errorf("expected ErrNullByte, got %v", err)
+	}
+	res, err := s.SanitizeDetailed("a\x00b", ShellArgQuoted)
+	if !errors.Is(err, ErrNullByte) || len(res.Findings) != 1 || res.Findings[0].Offset != 1 {
+		t.Errorf("SanitizeDetailed = %+v, %v", res, err)
+	}
+	if got, err := s.Sanitize("a\x00b", ShellArg); err != nil || got != "ab" {
+		t.Errorf("ShellArg = %q, %v", got, err)
+	}
+}
+
+func TestQuoteWindowsArg(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", `^"^"`},
+		{"file.txt", "file.txt"},
+		{`C:/Users/me/report.pdf`, `C:/Users/me/report.pdf`},
+		{`C:\path\`, `C:\path\`},
+		{`C:\Program Files\`, `^"C:\Program Files\\^"`},
+		{`C:\Program Files\\`, `^"C:\Program Files\\\\^"`},
+		{`a\"b`, `^"a\\\^"b^"`},
+		{`a\\"b`, `^"a\\\\\^"b^"`},
+		{`say "hi"`, `^"say \^"hi\^"^"`},
+		{`\\server\share\dir name\`, `^"\\server\share\dir name\\^"`},
+		{"a&b|c", "a^&b^|c"},
+		{"50% <in> (x)!^", `^"50^% ^<in^> ^(x^)^!^^^"`},
+	}
+	for _, tt := range tests {
+		if got := QuoteWindowsArg(tt.input); got != tt.want {
+			t.Errorf("QuoteWindowsArg(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+		if got := parseWindowsArgs(uncaret(QuoteWindowsArg(tt.input))); len(got) != 1 || got[0] != tt.input {
+			t.Errorf("QuoteWindowsArg(%q) parses as %q", tt.input, got)
+		}
+	}
+}
+
+func TestSanitize_ShellArgWindows(t *testing.T) {
+	s := Default()
+	got, err := s.Sanitize(`C:\Program Files\App\`, ShellArgWindows)
+	if err != nil || got != `^"C:\Program Files\App\\^"` {
+		t.Errorf("Sanitize = %s, %v", got, err)
+	}
+	for _, input := range []string{"a\x00b", "a\r\nb"} {
+		if _, err := s.Sanitize(input, ShellArgWindows); err == nil {
+			t.Errorf("Sanitize(%q) succeeded", input)
+		}
+	}
+	_, err = s.Sanitize("echo\nwhoami", ShellArgWindows)
+	var se *SanitizeError
+	if !errors.As(err, &se) || !errors.Is(err, ErrUnquotableShellArg) || se.Offset != 4 {
+		t.Errorf("got %v", err)
+	}
+}
+
+// uncaret removes the caret escapes cmd.exe removes from a command line
+// with no quoted regions
+func uncaret(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '^' && i+1 < len(s) {
+			i++
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
+
+// parseWindowsArgs splits a command line as the Microsoft C runtime does
+func parseWindowsArgs(s string) []string {
+	var args []string
+	var arg []byte
+	inArg, quoted := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			n := 0
+			for ; i < len(s) && s[i] == '\\'; i++ {
+				n++
+			}
+			if i < len(s) && s[i] == '"' {
+				arg = append(arg, []byte(strings.Repeat(`\`, n/2))...)
+				if n%2 == 1 {
+					arg = append(arg, '"')
+				} else {
+					quoted = !quoted
+				}
+			} else {
+				arg = append(arg, []byte(strings.Repeat(`\`, n))...)
+				i--
+			}
+			inArg = true
+		case c == '"':
+			quoted, inArg = !quoted, true
+		case (c == ' ' || c == '\t') && !quoted:
+			if inArg {
+				args = append(args, string(arg))
+				arg, inArg = nil, false
+			}
+		default:
+			arg, inArg = append(arg, c), true
+		}
+	}
+	if inArg {
+		args = append(args, string(arg))
+	}
+	return args
+}
+
+func TestSanitizeShellArgAllowing(t *testing.T) {
+	tests := []struct {
+		input string
+		extra string
+		want  string
+	}{
+		{"--output=out.txt", "=", "--output=out.txt"},
+		{"user@example.com", "@", "user@example.com"},
+		{"C:/Users/me", ":", "C:/Users/me"},
+		{"a,b c", ",", "a,bc"},
+		{"a,b c", ", ", "a,b c"},
+		{"--flag=value", "", "--flagvalue"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeShellArgAllowing(tt.input, tt.extra); got != tt.want {
+			t.Errorf("SanitizeShellArgAllowing(%q, %q) = %q, want %q", tt.input, tt.extra, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeShellArgAllowing_Metacharacters(t *testing.T) {
+	dangerous := ";|&$`<>()\\\"'*?[]{}~!#^\n\r\t\x00\u200b"
+	input := "a" + dangerous + "b"
+	if got := SanitizeShellArgAllowing(input, dangerous); got != "ab" {
+		t.Errorf("metacharacters were allowed back: %q", got)
+	}
+
+	s := New(Config{StripNullBytes: true, ShellExtraAllowed: "=" + dangerous})
+	if got := s.MustSanitize("x=1;"+dangerous, ShellArg); got != "x=1" {
+		t.Errorf("Sanitize = %q", got)
+	}
+	if err := s.Validate("x=1", ShellArg); err != nil {
+		t.Errorf("Validate(x=1) = %v", err)
+	}
+	if err := s.Validate("x=1;", ShellArg); !errors.Is(err, ErrUnsafeInput) {
+		t.Errorf("Validate(x=1;) = %v, want ErrUnsafeInput", err)
+	}
+}
+
+func TestSanitize_ShellAllowSpace(t *testing.T) {
+	if got := Default().MustSanitize("my file.txt", ShellArg); got != "myfile.txt" {
+		t.Errorf("default allowlist kept a space: %q", got)
+	}
+	s := New(Config{ShellAllowSpace: true, ShellExtraAllowed: "@"})
+	if got := s.MustSanitize("mail me@x.org; rm", ShellArg); got != "mail me@x.org rm" {
+		t.Errorf("Sanitize = %q", got)
+	}
+}
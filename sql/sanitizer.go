@@ -5,6 +5,7 @@ import (
 	"errors"
 	"regexp"
 	"strings"
+	"sync/atomic"
 )
 
 // Errors returned by the SQL sanitizer.
@@ -40,26 +41,63 @@ var dangerousPatterns = []*regexp.Regexp{
 
 var validIdentifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
 
-// Sanitizer provides SQL sanitization.
+// Sanitizer provides SQL sanitization. It is safe for concurrent use.
+// Configure it with options to New, or derive a differently configured
+// copy with WithStrictMode or WithMaxIdentifierLength.
 type Sanitizer struct {
-	maxLen int
-	strict bool
+	// maxLen and strict are atomic only so that the deprecated setters
+	// do not race with sanitization
+	maxLen atomic.Int64
+	strict atomic.Bool
+}
+
+// Option configures a Sanitizer created by New.
+type Option func(*Sanitizer)
+
+// WithStrictMode sets whether SanitizeIdentifier rejects reserved words.
+// It is on by default.
+func WithStrictMode(strict bool) Option {
+	return func(s *Sanitizer) { s.strict.Store(strict) }
+}
+
+// WithMaxIdentifierLength sets the longest identifier SanitizeIdentifier
+// accepts, 128 bytes by default.
+func WithMaxIdentifierLength(n int) Option {
+	return func(s *Sanitizer) { s.maxLen.Store(int64(n)) }
 }
 
 // New creates a SQL Sanitizer.
-func New() *Sanitizer {
-	return &Sanitizer{maxLen: 128, strict: true}
+func New(opts ...Option) *Sanitizer {
+	s := &Sanitizer{}
+	s.maxLen.Store(128)
+	s.strict.Store(true)
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WithStrictMode returns a copy of s with strict mode set, leaving s
+// unchanged.
+func (s *Sanitizer) WithStrictMode(strict bool) *Sanitizer {
+	return New(WithMaxIdentifierLength(s.MaxIdentifierLength()), WithStrictMode(strict))
+}
+
+// WithMaxIdentifierLength returns a copy of s with the maximum identifier
+// length set, leaving s unchanged.
+func (s *Sanitizer) WithMaxIdentifierLength(n int) *Sanitizer {
+	return New(WithMaxIdentifierLength(n), WithStrictMode(s.StrictMode()))
 }
 
 // SanitizeIdentifier validates a SQL identifier.
 func (s *Sanitizer) SanitizeIdentifier(input string) (string, error) {
-	if len(input) > s.maxLen {
+	if int64(len(input)) > s.maxLen.Load() {
 		return "", ErrIdentifierTooLong
 	}
 	if len(input) == 0 || !validIdentifier.MatchString(input) {
 		return "", ErrInvalidIdentifier
 	}
-	if s.strict && reservedWords[strings.ToLower(input)] {
+	if s.strict.Load() && reservedWords[strings.ToLower(input)] {
 		return "", ErrReservedWord
 	}
 	return input, nil
@@ -67,12 +105,22 @@ func (s *Sanitizer) SanitizeIdentifier(input string) (string, error) {
 
 // ValidateValue checks for suspicious SQL patterns.
 func (s *Sanitizer) ValidateValue(input string) (string, error) {
+	if _, _, offset := FindSuspiciousPattern(input); offset >= 0 {
+		return "", ErrSuspiciousPattern
+	}
+	return input, nil
+}
+
+// FindSuspiciousPattern returns the first suspicious SQL pattern that
+// matches input, the matched text and its byte offset. The offset is -1
+// if no pattern matches.
+func FindSuspiciousPattern(input string) (pattern, match string, offset int) {
 	for _, p := range dangerousPatterns {
-		if p.MatchString(input) {
-			return "", ErrSuspiciousPattern
+		if loc := p.FindStringIndex(input); loc != nil {
+			return p.String(), input[loc[0]:loc[1]], loc[0]
 		}
 	}
-	return input, nil
+	return "", "", -1
 }
 
 // QuoteStyle represents SQL quoting styles.
@@ -110,16 +158,24 @@ func (s *Sanitizer) QuoteIdentifier(input string, style QuoteStyle) (string, err
 }
 
 // SetStrictMode enables/disables strict mode.
-func (s *Sanitizer) SetStrictMode(strict bool) { s.strict = strict }
+//
+// Deprecated: Changing a shared Sanitizer changes it under the calls
+// already using it. Pass WithStrictMode to New, or use the
+// WithStrictMode method to derive a new Sanitizer.
+func (s *Sanitizer) SetStrictMode(strict bool) { s.strict.Store(strict) }
 
 // SetMaxIdentifierLength sets max identifier length.
-func (s *Sanitizer) SetMaxIdentifierLength(n int) { s.maxLen = n }
+//
+// Deprecated: Changing a shared Sanitizer changes it under the calls
+// already using it. Pass WithMaxIdentifierLength to New, or use the
+// WithMaxIdentifierLength method to derive a new Sanitizer.
+func (s *Sanitizer) SetMaxIdentifierLength(n int) { s.maxLen.Store(int64(n)) }
 
 // StrictMode returns strict mode status.
-func (s *Sanitizer) StrictMode() bool { return s.strict }
+func (s *Sanitizer) StrictMode() bool { return s.strict.Load() }
 
 // MaxIdentifierLength returns max length.
-func (s *Sanitizer) MaxIdentifierLength() int { return s.maxLen }
+func (s *Sanitizer) MaxIdentifierLength() int { return int(s.maxLen.Load()) }
 
 // IsReservedWord checks if word is reserved.
 func IsReservedWord(word string) bool { return reservedWords[strings.ToLower(word)] }
@@ -85,6 +85,16 @@ func TestValidateValue(t *testing.T) {
 	}
 }
 
+func TestFindSuspiciousPattern(t *testing.T) {
+	pattern, match, offset := FindSuspiciousPattern("name; DROP TABLE users")
+	if pattern != `(?i);\s*(drop|delete|truncate|alter|exec|insert|update|select)` || match != "; DROP" || offset != 4 {
+		t.Errorf("FindSuspiciousPattern = %q, %q, %d", pattern, match, offset)
+	}
+	if pattern, match, offset := FindSuspiciousPattern("normal text"); pattern != "" || match != "" || offset != -1 {
+		t.Errorf("FindSuspiciousPattern(normal text) = %q, %q, %d", pattern, match, offset)
+	}
+}
+
 func TestQuoteIdentifier(t *testing.T) {
 	s := New()
 	tests := []struct {
@@ -125,6 +135,50 @@ func TestStrictMode(t *testing.T) {
 	}
 }
 
+func TestOptions(t *testing.T) {
+	s := New(WithStrictMode(false), WithMaxIdentifierLength(6))
+	if s.StrictMode() || s.MaxIdentifierLength() != 6 {
+		t.Fatalf("options not applied: strict %v, max %d", s.StrictMode(), s.MaxIdentifierLength())
+	}
+	if _, err := s.SanitizeIdentifier("select"); err != nil {
+		t.Errorf("Should allow reserved when strict=false: %v", err)
+	}
+	if _, err := s.SanitizeIdentifier("columns"); err != ErrIdentifierTooLong {
+		t.Errorf("expected ErrIdentifierTooLong, got %v", err)
+	}
+
+	strict := s.WithStrictMode(true)
+	if s.StrictMode() || !strict.StrictMode() || strict.MaxIdentifierLength() != 6 {
+		t.Error("WithStrictMode should copy s with strict mode set")
+	}
+	longer := s.WithMaxIdentifierLength(64)
+	if s.MaxIdentifierLength() != 6 || longer.MaxIdentifierLength() != 64 || longer.StrictMode() {
+		t.Error("WithMaxIdentifierLength should copy s with the length set")
+	}
+}
+
+// TestConcurrentConfiguration fails under -race if changing a Sanitizer
+// races with calls using it.
+func TestConcurrentConfiguration(t *testing.T) {
+	s := New()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			s.SetStrictMode(i%2 == 0)
+			s.SetMaxIdentifierLength(64 + i%2)
+			_ = s.WithStrictMode(i%2 == 1)
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		if _, err := s.SanitizeIdentifier("users"); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		_, _ = s.QuoteIdentifier("select", QuoteStyleStandard)
+	}
+	<-done
+}
+
 func TestIsReservedWord(t *testing.T) {
 	tests := []struct {
 		word string
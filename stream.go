@@ -0,0 +1,328 @@
+package safeinput
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// streamChunkSize is how much SanitizeCopy reads at a time.
+const streamChunkSize = 32 << 10
+
+// SanitizeCopy sanitizes the text read from src for ctx and writes it to
+// dst, returning the number of bytes written. It works through src in
+// chunks rather than reading it into one string, for HTMLBody,
+// HTMLAttribute and LogLine; other contexts need all of their input at once
+// and fail with ErrStreamingUnsupported.
+//
+// HTMLBody feeds the HTML tokenizer as it reads, writing each token once it
+// is complete. The other contexts split chunks only where their processing
+// cannot span the split: for LogLine outside ANSI escape sequences, and
+// never inside a UTF-8 sequence. Text that cannot be split yet is held
+// until it can, so an unterminated tag, <script> element or escape
+// sequence is held to the end of src.
+//
+// The shared checks apply as in Sanitize. The length limit for ctx, which
+// MaxLengths can raise for large documents, counts all the text read, so
+// a stream over the limit fails with ErrInputTooLong after the output for
+// the text before it has been written. Likewise a rejected character fails
+// with a *SanitizeError whose offset is into the whole stream. Once LogLine
+// output reaches MaxLogLength, the rest of src is still read and checked.
+func (s *Sanitizer) SanitizeCopy(dst io.Writer, src io.Reader, ctx Context) (int64, error) {
+	var cut func(string) int
+	switch ctx {
+	case HTMLBody:
+	case HTMLAttribute:
+		cut = func(p string) int { return len(p) }
+	case LogLine:
+		cut = logLineCut
+	default:
+		return 0, ErrStreamingUnsupported
+	}
+	if s.config.NormalizeUnicode {
+		src = norm.NFC.Reader(src)
+	}
+
+	st := &sanitizeStream{s: s, ctx: ctx, w: dst, length: lengthCounter{unit: s.config.LengthUnit}}
+	in := &checkedReader{st: st, src: src, buf: make([]byte, streamChunkSize)}
+	if ctx == HTMLBody {
+		err := s.html.CopyBody(writerFunc(st.writeHTML), in)
+		return st.written, err
+	}
+
+	var clean string
+	for {
+		checked, err := in.next()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return st.written, err
+		}
+		final := err != nil
+
+		// Hand the context what it can process without the text still to
+		// come. Once LogLine output is cut short the rest of src is only
+		// checked, so it is rejected as Sanitize would reject it.
+		if !st.done {
+			clean += checked
+			end := len(clean)
+			if !final {
+				end = cut(clean)
+			}
+			if err := st.write(clean[:end]); err != nil {
+				return st.written, err
+			}
+			clean = clean[end:]
+		}
+		if final {
+			return st.written, nil
+		}
+	}
+}
+
+// checkedReader reads src for SanitizeCopy, applying the length limit and
+// the shared checks to whole runes.
+type checkedReader struct {
+	st  *sanitizeStream
+	src io.Reader
+	buf []byte
+
+	// raw holds an incomplete rune from the last read, out checked text
+	// not yet read, and err the error to return once out is
+	raw []byte
+	out string
+	err error
+}
+
+// next returns the checked text of the next read from src, with io.EOF
+// for the last of it, or the error from reading or checking it.
+func (r *checkedReader) next() (string, error) {
+	if r.err != nil {
+		return "", r.err
+	}
+	n, err := r.src.Read(r.buf)
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.err = err
+		return "", err
+	}
+	final := err != nil
+
+	r.raw = append(r.raw, r.buf[:n]...)
+	whole := len(r.raw)
+	if !final {
+		whole = completeRunes(r.raw)
+	}
+	chunk := string(r.raw[:whole])
+	if err := r.st.count(chunk); err != nil {
+		r.err = err
+		return "", err
+	}
+	checked, err := r.st.s.checkCharacters(chunk, r.st.ctx)
+	if err != nil {
+		r.err = r.st.error(chunk, err)
+		return "", r.err
+	}
+	r.st.checked += int64(whole)
+	r.raw = append(r.raw[:0], r.raw[whole:]...)
+	if final {
+		r.err = io.EOF
+	}
+	return checked, r.err
+}
+
+// Read reads checked text, so that the HTML tokenizer can read it as it
+// reads src.
+func (r *checkedReader) Read(p []byte) (int, error) {
+	for r.out == "" {
+		if r.err != nil {
+			return 0, r.err
+		}
+		checked, err := r.next()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		r.out = checked
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// writerFunc adapts a function to io.Writer.
+type writerFunc func([]byte) (int, error)
+
+// Write calls f(p).
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+// sanitizeStream holds the state of a SanitizeCopy call.
+type sanitizeStream struct {
+	s   *Sanitizer
+	ctx Context
+	w   io.Writer
+
+	// length measures the text read, and checked counts the bytes the
+	// shared checks have passed, after normalization
+	length  lengthCounter
+	checked int64
+	written int64
+
+	// started is set once HTMLBody has written text other than
+	// whitespace, and space holds whitespace that may turn out to be
+	// trailing
+	started bool
+	space   string
+
+	// logged counts the LogLine output against MaxLogLength, and done is
+	// set once it has been cut short
+	logged int
+	done   bool
+}
+
+// count adds chunk to the length of the stream, returning ErrInputTooLong
+// once it exceeds the limit.
+func (st *sanitizeStream) count(chunk string) error {
+	st.length.add(chunk)
+	limit, unit := st.s.maxLength(st.ctx), st.length.unit
+	bytes := st.checked + int64(len(chunk))
+	if maxBytes := st.s.maxBytes(st.ctx); bytes > int64(maxBytes) {
+		return st.tooLong(int(bytes), maxBytes, Bytes)
+	}
+	if st.length.n > limit {
+		return st.tooLong(st.length.n, limit, unit)
+	}
+	return nil
+}
+
+// tooLong returns ErrInputTooLong for the stream as a *SanitizeError.
+func (st *sanitizeStream) tooLong(length, limit int, unit LengthUnit) error {
+	err := st.s.tooLong(st.ctx, length, limit, unit)
+	return &SanitizeError{Context: st.ctx, Kind: ErrorKindTooLong, Offset: -1, Err: err, Severity: SeverityInfo}
+}
+
+// error returns err from the shared checks on chunk as a *SanitizeError
+// with its offset into the whole stream.
+func (st *sanitizeStream) error(chunk string, err error) error {
+	err = st.s.sanitizeError(chunk, st.ctx, err)
+	var se *SanitizeError
+	if errors.As(err, &se) && se.Offset >= 0 {
+		se.Offset += int(st.checked)
+	}
+	return err
+}
+
+// writeHTML writes sanitized HTML, trimmed as SanitizeBody trims it.
+func (st *sanitizeStream) writeHTML(p []byte) (int, error) {
+	n, err := io.WriteString(st.w, st.trimSpace(string(p)))
+	st.written += int64(n)
+	return len(p), err
+}
+
+// write sanitizes a chunk that can be processed on its own and writes it.
+func (st *sanitizeStream) write(chunk string) error {
+	var out string
+	switch st.ctx {
+	case HTMLAttribute:
+		out = st.s.html.SanitizeAttribute(chunk)
+	case LogLine:
+		out = st.limitLog(chunk)
+	}
+	n, err := io.WriteString(st.w, out)
+	st.written += int64(n)
+	return err
+}
+
+// trimSpace drops the whitespace that SanitizeBody trims from the whole
+// document: everything before the first other text, and whitespace after
+// the last, which is held back until more text follows.
+func (st *sanitizeStream) trimSpace(out string) string {
+	if !st.started {
+		out = strings.TrimLeftFunc(out, unicode.IsSpace)
+		if out == "" {
+			return ""
+		}
+		st.started = true
+	}
+	trimmed := strings.TrimRightFunc(out, unicode.IsSpace)
+	if trimmed == "" {
+		st.space += out
+		return ""
+	}
+	out, st.space = st.space+trimmed, out[len(trimmed):]
+	return out
+}
+
+// limitLog sanitizes chunk for LogLine, cutting the output short as
+// sanitizeLogLine does once the stream's output reaches MaxLogLength.
+func (st *sanitizeStream) limitLog(chunk string) string {
+	maxLength := st.s.config.MaxLogLength
+	if maxLength <= 0 {
+		return sanitizeLogLine(chunk, 0)
+	}
+	remaining := maxLength - st.logged
+	if remaining <= 0 {
+		if sanitizeLogLine(chunk, 0) == "" {
+			return ""
+		}
+		st.done = true
+		return "..."
+	}
+	out := sanitizeLogLine(chunk, 0)
+	if len(out) > remaining {
+		out = sanitizeLogLine(chunk, remaining)
+		st.done = true
+	}
+	st.logged += len(out)
+	return out
+}
+
+// completeRunes returns the length of p without an incomplete UTF-8
+// sequence at its end.
+func completeRunes(p []byte) int {
+	for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(p[i]) {
+			if !utf8.FullRune(p[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(p)
+}
+
+// logLineCut returns the length of the longest prefix of p that LogLine
+// can sanitize on its own: p up to an ANSI escape sequence that may
+// continue past its end. A CSI sequence broken off by a byte that cannot
+// be part of it loses only its introducer, so p is not cut again before
+// that byte, where the sequence would look unterminated.
+func logLineCut(p string) int {
+	cut, settled := 0, 0
+	for i := 0; i < len(p); i++ {
+		if i >= settled && utf8.RuneStart(p[i]) {
+			cut = i
+		}
+		if p[i] != '\x1b' && !strings.HasPrefix(p[i:], "\u009b") {
+			continue
+		}
+		n := ansiSequenceLength(p[i:])
+		if i+n == len(p) || (n == 0 && i == len(p)-1) {
+			return cut
+		}
+		if n == 2 && (p[i] != '\x1b' || p[i+1] == '[') {
+			// A malformed CSI sequence: it ended where the first byte
+			// outside its parameters is
+			end := i + 2
+			for end < len(p) && p[end] >= 0x20 && p[end] <= 0x7e {
+				end++
+			}
+			settled = end + 1
+		}
+		if n > 0 {
+			i += n - 1
+		}
+	}
+	return len(p)
+}
@@ -0,0 +1,151 @@
+package safeinput
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"testing/quick"
+)
+
+// streamDocuments have markup, escape sequences and multi-byte characters
+// that a chunked reader splits at every possible position
+var streamDocuments = []string{
+	"",
+	"   \n  ",
+	"  plain text  \n",
+	"<p>Hello <b>world</b></p>\n<script>alert('x')</script>after",
+	"<div onclick=\"steal()\">click</div> <img src=x onerror=alert(1)>",
+	"<SCRIPT>\nvar a = '<b>';\n</SCRIPT>\ncafé ✓ <style>p{}</style>  ",
+	"<a href='x'>unterminated <script> content",
+	"Tom & Jerry's \"quote\" <tag>",
+	"user logged in\n\x1b[31mred\x1b[0m\r\n\x1b]0;title\a done\tend",
+	"\u009b2Jcleared \x1b[1;31",
+	"bad \xff\xfe utf8 ünïcödé ",
+	"a\x00b<c>\x00",
+}
+
+func TestSanitizeCopy_MatchesSanitize(t *testing.T) {
+	sanitizers := map[string]*Sanitizer{
+		"default": Default(),
+		"tags":    New(Config{AllowedHTMLTags: []string{"p", "b", "a"}, StripNullBytes: true}),
+		"log":     New(Config{MaxLogLength: 20, StripNullBytes: true}),
+	}
+	for name, s := range sanitizers {
+		for _, ctx := range []Context{HTMLBody, HTMLAttribute, LogLine} {
+			for _, doc := range streamDocuments {
+				want, wantErr := s.Sanitize(doc, ctx)
+				for _, oneByte := range []bool{false, true} {
+					var r = strings.NewReader(doc)
+					var src = iotest.DataErrReader(r)
+					if oneByte {
+						src = iotest.OneByteReader(r)
+					}
+					var out bytes.Buffer
+					n, err := s.SanitizeCopy(&out, src, ctx)
+					if wantErr != nil {
+						if err == nil {
+							t.Errorf("%s %v %q: expected %v", name, ctx, doc, wantErr)
+						}
+						continue
+					}
+					if err != nil || out.String() != want || n != int64(out.Len()) {
+						t.Errorf("%s %v %q (one byte %v): SanitizeCopy = %q, %d, %v, want %q", name, ctx, doc, oneByte, out.String(), n, err, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+// logStreamInput generates text made of escape sequence pieces, controls
+// and runes that LogLine escapes or a configuration rejects
+type logStreamInput string
+
+func (logStreamInput) Generate(r *rand.Rand, size int) reflect.Value {
+	pieces := []string{
+		"\x1b", "[", "]", "\u009b", "3", ";", "m", "\a", "\\", ">", "-",
+		"a", "\n", "\x00", "\u2028", "\u202e", "\u200b", "é", "\xff",
+	}
+	var b strings.Builder
+	for i := r.Intn(size + 1); i > 0; i-- {
+		b.WriteString(pieces[r.Intn(len(pieces))])
+	}
+	return reflect.ValueOf(logStreamInput(b.String()))
+}
+
+func TestSanitizeCopy_LogLineMatchesSanitize(t *testing.T) {
+	sanitizers := map[string]*Sanitizer{
+		"default": Default(),
+		"plain":   New(Config{}),
+		"limited": New(Config{MaxLogLength: 8, RejectBidiControls: true}),
+	}
+	for name, s := range sanitizers {
+		matches := func(input logStreamInput) bool {
+			want, wantErr := s.Sanitize(string(input), LogLine)
+			var out bytes.Buffer
+			_, err := s.SanitizeCopy(&out, iotest.OneByteReader(strings.NewReader(string(input))), LogLine)
+			if wantErr != nil || err != nil {
+				return (wantErr == nil) == (err == nil)
+			}
+			return out.String() == want
+		}
+		if err := quick.Check(matches, &quick.Config{MaxCount: 2000}); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	for _, input := range []string{"><-->\u009b3\u009b", "\x1b[3\x1b[", "\x1b[3\x1b[m"} {
+		want, _ := Default().Sanitize(input, LogLine)
+		var out bytes.Buffer
+		if _, err := Default().SanitizeCopy(&out, iotest.OneByteReader(strings.NewReader(input)), LogLine); err != nil || out.String() != want {
+			t.Errorf("SanitizeCopy(%q) = %q, %v, want %q", input, out.String(), err, want)
+		}
+	}
+}
+
+func TestSanitizeCopy_LargeDocument(t *testing.T) {
+	part := "<p onclick='x()'>paragraph with <b>bold</b> and <script>evil()</script> text</p>\n"
+	doc := strings.Repeat(part, 2*streamChunkSize/len(part)+1)
+	s := New(Config{MaxLengths: map[Context]int{HTMLBody: 1 << 20}})
+	want, err := s.Sanitize(doc, HTMLBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if _, err := s.SanitizeCopy(&out, strings.NewReader(doc), HTMLBody); err != nil || out.String() != want {
+		t.Errorf("SanitizeCopy output differs from Sanitize: %v", err)
+	}
+}
+
+func TestSanitizeCopy_Errors(t *testing.T) {
+	s := Default()
+	for _, ctx := range []Context{FilePath, SQLIdentifier, ShellArg} {
+		if _, err := s.SanitizeCopy(&bytes.Buffer{}, strings.NewReader("x"), ctx); !errors.Is(err, ErrStreamingUnsupported) {
+			t.Errorf("%v: expected ErrStreamingUnsupported, got %v", ctx, err)
+		}
+	}
+
+	s = New(Config{MaxLengths: map[Context]int{LogLine: 100}})
+	var out bytes.Buffer
+	_, err := s.SanitizeCopy(&out, iotest.OneByteReader(strings.NewReader(strings.Repeat("a", 150))), LogLine)
+	if !errors.Is(err, ErrInputTooLong) || out.Len() > 100 {
+		t.Errorf("expected ErrInputTooLong after at most 100 bytes, got %v with %d bytes", err, out.Len())
+	}
+
+	s = New(Config{MaxLengths: map[Context]int{HTMLAttribute: 1 << 20}})
+	doc := strings.Repeat("x", 3*streamChunkSize/2) + "\x00"
+	_, err = s.SanitizeCopy(&bytes.Buffer{}, strings.NewReader(doc), HTMLAttribute)
+	var se *SanitizeError
+	if !errors.As(err, &se) || !errors.Is(err, ErrNullByte) || se.Offset != len(doc)-1 {
+		t.Errorf("expected ErrNullByte at offset %d, got %v", len(doc)-1, err)
+	}
+
+	readErr := errors.New("read failed")
+	if _, err := s.SanitizeCopy(&bytes.Buffer{}, iotest.ErrReader(readErr), HTMLBody); !errors.Is(err, readErr) {
+		t.Errorf("expected the read error, got %v", err)
+	}
+}
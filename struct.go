@@ -0,0 +1,207 @@
+package safeinput
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// tagContexts maps the short values of the sanitize struct tag to contexts.
+// Tags are resolved with ParseContext, so the context names it accepts,
+// such as "filepath" or "SQLValue", work as tags too.
+var tagContexts = map[string]Context{
+	"html":           HTMLBody,
+	"html_attr":      HTMLAttribute,
+	"sql_identifier": SQLIdentifier,
+	"sql_value":      SQLValue,
+	"path":           FilePath,
+	"url_path":       URLPath,
+	"url_query":      URLQuery,
+	"shell":          ShellArg,
+	"ldap_filter":    LDAPFilter,
+	"ldap_dn":        LDAPDN,
+	"header":         HTTPHeader,
+	"log":            LogLine,
+	"json_string":    JSONString,
+	"url":            URL,
+	"regex_literal":  RegexLiteral,
+	"regex":          RegexPattern,
+	"csv":            CSVField,
+	"xml":            XMLText,
+	"xml_attr":       XMLAttr,
+	"hostname":       Hostname,
+	"ip":             IPAddress,
+	"uuid":           UUID,
+	"cookie_name":    CookieName,
+	"cookie":         CookieValue,
+	"template":       TemplateLiteral,
+	"filename":       Filename,
+	"shell_quoted":   ShellArgQuoted,
+	"shell_windows":  ShellArgWindows,
+}
+
+// StringField describes a string reached by WalkStrings.
+type StringField struct {
+	// Path locates the string below the walked value as a Go path such as
+	// ".Name" or ".Comments[].Tags[]", where [] marks a slice, array or map
+	// element.
+	Path string
+
+	// Field is the innermost struct field holding the string, or the zero
+	// StructField if the string is not inside a struct.
+	Field reflect.StructField
+
+	// Context comes from the nearest enclosing sanitize tag. Tagged is
+	// false, and Context meaningless, if there is none.
+	Context Context
+	Tagged  bool
+}
+
+// WalkStrings calls fn for every settable string reachable from v, which
+// must be a non-nil pointer, and stores the string fn returns in its place.
+// It descends into pointers, interfaces, slices, arrays, map values and the
+// exported fields of structs, visiting each pointer once. A sanitize tag
+// naming a context applies to every string below its field unless a deeper
+// tag overrides it; strings below a field tagged sanitize:"-" are skipped.
+// An unknown tag value fails the walk with a *FieldError wrapping
+// ErrUnknownContext, as does any error fn returns.
+func WalkStrings(v any, fn func(f StringField, value string) (string, error)) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return ErrInvalidTarget
+	}
+	w := &stringWalker{fn: fn, visited: make(map[uintptr]bool)}
+	return w.value(rv, StringField{}, false)
+}
+
+// stringWalker holds the state of one WalkStrings call.
+type stringWalker struct {
+	fn      func(StringField, string) (string, error)
+	visited map[uintptr]bool
+}
+
+// value walks v, which was reached via f; skip is set below a field tagged
+// sanitize:"-".
+func (w *stringWalker) value(v reflect.Value, f StringField, skip bool) error {
+	switch v.Kind() {
+	case reflect.String:
+		if skip || !v.CanSet() {
+			return nil
+		}
+		s, err := w.fn(f, v.String())
+		if err != nil {
+			return err
+		}
+		if s != v.String() {
+			v.SetString(s)
+		}
+	case reflect.Pointer:
+		if v.IsNil() || w.visited[v.Pointer()] {
+			return nil
+		}
+		w.visited[v.Pointer()] = true
+		return w.value(v.Elem(), f, skip)
+	case reflect.Interface:
+		if v.IsNil() || !v.CanSet() {
+			return nil
+		}
+		// The dynamic value is not addressable, so walk a copy
+		inner := reflect.New(v.Elem().Type()).Elem()
+		inner.Set(v.Elem())
+		if err := w.value(inner, f, skip); err != nil {
+			return err
+		}
+		v.Set(inner)
+	case reflect.Slice, reflect.Array:
+		elem := f
+		elem.Path += "[]"
+		for i := 0; i < v.Len(); i++ {
+			if err := w.value(v.Index(i), elem, skip); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		elem := f
+		elem.Path += "[]"
+		iter := v.MapRange()
+		for iter.Next() {
+			// Map values are not addressable, so walk a copy
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(iter.Value())
+			if err := w.value(value, elem, skip); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), value)
+		}
+	case reflect.Struct:
+		return w.structFields(v, f, skip)
+	}
+	return nil
+}
+
+// structFields walks the exported fields of struct v, applying their
+// sanitize tags.
+func (w *stringWalker) structFields(v reflect.Value, f StringField, skip bool) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		inner := StringField{Path: f.Path + "." + field.Name, Field: field, Context: f.Context, Tagged: f.Tagged}
+		fieldSkip := skip
+		if tag, ok := field.Tag.Lookup("sanitize"); ok {
+			if tag == "-" {
+				fieldSkip = true
+			} else if ctx, err := ParseContext(tag); err == nil {
+				inner.Context, inner.Tagged, fieldSkip = ctx, true, false
+			} else {
+				return &FieldError{Key: inner.Path, Err: err}
+			}
+		}
+
+		if err := w.value(v.Field(i), inner, fieldSkip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SanitizeStruct sanitizes in place every string field of the struct v
+// points to that carries a sanitize tag, such as sanitize:"html" or
+// sanitize:"path", including the strings in tagged slices, maps, pointers
+// and nested structs. Untagged fields are left alone, though tags deeper
+// inside them apply.
+//
+// Fields whose value the context rejects are left unchanged, and the
+// returned error joins a *FieldError naming each one by its path, such as
+// "Address.City". An unknown tag value fails immediately, and v must be a
+// non-nil pointer to a struct or SanitizeStruct returns ErrInvalidTarget.
+func (s *Sanitizer) SanitizeStruct(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrInvalidTarget
+	}
+
+	var errs []error
+	err := WalkStrings(v, func(f StringField, value string) (string, error) {
+		if !f.Tagged {
+			return value, nil
+		}
+		sanitized, err := s.Sanitize(value, f.Context)
+		if err != nil {
+			errs = append(errs, &FieldError{Key: strings.TrimPrefix(f.Path, "."), Err: err})
+			return value, nil
+		}
+		return sanitized, nil
+	})
+	if err != nil {
+		var fe *FieldError
+		if errors.As(err, &fe) {
+			fe.Key = strings.TrimPrefix(fe.Key, ".")
+		}
+		return err
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,168 @@
+package safeinput
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ravisastryk/go-safeinput/path"
+)
+
+type profileAddress struct {
+	City string `sanitize:"html"`
+	Zip  string
+}
+
+type profileForm struct {
+	Name     string            `sanitize:"html"`
+	Website  string            `sanitize:"url"`
+	Avatar   string            `sanitize:"filename"`
+	Upload   string            `sanitize:"path"`
+	Tags     []string          `sanitize:"html"`
+	Meta     map[string]string `sanitize:"log"`
+	Nickname *string           `sanitize:"html"`
+	Address  profileAddress
+	Previous *profileAddress
+	Raw      string
+	Skipped  []string `sanitize:"-"`
+	Any      any      `sanitize:"html"`
+	internal string   `sanitize:"html"`
+}
+
+func TestSanitizeStruct(t *testing.T) {
+	nick := "<b>al</b>"
+	form := profileForm{
+		Name:     "<i>Alice</i>",
+		Website:  "https://Example.com/me",
+		Avatar:   "my photo?.png",
+		Upload:   "uploads/a.png",
+		Tags:     []string{"<b>go</b>", "rust"},
+		Meta:     map[string]string{"ref": "a\nb"},
+		Nickname: &nick,
+		Address:  profileAddress{City: "<u>Paris</u>", Zip: "<75001>"},
+		Previous: &profileAddress{City: "<s>Lyon</s>"},
+		Raw:      "<b>raw</b>",
+		Skipped:  []string{"<b>x</b>"},
+		Any:      []any{"<i>y</i>", 1},
+		internal: "<b>x</b>",
+	}
+	if err := Default().SanitizeStruct(&form); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name, got, want string
+	}{
+		{"field", form.Name, "Alice"},
+		{"url", form.Website, "https://example.com/me"},
+		{"filename", form.Avatar, "my photo_.png"},
+		{"path", form.Upload, "uploads/a.png"},
+		{"slice", form.Tags[0], "go"},
+		{"map", form.Meta["ref"], `a\nb`},
+		{"pointer", nick, "al"},
+		{"nested", form.Address.City, "Paris"},
+		{"nested untagged", form.Address.Zip, "<75001>"},
+		{"nested pointer", form.Previous.City, "Lyon"},
+		{"untagged", form.Raw, "<b>raw</b>"},
+		{"skip", form.Skipped[0], "<b>x</b>"},
+		{"interface", form.Any.([]any)[0].(string), "y"},
+		{"unexported", form.internal, "<b>x</b>"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeStruct_ContextNames(t *testing.T) {
+	var form struct {
+		Path  string `sanitize:"filepath"`
+		Value string `sanitize:"sqlvalue"`
+		Body  string `sanitize:"HTMLBody"`
+	}
+	form.Path, form.Value, form.Body = "a//b", "42", "<b>x</b>"
+	if err := Default().SanitizeStruct(&form); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if form.Path != "a/b" || form.Value != "42" || form.Body != "x" {
+		t.Errorf("got %+v", form)
+	}
+
+	form.Path = "../etc/passwd"
+	if err := Default().SanitizeStruct(&form); !errors.Is(err, path.ErrPathTraversal) {
+		t.Errorf("expected the filepath tag to reject traversal, got %v", err)
+	}
+}
+
+func TestSanitizeStruct_Errors(t *testing.T) {
+	form := profileForm{
+		Name:    "Alice",
+		Website: "javascript:alert(1)",
+		Avatar:  "a.png",
+		Upload:  "../etc/passwd",
+		Tags:    []string{"ok", "a\x00b"},
+	}
+	s := New(Config{StrictMode: true})
+	err := s.SanitizeStruct(&form)
+
+	errs := fieldErrors(t, err)
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %v", errs)
+	}
+	if !errors.Is(errs["Website"], ErrURLSchemeNotAllowed) ||
+		!errors.Is(errs["Upload"], path.ErrPathTraversal) ||
+		!errors.Is(errs["Tags[]"], ErrNullByte) {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	// Rejected fields keep their value
+	if form.Upload != "../etc/passwd" || form.Name != "Alice" {
+		t.Errorf("unexpected values %+v", form)
+	}
+
+	type badTag struct {
+		Inner struct {
+			Name string `sanitize:"markdown"`
+		}
+	}
+	err = s.SanitizeStruct(&badTag{})
+	var fe *FieldError
+	if !errors.Is(err, ErrUnknownContext) || !errors.As(err, &fe) || fe.Key != "Inner.Name" {
+		t.Errorf("expected ErrUnknownContext for Inner.Name, got %v", err)
+	}
+
+	for _, target := range []any{nil, form, (*profileForm)(nil), new(string)} {
+		if err := s.SanitizeStruct(target); !errors.Is(err, ErrInvalidTarget) {
+			t.Errorf("SanitizeStruct(%T) = %v, want ErrInvalidTarget", target, err)
+		}
+	}
+}
+
+func TestWalkStrings(t *testing.T) {
+	type node struct {
+		Name string `json:"name" sanitize:"html"`
+		Next *node
+	}
+	loop := &node{Name: "a"}
+	loop.Next = &node{Name: "b", Next: loop}
+
+	var paths []string
+	err := WalkStrings(loop, func(f StringField, value string) (string, error) {
+		paths = append(paths, f.Path+"="+value+":"+f.Field.Tag.Get("json"))
+		return strings.ToUpper(value), nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(paths, ","); got != ".Name=a:name,.Next.Name=b:name" {
+		t.Errorf("unexpected walk %s", got)
+	}
+	if loop.Name != "A" || loop.Next.Name != "B" {
+		t.Errorf("strings not replaced: %q, %q", loop.Name, loop.Next.Name)
+	}
+
+	failure := errors.New("stop")
+	if err := WalkStrings(loop, func(StringField, string) (string, error) { return "", failure }); err != failure {
+		t.Errorf("expected the callback error, got %v", err)
+	}
+}
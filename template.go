@@ -0,0 +1,84 @@
+package safeinput
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Default template action delimiters, shared by Go templates and mustache.
+const (
+	defaultTemplateLeftDelim  = "{{"
+	defaultTemplateRightDelim = "}}"
+)
+
+// EscapeTemplateLiteral escapes a value for inclusion in the text of a Go
+// text/template or html/template, so that it renders as itself and never
+// starts an action. Every "{", every "}}" and every backtick is replaced by
+// an action printing it as a string constant: "{{" becomes {{"{"}}{{"{"}}.
+func EscapeTemplateLiteral(input string) string {
+	out, _ := sanitizeTemplateLiteral(input, defaultTemplateLeftDelim, defaultTemplateRightDelim, false)
+	return out
+}
+
+// sanitizeTemplateLiteral implements the TemplateLiteral context for the
+// given delimiters. With reject set, input containing either delimiter or a
+// backtick fails with ErrTemplateAction naming the sequence and its offset,
+// which also suits engines such as mustache that cannot escape delimiters.
+// Otherwise the escaping of EscapeTemplateLiteral applies, with every byte
+// equal to the first byte of left escaped so that no delimiter can form
+// across the escaped output.
+func sanitizeTemplateLiteral(input, left, right string, reject bool) (string, error) {
+	if left == "" {
+		left = defaultTemplateLeftDelim
+	}
+	if right == "" {
+		right = defaultTemplateRightDelim
+	}
+
+	if reject {
+		for i := 0; i < len(input); i++ {
+			if seq := templateSequence(input[i:], left, right); seq != "" {
+				return "", fmt.Errorf("%w: %q at offset %d", ErrTemplateAction, seq, i)
+			}
+		}
+		return input, nil
+	}
+
+	var b strings.Builder
+	b.Grow(len(input))
+	for i := 0; i < len(input); {
+		switch seq := templateSequence(input[i:], left, right); {
+		case seq == right || seq == "`":
+			writeTemplateString(&b, seq, left, right)
+			i += len(seq)
+		case input[i] == left[0]:
+			writeTemplateString(&b, input[i:i+1], left, right)
+			i++
+		default:
+			b.WriteByte(input[i])
+			i++
+		}
+	}
+	return b.String(), nil
+}
+
+// templateSequence returns the delimiter or backtick s starts with, if any.
+func templateSequence(s, left, right string) string {
+	switch {
+	case strings.HasPrefix(s, left):
+		return left
+	case strings.HasPrefix(s, right):
+		return right
+	case strings.HasPrefix(s, "`"):
+		return "`"
+	}
+	return ""
+}
+
+// writeTemplateString writes an action printing s as a string constant.
+func writeTemplateString(b *strings.Builder, s, left, right string) {
+	b.WriteString(left)
+	b.WriteString(strconv.Quote(s))
+	b.WriteString(right)
+}
@@ -0,0 +1,95 @@
+package safeinput
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// templateInputs are values that must render as themselves once escaped
+var templateInputs = []string{
+	"Hello, customer",
+	"{{.Secret}}",
+	`{{template "admin" .}}`,
+	"{{call .Func}}",
+	"{{{triple}}}",
+	"{{- trim -}}",
+	"a{`",
+	"{}}",
+	"}}{{",
+	"{{`raw`}}",
+	"{\"json\": {\"nested\": true}}",
+	"[[.Secret]]",
+	"",
+}
+
+// renderTemplate parses text with the given delimiters and executes it
+// with data that would leak if an action ran
+func renderTemplate(t *testing.T, text, left, right string) string {
+	t.Helper()
+	tmpl, err := template.New("t").Delims(left, right).Parse(text)
+	if err != nil {
+		t.Fatalf("escaped text %q does not parse: %v", text, err)
+	}
+	var b strings.Builder
+	data := map[string]any{"Secret": "LEAKED", "Func": func() string { return "CALLED" }}
+	if err := tmpl.Execute(&b, data); err != nil {
+		t.Fatalf("escaped text %q does not execute: %v", text, err)
+	}
+	return b.String()
+}
+
+func TestEscapeTemplateLiteral(t *testing.T) {
+	for _, input := range templateInputs {
+		escaped := EscapeTemplateLiteral(input)
+		if got := renderTemplate(t, "<p>"+escaped+"</p>", "{{", "}}"); got != "<p>"+input+"</p>" {
+			t.Errorf("EscapeTemplateLiteral(%q) = %q renders %q", input, escaped, got)
+		}
+	}
+	if got := EscapeTemplateLiteral("{{"); got != `{{"{"}}{{"{"}}` {
+		t.Errorf("EscapeTemplateLiteral(%q) = %q", "{{", got)
+	}
+}
+
+func TestSanitize_TemplateLiteral(t *testing.T) {
+	// Custom delimiters escape their own sequences and leave {{ alone
+	s := New(Config{TemplateLeftDelim: "[[", TemplateRightDelim: "]]"})
+	for _, input := range templateInputs {
+		escaped, err := s.Sanitize(input, TemplateLiteral)
+		if err != nil {
+			t.Fatalf("Sanitize(%q) unexpected error %v", input, err)
+		}
+		if got := renderTemplate(t, escaped, "[[", "]]"); got != input {
+			t.Errorf("Sanitize(%q) = %q renders %q", input, escaped, got)
+		}
+	}
+
+	// Strict mode rejects actions, naming the sequence and offset
+	tests := []struct {
+		cfg     Config
+		input   string
+		wantMsg string
+	}{
+		{Config{StrictMode: true}, "Hi {{.Name}}", `"{{" at offset 3`},
+		{Config{StrictMode: true}, "Hi {{{name}}}", `"{{" at offset 3`},
+		{Config{StrictMode: true}, "done }}", `"}}" at offset 5`},
+		{Config{StrictMode: true}, "use `code`", "\"`\" at offset 4"},
+		{Config{StrictMode: true, TemplateLeftDelim: "<%", TemplateRightDelim: "%>"}, "x <%= y %>", `"<%" at offset 2`},
+	}
+	for _, tt := range tests {
+		_, err := New(tt.cfg).Sanitize(tt.input, TemplateLiteral)
+		if !errors.Is(err, ErrTemplateAction) {
+			t.Errorf("strict Sanitize(%q) error = %v, want ErrTemplateAction", tt.input, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.wantMsg) {
+			t.Errorf("strict Sanitize(%q) error = %q, want it to contain %s", tt.input, err, tt.wantMsg)
+		}
+	}
+
+	// Input without delimiters passes strict mode unchanged
+	if got, err := Default().Sanitize("{single} braces", TemplateLiteral); err != nil || got != "{single} braces" {
+		t.Errorf("strict plain input: got %q, %v", got, err)
+	}
+}
@@ -0,0 +1,188 @@
+package safeinput
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/ravisastryk/go-safeinput/path"
+)
+
+// defaultURLSchemes is the scheme allowlist used by SanitizeURL and by a
+// Sanitizer whose config does not set AllowedURLSchemes.
+var defaultURLSchemes = []string{"https", "http"}
+
+// hostRequiredSchemes are the schemes whose URLs browsers always resolve
+// against a host, so "https:evil.com" and "https:///evil.com" are rejected
+// rather than passed through as hostless URLs.
+var hostRequiredSchemes = map[string]bool{
+	"http": true, "https": true, "ws": true, "wss": true, "ftp": true,
+}
+
+// SanitizeURL validates an absolute http or https URL, such as a redirect
+// target or a link supplied by a user (CWE-601, CWE-79). Relative and
+// scheme-relative URLs like "//evil.com", URLs with userinfo like
+// "https://trusted.com@evil.com", and URLs containing backslashes, spaces
+// or control characters are rejected. It returns the normalized URL, with
+// the scheme and host lowercased.
+func SanitizeURL(input string) (string, error) {
+	return sanitizeURL(input, nil, nil, nil)
+}
+
+// sanitizeURL implements the URL context. An empty schemes list means
+// defaultURLSchemes, and an empty allowed list allows every host that is
+// not denied.
+func sanitizeURL(input string, schemes, allowed, denied []string) (string, error) {
+	if input == "" {
+		return "", ErrInvalidURL
+	}
+	for i := 0; i < len(input); i++ {
+		// Browsers treat a backslash like a slash, and strip or skip
+		// whitespace and control characters, so none of them can be trusted
+		// to mean what net/url parses them as.
+		if c := input[i]; c <= ' ' || c == 0x7f || c == '\\' {
+			return "", ErrInvalidURL
+		}
+	}
+
+	u, err := url.Parse(input)
+	if err != nil || u.Scheme == "" {
+		return "", ErrInvalidURL
+	}
+	if u.User != nil {
+		return "", ErrURLCredentials
+	}
+	if len(schemes) == 0 {
+		schemes = defaultURLSchemes
+	}
+	if !containsFold(schemes, u.Scheme) {
+		return "", ErrURLSchemeNotAllowed
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		if u.Host != "" || hostRequiredSchemes[u.Scheme] {
+			return "", ErrInvalidURL
+		}
+		if len(allowed) > 0 {
+			return "", ErrURLHostNotAllowed
+		}
+		return u.String(), nil
+	}
+	if matchesHost(denied, host) || (len(allowed) > 0 && !matchesHost(allowed, host)) {
+		return "", ErrURLHostNotAllowed
+	}
+
+	u.Host = strings.ToLower(u.Host)
+	return u.String(), nil
+}
+
+// matchesHost reports whether host matches one of patterns. A pattern
+// matches the same host name, ignoring case and a trailing dot, and a
+// pattern starting with "*." matches any subdomain of the rest of it.
+func matchesHost(patterns []string, host string) bool {
+	for _, p := range patterns {
+		p = strings.TrimSuffix(strings.ToLower(p), ".")
+		if suffix, ok := strings.CutPrefix(p, "*"); ok {
+			if strings.HasPrefix(suffix, ".") && strings.HasSuffix(host, suffix) {
+				return true
+			}
+			continue
+		}
+		if host == p {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeURLQuery implements the URLQuery context. It percent-encodes
+// input as url.QueryEscape does, so that it parses back as one value of a
+// query parameter. In strict mode input is instead taken to be encoded
+// already and returned unchanged, unless it holds a raw &, ;, = or #,
+// which would end the value, a control character or a malformed percent
+// escape.
+func sanitizeURLQuery(input string, strict bool) (string, error) {
+	if !strict {
+		return url.QueryEscape(input), nil
+	}
+	for i := 0; i < len(input); i++ {
+		switch c := input[i]; {
+		case c == '&' || c == ';' || c == '=' || c == '#' || c < ' ' || c == 0x7f:
+			return "", &SanitizeError{Kind: ErrorKindInvalidChar, Offset: i, Text: input[i : i+1], Err: ErrInvalidQueryValue}
+		case c == '%' && !isPercentEscape(input, i):
+			return "", &SanitizeError{Kind: ErrorKindInvalidChar, Offset: i, Text: input[i:min(i+3, len(input))], Err: ErrInvalidQueryValue}
+		}
+	}
+	return input, nil
+}
+
+// sanitizeURLPath implements the URLPath context. It percent-encodes each
+// segment of input as url.PathEscape does, keeping the slashes between them
+// unless segment is set, and also encodes colons so that the path cannot be
+// read as a scheme and the second of two leading slashes so that it cannot
+// be read as a host. In strict mode input is instead taken to be encoded
+// already and returned unchanged, unless it holds a raw ? or #, which would
+// end the path, a backslash, a colon before the first slash, two leading
+// slashes, a slash when segment is set, or a malformed percent escape.
+// Either way, control characters and the traversal sequences the path
+// package blocks, encoded or not, are rejected.
+func sanitizeURLPath(input string, segment, strict bool) (string, error) {
+	if seq, offset := path.FindTraversal(input); offset >= 0 {
+		return "", &SanitizeError{Kind: ErrorKindTraversal, Offset: offset, Text: seq, Err: path.ErrPathTraversal}
+	}
+	colonEnd := strings.IndexByte(input, '/')
+	if segment || colonEnd < 0 {
+		colonEnd = len(input)
+	}
+	for i := 0; i < len(input); i++ {
+		switch c := input[i]; {
+		case c < ' ' || c == 0x7f:
+			return "", &SanitizeError{Kind: ErrorKindInvalidChar, Offset: i, Text: input[i : i+1], Err: path.ErrInvalidCharacter}
+		case strict && (c == '?' || c == '#' || c == '\\' || (c == ':' && i < colonEnd) || (c == '/' && (segment || i == 1 && input[0] == '/'))):
+			return "", &SanitizeError{Kind: ErrorKindInvalidChar, Offset: i, Text: input[i : i+1], Err: ErrInvalidURLPath}
+		case strict && c == '%' && !isPercentEscape(input, i):
+			return "", &SanitizeError{Kind: ErrorKindInvalidChar, Offset: i, Text: input[i:min(i+3, len(input))], Err: ErrInvalidURLPath}
+		}
+	}
+
+	if strict {
+		return input, nil
+	}
+	if segment {
+		return escapePathSegment(input), nil
+	}
+	segments := strings.Split(input, "/")
+	for i, seg := range segments {
+		segments[i] = escapePathSegment(seg)
+	}
+	escaped := strings.Join(segments, "/")
+	if strings.HasPrefix(escaped, "//") {
+		// a network-path reference: //host/path
+		escaped = "/%2F" + escaped[2:]
+	}
+	return escaped, nil
+}
+
+// escapePathSegment percent-encodes s as url.PathEscape does, and colons.
+func escapePathSegment(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), ":", "%3A")
+}
+
+// isPercentEscape reports whether s has a percent escape at i.
+func isPercentEscape(s string, i int) bool {
+	return i+2 < len(s) && s[i] == '%' && isHexDigit(s[i+1]) && isHexDigit(s[i+2])
+}
+
+// isHexDigit reports whether c is a hexadecimal digit.
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
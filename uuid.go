@@ -0,0 +1,61 @@
+package safeinput
+
+import (
+	"slices"
+	"strings"
+)
+
+// SanitizeUUID validates a UUID in the canonical 8-4-4-4-12 hexadecimal
+// form, in either case, and returns it lowercased. Any other form is
+// rejected with ErrInvalidUUID.
+func SanitizeUUID(input string) (string, error) {
+	return sanitizeUUID(input, false, nil)
+}
+
+// sanitizeUUID implements the UUID context. With wrapped set, the forms
+// "{...}" and "urn:uuid:..." are accepted too. A non-empty versions list
+// requires an RFC 4122 variant UUID with one of the listed versions.
+func sanitizeUUID(input string, wrapped bool, versions []int) (string, error) {
+	if wrapped {
+		if len(input) > 9 && strings.EqualFold(input[:9], "urn:uuid:") {
+			input = input[9:]
+		} else if len(input) > 2 && input[0] == '{' && input[len(input)-1] == '}' {
+			input = input[1 : len(input)-1]
+		}
+	}
+	if len(input) != 36 {
+		return "", ErrInvalidUUID
+	}
+
+	b := []byte(input)
+	for i, c := range b {
+		switch {
+		case i == 8 || i == 13 || i == 18 || i == 23:
+			if c != '-' {
+				return "", ErrInvalidUUID
+			}
+		case c >= '0' && c <= '9', c >= 'a' && c <= 'f':
+		case c >= 'A' && c <= 'F':
+			b[i] = c + 'a' - 'A'
+		default:
+			return "", ErrInvalidUUID
+		}
+	}
+
+	if len(versions) > 0 {
+		version := int(hexValue(b[14]))
+		variant := hexValue(b[19])
+		if variant&0xc != 0x8 || !slices.Contains(versions, version) {
+			return "", ErrUUIDVersionNotAllowed
+		}
+	}
+	return string(b), nil
+}
+
+// hexValue returns the value of a lowercase hexadecimal digit.
+func hexValue(c byte) byte {
+	if c >= 'a' {
+		return c - 'a' + 10
+	}
+	return c - '0'
+}
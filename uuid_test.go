@@ -0,0 +1,74 @@
+package safeinput
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSanitizeUUID(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"f47ac10b-58cc-4372-a567-0e02b2c3d479", "f47ac10b-58cc-4372-a567-0e02b2c3d479", nil},
+		{"F47AC10B-58CC-4372-A567-0E02B2C3D479", "f47ac10b-58cc-4372-a567-0e02b2c3d479", nil},
+		{"00000000-0000-0000-0000-000000000000", "00000000-0000-0000-0000-000000000000", nil},
+
+		{"", "", ErrInvalidUUID},
+		{"f47ac10b58cc4372a5670e02b2c3d479", "", ErrInvalidUUID},
+		{"{f47ac10b-58cc-4372-a567-0e02b2c3d479}", "", ErrInvalidUUID},
+		{"urn:uuid:f47ac10b-58cc-4372-a567-0e02b2c3d479", "", ErrInvalidUUID},
+		{"f47ac10b-58cc-4372-a567-0e02b2c3d47g", "", ErrInvalidUUID},
+		{"f47ac10b-58cc-4372-a567+0e02b2c3d479", "", ErrInvalidUUID},
+		{"f47ac10b-58cc4-372-a567-0e02b2c3d479", "", ErrInvalidUUID},
+		{"f47ac10b-58cc-4372-a567-0e02b2c3d479 ", "", ErrInvalidUUID},
+		{"f47ac10b-58cc-4372-a567-0e02b2c3d4'--", "", ErrInvalidUUID},
+		{"1 OR 1=1", "", ErrInvalidUUID},
+	}
+	for _, tt := range tests {
+		got, err := SanitizeUUID(tt.input)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("SanitizeUUID(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("SanitizeUUID(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSanitize_UUID(t *testing.T) {
+	const v4 = "f47ac10b-58cc-4372-a567-0e02b2c3d479"
+	const v7 = "01890a5d-ac96-774b-bcce-b302099a8057"
+	tests := []struct {
+		name    string
+		cfg     Config
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"braces", Config{AllowWrappedUUIDs: true}, "{F47AC10B-58CC-4372-A567-0E02B2C3D479}", v4, nil},
+		{"urn", Config{AllowWrappedUUIDs: true}, "URN:UUID:" + v4, v4, nil},
+		{"plain with wrapping allowed", Config{AllowWrappedUUIDs: true}, v4, v4, nil},
+		{"unbalanced braces", Config{AllowWrappedUUIDs: true}, "{" + v4, "", ErrInvalidUUID},
+		{"both wrappers", Config{AllowWrappedUUIDs: true}, "urn:uuid:{" + v4 + "}", "", ErrInvalidUUID},
+		{"version 4", Config{UUIDVersions: []int{4}}, v4, v4, nil},
+		{"version 7 rejected", Config{UUIDVersions: []int{4}}, v7, "", ErrUUIDVersionNotAllowed},
+		{"version set", Config{UUIDVersions: []int{4, 7}}, v7, v7, nil},
+		{"nil UUID rejected", Config{UUIDVersions: []int{4}}, "00000000-0000-0000-0000-000000000000", "", ErrUUIDVersionNotAllowed},
+		{"wrong variant", Config{UUIDVersions: []int{4}}, "f47ac10b-58cc-4372-c567-0e02b2c3d479", "", ErrUUIDVersionNotAllowed},
+		{"malformed before version", Config{UUIDVersions: []int{4}}, "not-a-uuid", "", ErrInvalidUUID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.cfg).Sanitize(tt.input, UUID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Sanitize(%q, UUID) error = %v, want %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Sanitize(%q, UUID) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package safeinput
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Validate reports whether input can be used in ctx as it is, without
+// building sanitized output. It returns an error matching, under errors.Is,
+// the one Sanitize would return, or ErrUnsafeInput if Sanitize would have to
+// change input: when HTMLBody would remove markup, ShellArg would drop
+// characters, or an escaping context such as HTMLAttribute or LDAPFilter
+// would escape something. FilePath and the SQL contexts reuse the checks of
+// the path and sql packages. Null bytes fail with ErrNullByte even when
+// StripNullBytes is set, and bidirectional control characters with
+// ErrBidiControl when StripBidiControls or RejectBidiControls is set.
+// Likewise invisible characters fail with ErrInvisibleCharacter when
+// StripInvisible or RejectInvisible is set.
+//
+// Contexts that validate and normalize, such as FilePath, SQLValue, URL,
+// Hostname and UUID, only fail when Sanitize would reject input; a path
+// that Sanitize would clean or a host name it would lowercase is valid.
+// HTMLBody ignores surrounding whitespace, which Sanitize trims.
+//
+// With NormalizeUnicode set, input that is not in NFC is checked in its
+// normalized form and, outside the normalizing contexts, fails with
+// ErrUnsafeInput since Sanitize would compose it.
+func (s *Sanitizer) Validate(input string, ctx Context) error {
+	normalized, err := s.normalize(input, ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.checkLength(normalized, ctx); err != nil {
+		return err
+	}
+	if strings.IndexByte(normalized, 0) >= 0 {
+		return ErrNullByte
+	}
+	if (s.config.RejectBidiControls || s.config.StripBidiControls) && IndexBidiControl(normalized) >= 0 {
+		return ErrBidiControl
+	}
+	if (s.config.RejectInvisible || s.config.StripInvisible) && strings.IndexFunc(normalized, isInvisibleRune) >= 0 {
+		return ErrInvisibleCharacter
+	}
+
+	err = s.validate(normalized, ctx)
+	if err == nil && normalized != input && !normalizingContexts[ctx] {
+		return ErrUnsafeInput
+	}
+	return err
+}
+
+// normalizingContexts are the contexts that validate and normalize input,
+// for which Validate only reports rejections.
+var normalizingContexts = map[Context]bool{
+	SQLIdentifier: true, SQLValue: true, FilePath: true, URL: true,
+	RegexPattern: true, Hostname: true, IPAddress: true, UUID: true,
+}
+
+// validate implements Validate for input that is normalized, within the
+// length limit and free of null bytes.
+func (s *Sanitizer) validate(input string, ctx Context) error {
+	switch ctx {
+	case SQLIdentifier:
+		if err := s.checkMixedScript(input, ctx); err != nil {
+			return err
+		}
+		_, err := s.sql.SanitizeIdentifier(input)
+		return err
+	case SQLValue:
+		_, err := s.sql.ValidateValue(input)
+		return err
+	case FilePath:
+		_, err := s.path.Sanitize(input)
+		return err
+	case HTMLBody:
+		return unsafeIf(!s.html.IsSafeBody(input))
+	case HTMLAttribute:
+		return unsafeIf(strings.ContainsAny(input, `<>&'"`))
+	case ShellArg:
+		return unsafeIf(strings.ContainsFunc(input, func(r rune) bool { return !isAllowedShellRune(r, s.shellExtra) }))
+	case LDAPFilter:
+		return unsafeIf(strings.ContainsAny(input, `*()\`))
+	case JSONString:
+		return unsafeIf(needsJSONEscape(input))
+	case RegexLiteral:
+		return unsafeIf(regexp.QuoteMeta(input) != input)
+	default:
+		out, err := s.sanitizeNormalized(input, ctx, nil)
+		if err != nil || normalizingContexts[ctx] {
+			return err
+		}
+		return unsafeIf(out != input)
+	}
+}
+
+// unsafeIf returns ErrUnsafeInput if unsafe is set.
+func unsafeIf(unsafe bool) error {
+	if unsafe {
+		return ErrUnsafeInput
+	}
+	return nil
+}
+
+// needsJSONEscape reports whether EscapeJSONString would change input.
+func needsJSONEscape(input string) bool {
+	for i := 0; i < len(input); {
+		c := input[i]
+		if c < utf8.RuneSelf {
+			if c < 0x20 || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(input[i:])
+		if (r == utf8.RuneError && size == 1) || r == '\u2028' || r == '\u2029' {
+			return true
+		}
+		i += size
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package safeinput
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ravisastryk/go-safeinput/path"
+	"github.com/ravisastryk/go-safeinput/sql"
+)
+
+func TestValidate(t *testing.T) {
+	s := Default()
+	tests := []struct {
+		input   string
+		ctx     Context
+		wantErr error
+	}{
+		{"Hello, world", HTMLBody, nil},
+		{"  padded  ", HTMLBody, nil},
+		{"<script>alert(1)</script>", HTMLBody, ErrUnsafeInput},
+		{"<b>bold</b>", HTMLBody, ErrUnsafeInput},
+		{"plain value", HTMLAttribute, nil},
+		{`" onmouseover="x`, HTMLAttribute, ErrUnsafeInput},
+		{"a&b", URLQuery, ErrInvalidQueryValue},
+		{"file-1.txt", ShellArg, nil},
+		{"file; rm -rf /", ShellArg, ErrUnsafeInput},
+		{"alice", LDAPFilter, nil},
+		{"*)(uid=*", LDAPFilter, ErrUnsafeInput},
+		{"café", JSONString, nil},
+		{`say "hi"`, JSONString, ErrUnsafeInput},
+		{"a\u2028b", JSONString, ErrUnsafeInput},
+		{"bad\xffutf8", JSONString, ErrUnsafeInput},
+		{"abc", RegexLiteral, nil},
+		{"a.b", RegexLiteral, ErrUnsafeInput},
+		{"users", SQLIdentifier, nil},
+		{"users;drop", SQLIdentifier, sql.ErrInvalidIdentifier},
+		{"' OR 1=1--", SQLValue, sql.ErrSuspiciousPattern},
+		{"a//b/./c", FilePath, nil},
+		{"../etc/passwd", FilePath, path.ErrPathTraversal},
+		{"Example.COM", Hostname, nil},
+		{"https://trusted.com@evil.com", URL, ErrURLCredentials},
+		{"F47AC10B-58CC-4372-A567-0E02B2C3D479", UUID, nil},
+		{"value", HTTPHeader, nil},
+		{"a\r\nb", HTTPHeader, ErrHeaderInjection},
+		{"line\nbreak", LogLine, ErrUnsafeInput},
+		{"=1+2", CSVField, ErrUnsafeInput},
+		{"a\x00b", HTMLBody, ErrNullByte},
+		{strings.Repeat("a", 10001), HTMLBody, ErrInputTooLong},
+		{"x", Context(999), ErrUnknownContext},
+	}
+	for _, tt := range tests {
+		want := tt.wantErr
+		if err := s.Validate(tt.input, tt.ctx); !errors.Is(err, want) {
+			t.Errorf("Validate(%.40q, %v) = %v, want %v", tt.input, tt.ctx, err, want)
+		}
+		if got := s.IsValid(tt.input, tt.ctx); got != (want == nil) {
+			t.Errorf("IsValid(%.40q, %v) = %v", tt.input, tt.ctx, got)
+		}
+	}
+}
+
+// TestValidate_MatchesSanitize checks that Validate accepts exactly the
+// inputs Sanitize leaves unchanged, or for normalizing contexts the
+// inputs Sanitize accepts
+func TestValidate_MatchesSanitize(t *testing.T) {
+	normalizing := map[Context]bool{
+		SQLIdentifier: true, SQLValue: true, FilePath: true, URL: true,
+		RegexPattern: true, Hostname: true, IPAddress: true, UUID: true,
+	}
+	inputs := []string{
+		"", "plain", "  spaced  ", "<i>x</i>", "<script>x</script>", "a onclick=x",
+		`"quoted" & 'single'`, "a*b(c)\\d", "line\nbreak\ttab", "a\u2028b",
+		"bad\xff", "{{.X}}", "=SUM(A1)", "a;b,c d", "../x", "x.y", "CON",
+		"https://example.com/", "Example.com", "127.0.0.1", "café", "$(id)",
+	}
+	for _, cfg := range []Config{{StrictMode: true, StripNullBytes: true}, {}} {
+		s := New(cfg)
+		for ctx := HTMLBody; ctx.String() != "Unknown"; ctx++ {
+			for _, input := range inputs {
+				out, err := s.Sanitize(input, ctx)
+				want := err == nil
+				if !normalizing[ctx] {
+					if ctx == HTMLBody {
+						input = strings.TrimSpace(input)
+					}
+					want = want && out == input
+				}
+				if got := s.Validate(input, ctx) == nil; got != want {
+					t.Errorf("%+v: Validate(%q, %v) valid = %v, want %v (Sanitize %q, %v)",
+						cfg, input, ctx, got, want, out, err)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkIsValid_HTMLBody(b *testing.B) {
+	s := Default()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = s.IsValid("<script>alert('xss')</script>Hello", HTMLBody)
+	}
+}
+
+func BenchmarkIsValidViaSanitize_HTMLBody(b *testing.B) {
+	s := Default()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := s.Sanitize("<script>alert('xss')</script>Hello", HTMLBody)
+		_ = err == nil
+	}
+}
+
+func BenchmarkIsValid_ShellArg(b *testing.B) {
+	s := Default()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = s.IsValid("user/uploads/avatar-2024.png", ShellArg)
+	}
+}
+
+func BenchmarkIsValidViaSanitize_ShellArg(b *testing.B) {
+	s := Default()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := s.Sanitize("user/uploads/avatar-2024.png", ShellArg)
+		_ = err == nil
+	}
+}
+
+func BenchmarkIsValid_FilePath(b *testing.B) {
+	s := Default()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = s.IsValid("../../etc/passwd", FilePath)
+	}
+}
+
+func BenchmarkIsValidViaSanitize_FilePath(b *testing.B) {
+	s := Default()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := s.Sanitize("../../etc/passwd", FilePath)
+		_ = err == nil
+	}
+}
+
+func BenchmarkIsValid_SQLValue(b *testing.B) {
+	s := Default()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = s.IsValid("1' OR '1'='1", SQLValue)
+	}
+}
+
+func BenchmarkIsValidViaSanitize_SQLValue(b *testing.B) {
+	s := Default()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := s.Sanitize("1' OR '1'='1", SQLValue)
+		_ = err == nil
+	}
+}
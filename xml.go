@@ -0,0 +1,72 @@
+package safeinput
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// EscapeXMLText escapes a value for XML character data, replacing &, < and
+// > with entities and CR with a character reference so that it survives
+// line-end normalization. Characters that are not allowed in XML 1.0, such
+// as most C0 controls, are stripped.
+func EscapeXMLText(input string) string {
+	out, _ := escapeXML(input, false, false)
+	return out
+}
+
+// EscapeXMLAttr escapes a value for a quoted XML attribute. In addition to
+// the escaping done by EscapeXMLText, quotes are replaced with entities and
+// tab and LF with character references, so that attribute value
+// normalization does not turn them into spaces.
+func EscapeXMLAttr(input string) string {
+	out, _ := escapeXML(input, true, false)
+	return out
+}
+
+// escapeXML implements the XMLText and XMLAttr contexts. With reject set,
+// characters not allowed in XML 1.0 fail with ErrInvalidXMLChar instead of
+// being stripped.
+func escapeXML(input string, attr, reject bool) (string, error) {
+	var b strings.Builder
+	b.Grow(len(input))
+	for i := 0; i < len(input); {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		i += size
+		if (r == utf8.RuneError && size == 1) || !isXMLChar(r) {
+			if reject {
+				return "", ErrInvalidXMLChar
+			}
+			continue
+		}
+
+		switch {
+		case r == '&':
+			b.WriteString("&amp;")
+		case r == '<':
+			b.WriteString("&lt;")
+		case r == '>':
+			b.WriteString("&gt;")
+		case r == '\r':
+			b.WriteString("&#xD;")
+		case attr && r == '"':
+			b.WriteString("&quot;")
+		case attr && r == '\'':
+			b.WriteString("&apos;")
+		case attr && r == '\t':
+			b.WriteString("&#x9;")
+		case attr && r == '\n':
+			b.WriteString("&#xA;")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String(), nil
+}
+
+// isXMLChar reports whether r matches the Char production of XML 1.0.
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		(r >= 0x20 && r <= 0xd7ff) ||
+		(r >= 0xe000 && r <= 0xfffd) ||
+		(r >= 0x10000 && r <= 0x10ffff)
+}
@@ -0,0 +1,101 @@
+package safeinput
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// xmlRoundTrip is the set of values the XML tests expect to survive
+// escaping and parsing unchanged
+var xmlRoundTrip = []string{
+	"plain text",
+	"<script>alert('x')</script>",
+	`Tom & "Jerry" <tj@example.com>`,
+	"]]><!-- --><?pi?>",
+	"&amp; already escaped",
+	"tab\tnewline\ncarriage\rreturn\r\n",
+	"naïve café 日本 😀",
+	"\ufffd replacement char",
+	"",
+}
+
+func TestEscapeXMLText(t *testing.T) {
+	for _, input := range xmlRoundTrip {
+		escaped := EscapeXMLText(input)
+		var got struct {
+			Text string `xml:",chardata"`
+		}
+		if err := xml.Unmarshal([]byte("<v>"+escaped+"</v>"), &got); err != nil {
+			t.Errorf("EscapeXMLText(%q) = %q does not parse: %v", input, escaped, err)
+			continue
+		}
+		if got.Text != input {
+			t.Errorf("EscapeXMLText(%q) round trip = %q", input, got.Text)
+		}
+	}
+}
+
+func TestEscapeXMLAttr(t *testing.T) {
+	for _, input := range xmlRoundTrip {
+		escaped := EscapeXMLAttr(input)
+		for _, quote := range []string{`"`, `'`} {
+			var got struct {
+				Attr string `xml:"a,attr"`
+			}
+			doc := "<v a=" + quote + escaped + quote + "/>"
+			if err := xml.Unmarshal([]byte(doc), &got); err != nil {
+				t.Errorf("EscapeXMLAttr(%q) = %q does not parse: %v", input, escaped, err)
+				continue
+			}
+			if got.Attr != input {
+				t.Errorf("EscapeXMLAttr(%q) round trip in %s quotes = %q", input, quote, got.Attr)
+			}
+		}
+	}
+}
+
+func TestEscapeXML_InvalidChars(t *testing.T) {
+	input := "a\x00b\x01c\x0bd\x1fe\ufffef\xffg"
+	if got := EscapeXMLText(input); got != "abcdefg" {
+		t.Errorf("EscapeXMLText(%q) = %q, want %q", input, got, "abcdefg")
+	}
+	if got := EscapeXMLAttr(input); got != "abcdefg" {
+		t.Errorf("EscapeXMLAttr(%q) = %q, want %q", input, got, "abcdefg")
+	}
+
+	// Stripped output still parses
+	var v struct {
+		Text string `xml:",chardata"`
+		Attr string `xml:"a,attr"`
+	}
+	doc := `<v a="` + EscapeXMLAttr(input) + `">` + EscapeXMLText(input) + "</v>"
+	if err := xml.Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatalf("stripped output does not parse: %v", err)
+	}
+}
+
+func TestSanitize_XML(t *testing.T) {
+	// Strict mode rejects characters that escaping cannot make legal
+	s := Default()
+	for _, ctx := range []Context{XMLText, XMLAttr} {
+		if _, err := s.Sanitize("bell\x07", ctx); !errors.Is(err, ErrInvalidXMLChar) {
+			t.Errorf("strict %v: error = %v, want ErrInvalidXMLChar", ctx, err)
+		}
+	}
+	got, err := s.Sanitize(`a<b & "c"`, XMLAttr)
+	if err != nil || got != "a&lt;b &amp; &quot;c&quot;" {
+		t.Errorf("strict XMLAttr: got %q, %v", got, err)
+	}
+
+	// Otherwise they are stripped
+	s = New(Config{})
+	got, err = s.Sanitize("bell\x07<ring>", XMLText)
+	if err != nil || got != "bell&lt;ring&gt;" {
+		t.Errorf("non-strict XMLText: got %q, %v", got, err)
+	}
+	if got, _ := s.Sanitize(strings.Repeat("\x1b", 3), XMLAttr); got != "" {
+		t.Errorf("non-strict XMLAttr: got %q, want empty", got)
+	}
+}